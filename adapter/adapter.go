@@ -10,21 +10,31 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"net/http"
 	"strings"
 
+	"github.com/gogo/googleapis/google/rpc"
+	"github.com/gogo/protobuf/types"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
 	"istio.io/api/mixer/adapter/model/v1beta1"
+	istiopolicy "istio.io/api/policy/v1beta1"
 	"istio.io/istio/mixer/pkg/status"
 
 	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/config"
+	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/extauthz"
+	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/forwardauth"
 	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/policy"
 	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/policy/engine"
 	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/policy/initializer"
 	storePolicy "github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/policy/store/policy"
 	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/strategy"
-	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/strategy/api"
-	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/strategy/web"
+	// Register the built-in strategies
+	_ "github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/strategy/api"
+	_ "github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/strategy/web"
+	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/webhook"
+	authv3 "github.com/ibm-cloud-security/app-identity-and-access-adapter/config/envoy/service/auth/v3"
 	"github.com/ibm-cloud-security/app-identity-and-access-adapter/config/template"
 )
 
@@ -40,9 +50,12 @@ type (
 	AppidAdapter struct {
 		listener    net.Listener
 		server      *grpc.Server
-		apistrategy strategy.Strategy
-		webstrategy strategy.Strategy
+		strategies  map[policy.Type]strategy.Strategy
 		engine      engine.PolicyEngine
+		webhook     *http.Server
+		metrics     *http.Server
+		forwardAuth *http.Server
+		cfg         *config.Config
 	}
 )
 
@@ -56,25 +69,96 @@ func (s *AppidAdapter) HandleAuthnZ(ctx context.Context, r *authnz.HandleAuthnZR
 		return nil, err
 	}
 
+	if s.cfg != nil && s.cfg.CORSPreflightBypass && isPreflight(r) {
+		zap.L().Debug("Allowing CORS preflight request", zap.String("path", r.Instance.Target.Path))
+		return &authnz.HandleAuthnZResponse{
+			Result: &v1beta1.CheckResult{Status: status.OK},
+		}, nil
+	}
+
 	///// Check policy
-	action, err := s.engine.Evaluate(r.Instance.Target)
+	decision, err := s.engine.Evaluate(r.Instance.Target, r.Instance.Request)
 	if err != nil {
 		zap.L().Debug("Could not check policies", zap.Error(err))
 		return nil, err
 	}
 
-	switch action.Type {
-	case policy.JWT:
-		zap.L().Info("Executing JWT policies")
-		return s.apistrategy.HandleAuthnZRequest(r, action)
-	case policy.OIDC:
-		zap.L().Info("Executing OIDC policies")
-		return s.webstrategy.HandleAuthnZRequest(r, action)
+	if len(decision.Actions) == 0 {
+		zap.L().Info("No OIDC/JWT policies configured")
+		return &authnz.HandleAuthnZResponse{
+			Result: &v1beta1.CheckResult{Status: status.OK},
+		}, nil
+	}
+
+	switch decision.Mode {
+	case policy.ALLOF:
+		return s.handleAllOf(r, decision.Actions)
+	case policy.ANYOF:
+		return s.handleAnyOf(r, decision.Actions)
 	default:
+		return s.handleAction(r, firstMatch(r, decision.Actions))
+	}
+}
+
+// handleAllOf requires every action to pass. The first failure is returned,
+// otherwise the headers forwarded by each action are combined.
+func (s *AppidAdapter) handleAllOf(r *authnz.HandleAuthnZRequest, actions []engine.Action) (*authnz.HandleAuthnZResponse, error) {
+	var output *authnz.OutputMsg
+	for i := range actions {
+		response, err := s.handleAction(r, &actions[i])
+		if err != nil || !succeeded(response) {
+			return response, err
+		}
+		output = mergeOutput(output, response.Output)
+	}
+	return &authnz.HandleAuthnZResponse{
+		Result: &v1beta1.CheckResult{Status: status.OK},
+		Output: output,
+	}, nil
+}
+
+// handleAnyOf returns the response of the first action to pass.
+// When every action fails the response of the last action is returned,
+// allowing an interactive OIDC policy listed last to begin a login.
+func (s *AppidAdapter) handleAnyOf(r *authnz.HandleAuthnZRequest, actions []engine.Action) (response *authnz.HandleAuthnZResponse, err error) {
+	for i := range actions {
+		response, err = s.handleAction(r, &actions[i])
+		if err == nil && succeeded(response) {
+			return response, nil
+		}
+		zap.L().Debug("Policy did not pass, trying next policy", zap.String("type", actions[i].Type.String()), zap.Error(err))
+	}
+	return response, err
+}
+
+// handleAction executes a single action using the strategy registered for its type
+func (s *AppidAdapter) handleAction(r *authnz.HandleAuthnZRequest, action *engine.Action) (*authnz.HandleAuthnZResponse, error) {
+	if len(action.Problems) > 0 {
+		zap.L().Error("Denying request protected by an invalid policy", zap.Strings("problems", action.Problems))
+		return buildInternalErrorResponse("invalid policy: cannot authorize request"), nil
+	}
+	if handler, ok := s.strategies[action.Type]; ok {
+		zap.L().Info("Executing policies", zap.String("type", action.Type.String()))
+		return handler.HandleAuthnZRequest(r, action)
+	}
+	switch action.Type {
+	case policy.ALLOW:
+		zap.L().Info("Allowing request by policy")
+		return &authnz.HandleAuthnZResponse{
+			Result: &v1beta1.CheckResult{Status: status.OK},
+		}, nil
+	case policy.DENY:
+		zap.L().Info("Denying request by policy", zap.Int32("status", action.Status))
+		return buildDenyResponse(action.Status), nil
+	case policy.NONE:
 		zap.L().Info("No OIDC/JWT policies configured")
 		return &authnz.HandleAuthnZResponse{
 			Result: &v1beta1.CheckResult{Status: status.OK},
 		}, nil
+	default:
+		// Types registered without a strategy cannot be enforced, so requests they protect are denied
+		zap.L().Error("No strategy is registered for policy type", zap.String("type", action.Type.String()))
+		return buildInternalErrorResponse("policy type cannot be enforced"), nil
 	}
 }
 
@@ -87,6 +171,21 @@ func (s *AppidAdapter) Addr() string {
 
 // Run starts the server run
 func (s *AppidAdapter) Run(shutdown chan error) {
+	if s.webhook != nil {
+		go func() {
+			shutdown <- s.webhook.ListenAndServeTLS(s.cfg.WebhookCertFile, s.cfg.WebhookKeyFile)
+		}()
+	}
+	if s.metrics != nil {
+		go func() {
+			shutdown <- s.metrics.ListenAndServe()
+		}()
+	}
+	if s.forwardAuth != nil {
+		go func() {
+			shutdown <- s.forwardAuth.ListenAndServe()
+		}()
+	}
 	shutdown <- s.server.Serve(s.listener)
 }
 
@@ -100,6 +199,18 @@ func (s *AppidAdapter) Close() error {
 		_ = s.listener.Close()
 	}
 
+	if s.webhook != nil {
+		_ = s.webhook.Close()
+	}
+
+	if s.metrics != nil {
+		_ = s.metrics.Close()
+	}
+
+	if s.forwardAuth != nil {
+		_ = s.forwardAuth.Close()
+	}
+
 	return nil
 }
 
@@ -125,23 +236,41 @@ func NewAppIDAdapter(cfg *config.Config) (Server, error) {
 		return nil, err
 	}
 
-	eng, err := engine.New(localStore)
+	eng, err := engine.New(localStore, cfg.PolicyCacheSize)
 	if err != nil {
 		zap.L().Fatal("Unable to initialize policy engine", zap.Error(err))
 		return nil, err
 	}
 
 	s := &AppidAdapter{
-		listener:    listener,
-		apistrategy: apistrategy.New(),
-		webstrategy: webstrategy.New(cfg, init.GetKubeClient()),
-		server:      grpc.NewServer(),
-		engine:      eng,
+		listener:   listener,
+		strategies: strategy.NewStrategies(cfg, init.GetKubeClient()),
+		server:     grpc.NewServer(),
+		engine:     eng,
+		cfg:        cfg,
 	}
 
 	zap.S().Infof("Listening on: %v", s.Addr())
 
+	if cfg.WebhookPort != 0 {
+		s.webhook = webhook.NewServer(fmt.Sprintf(":%d", cfg.WebhookPort), webhook.New(init.GetKubeClient(), init.GetPoliciesClient()))
+		zap.S().Infof("Serving validating admission webhook on: %v", s.webhook.Addr)
+	}
+
+	if cfg.MetricsPort != 0 {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", promhttp.Handler())
+		s.metrics = &http.Server{Addr: fmt.Sprintf(":%d", cfg.MetricsPort), Handler: mux}
+		zap.S().Infof("Serving metrics on: %v", s.metrics.Addr)
+	}
+
+	if cfg.ForwardAuthPort != 0 {
+		s.forwardAuth = forwardauth.NewServer(fmt.Sprintf(":%d", cfg.ForwardAuthPort), forwardauth.New(s))
+		zap.S().Infof("Serving forward authentication on: %v", s.forwardAuth.Addr)
+	}
+
 	authnz.RegisterHandleAuthnZServiceServer(s.server, s)
+	authv3.RegisterAuthorizationServer(s.server, extauthz.New(s))
 
 	return s, nil
 }
@@ -163,3 +292,93 @@ func validateAuthnzRequest(r *authnz.HandleAuthnZRequest) error {
 	}
 	return nil
 }
+
+// isPreflight returns true for CORS preflight requests: OPTIONS requests carrying
+// the Origin and Access-Control-Request-Method headers
+func isPreflight(r *authnz.HandleAuthnZRequest) bool {
+	if r.Instance.Target.Method != http.MethodOptions {
+		return false
+	}
+	properties := r.Instance.Request.Headers.Properties
+	return properties["origin"].GetStringValue() != "" && properties["access-control-request-method"].GetStringValue() != ""
+}
+
+// firstMatch returns the first action applicable to the request.
+// Policies of types reading the authorization header, such as API policies, apply when it is present;
+// other policies always apply. If no action applies the first action is returned.
+func firstMatch(r *authnz.HandleAuthnZRequest, actions []engine.Action) *engine.Action {
+	for i, action := range actions {
+		if !action.Type.Info().Authorization || r.Instance.Request.Headers.Authorization != "" {
+			return &actions[i]
+		}
+	}
+	return &actions[0]
+}
+
+// buildDenyResponse rejects a request with the given HTTP status code, 403 if it is not set
+func buildDenyResponse(code int32) *authnz.HandleAuthnZResponse {
+	if code == 0 {
+		code = http.StatusForbidden
+	}
+	rpcCode := rpc.PERMISSION_DENIED
+	switch {
+	case code == http.StatusUnauthorized:
+		rpcCode = rpc.UNAUTHENTICATED
+	case code == http.StatusNotFound:
+		rpcCode = rpc.NOT_FOUND
+	case code >= http.StatusInternalServerError:
+		rpcCode = rpc.UNAVAILABLE
+	}
+	return &authnz.HandleAuthnZResponse{
+		Result: &v1beta1.CheckResult{Status: rpc.Status{
+			Code:    int32(rpcCode),
+			Message: "request denied by policy",
+			Details: []*types.Any{status.PackErrorDetail(&istiopolicy.DirectHttpResponse{
+				Code: istiopolicy.HttpStatusCode(code),
+				Body: http.StatusText(int(code)),
+			})},
+		}},
+	}
+}
+
+// buildInternalErrorResponse rejects a request that cannot be authorized with 500 Internal Server Error
+func buildInternalErrorResponse(message string) *authnz.HandleAuthnZResponse {
+	return &authnz.HandleAuthnZResponse{
+		Result: &v1beta1.CheckResult{Status: rpc.Status{
+			Code:    int32(rpc.INTERNAL),
+			Message: message,
+			Details: []*types.Any{status.PackErrorDetail(&istiopolicy.DirectHttpResponse{
+				Code: istiopolicy.InternalServerError,
+				Body: http.StatusText(http.StatusInternalServerError),
+			})},
+		}},
+	}
+}
+
+// succeeded returns true if the response allows the request
+func succeeded(response *authnz.HandleAuthnZResponse) bool {
+	return response != nil && response.Result != nil && response.Result.Status.Code == status.OK.Code
+}
+
+// mergeOutput combines the forwarded headers of two successful responses.
+// The first authorization header is kept.
+func mergeOutput(current *authnz.OutputMsg, next *authnz.OutputMsg) *authnz.OutputMsg {
+	if next == nil {
+		return current
+	}
+	if current == nil {
+		current = &authnz.OutputMsg{}
+	}
+	if current.Authorization == "" {
+		current.Authorization = next.Authorization
+	}
+	for k, v := range next.Headers {
+		if current.Headers == nil {
+			current.Headers = make(map[string]string)
+		}
+		if _, ok := current.Headers[k]; !ok {
+			current.Headers[k] = v
+		}
+	}
+	return current
+}
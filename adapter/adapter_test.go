@@ -9,12 +9,17 @@ import (
 	"github.com/ibm-cloud-security/app-identity-and-access-adapter/tests/fake"
 
 	"github.com/gogo/googleapis/google/rpc"
+	"github.com/gogo/protobuf/types"
 	"github.com/stretchr/testify/assert"
+	"istio.io/api/mixer/adapter/model/v1beta1"
+	istiopolicy "istio.io/api/policy/v1beta1"
 	"istio.io/istio/mixer/pkg/status"
 
 	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/config"
+	v1 "github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/pkg/apis/policies/v1"
 	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/policy"
 	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/policy/engine"
+	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/strategy"
 	"github.com/ibm-cloud-security/app-identity-and-access-adapter/config/template"
 )
 
@@ -23,8 +28,9 @@ func TestNew(t *testing.T) {
 	defer server.Close()
 	assert.Nil(t, err)
 	s := server.(*AppidAdapter)
-	assert.NotNil(t, s.webstrategy)
-	assert.NotNil(t, s.apistrategy)
+	assert.NotNil(t, s.strategies[policy.JWT])
+	assert.NotNil(t, s.strategies[policy.OPA])
+	assert.NotNil(t, s.strategies[policy.OIDC])
 	assert.NotNil(t, s.listener)
 	assert.NotNil(t, s.engine)
 	assert.NotNil(t, s.server)
@@ -42,13 +48,13 @@ func TestHandleAuthorization(t *testing.T) {
 	tests := []struct {
 		req    *authnz.HandleAuthnZRequest
 		status rpc.Status
-		action *engine.Action
+		action *engine.Decision
 		err    error
 	}{
 		{
 			req:    &authnz.HandleAuthnZRequest{},
 			status: status.OK,
-			action: &engine.Action{Type: policy.NONE},
+			action: &engine.Decision{},
 			err:    errors.New("invalid *authnz.HandleAuthnZRequest instance format"),
 		},
 		{
@@ -59,28 +65,28 @@ func TestHandleAuthorization(t *testing.T) {
 				},
 			},
 			status: status.OK,
-			action: &engine.Action{Type: policy.NONE},
+			action: &engine.Decision{},
 			err:    errors.New("invalid *authnz.HandleAuthnZRequest instance format"),
 		},
 		{
 			req:    generateAuthRequest("", "/hello/"),
 			status: status.OK,
-			action: &engine.Action{Type: policy.NONE},
+			action: &engine.Decision{},
 			err:    nil,
 		},
 		{
 			req:    generateAuthRequest("bearer token1 token2", "/"),
 			status: status.New(16),
-			action: &engine.Action{Type: policy.JWT},
+			action: &engine.Decision{Actions: []engine.Action{{Type: policy.JWT}}},
 			err:    nil,
 		},
 		{
 			req:    generateAuthRequest("", "/"),
 			status: status.New(16),
-			action: &engine.Action{
+			action: &engine.Decision{Actions: []engine.Action{{
 				Type:   policy.OIDC,
 				Client: fake.NewClient(nil),
-			},
+			}}},
 			err: nil,
 		},
 		{
@@ -108,15 +114,328 @@ func TestHandleAuthorization(t *testing.T) {
 	}
 }
 
+func TestHandleCombinedPolicies(t *testing.T) {
+	jwt := engine.Action{Type: policy.JWT}
+	oidc := engine.Action{Type: policy.OIDC}
+
+	tests := []struct {
+		name     string
+		header   string
+		mode     policy.Mode
+		api      *mockStrategy
+		web      *mockStrategy
+		status   int32
+		apiCalls int
+		webCalls int
+		headers  map[string]string
+	}{
+		{
+			name:     "firstMatch uses jwt when a bearer token is present",
+			header:   "Bearer token",
+			mode:     policy.FIRSTMATCH,
+			api:      &mockStrategy{code: int32(rpc.PERMISSION_DENIED)},
+			web:      &mockStrategy{},
+			status:   int32(rpc.PERMISSION_DENIED),
+			apiCalls: 1,
+		},
+		{
+			name:     "firstMatch skips jwt without a bearer token",
+			mode:     policy.FIRSTMATCH,
+			api:      &mockStrategy{},
+			web:      &mockStrategy{code: int32(rpc.UNAUTHENTICATED)},
+			status:   int32(rpc.UNAUTHENTICATED),
+			webCalls: 1,
+		},
+		{
+			name:     "any stops at the first success",
+			header:   "Bearer token",
+			mode:     policy.ANYOF,
+			api:      &mockStrategy{},
+			web:      &mockStrategy{},
+			status:   status.OK.Code,
+			apiCalls: 1,
+		},
+		{
+			name:     "any falls through to oidc",
+			mode:     policy.ANYOF,
+			api:      &mockStrategy{code: int32(rpc.UNAUTHENTICATED)},
+			web:      &mockStrategy{code: int32(rpc.UNAUTHENTICATED)},
+			status:   int32(rpc.UNAUTHENTICATED),
+			apiCalls: 1,
+			webCalls: 1,
+		},
+		{
+			name:     "all stops at the first failure",
+			mode:     policy.ALLOF,
+			api:      &mockStrategy{code: int32(rpc.UNAUTHENTICATED)},
+			web:      &mockStrategy{},
+			status:   int32(rpc.UNAUTHENTICATED),
+			apiCalls: 1,
+		},
+		{
+			name:     "all merges forwarded headers",
+			mode:     policy.ALLOF,
+			api:      &mockStrategy{headers: map[string]string{"x-user": "api", "x-tenant": "t1"}},
+			web:      &mockStrategy{headers: map[string]string{"x-user": "web", "x-email": "e"}},
+			status:   status.OK.Code,
+			apiCalls: 1,
+			webCalls: 1,
+			headers:  map[string]string{"x-user": "api", "x-tenant": "t1", "x-email": "e"},
+		},
+	}
+
+	for _, ts := range tests {
+		test := ts
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			s := &AppidAdapter{
+				strategies: strategies(test.api, test.web),
+				engine:     &mockEngine{action: &engine.Decision{Mode: test.mode, Actions: []engine.Action{jwt, oidc}}},
+			}
+			result, err := s.HandleAuthnZ(context.Background(), generateAuthRequest(test.header, "/"))
+			assert.Nil(t, err)
+			assert.Equal(t, test.status, result.Result.Status.Code)
+			assert.Equal(t, test.apiCalls, test.api.calls)
+			assert.Equal(t, test.webCalls, test.web.calls)
+			if test.headers != nil {
+				assert.Equal(t, test.headers, result.Output.Headers)
+			}
+		})
+	}
+}
+
+func TestHandleAllowDenyPolicies(t *testing.T) {
+	tests := []struct {
+		name     string
+		actions  []engine.Action
+		status   int32
+		httpCode istiopolicy.HttpStatusCode
+		apiCalls int
+	}{
+		{
+			name:    "allow",
+			actions: []engine.Action{{Type: policy.ALLOW}},
+			status:  status.OK.Code,
+		},
+		{
+			name:     "deny defaults to forbidden",
+			actions:  []engine.Action{{Type: policy.DENY}},
+			status:   int32(rpc.PERMISSION_DENIED),
+			httpCode: istiopolicy.Forbidden,
+		},
+		{
+			name:     "deny with status",
+			actions:  []engine.Action{{Type: policy.DENY, PathPolicy: v1.PathPolicy{Status: 404}}},
+			status:   int32(rpc.NOT_FOUND),
+			httpCode: istiopolicy.NotFound,
+		},
+		{
+			name:    "firstMatch allows requests without a bearer token",
+			actions: []engine.Action{{Type: policy.JWT}, {Type: policy.ALLOW}},
+			status:  status.OK.Code,
+		},
+		{
+			name:     "invalid policy",
+			actions:  []engine.Action{{Type: policy.DENY, PathPolicy: v1.PathPolicy{Status: 500}, Problems: []string{"/path: unknown policyType \"saml\""}}},
+			status:   int32(rpc.INTERNAL),
+			httpCode: istiopolicy.InternalServerError,
+		},
+		{
+			name:    "none",
+			actions: []engine.Action{{Type: policy.NONE}},
+			status:  status.OK.Code,
+		},
+		{
+			name:     "registered type without a strategy",
+			actions:  []engine.Action{{Type: unenforcedType}},
+			status:   int32(rpc.INTERNAL),
+			httpCode: istiopolicy.InternalServerError,
+		},
+	}
+
+	for _, ts := range tests {
+		test := ts
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			api := &mockStrategy{code: int32(rpc.UNAUTHENTICATED)}
+			s := &AppidAdapter{
+				strategies: strategies(api, &mockStrategy{}),
+				engine:     &mockEngine{action: &engine.Decision{Actions: test.actions}},
+			}
+			result, err := s.HandleAuthnZ(context.Background(), generateAuthRequest("", "/"))
+			assert.Nil(t, err)
+			assert.Equal(t, test.status, result.Result.Status.Code)
+			assert.Equal(t, 0, api.calls)
+			if test.httpCode != 0 {
+				response := &istiopolicy.DirectHttpResponse{}
+				assert.Nil(t, types.UnmarshalAny(result.Result.Status.Details[0], response))
+				assert.Equal(t, test.httpCode, response.Code)
+			}
+		})
+	}
+}
+
+// Types registered by plugins
+var (
+	// apiKeyType is a registered type enforced by a strategy plugin
+	apiKeyType = policy.RegisterType(policy.TypeInfo{Name: "apikey", ConfigKind: v1.NONE, Authorization: true})
+	// unenforcedType is a registered type without a strategy
+	unenforcedType = policy.RegisterType(policy.TypeInfo{Name: "unenforced", ConfigKind: v1.NONE})
+)
+
+func TestHandleRegisteredStrategies(t *testing.T) {
+	tests := []struct {
+		name        string
+		header      string
+		actions     []engine.Action
+		status      int32
+		apiKeyCalls int
+		webCalls    int
+	}{
+		{
+			name:        "registered strategy",
+			actions:     []engine.Action{{Type: apiKeyType}},
+			status:      int32(rpc.PERMISSION_DENIED),
+			apiKeyCalls: 1,
+		},
+		{
+			name:        "firstMatch uses the registered strategy with an authorization header",
+			header:      "ApiKey key",
+			actions:     []engine.Action{{Type: apiKeyType}, {Type: policy.OIDC}},
+			status:      int32(rpc.PERMISSION_DENIED),
+			apiKeyCalls: 1,
+		},
+		{
+			name:     "firstMatch skips the registered strategy without an authorization header",
+			actions:  []engine.Action{{Type: apiKeyType}, {Type: policy.OIDC}},
+			status:   status.OK.Code,
+			webCalls: 1,
+		},
+	}
+
+	for _, ts := range tests {
+		test := ts
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			apiKey := &mockStrategy{code: int32(rpc.PERMISSION_DENIED)}
+			web := &mockStrategy{}
+			registered := strategies(nil, web)
+			registered[apiKeyType] = apiKey
+			s := &AppidAdapter{
+				strategies: registered,
+				engine:     &mockEngine{action: &engine.Decision{Actions: test.actions}},
+			}
+			result, err := s.HandleAuthnZ(context.Background(), generateAuthRequest(test.header, "/"))
+			assert.Nil(t, err)
+			assert.Equal(t, test.status, result.Result.Status.Code)
+			assert.Equal(t, test.apiKeyCalls, apiKey.calls)
+			assert.Equal(t, test.webCalls, web.calls)
+		})
+	}
+}
+
+func TestHandleCORSPreflight(t *testing.T) {
+	preflight := func(method string, headers map[string]string) *authnz.HandleAuthnZRequest {
+		r := generateAuthRequest("", "/")
+		r.Instance.Target.Method = method
+		r.Instance.Request.Headers.Properties = make(map[string]*istiopolicy.Value)
+		for name, value := range headers {
+			r.Instance.Request.Headers.Properties[name] = &istiopolicy.Value{Value: &istiopolicy.Value_StringValue{StringValue: value}}
+		}
+		return r
+	}
+	cors := map[string]string{"origin": "https://example.com", "access-control-request-method": "POST"}
+
+	tests := []struct {
+		name     string
+		bypass   bool
+		req      *authnz.HandleAuthnZRequest
+		status   int32
+		webCalls int
+	}{
+		{
+			name:   "preflight bypasses authentication",
+			bypass: true,
+			req:    preflight("OPTIONS", cors),
+			status: status.OK.Code,
+		},
+		{
+			name:     "preflight is authenticated unless enabled",
+			req:      preflight("OPTIONS", cors),
+			status:   int32(rpc.UNAUTHENTICATED),
+			webCalls: 1,
+		},
+		{
+			name:     "options without cors headers is authenticated",
+			bypass:   true,
+			req:      preflight("OPTIONS", map[string]string{"origin": "https://example.com"}),
+			status:   int32(rpc.UNAUTHENTICATED),
+			webCalls: 1,
+		},
+		{
+			name:     "other methods are authenticated",
+			bypass:   true,
+			req:      preflight("POST", cors),
+			status:   int32(rpc.UNAUTHENTICATED),
+			webCalls: 1,
+		},
+	}
+
+	for _, ts := range tests {
+		test := ts
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			web := &mockStrategy{code: int32(rpc.UNAUTHENTICATED)}
+			s := &AppidAdapter{
+				strategies: strategies(nil, web),
+				engine:     &mockEngine{action: &engine.Decision{Actions: []engine.Action{{Type: policy.OIDC}}}},
+				cfg:        &config.Config{CORSPreflightBypass: test.bypass},
+			}
+			result, err := s.HandleAuthnZ(context.Background(), test.req)
+			assert.Nil(t, err)
+			assert.Equal(t, test.status, result.Result.Status.Code)
+			assert.Equal(t, test.webCalls, web.calls)
+		})
+	}
+}
+
 type mockEngine struct {
-	action *engine.Action
+	action *engine.Decision
 	err    error
 }
 
-func (m *mockEngine) Evaluate(msg *authnz.TargetMsg) (*engine.Action, error) {
+func (m *mockEngine) Evaluate(msg *authnz.TargetMsg, request *authnz.RequestMsg) (*engine.Decision, error) {
 	return m.action, m.err
 }
 
+type mockStrategy struct {
+	code    int32
+	headers map[string]string
+	calls   int
+}
+
+func (m *mockStrategy) HandleAuthnZRequest(*authnz.HandleAuthnZRequest, *engine.Action) (*authnz.HandleAuthnZResponse, error) {
+	m.calls++
+	response := &authnz.HandleAuthnZResponse{Result: &v1beta1.CheckResult{Status: rpc.Status{Code: m.code}}}
+	if m.headers != nil {
+		response.Output = &authnz.OutputMsg{Headers: m.headers}
+	}
+	return response, nil
+}
+
+// strategies returns the strategies of the built-in policy types, using api for JWT and OPA policies
+func strategies(api strategy.Strategy, web strategy.Strategy) map[policy.Type]strategy.Strategy {
+	result := make(map[policy.Type]strategy.Strategy)
+	if api != nil {
+		result[policy.JWT] = api
+		result[policy.OPA] = api
+	}
+	if web != nil {
+		result[policy.OIDC] = web
+	}
+	return result
+}
+
 func generateAuthRequest(header string, path string) *authnz.HandleAuthnZRequest {
 	return &authnz.HandleAuthnZRequest{
 		Instance: &authnz.InstanceMsg{
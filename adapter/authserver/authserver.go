@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
+	"sync"
 
 	"github.com/golang/groupcache/singleflight"
 	"go.uber.org/zap"
@@ -50,6 +51,8 @@ type AuthorizationServerService interface {
 	UserInfoEndpoint() string
 	KeySet() keyset.KeySet
 	SetKeySet(keyset.KeySet)
+	SyncError() error
+	OnSync(handler func(err error))
 	GetTokens(authnMethod string, clientID string, clientSecret string, authorizationCode string, redirectURI string, refreshToken string) (*TokenResponse, error)
 }
 
@@ -62,6 +65,10 @@ type RemoteService struct {
 	httpclient   *networking.HTTPClient
 	requestGroup singleflight.Group
 	initialized  bool
+
+	syncMu     sync.Mutex
+	syncErr    error
+	syncNotify func(err error)
 }
 
 // New creates a RemoteService returning a AuthorizationServerService interface
@@ -95,6 +102,32 @@ func (s *RemoteService) SetKeySet(jwks keyset.KeySet) {
 	s.jwks = jwks
 }
 
+// SyncError returns the error of the last attempt to load the discovery endpoint, or nil if it succeeded
+func (s *RemoteService) SyncError() error {
+	s.syncMu.Lock()
+	defer s.syncMu.Unlock()
+	return s.syncErr
+}
+
+// OnSync sets a function called when an attempt to load the discovery endpoint changes the sync error
+func (s *RemoteService) OnSync(handler func(err error)) {
+	s.syncMu.Lock()
+	defer s.syncMu.Unlock()
+	s.syncNotify = handler
+}
+
+// setSyncError records the outcome of an attempt to load the discovery endpoint
+func (s *RemoteService) setSyncError(err error) {
+	s.syncMu.Lock()
+	changed := (s.syncErr == nil) != (err == nil) || (err != nil && s.syncErr.Error() != err.Error())
+	s.syncErr = err
+	notify := s.syncNotify
+	s.syncMu.Unlock()
+	if changed && notify != nil {
+		notify(err)
+	}
+}
+
 // JwksEndpoint returns the /publicKeys endpoint of the OAuth server
 func (s *RemoteService) JwksEndpoint() string {
 	_ = s.initialize()
@@ -180,7 +213,9 @@ func (s *RemoteService) initialize() error {
 		if s.initialized {
 			return http.StatusOK, nil
 		}
-		return s.loadDiscoveryEndpoint()
+		res, err := s.loadDiscoveryEndpoint()
+		s.setSyncError(err)
+		return res, err
 	})
 
 	if err != nil {
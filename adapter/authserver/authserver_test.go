@@ -44,6 +44,31 @@ func TestAuthServerNew(t *testing.T) {
 	assert.Equal(t, userInfoUrl, server.UserInfoEndpoint())
 }
 
+func TestOnSync(t *testing.T) {
+	status := http.StatusNotFound
+	h := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(status)
+		w.Write([]byte(discoveryResponse))
+	})
+	s := httptest.NewServer(h)
+	defer s.Close()
+
+	server := New(s.URL)
+	assert.NotNil(t, server.SyncError())
+	errs := make([]error, 0)
+	server.OnSync(func(err error) { errs = append(errs, err) })
+
+	// Failing with the same error is not reported
+	assert.Equal(t, "", server.TokenEndpoint())
+	assert.Empty(t, errs)
+
+	// Loading the discovery endpoint is reported once
+	status = http.StatusOK
+	assert.Equal(t, tokenURL, server.TokenEndpoint())
+	assert.Equal(t, authURL, server.AuthorizationEndpoint())
+	assert.Equal(t, []error{nil}, errs)
+}
+
 func TestSetKeySet(t *testing.T) {
 	server := New("")
 	assert.Nil(t, server.KeySet())
@@ -110,6 +135,7 @@ func TestInitialize(t *testing.T) {
 			s := httptest.NewServer(h)
 			server := &RemoteService{discoveryURL: s.URL, httpclient: &networking.HTTPClient{Client: s.Client()}}
 			err := server.initialize()
+			assert.Equal(t2, err, server.SyncError())
 			if test.statusCode != 200 {
 				if err == nil {
 					t2.FailNow()
@@ -5,6 +5,9 @@ import (
 	"crypto"
 	"fmt"
 	"net/http"
+	"reflect"
+	"sync"
+	"sync/atomic"
 
 	"go.uber.org/zap"
 
@@ -18,8 +21,16 @@ import (
 type KeySet interface {
 	PublicKeyURL() string
 	PublicKey(kid string) crypto.PublicKey
+	SyncError() error
+	// Generation identifies the retrieved keys. It changes whenever keys are added, replaced or removed.
+	Generation() uint64
+	// OnSync sets a function called when an attempt to retrieve the keys changes the sync error
+	OnSync(handler func(err error))
 }
 
+// generations numbers the keys retrieved by all key sets, so that a generation is never reused
+var generations uint64
+
 // RemoteKeySet manages the retrieval and storage of OIDC public keys
 type RemoteKeySet struct {
 	publicKeyURL string
@@ -27,6 +38,12 @@ type RemoteKeySet struct {
 
 	requestGroup singleflight.Group
 	publicKeys   map[string]crypto.PublicKey
+
+	syncMu     sync.Mutex
+	syncErr    error
+	syncNotify func(err error)
+
+	generation uint64
 }
 
 ////////////////// constructor //////////////////////////
@@ -67,9 +84,45 @@ func (s *RemoteKeySet) PublicKeyURL() string {
 	return s.publicKeyURL
 }
 
+// Generation returns the generation of the retrieved keys, or 0 if none were retrieved
+func (s *RemoteKeySet) Generation() uint64 {
+	return atomic.LoadUint64(&s.generation)
+}
+
+// SyncError returns the error of the last attempt to retrieve the public keys, or nil if it succeeded
+func (s *RemoteKeySet) SyncError() error {
+	s.syncMu.Lock()
+	defer s.syncMu.Unlock()
+	return s.syncErr
+}
+
+// OnSync sets a function called when an attempt to retrieve the public keys succeeds after failing,
+// fails after succeeding or fails with a different error
+func (s *RemoteKeySet) OnSync(handler func(err error)) {
+	s.syncMu.Lock()
+	defer s.syncMu.Unlock()
+	s.syncNotify = handler
+}
+
+// setSyncError records the outcome of an attempt to retrieve the public keys
+func (s *RemoteKeySet) setSyncError(err error) {
+	s.syncMu.Lock()
+	changed := (s.syncErr == nil) != (err == nil) || (err != nil && s.syncErr.Error() != err.Error())
+	s.syncErr = err
+	notify := s.syncNotify
+	s.syncMu.Unlock()
+	if changed && notify != nil {
+		notify(err)
+	}
+}
+
 // updateKeyGroup issues /publicKeys request using shared request group
 func (s *RemoteKeySet) updateKeysGrouped() error {
-	_, err, _ := s.requestGroup.Do(s.publicKeyURL, s.updateKeys)
+	_, err, _ := s.requestGroup.Do(s.publicKeyURL, func() (interface{}, error) {
+		res, err := s.updateKeys()
+		s.setSyncError(err)
+		return res, err
+	})
 
 	if err != nil {
 		zap.L().Debug("An error occurred requesting public keys", zap.Error(err))
@@ -116,6 +169,9 @@ func (s *RemoteKeySet) updateKeys() (interface{}, error) {
 
 	zap.L().Info("Synced public keys", zap.String("url", s.publicKeyURL))
 
+	if s.Generation() == 0 || !reflect.DeepEqual(s.publicKeys, keymap) {
+		atomic.StoreUint64(&s.generation, atomic.AddUint64(&generations, 1))
+	}
 	s.publicKeys = keymap
 
 	return http.StatusOK, nil
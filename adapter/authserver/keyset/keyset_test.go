@@ -62,6 +62,59 @@ func TestNew(t *testing.T) {
 		server.Close()
 	}
 }
+
+func TestSyncError(t *testing.T) {
+	// Fail the first request only
+	var once sync.Once
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		failed := false
+		once.Do(func() {
+			failed = true
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(badReqResponse))
+		})
+		if !failed {
+			w.Write([]byte(publicKeysOkResponse))
+		}
+	})
+	httpClient, server := httpClient(h)
+	defer server.Close()
+
+	util := New(testURL, httpClient)
+	assert.NotNil(t, util.SyncError())
+
+	// A successful retry clears the error
+	assert.NotNil(t, util.PublicKey(testKid))
+	assert.Nil(t, util.SyncError())
+}
+
+func TestOnSync(t *testing.T) {
+	status := http.StatusBadRequest
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		w.Write([]byte(publicKeysOkResponse))
+	})
+	httpClient, server := httpClient(h)
+	defer server.Close()
+
+	util := New(testURL, httpClient)
+	errs := make([]error, 0)
+	util.OnSync(func(err error) { errs = append(errs, err) })
+
+	// Failing with the same error is not reported
+	assert.Nil(t, util.PublicKey(testKid))
+	assert.Empty(t, errs)
+
+	// Recovering is reported
+	status = http.StatusOK
+	assert.NotNil(t, util.PublicKey(testKid))
+	assert.Equal(t, []error{nil}, errs)
+
+	// Succeeding again is not reported
+	assert.Nil(t, util.PublicKey("unknown"))
+	assert.Equal(t, 1, len(errs))
+}
+
 func TestResyncMissingKeys(t *testing.T) {
 	// Overwrite Http req handler
 	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -81,6 +134,40 @@ func TestResyncMissingKeys(t *testing.T) {
 	server.Close()
 }
 
+func TestGeneration(t *testing.T) {
+	response := publicKeysOkResponse
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(response))
+	})
+	httpClient, server := httpClient(h)
+	defer server.Close()
+
+	util := &RemoteKeySet{
+		publicKeyURL: testURL,
+		httpClient:   httpClient,
+	}
+	assert.Equal(t, uint64(0), util.Generation())
+
+	// Retrieved keys start a generation
+	assert.Nil(t, util.updateKeysGrouped())
+	first := util.Generation()
+	assert.NotEqual(t, uint64(0), first)
+
+	// Syncing the same keys keeps the generation
+	assert.Nil(t, util.PublicKey("unknown"))
+	assert.Equal(t, first, util.Generation())
+
+	// Removed keys start a new generation
+	response = "{\"keys\": []}"
+	assert.Nil(t, util.updateKeysGrouped())
+	assert.True(t, util.Generation() > first)
+
+	// Generations are not shared by key sets of the same url
+	other := &RemoteKeySet{publicKeyURL: testURL, httpClient: httpClient}
+	assert.Nil(t, other.updateKeysGrouped())
+	assert.NotEqual(t, util.Generation(), other.Generation())
+}
+
 func TestUpdateKeys(t *testing.T) {
 	var tests = []struct {
 		name          string
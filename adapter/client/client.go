@@ -16,6 +16,7 @@ type Client interface {
 	ID() string
 	Secret() string
 	Scope() string
+	TokenValidation() v1.TokenValidation
 	AuthorizationServer() authserver.AuthorizationServerService
 	ExchangeGrantCode(code string, redirectURI string) (*authserver.TokenResponse, error)
 	RefreshToken(refreshToken string) (*authserver.TokenResponse, error)
@@ -45,6 +46,10 @@ func (c *remoteClient) Scope() string {
 	return strings.Join(c.Scopes, " ")
 }
 
+func (c *remoteClient) TokenValidation() v1.TokenValidation {
+	return c.OidcConfigSpec.TokenValidation
+}
+
 func (c *remoteClient) AuthorizationServer() authserver.AuthorizationServerService {
 	return c.authServer
 }
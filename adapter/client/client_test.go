@@ -2,8 +2,10 @@ package client
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/pkg/apis/policies/v1"
 	"github.com/ibm-cloud-security/app-identity-and-access-adapter/tests/fake"
@@ -14,11 +16,15 @@ func TestClientNew(t *testing.T) {
 		ClientSecret: "secret",
 		ClientID:     "id",
 		ClientName:   "name",
+		TokenValidation: v1.TokenValidation{
+			MaxAge: &metav1.Duration{Duration: time.Hour},
+		},
 	}, nil)
 	assert.NotNil(t, n)
 	assert.Equal(t, "id", n.ID())
 	assert.Equal(t, "name", n.Name())
 	assert.Equal(t, "secret", n.Secret())
+	assert.Equal(t, time.Hour, n.TokenValidation().MaxAge.Duration)
 	assert.Nil(t, n.AuthorizationServer())
 }
 
@@ -1,5 +1,7 @@
 package config
 
+import "time"
+
 // Config contains the
 type Config struct {
 	// port to start the grpc adapter on
@@ -14,6 +16,25 @@ type Config struct {
 	// The blockKey is used to encrypt the cookie value
 	// Valid lengths are 16, 24, or 32 bytes to select AES-128, AES-192, or AES-256.
 	BlockKeySize IntOptions
+	// Default clock skew tolerated when validating time based token claims
+	TokenLeeway time.Duration
+	// Default maximum age of a token based on its iat and auth_time claims. Zero disables the check.
+	TokenMaxAge time.Duration
+	// Maximum number of accepted tokens cached to skip repeated validations. Zero disables the cache.
+	TokenCacheSize int
+	// port to serve the validating admission webhook on. Zero disables the webhook.
+	WebhookPort uint16
+	// TLS certificate and private key files of the webhook server
+	WebhookCertFile string
+	WebhookKeyFile  string
+	// Allow CORS preflight requests without authentication
+	CORSPreflightBypass bool
+	// Maximum number of policy decisions cached by the policy engine. Zero disables the cache.
+	PolicyCacheSize int
+	// port to serve Prometheus metrics on. Zero disables the metrics endpoint.
+	MetricsPort uint16
+	// port to serve the HTTP forward authentication endpoint on. Zero disables the endpoint.
+	ForwardAuthPort uint16
 }
 
 // defaultArgs returns the default configuration size
@@ -37,5 +58,12 @@ func NewConfig() *Config {
 			},
 			Value: 16,
 		},
+		TokenLeeway:     0,
+		TokenMaxAge:     0,
+		TokenCacheSize:  1024,
+		WebhookPort:     0,
+		PolicyCacheSize: 1024,
+		MetricsPort:     0,
+		ForwardAuthPort: 0,
 	}
 }
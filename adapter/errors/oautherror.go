@@ -23,6 +23,9 @@ const (
 	InsufficientScope = "insufficient_scope"
 )
 
+// AccessDenied is the error of authenticated requests denied by a policy - https://tools.ietf.org/html/rfc6749#section-4.1.2.1
+const AccessDenied = "access_denied"
+
 const (
 	ExpiredToken = "Token is expired"
 )
@@ -52,6 +55,33 @@ func UnauthorizedHTTPException(msg string, scopes []string) *OAuthError {
 	}
 }
 
+// ForbiddenHTTPException creates a new insufficient scope error
+func ForbiddenHTTPException(msg string, scopes []string) *OAuthError {
+	return &OAuthError{
+		Msg:    msg,
+		Code:   InsufficientScope,
+		Scopes: scopes,
+	}
+}
+
+// InternalServerErrorHTTPException creates a new internal server error
+func InternalServerErrorHTTPException(msg string) *OAuthError {
+	return &OAuthError{
+		Msg:    msg,
+		Code:   InternalServerError,
+		Scopes: nil,
+	}
+}
+
+// AccessDeniedHTTPException creates a new error denying an authenticated request by policy
+func AccessDeniedHTTPException(msg string) *OAuthError {
+	return &OAuthError{
+		Msg:    msg,
+		Code:   AccessDenied,
+		Scopes: nil,
+	}
+}
+
 // BadRequestHTTPException creates a new invalid request error
 func BadRequestHTTPException(msg string) *OAuthError {
 	return &OAuthError{
@@ -80,7 +110,7 @@ func (e *OAuthError) ShortDescription() string {
 		return BadRequest
 	case InvalidToken:
 		return Unauthorized
-	case InsufficientScope:
+	case InsufficientScope, AccessDenied:
 		return Forbidden
 	default:
 		return e.Code
@@ -102,13 +132,18 @@ func (e *OAuthError) HTTPCode() v1beta1.HttpStatusCode {
 		return v1beta1.BadRequest
 	case InvalidToken:
 		return v1beta1.Unauthorized
-	case InsufficientScope:
+	case InsufficientScope, AccessDenied:
 		return v1beta1.Forbidden
 	default:
 		return v1beta1.InternalServerError
 	}
 }
 
+// BearerError returns true if the error code is defined for the Bearer WWW-Authenticate header by RFC 6750
+func (e *OAuthError) BearerError() bool {
+	return e.Code == InvalidRequest || e.Code == InvalidToken || e.Code == InsufficientScope
+}
+
 // HTTPCode returns Istio compliant HTTPStatusCode
 func (e *OAuthError) OK() error {
 	if e.Code == "" {
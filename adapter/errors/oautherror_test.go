@@ -34,8 +34,28 @@ func TestInsufficientScopeException(t *testing.T) {
 	assert.Equal(t, "", err.ScopeStr())
 }
 
+func TestForbiddenException(t *testing.T) {
+	err := ForbiddenHTTPException("my error message", []string{"scope1", "scope2"})
+	assert.Equal(t, InsufficientScope, err.Code)
+	assert.Equal(t, v1beta1.Forbidden, err.HTTPCode())
+	assert.Equal(t, "insufficient_scope: my error message", err.Error())
+	assert.Equal(t, Forbidden, err.ShortDescription())
+	assert.Equal(t, "scope1 scope2", err.ScopeStr())
+}
+
+func TestAccessDeniedException(t *testing.T) {
+	err := AccessDeniedHTTPException("my error message")
+	assert.Equal(t, AccessDenied, err.Code)
+	assert.Equal(t, v1beta1.Forbidden, err.HTTPCode())
+	assert.Equal(t, "access_denied: my error message", err.Error())
+	assert.Equal(t, Forbidden, err.ShortDescription())
+	assert.Equal(t, "", err.ScopeStr())
+	assert.False(t, err.BearerError())
+	assert.True(t, ForbiddenHTTPException("my error message", nil).BearerError())
+}
+
 func TestInternalServerException(t *testing.T) {
-	err := &OAuthError{Msg: "my error message", Code: InternalServerError}
+	err := InternalServerErrorHTTPException("my error message")
 	assert.Equal(t, InternalServerError, err.Code)
 	assert.Equal(t, v1beta1.InternalServerError, err.HTTPCode())
 	assert.Equal(t, "Internal Server Error: my error message", err.Error())
@@ -0,0 +1,124 @@
+// Package expression compiles and evaluates CEL authorization expressions
+package expression
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/checker/decls"
+	"github.com/google/cel-go/common/types"
+	exprpb "google.golang.org/genproto/googleapis/api/expr/v1alpha1"
+)
+
+const (
+	claims  = "claims"
+	request = "request"
+	target  = "target"
+)
+
+// Program is a compiled authorization expression
+type Program interface {
+	Eval(input Input) (bool, error)
+}
+
+// Input contains the values an expression is evaluated against
+type Input struct {
+	// Claims holds the claims of the validated token
+	Claims map[string]interface{}
+	// Request describes the incoming HTTP request
+	Request Request
+	// Target describes the destination service
+	Target Target
+}
+
+// Request exposes the request attributes available to expressions
+type Request struct {
+	Method  string
+	Path    string
+	Headers map[string]string
+}
+
+// Target exposes the destination attributes available to expressions
+type Target struct {
+	Namespace string
+	Service   string
+}
+
+type program struct {
+	source string
+	prg    cel.Program
+}
+
+// Compile parses and type checks the given expression.
+// Expressions must evaluate to a boolean.
+func Compile(source string) (Program, error) {
+	env, err := cel.NewEnv(cel.Declarations(
+		decls.NewIdent(claims, decls.NewMapType(decls.String, decls.Dyn), nil),
+		decls.NewIdent(request, decls.NewMapType(decls.String, decls.Dyn), nil),
+		decls.NewIdent(target, decls.NewMapType(decls.String, decls.String), nil),
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	ast, issues := env.Parse(source)
+	if issues != nil && issues.Err() != nil {
+		return nil, issues.Err()
+	}
+
+	checked, issues := env.Check(ast)
+	if issues != nil && issues.Err() != nil {
+		return nil, issues.Err()
+	}
+
+	if !isBoolOrDyn(checked.ResultType()) {
+		return nil, fmt.Errorf("expression must evaluate to a bool, found: %v", checked.ResultType())
+	}
+
+	prg, err := env.Program(checked)
+	if err != nil {
+		return nil, err
+	}
+
+	return &program{source: source, prg: prg}, nil
+}
+
+// Eval evaluates the expression. Evaluation errors, such as missing claims, deny access.
+func (p *program) Eval(input Input) (bool, error) {
+	headers := input.Request.Headers
+	if headers == nil {
+		headers = make(map[string]string)
+	}
+	tokenClaims := input.Claims
+	if tokenClaims == nil {
+		tokenClaims = make(map[string]interface{})
+	}
+
+	out, _, err := p.prg.Eval(map[string]interface{}{
+		claims: tokenClaims,
+		request: map[string]interface{}{
+			"method":  input.Request.Method,
+			"path":    input.Request.Path,
+			"headers": headers,
+		},
+		target: map[string]string{
+			"namespace": input.Target.Namespace,
+			"service":   input.Target.Service,
+		},
+	})
+	if err != nil {
+		return false, fmt.Errorf("could not evaluate expression `%s`: %v", p.source, err)
+	}
+
+	result, ok := out.(types.Bool)
+	if !ok {
+		return false, errors.New("expression did not evaluate to a bool")
+	}
+	return result == types.True, nil
+}
+
+// isBoolOrDyn checks the expression result type. Expressions that return claim values are dynamically typed.
+func isBoolOrDyn(t *exprpb.Type) bool {
+	return t.GetPrimitive() == exprpb.Type_BOOL || t.GetDyn() != nil
+}
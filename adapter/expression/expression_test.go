@@ -0,0 +1,90 @@
+package expression
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompile(t *testing.T) {
+	var tests = []struct {
+		expression string
+		expectErr  bool
+	}{
+		{`"admin" in claims.groups`, false},
+		{`claims.scope`, false},
+		{`request.method == "GET" && target.service == "svc"`, false},
+		{`request.headers["x-tenant"] == claims.tenant`, false},
+		{`claims.groups +`, true},
+		{`unknown.value == "1"`, true},
+		{`target.service`, true},
+		{`1 + 1`, true},
+	}
+	for _, e := range tests {
+		test := e
+		t.Run(test.expression, func(st *testing.T) {
+			program, err := Compile(test.expression)
+			if test.expectErr {
+				assert.NotNil(st, err)
+				assert.Nil(st, program)
+			} else {
+				assert.Nil(st, err)
+				assert.NotNil(st, program)
+			}
+		})
+	}
+}
+
+func TestEval(t *testing.T) {
+	input := Input{
+		Claims: map[string]interface{}{
+			"groups": []interface{}{"admin", "dev"},
+			"tenant": "1234",
+			"scope":  "read write",
+			"obj":    map[string]interface{}{"level": 3.0},
+		},
+		Request: Request{
+			Method:  "GET",
+			Path:    "/api/users",
+			Headers: map[string]string{"x-tenant": "1234"},
+		},
+		Target: Target{Namespace: "ns", Service: "svc"},
+	}
+
+	var tests = []struct {
+		expression string
+		result     bool
+		expectErr  bool
+	}{
+		{`"admin" in claims.groups`, true, false},
+		{`"ops" in claims.groups`, false, false},
+		{`claims.obj.level >= 3.0 && request.method == "GET"`, true, false},
+		{`request.path.startsWith("/api") && target.namespace == "ns"`, true, false},
+		{`request.headers["x-tenant"] == claims.tenant`, true, false},
+		{`has(claims.missing)`, false, false},
+		{`claims.missing == "value"`, false, true},
+		{`claims.tenant`, false, true},
+	}
+	for _, e := range tests {
+		test := e
+		t.Run(test.expression, func(st *testing.T) {
+			program, err := Compile(test.expression)
+			assert.Nil(st, err)
+			result, err := program.Eval(input)
+			if test.expectErr {
+				assert.NotNil(st, err)
+			} else {
+				assert.Nil(st, err)
+			}
+			assert.Equal(st, test.result, result)
+		})
+	}
+}
+
+func TestEvalEmptyInput(t *testing.T) {
+	program, err := Compile(`size(claims) == 0 && size(request.headers) == 0`)
+	assert.Nil(t, err)
+	result, err := program.Eval(Input{})
+	assert.Nil(t, err)
+	assert.True(t, result)
+}
@@ -0,0 +1,236 @@
+// Package extauthz serves the adapter as an Envoy external authorization service
+package extauthz
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/gogo/googleapis/google/rpc"
+	"github.com/gogo/protobuf/types"
+	"go.uber.org/zap"
+	istiopolicy "istio.io/api/policy/v1beta1"
+	"istio.io/istio/mixer/pkg/status"
+
+	authv3 "github.com/ibm-cloud-security/app-identity-and-access-adapter/config/envoy/service/auth/v3"
+	"github.com/ibm-cloud-security/app-identity-and-access-adapter/config/template"
+)
+
+const (
+	// NamespaceExtension is the context extension naming the namespace of the destination service
+	NamespaceExtension = "namespace"
+	// ServiceExtension is the context extension naming the destination service
+	ServiceExtension = "service"
+
+	authorization = "authorization"
+	cookie        = "cookie"
+	setCookie     = "set-cookie"
+)
+
+// Server implements the Envoy ext_authz v3 Authorization service.
+// Each CheckRequest is converted to the authnz instance Mixer would send and handled
+// by the Mixer handler, so both APIs share the policy engine and strategies.
+type Server struct {
+	handler authnz.HandleAuthnZServiceServer
+}
+
+// New creates a new Server handling checks with the given Mixer handler
+func New(handler authnz.HandleAuthnZServiceServer) *Server {
+	return &Server{handler: handler}
+}
+
+// Check authorizes a request forwarded by Envoy.
+// Checks that do not describe an HTTP request or name the destination service are denied
+// with 500 Internal Server Error, as no policy would apply to them and the filter is misconfigured.
+func (s *Server) Check(ctx context.Context, r *authv3.CheckRequest) (*authv3.CheckResponse, error) {
+	request, err := newAuthnZRequest(r)
+	if err != nil {
+		zap.L().Warn("Invalid external authorization request", zap.Error(err))
+		return newInvalidResponse(err), nil
+	}
+	response, err := s.handler.HandleAuthnZ(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return newCheckResponse(response), nil
+}
+
+// newAuthnZRequest maps the attributes of a CheckRequest onto an authnz instance,
+// following the attribute expressions of the Mixer instance installed by the Helm chart.
+// The destination service is read from the namespace and service context extensions,
+// falling back to the destination peer formatted as <service>.<namespace>[.svc.cluster.local].
+// An error is returned if the namespace or service cannot be found.
+func newAuthnZRequest(r *authv3.CheckRequest) (*authnz.HandleAuthnZRequest, error) {
+	attributes := r.GetAttributes()
+	httpRequest := attributes.GetRequest().GetHttp()
+	if httpRequest == nil {
+		return nil, errors.New("missing HTTP request attributes")
+	}
+
+	path, query := splitPath(httpRequest.GetPath())
+	params, err := url.ParseQuery(query)
+	if err != nil {
+		zap.L().Debug("Could not parse query parameters", zap.String("query", query), zap.Error(err))
+	}
+
+	headers := &authnz.HeadersMsg{Properties: make(map[string]*istiopolicy.Value)}
+	for name, value := range httpRequest.GetHeaders() {
+		switch name = strings.ToLower(name); {
+		case name == authorization:
+			headers.Authorization = value
+		case name == cookie:
+			headers.Cookies = value
+		case strings.HasPrefix(name, ":"):
+			// Skip HTTP/2 pseudo headers, which are available as attributes
+		default:
+			headers.Properties[name] = &istiopolicy.Value{Value: &istiopolicy.Value_StringValue{StringValue: value}}
+		}
+	}
+
+	namespace, service, err := destination(attributes)
+	if err != nil {
+		return nil, err
+	}
+	return &authnz.HandleAuthnZRequest{
+		Instance: &authnz.InstanceMsg{
+			Target: &authnz.TargetMsg{
+				Namespace: namespace,
+				Service:   service,
+				Method:    httpRequest.GetMethod(),
+				Path:      path,
+			},
+			Request: &authnz.RequestMsg{
+				Scheme:  httpRequest.GetScheme(),
+				Host:    httpRequest.GetHost(),
+				Path:    path,
+				Headers: headers,
+				Params: &authnz.QueryParamsMsg{
+					Code:  params.Get("code"),
+					Error: params.Get("error"),
+					State: params.Get("state"),
+				},
+			},
+		},
+	}, nil
+}
+
+// splitPath separates a request path from its query, dropping any fragment
+func splitPath(path string) (string, string) {
+	if i := strings.IndexByte(path, '#'); i >= 0 {
+		path = path[:i]
+	}
+	query := ""
+	if i := strings.IndexByte(path, '?'); i >= 0 {
+		path, query = path[:i], path[i+1:]
+	}
+	if path == "" {
+		path = "/"
+	}
+	return path, query
+}
+
+// destination returns the namespace and name of the service receiving the request
+func destination(attributes *authv3.AttributeContext) (string, string, error) {
+	extensions := attributes.GetContextExtensions()
+	namespace, service := extensions[NamespaceExtension], extensions[ServiceExtension]
+	if service == "" {
+		parts := strings.SplitN(attributes.GetDestination().GetService(), ".", 3)
+		service = parts[0]
+		if namespace == "" && len(parts) > 1 {
+			namespace = parts[1]
+		}
+	}
+	if namespace == "" || service == "" {
+		return "", "", errors.New("missing destination namespace or service")
+	}
+	return namespace, service, nil
+}
+
+// newCheckResponse converts a Mixer response to a CheckResponse.
+// Allowed requests forward the authorization and claim headers to the service, and return any session cookie
+// to the client. Denied requests return the direct HTTP response of the Mixer status, such as a login redirect.
+func newCheckResponse(response *authnz.HandleAuthnZResponse) *authv3.CheckResponse {
+	if response == nil || response.Result == nil {
+		zap.L().Warn("Handler returned an empty response")
+		return newDeniedResponse(status.WithInternal("empty response"))
+	}
+	if !status.IsOK(response.Result.Status) {
+		return newDeniedResponse(response.Result.Status)
+	}
+
+	ok := &authv3.OkHttpResponse{}
+	output := response.Output
+	if output == nil {
+		output = &authnz.OutputMsg{}
+	}
+	if output.Authorization != "" {
+		ok.Headers = append(ok.Headers, headerValue(authorization, output.Authorization, authv3.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD))
+	}
+	for _, name := range sortedKeys(output.Headers) {
+		// Claim headers without a value are removed so clients cannot supply them
+		if output.Headers[name] == "" {
+			ok.HeadersToRemove = append(ok.HeadersToRemove, name)
+			continue
+		}
+		ok.Headers = append(ok.Headers, headerValue(name, output.Headers[name], authv3.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD))
+	}
+	if output.SessionCookie != "" {
+		ok.ResponseHeadersToAdd = append(ok.ResponseHeadersToAdd, headerValue(setCookie, output.SessionCookie, authv3.HeaderValueOption_APPEND_IF_EXISTS_OR_ADD))
+	}
+	return &authv3.CheckResponse{
+		Status:       &rpc.Status{Code: int32(rpc.OK)},
+		HttpResponse: &authv3.CheckResponse_OkResponse{OkResponse: ok},
+	}
+}
+
+// newDeniedResponse builds a denied CheckResponse from a Mixer status.
+// Without a direct HTTP response, the HTTP status is derived from the status code.
+func newDeniedResponse(s rpc.Status) *authv3.CheckResponse {
+	denied := &authv3.DeniedHttpResponse{
+		Status: &authv3.HttpStatus{Code: authv3.StatusCode(status.HTTPStatusFromCode(rpc.Code(s.Code)))},
+	}
+	if direct := status.GetDirectHTTPResponse(s); direct != nil {
+		if direct.Code != 0 {
+			denied.Status.Code = authv3.StatusCode(direct.Code)
+		}
+		denied.Body = direct.Body
+		for _, name := range sortedKeys(direct.Headers) {
+			denied.Headers = append(denied.Headers, headerValue(name, direct.Headers[name], authv3.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD))
+		}
+	}
+	return &authv3.CheckResponse{
+		Status:       &rpc.Status{Code: s.Code, Message: s.Message},
+		HttpResponse: &authv3.CheckResponse_DeniedResponse{DeniedResponse: denied},
+	}
+}
+
+// newInvalidResponse denies a CheckRequest that cannot be authorized
+func newInvalidResponse(err error) *authv3.CheckResponse {
+	return newDeniedResponse(rpc.Status{
+		Code:    int32(rpc.INVALID_ARGUMENT),
+		Message: err.Error(),
+		Details: []*types.Any{status.PackErrorDetail(&istiopolicy.DirectHttpResponse{
+			Code: istiopolicy.InternalServerError,
+			Body: http.StatusText(http.StatusInternalServerError),
+		})},
+	})
+}
+
+func headerValue(name string, value string, action authv3.HeaderValueOption_HeaderAppendAction) *authv3.HeaderValueOption {
+	return &authv3.HeaderValueOption{
+		Header:       &authv3.HeaderValue{Key: name, Value: value},
+		AppendAction: action,
+	}
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
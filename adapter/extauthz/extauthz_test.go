@@ -0,0 +1,255 @@
+package extauthz
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/gogo/googleapis/google/rpc"
+	"github.com/gogo/protobuf/types"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"istio.io/api/mixer/adapter/model/v1beta1"
+	istiopolicy "istio.io/api/policy/v1beta1"
+	"istio.io/istio/mixer/pkg/status"
+
+	authv3 "github.com/ibm-cloud-security/app-identity-and-access-adapter/config/envoy/service/auth/v3"
+	"github.com/ibm-cloud-security/app-identity-and-access-adapter/config/template"
+)
+
+// mockHandler records the last request and returns a fixed response
+type mockHandler struct {
+	request  *authnz.HandleAuthnZRequest
+	response *authnz.HandleAuthnZResponse
+	err      error
+}
+
+func (m *mockHandler) HandleAuthnZ(ctx context.Context, r *authnz.HandleAuthnZRequest) (*authnz.HandleAuthnZResponse, error) {
+	m.request = r
+	return m.response, m.err
+}
+
+func checkRequest(path string, headers map[string]string, extensions map[string]string, destination string) *authv3.CheckRequest {
+	return &authv3.CheckRequest{
+		Attributes: &authv3.AttributeContext{
+			Destination: &authv3.AttributeContext_Peer{Service: destination},
+			Request: &authv3.AttributeContext_Request{
+				Http: &authv3.AttributeContext_HttpRequest{
+					Method:  "GET",
+					Path:    path,
+					Host:    "api.example.com",
+					Scheme:  "https",
+					Headers: headers,
+				},
+			},
+			ContextExtensions: extensions,
+		},
+	}
+}
+
+func TestCheckRequest(t *testing.T) {
+	tests := []struct {
+		name      string
+		req       *authv3.CheckRequest
+		namespace string
+		service   string
+		path      string
+		params    authnz.QueryParamsMsg
+	}{
+		{
+			name:      "context extensions",
+			req:       checkRequest("/api/items?code=1&state=2#top", nil, map[string]string{"namespace": "ns", "service": "svc"}, "other.other.svc.cluster.local"),
+			namespace: "ns",
+			service:   "svc",
+			path:      "/api/items",
+			params:    authnz.QueryParamsMsg{Code: "1", State: "2"},
+		},
+		{
+			name:      "destination service",
+			req:       checkRequest("/api/items/?error=denied", nil, nil, "svc.ns.svc.cluster.local"),
+			namespace: "ns",
+			service:   "svc",
+			path:      "/api/items/",
+			params:    authnz.QueryParamsMsg{Error: "denied"},
+		},
+		{
+			name:      "root path",
+			req:       checkRequest("", nil, map[string]string{"namespace": "ns"}, "svc"),
+			namespace: "ns",
+			service:   "svc",
+			path:      "/",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(st *testing.T) {
+			r, err := newAuthnZRequest(test.req)
+			assert.Nil(st, err)
+			assert.Equal(st, &authnz.TargetMsg{Namespace: test.namespace, Service: test.service, Method: "GET", Path: test.path}, r.Instance.Target)
+			assert.Equal(st, "https", r.Instance.Request.Scheme)
+			assert.Equal(st, "api.example.com", r.Instance.Request.Host)
+			assert.Equal(st, test.path, r.Instance.Request.Path)
+			assert.Equal(st, &test.params, r.Instance.Request.Params)
+		})
+	}
+
+	_, err := newAuthnZRequest(&authv3.CheckRequest{})
+	assert.EqualError(t, err, "missing HTTP request attributes")
+
+	// Requests that no policy applies to are rejected rather than allowed
+	for _, req := range []*authv3.CheckRequest{
+		checkRequest("/", nil, nil, ""),
+		checkRequest("/", nil, nil, "svc"),
+		checkRequest("/", nil, map[string]string{"namespace": "ns"}, ""),
+	} {
+		_, err = newAuthnZRequest(req)
+		assert.EqualError(t, err, "missing destination namespace or service")
+	}
+}
+
+func TestCheckRequestHeaders(t *testing.T) {
+	r, err := newAuthnZRequest(checkRequest("/", map[string]string{
+		":authority":    "api.example.com",
+		"authorization": "Bearer token",
+		"cookie":        "session=1",
+		"X-Channel":     "beta",
+	}, nil, "svc.ns"))
+	assert.Nil(t, err)
+	assert.Equal(t, "Bearer token", r.Instance.Request.Headers.Authorization)
+	assert.Equal(t, "session=1", r.Instance.Request.Headers.Cookies)
+	assert.Equal(t, map[string]*istiopolicy.Value{
+		"x-channel": {Value: &istiopolicy.Value_StringValue{StringValue: "beta"}},
+	}, r.Instance.Request.Headers.Properties)
+}
+
+func TestCheckResponse(t *testing.T) {
+	overwrite := authv3.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD
+	tests := []struct {
+		name     string
+		response *authnz.HandleAuthnZResponse
+		expected *authv3.CheckResponse
+	}{
+		{
+			name:     "allowed",
+			response: &authnz.HandleAuthnZResponse{Result: &v1beta1.CheckResult{Status: status.OK}},
+			expected: &authv3.CheckResponse{
+				Status:       &rpc.Status{Code: int32(rpc.OK)},
+				HttpResponse: &authv3.CheckResponse_OkResponse{OkResponse: &authv3.OkHttpResponse{}},
+			},
+		},
+		{
+			name: "allowed with headers",
+			response: &authnz.HandleAuthnZResponse{
+				Result: &v1beta1.CheckResult{Status: status.OK},
+				Output: &authnz.OutputMsg{
+					Authorization: "Bearer access id",
+					SessionCookie: "session=1",
+					Headers:       map[string]string{"x-user": "user", "x-email": ""},
+				},
+			},
+			expected: &authv3.CheckResponse{
+				Status: &rpc.Status{Code: int32(rpc.OK)},
+				HttpResponse: &authv3.CheckResponse_OkResponse{OkResponse: &authv3.OkHttpResponse{
+					Headers: []*authv3.HeaderValueOption{
+						headerValue("authorization", "Bearer access id", overwrite),
+						headerValue("x-user", "user", overwrite),
+					},
+					HeadersToRemove:      []string{"x-email"},
+					ResponseHeadersToAdd: []*authv3.HeaderValueOption{headerValue("set-cookie", "session=1", authv3.HeaderValueOption_APPEND_IF_EXISTS_OR_ADD)},
+				}},
+			},
+		},
+		{
+			name: "redirect",
+			response: &authnz.HandleAuthnZResponse{Result: &v1beta1.CheckResult{Status: rpc.Status{
+				Code:    int32(rpc.UNAUTHENTICATED),
+				Message: "Redirecting to identity provider",
+				Details: []*types.Any{status.PackErrorDetail(&istiopolicy.DirectHttpResponse{
+					Code:    istiopolicy.Found,
+					Headers: map[string]string{"Location": "https://idp/authorize", "Set-Cookie": "state=1"},
+				})},
+			}}},
+			expected: &authv3.CheckResponse{
+				Status: &rpc.Status{Code: int32(rpc.UNAUTHENTICATED), Message: "Redirecting to identity provider"},
+				HttpResponse: &authv3.CheckResponse_DeniedResponse{DeniedResponse: &authv3.DeniedHttpResponse{
+					Status: &authv3.HttpStatus{Code: authv3.StatusCode_Found},
+					Headers: []*authv3.HeaderValueOption{
+						headerValue("Location", "https://idp/authorize", overwrite),
+						headerValue("Set-Cookie", "state=1", overwrite),
+					},
+				}},
+			},
+		},
+		{
+			name:     "denied without direct response",
+			response: &authnz.HandleAuthnZResponse{Result: &v1beta1.CheckResult{Status: status.WithPermissionDenied("denied")}},
+			expected: &authv3.CheckResponse{
+				Status: &rpc.Status{Code: int32(rpc.PERMISSION_DENIED), Message: "denied"},
+				HttpResponse: &authv3.CheckResponse_DeniedResponse{DeniedResponse: &authv3.DeniedHttpResponse{
+					Status: &authv3.HttpStatus{Code: authv3.StatusCode_Forbidden},
+				}},
+			},
+		},
+		{
+			name: "empty",
+			expected: &authv3.CheckResponse{
+				Status: &rpc.Status{Code: int32(rpc.INTERNAL), Message: "empty response"},
+				HttpResponse: &authv3.CheckResponse_DeniedResponse{DeniedResponse: &authv3.DeniedHttpResponse{
+					Status: &authv3.HttpStatus{Code: authv3.StatusCode_InternalServerError},
+				}},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(st *testing.T) {
+			assert.Equal(st, test.expected, newCheckResponse(test.response))
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	handler := &mockHandler{response: &authnz.HandleAuthnZResponse{Result: &v1beta1.CheckResult{Status: status.OK}}}
+	s := New(handler)
+
+	response, err := s.Check(context.Background(), checkRequest("/path", nil, nil, "svc.ns"))
+	assert.Nil(t, err)
+	assert.Equal(t, int32(rpc.OK), response.Status.Code)
+	assert.Equal(t, "/path", handler.request.Instance.Target.Path)
+
+	// Invalid checks are denied without reaching the handler
+	handler.request = nil
+	for _, req := range []*authv3.CheckRequest{{}, checkRequest("/path", nil, nil, "")} {
+		response, err = s.Check(context.Background(), req)
+		assert.Nil(t, err)
+		assert.Equal(t, int32(rpc.INVALID_ARGUMENT), response.Status.Code)
+		assert.Equal(t, authv3.StatusCode_InternalServerError, response.GetDeniedResponse().Status.Code)
+		assert.Equal(t, "Internal Server Error", response.GetDeniedResponse().Body)
+		assert.Nil(t, handler.request)
+	}
+
+	handler.err = errors.New("could not check policies")
+	_, err = s.Check(context.Background(), checkRequest("/path", nil, nil, "svc.ns"))
+	assert.EqualError(t, err, "could not check policies")
+}
+
+func TestCheckGRPC(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	server := grpc.NewServer()
+	handler := &mockHandler{response: &authnz.HandleAuthnZResponse{Result: &v1beta1.CheckResult{Status: status.WithPermissionDenied("denied")}}}
+	authv3.RegisterAuthorizationServer(server, New(handler))
+	go func() { _ = server.Serve(listener) }()
+	defer server.Stop()
+
+	conn, err := grpc.Dial(listener.Addr().String(), grpc.WithInsecure())
+	assert.Nil(t, err)
+	defer conn.Close()
+
+	response, err := authv3.NewAuthorizationClient(conn).Check(context.Background(), checkRequest("/path", nil, map[string]string{"namespace": "ns", "service": "svc"}, ""))
+	assert.Nil(t, err)
+	assert.Equal(t, int32(rpc.PERMISSION_DENIED), response.Status.Code)
+	assert.Equal(t, authv3.StatusCode_Forbidden, response.GetDeniedResponse().Status.Code)
+	assert.Equal(t, "svc", handler.request.Instance.Target.Service)
+}
@@ -0,0 +1,268 @@
+// Package forwardauth serves the adapter as an HTTP forward authentication endpoint
+// for proxies such as NGINX (auth_request) and Traefik (ForwardAuth)
+package forwardauth
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/gogo/googleapis/google/rpc"
+	"go.uber.org/zap"
+	istiopolicy "istio.io/api/policy/v1beta1"
+	"istio.io/istio/mixer/pkg/status"
+
+	"github.com/ibm-cloud-security/app-identity-and-access-adapter/config/template"
+)
+
+const (
+	// NamespaceParam is the query parameter of the endpoint naming the namespace of the protected service
+	NamespaceParam = "namespace"
+	// ServiceParam is the query parameter of the endpoint naming the protected service
+	ServiceParam = "service"
+
+	authorization = "Authorization"
+	cookie        = "Cookie"
+	setCookie     = "Set-Cookie"
+
+	// Traefik ForwardAuth headers
+	forwardedMethod = "X-Forwarded-Method"
+	forwardedProto  = "X-Forwarded-Proto"
+	forwardedHost   = "X-Forwarded-Host"
+	forwardedURI    = "X-Forwarded-Uri"
+
+	// Headers commonly set for NGINX auth_request
+	originalMethod = "X-Original-Method"
+	originalURI    = "X-Original-Uri"
+	originalURL    = "X-Original-Url"
+)
+
+// Handler answers forward authentication subrequests.
+// Each subrequest is converted to the authnz instance Mixer would send for the original request and handled
+// by the Mixer handler, so proxies share the policy engine and strategies of the mesh.
+type Handler struct {
+	handler authnz.HandleAuthnZServiceServer
+}
+
+// New creates a new Handler checking requests with the given Mixer handler
+func New(handler authnz.HandleAuthnZServiceServer) *Handler {
+	return &Handler{handler: handler}
+}
+
+// NewServer returns an HTTP server serving the handler on the given address
+func NewServer(addr string, handler *Handler) *http.Server {
+	return &http.Server{Addr: addr, Handler: handler}
+}
+
+// ServeHTTP authorizes the original request described by the subrequest.
+// Allowed requests are answered with 200 OK and the headers to forward to the service.
+// Denied requests are answered with the direct HTTP response of the policy, such as 401 Unauthorized
+// or a 302 Found redirect to the identity provider with its cookies.
+// Subrequests not naming the protected service are answered with 400 Bad Request,
+// as no policy would apply to them, and so are original paths that are not in canonical form.
+func (h *Handler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	query := req.URL.Query()
+	if query.Get(NamespaceParam) == "" || query.Get(ServiceParam) == "" {
+		zap.L().Warn("Forward authentication request does not name the protected service",
+			zap.String("namespace", query.Get(NamespaceParam)), zap.String("service", query.Get(ServiceParam)))
+		http.Error(rw, "the namespace and service query parameters are required", http.StatusBadRequest)
+		return
+	}
+	request, err := newAuthnZRequest(req)
+	if err != nil {
+		zap.L().Info("Invalid forward authentication request", zap.Error(err))
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	response, err := h.handler.HandleAuthnZ(req.Context(), request)
+	if err != nil {
+		zap.L().Debug("Could not handle forward authentication request", zap.Error(err))
+		http.Error(rw, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	if response == nil || response.Result == nil {
+		zap.L().Warn("Handler returned an empty response")
+		http.Error(rw, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	if !status.IsOK(response.Result.Status) {
+		writeDenied(rw, response.Result.Status)
+		return
+	}
+	if output := response.Output; output != nil {
+		if output.Authorization != "" {
+			rw.Header().Set(authorization, output.Authorization)
+		}
+		for name, value := range output.Headers {
+			// Proxies drop configured headers missing from the response, so empty claims are omitted
+			if value != "" {
+				rw.Header().Set(name, value)
+			}
+		}
+		if output.SessionCookie != "" {
+			rw.Header().Add(setCookie, output.SessionCookie)
+		}
+	}
+	rw.WriteHeader(http.StatusOK)
+}
+
+// writeDenied writes the direct HTTP response of a Mixer status.
+// Without a direct HTTP response, the HTTP status is derived from the status code.
+func writeDenied(rw http.ResponseWriter, s rpc.Status) {
+	code := status.HTTPStatusFromCode(rpc.Code(s.Code))
+	body := ""
+	if direct := status.GetDirectHTTPResponse(s); direct != nil {
+		if direct.Code != 0 {
+			code = int(direct.Code)
+		}
+		for name, value := range direct.Headers {
+			rw.Header().Set(name, value)
+		}
+		body = direct.Body
+	}
+	if body == "" {
+		body = http.StatusText(code)
+	}
+	rw.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	rw.WriteHeader(code)
+	_, _ = rw.Write([]byte(body))
+}
+
+// newAuthnZRequest maps a forward authentication subrequest onto an authnz instance.
+// The original method, URI, host and scheme are read from the X-Forwarded-* headers sent by Traefik,
+// then from the X-Original-* headers commonly set for NGINX, and finally from the subrequest itself.
+// These headers select the policies evaluated, so the proxy must overwrite any value sent by the client.
+// The protected service is named by the namespace and service query parameters of the subrequest.
+// An error is returned if the original path is not in canonical form.
+func newAuthnZRequest(req *http.Request) (*authnz.HandleAuthnZRequest, error) {
+	method := firstHeader(req.Header, forwardedMethod, originalMethod)
+	if method == "" {
+		method = req.Method
+	}
+
+	uri := firstHeader(req.Header, forwardedURI, originalURI)
+	scheme := req.Header.Get(forwardedProto)
+	host := req.Header.Get(forwardedHost)
+	if original, err := url.Parse(req.Header.Get(originalURL)); err == nil && original.Host != "" {
+		if uri == "" {
+			uri = original.RequestURI()
+		}
+		if scheme == "" {
+			scheme = original.Scheme
+		}
+		if host == "" {
+			host = original.Host
+		}
+	}
+	if uri == "" {
+		uri = req.URL.RequestURI()
+	}
+	if host == "" {
+		host = req.Host
+	}
+	if scheme == "" {
+		scheme = "http"
+		if req.TLS != nil {
+			scheme = "https"
+		}
+	}
+
+	rawPath, query := splitURI(uri)
+	path, err := canonicalPath(rawPath)
+	if err != nil {
+		return nil, err
+	}
+	params, err := url.ParseQuery(query)
+	if err != nil {
+		zap.L().Debug("Could not parse query parameters", zap.String("query", query), zap.Error(err))
+	}
+
+	headers := &authnz.HeadersMsg{
+		Authorization: req.Header.Get(authorization),
+		Cookies:       strings.Join(req.Header[cookie], "; "),
+		Properties:    make(map[string]*istiopolicy.Value),
+	}
+	for name, values := range req.Header {
+		if name == authorization || name == cookie {
+			continue
+		}
+		headers.Properties[strings.ToLower(name)] = &istiopolicy.Value{Value: &istiopolicy.Value_StringValue{StringValue: strings.Join(values, ",")}}
+	}
+
+	return &authnz.HandleAuthnZRequest{
+		Instance: &authnz.InstanceMsg{
+			Target: &authnz.TargetMsg{
+				Namespace: req.URL.Query().Get(NamespaceParam),
+				Service:   req.URL.Query().Get(ServiceParam),
+				Method:    strings.ToUpper(method),
+				Path:      path,
+			},
+			Request: &authnz.RequestMsg{
+				Scheme:  scheme,
+				Host:    host,
+				Path:    path,
+				Headers: headers,
+				Params: &authnz.QueryParamsMsg{
+					Code:  params.Get("code"),
+					Error: params.Get("error"),
+					State: params.Get("state"),
+				},
+			},
+		},
+	}, nil
+}
+
+// firstHeader returns the first non empty value of the given headers
+func firstHeader(header http.Header, names ...string) string {
+	for _, name := range names {
+		if value := header.Get(name); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// canonicalPath returns the decoded original path.
+// Policies are matched against the path the service will serve, so paths with dot-segments, empty segments,
+// backslashes or encoded slashes, which proxies and services may resolve differently, are rejected.
+func canonicalPath(rawPath string) (string, error) {
+	lower := strings.ToLower(rawPath)
+	if strings.Contains(lower, "%2f") || strings.Contains(lower, "%5c") {
+		return "", errors.New("path contains an encoded slash")
+	}
+	decoded, err := url.PathUnescape(rawPath)
+	if err != nil {
+		return "", errors.New("path is not properly escaped")
+	}
+	if strings.Contains(decoded, "\\") {
+		return "", errors.New("path contains a backslash")
+	}
+	if !strings.HasPrefix(decoded, "/") {
+		return "", errors.New("path is not absolute")
+	}
+	cleaned := path.Clean(decoded)
+	if strings.HasSuffix(decoded, "/") && cleaned != "/" {
+		cleaned += "/"
+	}
+	if cleaned != decoded {
+		return "", errors.New("path contains dot-segments or empty segments")
+	}
+	return decoded, nil
+}
+
+// splitURI separates a request URI into its path and query, dropping any fragment
+func splitURI(uri string) (string, string) {
+	if i := strings.IndexByte(uri, '#'); i >= 0 {
+		uri = uri[:i]
+	}
+	query := ""
+	if i := strings.IndexByte(uri, '?'); i >= 0 {
+		uri, query = uri[:i], uri[i+1:]
+	}
+	if uri == "" {
+		uri = "/"
+	}
+	return uri, query
+}
@@ -0,0 +1,279 @@
+package forwardauth
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gogo/googleapis/google/rpc"
+	"github.com/gogo/protobuf/types"
+	"github.com/stretchr/testify/assert"
+	"istio.io/api/mixer/adapter/model/v1beta1"
+	istiopolicy "istio.io/api/policy/v1beta1"
+	"istio.io/istio/mixer/pkg/status"
+
+	"github.com/ibm-cloud-security/app-identity-and-access-adapter/config/template"
+)
+
+// mockHandler records the last request and returns a fixed response
+type mockHandler struct {
+	request  *authnz.HandleAuthnZRequest
+	response *authnz.HandleAuthnZResponse
+	err      error
+}
+
+func (m *mockHandler) HandleAuthnZ(ctx context.Context, r *authnz.HandleAuthnZRequest) (*authnz.HandleAuthnZResponse, error) {
+	m.request = r
+	return m.response, m.err
+}
+
+func TestForwardedRequest(t *testing.T) {
+	tests := []struct {
+		name     string
+		url      string
+		headers  map[string]string
+		target   authnz.TargetMsg
+		scheme   string
+		host     string
+		params   authnz.QueryParamsMsg
+		property string
+	}{
+		{
+			name: "traefik",
+			url:  "/?namespace=ns&service=svc",
+			headers: map[string]string{
+				"X-Forwarded-Method": "post",
+				"X-Forwarded-Proto":  "https",
+				"X-Forwarded-Host":   "app.example.com",
+				"X-Forwarded-Uri":    "/oidc/callback?code=1&state=2",
+			},
+			target: authnz.TargetMsg{Namespace: "ns", Service: "svc", Method: "POST", Path: "/oidc/callback"},
+			scheme: "https",
+			host:   "app.example.com",
+			params: authnz.QueryParamsMsg{Code: "1", State: "2"},
+		},
+		{
+			name: "nginx original url",
+			url:  "/auth?namespace=ns&service=svc",
+			headers: map[string]string{
+				"X-Original-Method": "GET",
+				"X-Original-URL":    "https://app.example.com/api/items?error=denied",
+				"X-Channel":         "beta",
+			},
+			target:   authnz.TargetMsg{Namespace: "ns", Service: "svc", Method: "GET", Path: "/api/items"},
+			scheme:   "https",
+			host:     "app.example.com",
+			params:   authnz.QueryParamsMsg{Error: "denied"},
+			property: "beta",
+		},
+		{
+			name: "nginx original uri",
+			url:  "/auth?namespace=ns&service=svc",
+			headers: map[string]string{
+				"X-Original-URI": "/api/items",
+				"X-Original-URL": "https://other.example.com/other",
+			},
+			target: authnz.TargetMsg{Namespace: "ns", Service: "svc", Method: "GET", Path: "/api/items"},
+			scheme: "https",
+			host:   "other.example.com",
+		},
+		{
+			name: "encoded path",
+			url:  "/auth?namespace=ns&service=svc",
+			headers: map[string]string{
+				"X-Forwarded-Uri": "/api/it%65ms/?code=1",
+			},
+			target: authnz.TargetMsg{Namespace: "ns", Service: "svc", Method: "GET", Path: "/api/items/"},
+			scheme: "http",
+			host:   "example.com",
+			params: authnz.QueryParamsMsg{Code: "1"},
+		},
+		{
+			name:   "subrequest",
+			url:    "/api/items?namespace=ns&service=svc",
+			target: authnz.TargetMsg{Namespace: "ns", Service: "svc", Method: "GET", Path: "/api/items"},
+			scheme: "http",
+			host:   "example.com",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(st *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, test.url, nil)
+			for name, value := range test.headers {
+				req.Header.Set(name, value)
+			}
+			r, err := newAuthnZRequest(req)
+			assert.Nil(st, err)
+			assert.Equal(st, &test.target, r.Instance.Target)
+			assert.Equal(st, test.scheme, r.Instance.Request.Scheme)
+			assert.Equal(st, test.host, r.Instance.Request.Host)
+			assert.Equal(st, test.target.Path, r.Instance.Request.Path)
+			assert.Equal(st, &test.params, r.Instance.Request.Params)
+			assert.Equal(st, test.property, r.Instance.Request.Headers.Properties["x-channel"].GetStringValue())
+		})
+	}
+}
+
+func TestForwardedCredentials(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer token")
+	req.Header.Add("Cookie", "a=1")
+	req.Header.Add("Cookie", "b=2")
+	r, err := newAuthnZRequest(req)
+	assert.Nil(t, err)
+	assert.Equal(t, "Bearer token", r.Instance.Request.Headers.Authorization)
+	assert.Equal(t, "a=1; b=2", r.Instance.Request.Headers.Cookies)
+	assert.Nil(t, r.Instance.Request.Headers.Properties["authorization"])
+	assert.Nil(t, r.Instance.Request.Headers.Properties["cookie"])
+}
+
+func TestServeHTTP(t *testing.T) {
+	tests := []struct {
+		name     string
+		response *authnz.HandleAuthnZResponse
+		err      error
+		code     int
+		headers  map[string]string
+		body     string
+	}{
+		{
+			name: "allowed",
+			response: &authnz.HandleAuthnZResponse{
+				Result: &v1beta1.CheckResult{Status: status.OK},
+				Output: &authnz.OutputMsg{
+					Authorization: "Bearer access id",
+					SessionCookie: "session=1",
+					Headers:       map[string]string{"x-user": "user", "x-email": ""},
+				},
+			},
+			code:    http.StatusOK,
+			headers: map[string]string{"Authorization": "Bearer access id", "Set-Cookie": "session=1", "X-User": "user", "X-Email": ""},
+		},
+		{
+			name: "redirect",
+			response: &authnz.HandleAuthnZResponse{Result: &v1beta1.CheckResult{Status: rpc.Status{
+				Code: int32(rpc.UNAUTHENTICATED),
+				Details: []*types.Any{status.PackErrorDetail(&istiopolicy.DirectHttpResponse{
+					Code:    istiopolicy.Found,
+					Headers: map[string]string{"Location": "https://idp/authorize", "Set-Cookie": "state=1"},
+				})},
+			}}},
+			code:    http.StatusFound,
+			headers: map[string]string{"Location": "https://idp/authorize", "Set-Cookie": "state=1"},
+			body:    "Found",
+		},
+		{
+			name: "unauthorized",
+			response: &authnz.HandleAuthnZResponse{Result: &v1beta1.CheckResult{Status: rpc.Status{
+				Code: int32(rpc.UNAUTHENTICATED),
+				Details: []*types.Any{status.PackErrorDetail(&istiopolicy.DirectHttpResponse{
+					Code:    istiopolicy.Unauthorized,
+					Body:    "token expired",
+					Headers: map[string]string{"WWW-Authenticate": "Bearer"},
+				})},
+			}}},
+			code:    http.StatusUnauthorized,
+			headers: map[string]string{"Www-Authenticate": "Bearer"},
+			body:    "token expired",
+		},
+		{
+			name:     "denied without direct response",
+			response: &authnz.HandleAuthnZResponse{Result: &v1beta1.CheckResult{Status: status.WithPermissionDenied("denied")}},
+			code:     http.StatusForbidden,
+			body:     "Forbidden",
+		},
+		{
+			name: "error",
+			err:  errors.New("could not check policies"),
+			code: http.StatusInternalServerError,
+			body: "Internal Server Error\n",
+		},
+		{
+			name: "empty",
+			code: http.StatusInternalServerError,
+			body: "Internal Server Error\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(st *testing.T) {
+			handler := &mockHandler{response: test.response, err: test.err}
+			server := httptest.NewServer(NewServer("", New(handler)).Handler)
+			defer server.Close()
+
+			req, _ := http.NewRequest(http.MethodGet, server.URL+"/?namespace=ns&service=svc", nil)
+			req.Header.Set("X-Forwarded-Uri", "/path")
+			client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+			res, err := client.Do(req)
+			assert.Nil(st, err)
+			defer res.Body.Close()
+			body, _ := ioutil.ReadAll(res.Body)
+
+			assert.Equal(st, test.code, res.StatusCode)
+			assert.Equal(st, test.body, string(body))
+			for name, value := range test.headers {
+				assert.Equal(st, value, res.Header.Get(name))
+			}
+			assert.Equal(st, "/path", handler.request.Instance.Target.Path)
+		})
+	}
+}
+
+func TestMissingTarget(t *testing.T) {
+	tests := []struct {
+		name string
+		url  string
+	}{
+		{name: "no parameters", url: "/"},
+		{name: "no service", url: "/?namespace=ns"},
+		{name: "no namespace", url: "/?service=svc"},
+		{name: "empty service", url: "/?namespace=ns&service="},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(st *testing.T) {
+			handler := &mockHandler{response: &authnz.HandleAuthnZResponse{Result: &v1beta1.CheckResult{Status: status.OK}}}
+			rw := httptest.NewRecorder()
+			New(handler).ServeHTTP(rw, httptest.NewRequest(http.MethodGet, test.url, nil))
+			assert.Equal(st, http.StatusBadRequest, rw.Code)
+			assert.Nil(st, handler.request)
+		})
+	}
+}
+
+func TestNonCanonicalPath(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  string
+		uri     string
+		message string
+	}{
+		{name: "dot-segment", header: "X-Forwarded-Uri", uri: "/public/../admin", message: "path contains dot-segments or empty segments"},
+		{name: "encoded dot-segment", header: "X-Original-Uri", uri: "/public/%2e%2e/admin", message: "path contains dot-segments or empty segments"},
+		{name: "current segment", header: "X-Forwarded-Uri", uri: "/public/./admin", message: "path contains dot-segments or empty segments"},
+		{name: "empty segment", header: "X-Forwarded-Uri", uri: "//admin", message: "path contains dot-segments or empty segments"},
+		{name: "original url", header: "X-Original-Url", uri: "https://app.example.com/public/../admin", message: "path contains dot-segments or empty segments"},
+		{name: "encoded slash", header: "X-Forwarded-Uri", uri: "/public%2F..%2Fadmin", message: "path contains an encoded slash"},
+		{name: "encoded backslash", header: "X-Forwarded-Uri", uri: "/public/..%5cadmin", message: "path contains an encoded slash"},
+		{name: "backslash", header: "X-Forwarded-Uri", uri: "/public/..\\admin", message: "path contains a backslash"},
+		{name: "invalid escape", header: "X-Forwarded-Uri", uri: "/public/%zz", message: "path is not properly escaped"},
+		{name: "relative", header: "X-Forwarded-Uri", uri: "admin", message: "path is not absolute"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(st *testing.T) {
+			handler := &mockHandler{response: &authnz.HandleAuthnZResponse{Result: &v1beta1.CheckResult{Status: status.OK}}}
+			req := httptest.NewRequest(http.MethodGet, "/auth?namespace=ns&service=svc", nil)
+			req.Header.Set(test.header, test.uri)
+			rw := httptest.NewRecorder()
+			New(handler).ServeHTTP(rw, req)
+			assert.Equal(st, http.StatusBadRequest, rw.Code)
+			assert.Equal(st, test.message+"\n", rw.Body.String())
+			assert.Nil(st, handler.request)
+		})
+	}
+}
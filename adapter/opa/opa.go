@@ -0,0 +1,182 @@
+// Package opa evaluates Rego authorization policies using an embedded Open Policy Agent
+package opa
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/rego"
+	"github.com/open-policy-agent/opa/storage"
+	"github.com/open-policy-agent/opa/storage/inmem"
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultQuery is evaluated when a policy does not specify a query
+	DefaultQuery = "data.authz.allow"
+	// ModuleSuffix identifies the ConfigMap keys holding Rego modules
+	ModuleSuffix = ".rego"
+)
+
+// Policy is a compiled set of Rego modules
+type Policy interface {
+	Eval(query string, input Input) (bool, error)
+}
+
+// Input is the document Rego modules are evaluated against
+type Input struct {
+	// Claims holds the claims of the validated token
+	Claims map[string]interface{} `json:"claims"`
+	// Request describes the incoming HTTP request
+	Request Request `json:"request"`
+	// Target describes the destination service
+	Target Target `json:"target"`
+}
+
+// Request exposes the request attributes available to Rego modules
+type Request struct {
+	Scheme  string            `json:"scheme"`
+	Host    string            `json:"host"`
+	Method  string            `json:"method"`
+	Path    string            `json:"path"`
+	Headers map[string]string `json:"headers"`
+	Params  map[string]string `json:"params"`
+}
+
+// Target exposes the destination attributes available to Rego modules
+type Target struct {
+	Namespace string `json:"namespace"`
+	Service   string `json:"service"`
+}
+
+type policy struct {
+	name     string
+	compiler *ast.Compiler
+	store    storage.Store
+	// queries holds the queries prepared against the compiled modules, by query string
+	queriesMu sync.RWMutex
+	queries   map[string]*preparedQuery
+}
+
+// Compile parses and compiles the given Rego modules and prepares the default query.
+// Only modules whose name ends with ModuleSuffix are compiled.
+func Compile(name string, modules map[string]string) (Policy, error) {
+	parsed := make(map[string]*ast.Module)
+	for filename, source := range modules {
+		if !strings.HasSuffix(filename, ModuleSuffix) {
+			continue
+		}
+		module, err := ast.ParseModule(filename, source)
+		if err != nil {
+			return nil, err
+		}
+		if module == nil {
+			return nil, fmt.Errorf("rego module %s is empty", filename)
+		}
+		parsed[filename] = module
+	}
+
+	if len(parsed) == 0 {
+		return nil, errors.New("no rego modules found")
+	}
+
+	compiler := ast.NewCompiler()
+	if compiler.Compile(parsed); compiler.Failed() {
+		return nil, compiler.Errors
+	}
+
+	p := &policy{name: name, compiler: compiler, store: inmem.New(), queries: make(map[string]*preparedQuery)}
+	if q := p.prepare(DefaultQuery); q.err != nil {
+		return nil, q.err
+	}
+	return p, nil
+}
+
+// Eval evaluates the query against the compiled modules.
+// The query must produce a single boolean value; undefined results deny access.
+// Queries are compiled on first use and reused by later evaluations.
+func (p *policy) Eval(query string, input Input) (bool, error) {
+	if query == "" {
+		query = DefaultQuery
+	}
+
+	start := time.Now()
+	rs, err := p.prepare(query).eval(context.Background(), p.compiler, p.store, input)
+
+	allowed, err := decision(rs, err)
+	p.log(query, input, allowed, time.Since(start), err)
+	return allowed, err
+}
+
+// prepare returns the query compiled against the modules of the policy.
+// Queries that fail to compile are kept with their error, so they are not compiled again.
+func (p *policy) prepare(query string) *preparedQuery {
+	p.queriesMu.RLock()
+	q, ok := p.queries[query]
+	p.queriesMu.RUnlock()
+	if ok {
+		return q
+	}
+
+	q = newPreparedQuery(p.compiler, query)
+
+	p.queriesMu.Lock()
+	defer p.queriesMu.Unlock()
+	if existing, ok := p.queries[query]; ok {
+		return existing
+	}
+	p.queries[query] = q
+	return q
+}
+
+// decision converts a result set into an authorization decision
+func decision(rs rego.ResultSet, err error) (bool, error) {
+	if err != nil {
+		return false, err
+	}
+	if len(rs) == 0 || len(rs[0].Expressions) == 0 {
+		return false, nil
+	}
+	if len(rs) > 1 {
+		return false, errors.New("rego query returned multiple results")
+	}
+	allowed, ok := rs[0].Expressions[0].Value.(bool)
+	if !ok {
+		return false, fmt.Errorf("rego query did not evaluate to a bool, found: %v", rs[0].Expressions[0].Value)
+	}
+	return allowed, nil
+}
+
+// log writes the decision log entry for an evaluation
+func (p *policy) log(query string, input Input, allowed bool, duration time.Duration, err error) {
+	claims := make([]string, 0, len(input.Claims))
+	for k := range input.Claims {
+		claims = append(claims, k)
+	}
+	sort.Strings(claims)
+
+	fields := []zap.Field{
+		zap.String("policy", p.name),
+		zap.String("query", query),
+		zap.Bool("allowed", allowed),
+		zap.String("namespace", input.Target.Namespace),
+		zap.String("service", input.Target.Service),
+		zap.String("method", input.Request.Method),
+		zap.String("path", input.Request.Path),
+		zap.Strings("claims", claims),
+		zap.Duration("duration", duration),
+	}
+	if sub, ok := input.Claims["sub"].(string); ok {
+		fields = append(fields, zap.String("sub", sub))
+	}
+	if err != nil {
+		fields = append(fields, zap.Error(err))
+	}
+	zap.L().Debug("OPA decision", fields...)
+}
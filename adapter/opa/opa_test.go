@@ -0,0 +1,212 @@
+package opa
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	authzModule = `
+package authz
+
+default allow = false
+
+allow {
+	input.claims.scope[_] = "admin"
+}
+
+allow {
+	input.request.method = "GET"
+	input.request.headers["x-tenant"] = input.claims.tenant
+}
+`
+	customModule = `
+package custom.rules
+
+deny {
+	input.target.service = "secret"
+}
+
+name = input.claims.name
+`
+)
+
+func TestCompile(t *testing.T) {
+	var tests = []struct {
+		modules map[string]string
+		err     string
+	}{
+		{
+			modules: map[string]string{"authz.rego": authzModule},
+		},
+		{
+			modules: map[string]string{"authz.rego": authzModule, "custom.rego": customModule, "README.md": "not rego"},
+		},
+		{
+			modules: map[string]string{"README.md": "not rego"},
+			err:     "no rego modules found",
+		},
+		{
+			modules: map[string]string{},
+			err:     "no rego modules found",
+		},
+		{
+			modules: map[string]string{"authz.rego": "package authz\n\nallow {"},
+			err:     "rego_parse_error",
+		},
+		{
+			modules: map[string]string{"authz.rego": "package authz\n\nallow { unknown_func(1) }"},
+			err:     "rego_type_error",
+		},
+		{
+			modules: map[string]string{"authz.rego": ""},
+			err:     "rego module authz.rego is empty",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run("Compile", func(st *testing.T) {
+			p, err := Compile("ns/rego", test.modules)
+			if test.err != "" {
+				assert.Nil(st, p)
+				assert.Contains(st, err.Error(), test.err)
+			} else {
+				assert.Nil(st, err)
+				assert.NotNil(st, p)
+			}
+		})
+	}
+}
+
+func TestEval(t *testing.T) {
+	p, err := Compile("ns/rego", map[string]string{"authz.rego": authzModule, "custom.rego": customModule})
+	assert.Nil(t, err)
+
+	var tests = []struct {
+		name    string
+		query   string
+		input   Input
+		allowed bool
+		err     string
+	}{
+		{
+			name:    "default query allows admin",
+			input:   Input{Claims: map[string]interface{}{"scope": []interface{}{"read", "admin"}}},
+			allowed: true,
+		},
+		{
+			name:    "default query allows matching tenant",
+			query:   "data.authz.allow",
+			input:   Input{Claims: map[string]interface{}{"tenant": "t1"}, Request: Request{Method: "GET", Headers: map[string]string{"x-tenant": "t1"}}},
+			allowed: true,
+		},
+		{
+			name:  "default query denies mismatched tenant",
+			input: Input{Claims: map[string]interface{}{"tenant": "t1"}, Request: Request{Method: "GET", Headers: map[string]string{"x-tenant": "t2"}}},
+		},
+		{
+			name:  "default query denies empty input",
+			input: Input{},
+		},
+		{
+			name:    "custom query",
+			query:   "data.custom.rules.deny",
+			input:   Input{Target: Target{Service: "secret"}},
+			allowed: true,
+		},
+		{
+			name:  "undefined result denies",
+			query: "data.custom.rules.deny",
+			input: Input{Target: Target{Service: "public"}},
+		},
+		{
+			name:  "non boolean result",
+			query: "data.custom.rules.name",
+			input: Input{Claims: map[string]interface{}{"name": "user"}},
+			err:   "rego query did not evaluate to a bool, found: user",
+		},
+		{
+			name:  "invalid query",
+			query: "data.authz.allow[",
+			err:   "rego_parse_error",
+		},
+		{
+			name:    "function query",
+			query:   `startswith(input.request.path, "/public")`,
+			input:   Input{Request: Request{Path: "/public/index.html"}},
+			allowed: true,
+		},
+		{
+			name:  "false function query",
+			query: `startswith(input.request.path, "/public")`,
+			input: Input{Request: Request{Path: "/admin"}},
+		},
+		{
+			name:  "non boolean function query",
+			query: "count(input.claims.groups)",
+			input: Input{Claims: map[string]interface{}{"groups": []string{"a"}}},
+			err:   "rego query did not evaluate to a bool, found: 1",
+		},
+		{
+			name:  "several expressions",
+			query: `data.authz.allow; input.request.method = "POST"`,
+			input: Input{Claims: map[string]interface{}{"scope": []interface{}{"admin"}}, Request: Request{Method: "GET"}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(st *testing.T) {
+			allowed, err := p.Eval(test.query, test.input)
+			assert.Equal(st, test.allowed, allowed)
+			if test.err != "" {
+				assert.Contains(st, err.Error(), test.err)
+			} else {
+				assert.Nil(st, err)
+			}
+		})
+	}
+}
+
+func TestEvalPreparedQueries(t *testing.T) {
+	p, err := Compile("ns/rego", map[string]string{"authz.rego": authzModule, "custom.rego": customModule})
+	assert.Nil(t, err)
+	compiled := p.(*policy)
+	assert.Equal(t, 1, len(compiled.queries))
+
+	// Queries are compiled once and reused
+	input := Input{Target: Target{Service: "secret"}}
+	for i := 0; i < 2; i++ {
+		allowed, err := p.Eval("data.custom.rules.deny", input)
+		assert.True(t, allowed)
+		assert.Nil(t, err)
+	}
+	prepared := compiled.queries["data.custom.rules.deny"]
+	assert.NotNil(t, prepared)
+	_, _ = p.Eval("data.custom.rules.deny", input)
+	assert.True(t, prepared == compiled.queries["data.custom.rules.deny"])
+
+	// Queries failing to compile are not compiled again
+	_, err = p.Eval("data.authz.allow[", input)
+	assert.NotNil(t, err)
+	assert.Equal(t, err, compiled.queries["data.authz.allow["].err)
+	assert.Equal(t, 3, len(compiled.queries))
+
+	// Prepared queries are evaluated concurrently
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(admin bool) {
+			defer wg.Done()
+			claims := map[string]interface{}{}
+			if admin {
+				claims["scope"] = []interface{}{"admin"}
+			}
+			allowed, err := p.Eval("", Input{Claims: claims})
+			assert.Nil(t, err)
+			assert.Equal(t, admin, allowed)
+		}(i%2 == 0)
+	}
+	wg.Wait()
+}
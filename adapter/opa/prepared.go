@@ -0,0 +1,150 @@
+package opa
+
+// The query preparation below mirrors rego.Rego.Eval of github.com/open-policy-agent/opa v0.8.2, which
+// parses and compiles the query on every evaluation. Later OPA releases offer rego.PrepareForEval, which
+// should replace this file when the dependency is upgraded. prepared_test.go compares its results with
+// rego.Eval, so changes in the OPA evaluation semantics are caught by the tests.
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/rego"
+	"github.com/open-policy-agent/opa/storage"
+	"github.com/open-policy-agent/opa/topdown"
+	"github.com/open-policy-agent/opa/util"
+)
+
+// preparedQuery is a query compiled once and evaluated for each request
+type preparedQuery struct {
+	body ast.Body
+	// capture maps the expressions of the query to the variables holding their values
+	capture map[*ast.Expr]ast.Var
+	err     error
+}
+
+// newPreparedQuery parses and compiles the query against the compiled modules.
+// Compilation errors are kept in the prepared query and returned by eval.
+func newPreparedQuery(compiler *ast.Compiler, query string) *preparedQuery {
+	q := &preparedQuery{capture: make(map[*ast.Expr]ast.Var)}
+	body, err := ast.ParseBody(query)
+	if err == nil {
+		qc := compiler.QueryCompiler().WithStageAfter("ResolveRefs", func(_ ast.QueryCompiler, body ast.Body) (ast.Body, error) {
+			return q.captureValues(compiler, body), nil
+		})
+		body, err = qc.Compile(body)
+	}
+	q.body, q.err = body, err
+	return q
+}
+
+// captureValues rewrites the expressions of the query to bind their values to generated variables, as rego.Eval does.
+// The values of terms and function calls are captured, and checked when the query iterates or has several expressions.
+func (q *preparedQuery) captureValues(compiler *ast.Compiler, body ast.Body) ast.Body {
+	check := len(body) > 1 || iteration(body)
+	for _, expr := range body {
+		if expr.Negated || expr.IsAssignment() || expr.IsEquality() {
+			continue
+		}
+		capture := ast.VarTerm(fmt.Sprintf("%sterm%d", ast.WildcardPrefix, len(q.capture)+1))
+		switch terms := expr.Terms.(type) {
+		case *ast.Term:
+			expr.Terms = ast.Equality.Expr(terms, capture).Terms
+		case []*ast.Term:
+			if compiler.GetArity(expr.Operator()) != len(terms)-1 {
+				continue
+			}
+			expr.Terms = append(terms, capture)
+		default:
+			continue
+		}
+		q.capture[expr] = capture.Value.(ast.Var)
+		if check {
+			cpy := expr.Copy()
+			cpy.Terms = capture
+			cpy.Generated = true
+			body.Append(cpy)
+		}
+	}
+	return body
+}
+
+// iteration returns true if the query may produce several results, ignoring comprehensions
+func iteration(body ast.Body) bool {
+	found := false
+	vis := ast.NewGenericVisitor(func(x interface{}) bool {
+		switch x := x.(type) {
+		case *ast.Term:
+			if ast.IsComprehension(x.Value) {
+				return true
+			}
+		case ast.Ref:
+			if bi := ast.BuiltinMap[x.String()]; bi != nil && bi.Relation {
+				found = true
+			}
+			for i := 1; i < len(x) && !found; i++ {
+				if _, ok := x[i].Value.(ast.Var); ok {
+					found = true
+				}
+			}
+		}
+		return found
+	})
+	ast.Walk(vis, body)
+	return found
+}
+
+// eval evaluates the prepared query against the input in a read transaction on the store.
+// Only the values of the expressions are set in the results, the bindings of the query variables are not.
+func (q *preparedQuery) eval(ctx context.Context, compiler *ast.Compiler, store storage.Store, input Input) (rego.ResultSet, error) {
+	if q.err != nil {
+		return nil, q.err
+	}
+
+	value, err := inputValue(input)
+	if err != nil {
+		return nil, err
+	}
+
+	txn, err := store.NewTransaction(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer store.Abort(ctx, txn)
+
+	var rs rego.ResultSet
+	err = topdown.NewQuery(q.body).
+		WithCompiler(compiler).
+		WithStore(store).
+		WithTransaction(txn).
+		WithInput(ast.NewTerm(value)).
+		Iter(ctx, func(qr topdown.QueryResult) error {
+			result := rego.Result{Bindings: rego.Vars{}}
+			for _, expr := range q.body {
+				if expr.Generated {
+					continue
+				}
+				var v interface{} = true
+				if k, ok := q.capture[expr]; ok {
+					if v, err = ast.JSON(qr[k].Value); err != nil {
+						return err
+					}
+				}
+				result.Expressions = append(result.Expressions, &rego.ExpressionValue{Value: v})
+			}
+			rs = append(rs, result)
+			return nil
+		})
+	return rs, err
+}
+
+// inputValue converts the input to the value of the input document
+func inputValue(input Input) (ast.Value, error) {
+	// Round trip through json, so that the input only holds values ast.InterfaceToValue can convert
+	var raw interface{} = input
+	if err := util.RoundTrip(&raw); err != nil {
+		return nil, err
+	}
+	return ast.InterfaceToValue(raw)
+}
@@ -0,0 +1,94 @@
+package opa
+
+import (
+	"context"
+	"testing"
+
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/rego"
+	"github.com/open-policy-agent/opa/storage/inmem"
+	"github.com/stretchr/testify/assert"
+)
+
+const groupsModule = `
+package groups
+
+members[g] {
+	g = input.claims.groups[_]
+}
+
+admin {
+	members["admin"]
+}
+
+ratio = x {
+	x = 10 / count(input.claims.groups)
+}
+`
+
+// TestPreparedQueryMatchesRegoEval checks the prepared queries against rego.Eval of the vendored OPA release
+func TestPreparedQueryMatchesRegoEval(t *testing.T) {
+	modules := map[string]*ast.Module{}
+	for filename, source := range map[string]string{"authz.rego": authzModule, "custom.rego": customModule, "groups.rego": groupsModule} {
+		module, err := ast.ParseModule(filename, source)
+		assert.Nil(t, err)
+		modules[filename] = module
+	}
+	compiler := ast.NewCompiler()
+	compiler.Compile(modules)
+	assert.False(t, compiler.Failed())
+	store := inmem.New()
+
+	inputs := []Input{
+		{},
+		{Claims: map[string]interface{}{"scope": []interface{}{"admin"}}},
+		{Claims: map[string]interface{}{"tenant": "t1", "name": "user"}, Request: Request{Method: "GET", Path: "/public/index.html", Headers: map[string]string{"x-tenant": "t1"}}},
+		{Claims: map[string]interface{}{"groups": []interface{}{"admin", "dev"}}, Target: Target{Service: "secret"}},
+		{Claims: map[string]interface{}{"groups": []interface{}{}}},
+	}
+	queries := []string{
+		"data.authz.allow",
+		"data.custom.rules.deny",
+		"data.custom.rules.name",
+		"not data.authz.allow",
+		`startswith(input.request.path, "/public")`,
+		"count(input.claims.groups)",
+		`data.authz.allow; input.request.method = "GET"`,
+		`input.claims.groups[_] = "admin"`,
+		`input.claims.groups[_] == "dev"`,
+		`input.request.method = "GET"; startswith(input.request.path, "/admin")`,
+		"data.groups.members[x]",
+		"data.groups.admin",
+		"data.groups.ratio",
+		"x := input.claims.name",
+		`count([g | g = input.claims.groups[_]; g != "dev"]) > 0`,
+		"input.claims.groups[_]",
+		"data.authz.allow[",
+		"data.authz.missing",
+	}
+
+	for _, query := range queries {
+		for _, input := range inputs {
+			value, err := inputValue(input)
+			assert.Nil(t, err)
+			expected, expectedErr := rego.New(rego.Query(query), rego.Compiler(compiler), rego.Store(store), rego.ParsedInput(value)).Eval(context.Background())
+			rs, err := newPreparedQuery(compiler, query).eval(context.Background(), compiler, store, input)
+
+			assert.Equal(t, expectedErr != nil, err != nil, query)
+			assert.Equal(t, expressionValues(expected), expressionValues(rs), query)
+		}
+	}
+}
+
+// expressionValues returns the values of the expressions of each result, which decisions are based on
+func expressionValues(rs rego.ResultSet) [][]interface{} {
+	values := make([][]interface{}, 0, len(rs))
+	for _, result := range rs {
+		exprs := make([]interface{}, 0, len(result.Expressions))
+		for _, expr := range result.Expressions {
+			exprs = append(exprs, expr.Value)
+		}
+		values = append(values, exprs)
+	}
+	return values
+}
@@ -0,0 +1,37 @@
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +genclient
+// +genclient:nonNamespaced
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ClusterPolicy protects the services of every namespace matching its selector.
+// Namespaced Policies targeting a request take precedence over it.
+type ClusterPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ClusterPolicySpec `json:"spec"`
+	Status Status            `json:"status,omitempty"`
+}
+
+// ClusterPolicySpec is the spec for a ClusterPolicy resource
+type ClusterPolicySpec struct {
+	// NamespaceSelector selects the namespaces the policy applies to. An omitted or empty selector selects every namespace.
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+	// PolicySpec holds the targets of the policy. A serviceName of * targets every service.
+	PolicySpec `json:",inline"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ClusterPolicyList is a list of ClusterPolicy resources
+type ClusterPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []ClusterPolicy `json:"items"`
+}
@@ -1,5 +1,6 @@
 // +k8s:deepcopy-gen=package
 // +k8s:defaulter-gen=TypeMeta
 // +groupName=security.cloud.ibm.com
+// +groupGoName=Appid
 
 package v1
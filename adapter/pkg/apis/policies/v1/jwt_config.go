@@ -5,20 +5,23 @@ import (
 )
 
 // +genclient
-// +genclient:noStatus
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 
 type JwtConfig struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
 
-	Spec JwtConfigSpec `json:"spec"`
+	Spec   JwtConfigSpec `json:"spec"`
+	Status Status        `json:"status,omitempty"`
 }
 
 // JwtConfigSpec is the spec for a JwtConfig resource
 type JwtConfigSpec struct {
 	ClientName string
 	JwksURL    string `json:"jwksUrl"`
+	// AllowedNamespaces lists the namespaces whose Policies may reference this config, "*" allowing all of them
+	AllowedNamespaces []string `json:"allowedNamespaces,omitempty"`
+	TokenValidation   `json:",inline"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
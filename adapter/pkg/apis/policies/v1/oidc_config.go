@@ -5,14 +5,14 @@ import (
 )
 
 // +genclient
-// +genclient:noStatus
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 
 type OidcConfig struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
 
-	Spec OidcConfigSpec `json:"spec"`
+	Spec   OidcConfigSpec `json:"spec"`
+	Status Status         `json:"status,omitempty"`
 }
 
 // OidcConfigSpec is the spec for a OidcConfig resource
@@ -24,6 +24,9 @@ type OidcConfigSpec struct {
 	ClientSecret    string          `json:"clientSecret"`
 	ClientSecretRef ClientSecretRef `json:"clientSecretRef"`
 	Scopes          []string        `json:"scopes"`
+	// AllowedNamespaces lists the namespaces whose Policies may reference this config, "*" allowing all of them
+	AllowedNamespaces []string `json:"allowedNamespaces,omitempty"`
+	TokenValidation   `json:",inline"`
 }
 
 type ClientSecretRef struct {
@@ -5,19 +5,23 @@ import (
 )
 
 // +genclient
-// +genclient:noStatus
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 
 type Policy struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
 
-	Spec PolicySpec `json:"spec"`
+	Spec   PolicySpec `json:"spec"`
+	Status Status     `json:"status,omitempty"`
 }
 
 // PolicySpec is the spec for a Policy resource
 type PolicySpec struct {
 	Target []TargetElement `json:"targets"`
+	// Mode combines the results of the policies attached to an endpoint: firstMatch (default), all or any
+	Mode string `json:"mode,omitempty"`
+	// Priority decides which Policy is enforced when several target the same endpoint. Higher priorities win.
+	Priority int32 `json:"priority,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
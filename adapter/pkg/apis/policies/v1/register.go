@@ -45,6 +45,8 @@ func addKnownTypes(scheme *runtime.Scheme) error {
 		&OidcConfigList{},
 		&Policy{},
 		&PolicyList{},
+		&ClusterPolicy{},
+		&ClusterPolicyList{},
 	)
 
 	scheme.AddKnownTypes(SchemeGroupVersion,
@@ -0,0 +1,69 @@
+package v1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ConditionType is the type of a status condition
+type ConditionType string
+
+const (
+	// Ready indicates the resource was accepted and is enforced by the adapter
+	Ready ConditionType = "Ready"
+	// DiscoverySynced indicates the OIDC discovery document was retrieved
+	DiscoverySynced ConditionType = "DiscoverySynced"
+	// KeysSynced indicates the JSON Web Key Set was retrieved
+	KeysSynced ConditionType = "KeysSynced"
+	// SecretResolved indicates the OIDC client secret was found
+	SecretResolved ConditionType = "SecretResolved"
+	// ReferencesResolved indicates the configurations and Rego modules referenced by a Policy exist
+	ReferencesResolved ConditionType = "ReferencesResolved"
+	// ReferencesSynced indicates the configurations referenced by a Policy retrieved their discovery documents and keys
+	ReferencesSynced ConditionType = "ReferencesSynced"
+)
+
+// Condition describes the state of a resource at a certain point
+type Condition struct {
+	Type               ConditionType          `json:"type"`
+	Status             corev1.ConditionStatus `json:"status"`
+	LastTransitionTime metav1.Time            `json:"lastTransitionTime,omitempty"`
+	Reason             string                 `json:"reason,omitempty"`
+	Message            string                 `json:"message,omitempty"`
+}
+
+// Status is the state of a resource observed by the adapter
+type Status struct {
+	// ObservedGeneration is the generation of the resource the status was computed for
+	ObservedGeneration int64       `json:"observedGeneration,omitempty"`
+	Conditions         []Condition `json:"conditions,omitempty"`
+}
+
+// GetCondition returns the condition of the given type, or nil if it is not set
+func (s *Status) GetCondition(t ConditionType) *Condition {
+	for i := range s.Conditions {
+		if s.Conditions[i].Type == t {
+			return &s.Conditions[i]
+		}
+	}
+	return nil
+}
+
+// SetCondition adds or replaces the condition of the same type.
+// The transition time is only updated when the condition status changes.
+func (s *Status) SetCondition(c Condition) {
+	existing := s.GetCondition(c.Type)
+	if existing == nil {
+		if c.LastTransitionTime.IsZero() {
+			c.LastTransitionTime = metav1.Now()
+		}
+		s.Conditions = append(s.Conditions, c)
+		return
+	}
+	if existing.Status == c.Status {
+		c.LastTransitionTime = existing.LastTransitionTime
+	} else if c.LastTransitionTime.IsZero() {
+		c.LastTransitionTime = metav1.Now()
+	}
+	*existing = c
+}
@@ -2,26 +2,64 @@ package v1
 
 type TargetElement struct {
 	ServiceName string       `json:"serviceName"`
+	// Hosts restricts the target to requests for one of the hosts. A leading *. matches any subdomain.
+	Hosts       []string     `json:"hosts,omitempty"`
 	Paths       []PathConfig `json:"paths"`
 }
 
 type PathConfig struct {
 	Exact    string       `json:"exact"`
 	Prefix   string       `json:"prefix"`
+	// Regex holds an RE2 pattern matched against the whole request path
+	Regex    string       `json:"regex,omitempty"`
 	Method   string       `json:"method"`
+	// Methods lists further methods the policies apply to
+	Methods  []string     `json:"methods,omitempty"`
+	// Schemes restricts the path to requests using one of the schemes, such as https
+	Schemes  []string      `json:"schemes,omitempty"`
+	// Headers restricts the path to requests carrying every header
+	Headers  []HeaderMatch `json:"headers,omitempty"`
 	Policies []PathPolicy `json:"policies"`
 }
 
+// HeaderMatch matches a request header that is present and, if Value is set, equal to Value
+type HeaderMatch struct {
+	Name  string `json:"name"`
+	Value string `json:"value,omitempty"`
+}
+
 type PathPolicy struct {
 	PolicyType  string `json:"policyType"`
 	Config      string `json:"config"`
 	RedirectUri string `json:"redirectUri"`
 	Rules       []Rule `json:"rules"`
+	// Expression is a CEL expression that must evaluate to true for the request to be authorized
+	Expression  string `json:"expression,omitempty"`
+	// RegoModule names the ConfigMap holding the Rego modules evaluated by opa policies
+	RegoModule  string `json:"regoModule,omitempty"`
+	// RegoQuery is the Rego query that must evaluate to true for the request to be authorized
+	RegoQuery   string `json:"regoQuery,omitempty"`
+	// Headers maps token claims onto headers forwarded to the target service
+	Headers     []ClaimHeader `json:"headers,omitempty"`
+	// Status is the HTTP status code returned by deny policies, 403 by default
+	Status      int32  `json:"status,omitempty"`
+}
+
+// ClaimHeader forwards the value of a validated token claim as a request header
+type ClaimHeader struct {
+	Claim  string `json:"claim"`
+	Header string `json:"header"`
+	Source string `json:"source,omitempty"`
 }
 
+// Rule validates a token claim. A rule may instead group other rules using
+// exactly one of AllOf, AnyOf or Not to build a nested boolean rule tree.
 type Rule struct {
 	Claim  string   `json:"claim"`
 	Values  []string `json:"values"`
 	Match  string   `json:"match"`
 	Source string   `json:"source"`
+	AllOf  []Rule   `json:"allOf,omitempty"`
+	AnyOf  []Rule   `json:"anyOf,omitempty"`
+	Not    *Rule    `json:"not,omitempty"`
 }
\ No newline at end of file
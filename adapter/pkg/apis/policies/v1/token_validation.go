@@ -0,0 +1,14 @@
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// TokenValidation configures the validation of time based token claims.
+// Unset values default to the adapter configuration.
+type TokenValidation struct {
+	// Leeway is the clock skew tolerated when validating exp, nbf, iat and auth_time
+	Leeway *metav1.Duration `json:"leeway,omitempty"`
+	// MaxAge rejects tokens whose iat or auth_time is older than the given duration
+	MaxAge *metav1.Duration `json:"maxAge,omitempty"`
+}
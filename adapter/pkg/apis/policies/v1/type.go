@@ -1,14 +1,48 @@
 package v1
 
+import (
+	"fmt"
+	"sync"
+)
+
 type CrdType int
 
 const (
 	JWTCONFIG CrdType = iota
 	OIDCCONFIG
 	POLICY
+	CONFIGMAP
+	SECRET
+	CLUSTERPOLICY
+	NAMESPACE
 	NONE
 )
 
+var (
+	crdTypesMu sync.RWMutex
+	// crdTypeNames holds the names of the built-in and registered types, indexed by CrdType
+	crdTypeNames = []string{"JwtConfig", "OidcConfig", "Policy", "ConfigMap", "Secret", "ClusterPolicy", "Namespace", "None"}
+)
+
+// RegisterCrdType adds a kind of resource, such as the config of a policy type provided by a plugin.
+// Types are registered during initialization. It panics if the name is already registered.
+func RegisterCrdType(name string) CrdType {
+	crdTypesMu.Lock()
+	defer crdTypesMu.Unlock()
+	for _, n := range crdTypeNames {
+		if n == name || name == "" {
+			panic(fmt.Sprintf("v1: resource kind %q is already registered", name))
+		}
+	}
+	crdTypeNames = append(crdTypeNames, name)
+	return CrdType(len(crdTypeNames) - 1)
+}
+
 func (c CrdType) String() string {
-	return [...]string{"JwtConfig", "OidcConfig", "Policy"}[c]
-}
\ No newline at end of file
+	crdTypesMu.RLock()
+	defer crdTypesMu.RUnlock()
+	if c < 0 || int(c) >= len(crdTypeNames) {
+		return fmt.Sprintf("CrdType(%d)", int(c))
+	}
+	return crdTypeNames[c]
+}
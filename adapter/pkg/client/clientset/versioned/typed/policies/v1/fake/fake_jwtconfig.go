@@ -100,6 +100,18 @@ func (c *FakeJwtConfigs) Update(jwtConfig *policiesv1.JwtConfig) (result *polici
 	return obj.(*policiesv1.JwtConfig), err
 }
 
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+func (c *FakeJwtConfigs) UpdateStatus(jwtConfig *policiesv1.JwtConfig) (*policiesv1.JwtConfig, error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewUpdateSubresourceAction(jwtconfigsResource, "status", c.ns, jwtConfig), &policiesv1.JwtConfig{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*policiesv1.JwtConfig), err
+}
+
 // Delete takes name of the jwtConfig and deletes it. Returns an error if one occurs.
 func (c *FakeJwtConfigs) Delete(name string, options *v1.DeleteOptions) error {
 	_, err := c.Fake.
@@ -100,6 +100,18 @@ func (c *FakeOidcConfigs) Update(oidcConfig *policiesv1.OidcConfig) (result *pol
 	return obj.(*policiesv1.OidcConfig), err
 }
 
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+func (c *FakeOidcConfigs) UpdateStatus(oidcConfig *policiesv1.OidcConfig) (*policiesv1.OidcConfig, error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewUpdateSubresourceAction(oidcconfigsResource, "status", c.ns, oidcConfig), &policiesv1.OidcConfig{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*policiesv1.OidcConfig), err
+}
+
 // Delete takes name of the oidcConfig and deletes it. Returns an error if one occurs.
 func (c *FakeOidcConfigs) Delete(name string, options *v1.DeleteOptions) error {
 	_, err := c.Fake.
@@ -28,6 +28,10 @@ type FakeAppidV1 struct {
 	*testing.Fake
 }
 
+func (c *FakeAppidV1) ClusterPolicies() v1.ClusterPolicyInterface {
+	return &FakeClusterPolicies{c}
+}
+
 func (c *FakeAppidV1) JwtConfigs(namespace string) v1.JwtConfigInterface {
 	return &FakeJwtConfigs{c, namespace}
 }
@@ -100,6 +100,18 @@ func (c *FakePolicies) Update(policy *policiesv1.Policy) (result *policiesv1.Pol
 	return obj.(*policiesv1.Policy), err
 }
 
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+func (c *FakePolicies) UpdateStatus(policy *policiesv1.Policy) (*policiesv1.Policy, error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewUpdateSubresourceAction(policiesResource, "status", c.ns, policy), &policiesv1.Policy{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*policiesv1.Policy), err
+}
+
 // Delete takes name of the policy and deletes it. Returns an error if one occurs.
 func (c *FakePolicies) Delete(name string, options *v1.DeleteOptions) error {
 	_, err := c.Fake.
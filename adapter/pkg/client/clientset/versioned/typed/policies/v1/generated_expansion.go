@@ -18,6 +18,8 @@ limitations under the License.
 
 package v1
 
+type ClusterPolicyExpansion interface{}
+
 type JwtConfigExpansion interface{}
 
 type OidcConfigExpansion interface{}
@@ -39,6 +39,7 @@ type JwtConfigsGetter interface {
 type JwtConfigInterface interface {
 	Create(*v1.JwtConfig) (*v1.JwtConfig, error)
 	Update(*v1.JwtConfig) (*v1.JwtConfig, error)
+	UpdateStatus(*v1.JwtConfig) (*v1.JwtConfig, error)
 	Delete(name string, options *metav1.DeleteOptions) error
 	DeleteCollection(options *metav1.DeleteOptions, listOptions metav1.ListOptions) error
 	Get(name string, options metav1.GetOptions) (*v1.JwtConfig, error)
@@ -132,6 +133,22 @@ func (c *jwtConfigs) Update(jwtConfig *v1.JwtConfig) (result *v1.JwtConfig, err
 	return
 }
 
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+
+func (c *jwtConfigs) UpdateStatus(jwtConfig *v1.JwtConfig) (result *v1.JwtConfig, err error) {
+	result = &v1.JwtConfig{}
+	err = c.client.Put().
+		Namespace(c.ns).
+		Resource("jwtconfigs").
+		Name(jwtConfig.Name).
+		SubResource("status").
+		Body(jwtConfig).
+		Do().
+		Into(result)
+	return
+}
+
 // Delete takes name of the jwtConfig and deletes it. Returns an error if one occurs.
 func (c *jwtConfigs) Delete(name string, options *metav1.DeleteOptions) error {
 	return c.client.Delete().
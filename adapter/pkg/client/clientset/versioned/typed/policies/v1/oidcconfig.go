@@ -39,6 +39,7 @@ type OidcConfigsGetter interface {
 type OidcConfigInterface interface {
 	Create(*v1.OidcConfig) (*v1.OidcConfig, error)
 	Update(*v1.OidcConfig) (*v1.OidcConfig, error)
+	UpdateStatus(*v1.OidcConfig) (*v1.OidcConfig, error)
 	Delete(name string, options *metav1.DeleteOptions) error
 	DeleteCollection(options *metav1.DeleteOptions, listOptions metav1.ListOptions) error
 	Get(name string, options metav1.GetOptions) (*v1.OidcConfig, error)
@@ -132,6 +133,22 @@ func (c *oidcConfigs) Update(oidcConfig *v1.OidcConfig) (result *v1.OidcConfig,
 	return
 }
 
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+
+func (c *oidcConfigs) UpdateStatus(oidcConfig *v1.OidcConfig) (result *v1.OidcConfig, err error) {
+	result = &v1.OidcConfig{}
+	err = c.client.Put().
+		Namespace(c.ns).
+		Resource("oidcconfigs").
+		Name(oidcConfig.Name).
+		SubResource("status").
+		Body(oidcConfig).
+		Do().
+		Into(result)
+	return
+}
+
 // Delete takes name of the oidcConfig and deletes it. Returns an error if one occurs.
 func (c *oidcConfigs) Delete(name string, options *metav1.DeleteOptions) error {
 	return c.client.Delete().
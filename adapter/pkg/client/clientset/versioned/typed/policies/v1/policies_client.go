@@ -26,6 +26,7 @@ import (
 
 type AppidV1Interface interface {
 	RESTClient() rest.Interface
+	ClusterPoliciesGetter
 	JwtConfigsGetter
 	OidcConfigsGetter
 	PoliciesGetter
@@ -36,6 +37,10 @@ type AppidV1Client struct {
 	restClient rest.Interface
 }
 
+func (c *AppidV1Client) ClusterPolicies() ClusterPolicyInterface {
+	return newClusterPolicies(c)
+}
+
 func (c *AppidV1Client) JwtConfigs(namespace string) JwtConfigInterface {
 	return newJwtConfigs(c, namespace)
 }
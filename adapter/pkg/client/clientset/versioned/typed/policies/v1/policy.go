@@ -39,6 +39,7 @@ type PoliciesGetter interface {
 type PolicyInterface interface {
 	Create(*v1.Policy) (*v1.Policy, error)
 	Update(*v1.Policy) (*v1.Policy, error)
+	UpdateStatus(*v1.Policy) (*v1.Policy, error)
 	Delete(name string, options *metav1.DeleteOptions) error
 	DeleteCollection(options *metav1.DeleteOptions, listOptions metav1.ListOptions) error
 	Get(name string, options metav1.GetOptions) (*v1.Policy, error)
@@ -132,6 +133,22 @@ func (c *policies) Update(policy *v1.Policy) (result *v1.Policy, err error) {
 	return
 }
 
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+
+func (c *policies) UpdateStatus(policy *v1.Policy) (result *v1.Policy, err error) {
+	result = &v1.Policy{}
+	err = c.client.Put().
+		Namespace(c.ns).
+		Resource("policies").
+		Name(policy.Name).
+		SubResource("status").
+		Body(policy).
+		Do().
+		Into(result)
+	return
+}
+
 // Delete takes name of the policy and deletes it. Returns an error if one occurs.
 func (c *policies) Delete(name string, options *metav1.DeleteOptions) error {
 	return c.client.Delete().
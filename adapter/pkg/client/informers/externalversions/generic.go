@@ -53,6 +53,8 @@ func (f *genericInformer) Lister() cache.GenericLister {
 func (f *sharedInformerFactory) ForResource(resource schema.GroupVersionResource) (GenericInformer, error) {
 	switch resource {
 	// Group=security.cloud.ibm.com, Version=v1
+	case v1.SchemeGroupVersion.WithResource("clusterpolicies"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Appid().V1().ClusterPolicies().Informer()}, nil
 	case v1.SchemeGroupVersion.WithResource("jwtconfigs"):
 		return &genericInformer{resource: resource.GroupResource(), informer: f.Appid().V1().JwtConfigs().Informer()}, nil
 	case v1.SchemeGroupVersion.WithResource("oidcconfigs"):
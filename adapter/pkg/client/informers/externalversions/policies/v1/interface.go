@@ -24,6 +24,8 @@ import (
 
 // Interface provides access to all the informers in this group version.
 type Interface interface {
+	// ClusterPolicies returns a ClusterPolicyInformer.
+	ClusterPolicies() ClusterPolicyInformer
 	// JwtConfigs returns a JwtConfigInformer.
 	JwtConfigs() JwtConfigInformer
 	// OidcConfigs returns a OidcConfigInformer.
@@ -43,6 +45,11 @@ func New(f internalinterfaces.SharedInformerFactory, namespace string, tweakList
 	return &version{factory: f, namespace: namespace, tweakListOptions: tweakListOptions}
 }
 
+// ClusterPolicies returns a ClusterPolicyInformer.
+func (v *version) ClusterPolicies() ClusterPolicyInformer {
+	return &clusterPolicyInformer{factory: v.factory, tweakListOptions: v.tweakListOptions}
+}
+
 // JwtConfigs returns a JwtConfigInformer.
 func (v *version) JwtConfigs() JwtConfigInformer {
 	return &jwtConfigInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
@@ -18,6 +18,10 @@ limitations under the License.
 
 package v1
 
+// ClusterPolicyListerExpansion allows custom methods to be added to
+// ClusterPolicyLister.
+type ClusterPolicyListerExpansion interface{}
+
 // JwtConfigListerExpansion allows custom methods to be added to
 // JwtConfigLister.
 type JwtConfigListerExpansion interface{}
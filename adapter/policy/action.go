@@ -1,5 +1,7 @@
 package policy
 
+import "fmt"
+
 type Method int
 
 const (
@@ -9,34 +11,42 @@ const (
 	POST
 	DELETE
 	PATCH
+	HEAD
+	OPTIONS
+	CONNECT
+	TRACE
+	// UNKNOWN methods only match policies registered for ALL methods
+	UNKNOWN
 )
 
+var methodNames = [...]string{"ALL", "GET", "PUT", "POST", "DELETE", "PATCH", "HEAD", "OPTIONS", "CONNECT", "TRACE", "UNKNOWN"}
+
 func (m Method) String() string {
-	return [...]string{"ALL", "GET", "PUT", "POST", "DELETE", "PATCH"}[m]
+	return methodNames[m]
 }
 
+// NewMethod parses a request method. Methods are case sensitive, and unknown methods return UNKNOWN.
 func NewMethod(method string) Method {
-	switch method {
-	case "ALL":
-		return ALL
-	case "GET":
-		return GET
-	case "PUT":
-		return PUT
-	case "POST":
-		return POST
-	case "DELETE":
-		return DELETE
-	case "PATCH":
-		return PATCH
-	default:
-		return ALL
+	for m, name := range methodNames[:UNKNOWN] {
+		if name == method {
+			return Method(m)
+		}
+	}
+	return UNKNOWN
+}
+
+// ParseMethod parses the method of a Policy path, returning an error for unknown methods
+func ParseMethod(method string) (Method, error) {
+	if m := NewMethod(method); m != UNKNOWN {
+		return m, nil
 	}
+	return UNKNOWN, fmt.Errorf("unknown method %q", method)
 }
 
-type Actions = map[Method]RoutePolicy
+// Actions maps a method to the policies contributed by each Policy, ordered by precedence
+type Actions = map[Method][]RoutePolicy
 
 // New creates a new Actions
 func NewActions() Actions {
-	return make(map[Method]RoutePolicy)
+	return make(map[Method][]RoutePolicy)
 }
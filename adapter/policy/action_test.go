@@ -0,0 +1,27 @@
+package policy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewMethod(t *testing.T) {
+	for _, method := range []Method{ALL, GET, PUT, POST, DELETE, PATCH, HEAD, OPTIONS, CONNECT, TRACE} {
+		assert.Equal(t, method, NewMethod(method.String()))
+	}
+	assert.Equal(t, UNKNOWN, NewMethod("get"))
+	assert.Equal(t, UNKNOWN, NewMethod("PROPFIND"))
+	assert.Equal(t, UNKNOWN, NewMethod("UNKNOWN"))
+}
+
+func TestParseMethod(t *testing.T) {
+	method, err := ParseMethod("OPTIONS")
+	assert.Nil(t, err)
+	assert.Equal(t, OPTIONS, method)
+
+	_, err = ParseMethod("UNKNOWN")
+	assert.EqualError(t, err, `unknown method "UNKNOWN"`)
+	_, err = ParseMethod("")
+	assert.EqualError(t, err, `unknown method ""`)
+}
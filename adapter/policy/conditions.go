@@ -0,0 +1,114 @@
+package policy
+
+import (
+	"net"
+	"sort"
+	"strings"
+
+	v1 "github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/pkg/apis/policies/v1"
+)
+
+// Request holds the attributes of a request matched against the Conditions of a RoutePolicy
+type Request struct {
+	Scheme string
+	Host   string
+	// Headers maps lower case header names to their values
+	Headers map[string]string
+}
+
+// Conditions restrict a RoutePolicy to matching requests. Empty conditions match every request.
+type Conditions struct {
+	// Hosts holds lower case hosts. A leading *. matches any subdomain.
+	Hosts []string
+	// Schemes holds lower case schemes
+	Schemes []string
+	// Headers holds header matches with lower case names
+	Headers []v1.HeaderMatch
+}
+
+// NewConditions normalizes the request conditions of a Policy target
+func NewConditions(hosts []string, schemes []string, headers []v1.HeaderMatch) Conditions {
+	c := Conditions{}
+	for _, host := range hosts {
+		c.Hosts = append(c.Hosts, strings.ToLower(host))
+	}
+	for _, scheme := range schemes {
+		c.Schemes = append(c.Schemes, strings.ToLower(scheme))
+	}
+	for _, header := range headers {
+		c.Headers = append(c.Headers, v1.HeaderMatch{Name: strings.ToLower(header.Name), Value: header.Value})
+	}
+	sort.Strings(c.Hosts)
+	sort.Strings(c.Schemes)
+	sort.Slice(c.Headers, func(i, j int) bool {
+		if c.Headers[i].Name != c.Headers[j].Name {
+			return c.Headers[i].Name < c.Headers[j].Name
+		}
+		return c.Headers[i].Value < c.Headers[j].Value
+	})
+	return c
+}
+
+// Matches returns true if the request satisfies every condition
+func (c Conditions) Matches(r Request) bool {
+	if len(c.Hosts) > 0 && !matchesHost(c.Hosts, r.Host) {
+		return false
+	}
+	if len(c.Schemes) > 0 && !contains(c.Schemes, strings.ToLower(r.Scheme)) {
+		return false
+	}
+	for _, header := range c.Headers {
+		// Headers missing from the request are reported as empty values
+		value := r.Headers[header.Name]
+		if value == "" || (header.Value != "" && value != header.Value) {
+			return false
+		}
+	}
+	return true
+}
+
+// Specificity returns the number of kinds of condition that are set
+func (c Conditions) Specificity() int {
+	specificity := 0
+	for _, n := range []int{len(c.Hosts), len(c.Schemes), len(c.Headers)} {
+		if n > 0 {
+			specificity++
+		}
+	}
+	return specificity
+}
+
+// String returns a canonical representation of the conditions
+func (c Conditions) String() string {
+	headers := make([]string, len(c.Headers))
+	for i, header := range c.Headers {
+		headers[i] = header.Name + "=" + header.Value
+	}
+	return "hosts=" + strings.Join(c.Hosts, ",") + ";schemes=" + strings.Join(c.Schemes, ",") + ";headers=" + strings.Join(headers, ",")
+}
+
+// matchesHost returns true if the host, without its port, matches one of the patterns
+func matchesHost(patterns []string, host string) bool {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.ToLower(host)
+	for _, pattern := range patterns {
+		if pattern == "*" || pattern == host {
+			return true
+		}
+		if strings.HasPrefix(pattern, "*.") && strings.HasSuffix(host, pattern[1:]) && len(host) > len(pattern)-1 {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,96 @@
+package policy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	v1 "github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/pkg/apis/policies/v1"
+)
+
+func TestConditions_Matches(t *testing.T) {
+	tests := []struct {
+		name       string
+		conditions Conditions
+		request    Request
+		matches    bool
+	}{
+		{
+			name:    "no conditions",
+			request: Request{Host: "example.com"},
+			matches: true,
+		},
+		{
+			name:       "exact host with port",
+			conditions: NewConditions([]string{"API.example.com"}, nil, nil),
+			request:    Request{Host: "api.example.com:8080"},
+			matches:    true,
+		},
+		{
+			name:       "other host",
+			conditions: NewConditions([]string{"api.example.com"}, nil, nil),
+			request:    Request{Host: "www.example.com"},
+		},
+		{
+			name:       "wildcard host",
+			conditions: NewConditions([]string{"*.example.com"}, nil, nil),
+			request:    Request{Host: "a.b.example.com"},
+			matches:    true,
+		},
+		{
+			name:       "wildcard does not match the domain",
+			conditions: NewConditions([]string{"*.example.com"}, nil, nil),
+			request:    Request{Host: "example.com"},
+		},
+		{
+			name:       "any host",
+			conditions: NewConditions([]string{"*"}, nil, nil),
+			request:    Request{Host: "example.com"},
+			matches:    true,
+		},
+		{
+			name:       "scheme",
+			conditions: NewConditions(nil, []string{"https"}, nil),
+			request:    Request{Scheme: "HTTPS"},
+			matches:    true,
+		},
+		{
+			name:       "other scheme",
+			conditions: NewConditions(nil, []string{"https"}, nil),
+			request:    Request{Scheme: "http"},
+		},
+		{
+			name:       "header present",
+			conditions: NewConditions(nil, nil, []v1.HeaderMatch{{Name: "X-Tenant"}}),
+			request:    Request{Headers: map[string]string{"x-tenant": "a"}},
+			matches:    true,
+		},
+		{
+			name:       "header empty",
+			conditions: NewConditions(nil, nil, []v1.HeaderMatch{{Name: "x-tenant"}}),
+			request:    Request{Headers: map[string]string{"x-tenant": ""}},
+		},
+		{
+			name:       "header value",
+			conditions: NewConditions(nil, nil, []v1.HeaderMatch{{Name: "x-tenant", Value: "a"}}),
+			request:    Request{Headers: map[string]string{"x-tenant": "b"}},
+		},
+		{
+			name:       "every condition",
+			conditions: NewConditions([]string{"*.example.com"}, []string{"https"}, []v1.HeaderMatch{{Name: "x-tenant", Value: "a"}}),
+			request:    Request{Host: "api.example.com", Scheme: "https", Headers: map[string]string{"x-tenant": "a"}},
+			matches:    true,
+		},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.matches, test.conditions.Matches(test.request), test.name)
+	}
+}
+
+func TestRoutePolicy_Precedes(t *testing.T) {
+	conditioned := RoutePolicy{PolicyReference: "b", Conditions: NewConditions([]string{"example.com"}, nil, nil)}
+	assert.True(t, RoutePolicy{PolicyReference: "a"}.Precedes(conditioned))
+	assert.True(t, conditioned.Precedes(RoutePolicy{PolicyReference: "b"}))
+	assert.True(t, RoutePolicy{PolicyReference: "c", Priority: 1}.Precedes(conditioned))
+}
@@ -0,0 +1,74 @@
+package policy
+
+import (
+	"sort"
+	"strings"
+
+	v1 "github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/pkg/apis/policies/v1"
+)
+
+// Dependency identifies a resource referenced by a Policy
+type Dependency struct {
+	Kind v1.CrdType
+	// Name holds the namespace/name of the resource
+	Name string
+}
+
+func (d Dependency) String() string {
+	return d.Kind.String() + " " + d.Name
+}
+
+// Dependencies returns the configurations and other resources, such as Rego modules, referenced by the mappings of a Policy
+func Dependencies(mappings []PolicyMapping) []Dependency {
+	found := make(map[Dependency]struct{})
+	for _, mapping := range mappings {
+		namespace := mapping.Endpoint.Service.Namespace
+		for _, action := range mapping.Actions {
+			info := NewType(action.PolicyType).Info()
+			if info.ConfigKind != v1.NONE {
+				found[Dependency{Kind: info.ConfigKind, Name: ConfigKey(namespace, action.Config)}] = struct{}{}
+			}
+			if info.References != nil {
+				for _, dependency := range info.References(namespace, action) {
+					found[dependency] = struct{}{}
+				}
+			}
+		}
+	}
+	dependencies := make([]Dependency, 0, len(found))
+	for d := range found {
+		dependencies = append(dependencies, d)
+	}
+	sort.Slice(dependencies, func(i, j int) bool {
+		return dependencies[i].String() < dependencies[j].String()
+	})
+	return dependencies
+}
+
+// Namespace returns the namespace of the resource
+func (d Dependency) Namespace() string {
+	return strings.SplitN(d.Name, "/", 2)[0]
+}
+
+// ConfigKey returns the namespace/name key of the config referenced by a Policy in the given namespace.
+// The config may be given as name, in the namespace of the Policy, or as namespace/name.
+func ConfigKey(namespace string, config string) string {
+	if strings.Contains(config, "/") {
+		return config
+	}
+	return namespace + "/" + config
+}
+
+// NamespaceAllowed returns true if a config in configNamespace allowing the given namespaces
+// can be referenced by a Policy in namespace
+func NamespaceAllowed(allowed []string, configNamespace string, namespace string) bool {
+	if configNamespace == namespace {
+		return true
+	}
+	for _, n := range allowed {
+		if n == "*" || n == namespace {
+			return true
+		}
+	}
+	return false
+}
@@ -4,6 +4,8 @@ package engine
 import (
 	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/authserver/keyset"
 	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/client"
+	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/expression"
+	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/opa"
 	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/pkg/apis/policies/v1"
 	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/policy"
 )
@@ -14,4 +16,22 @@ type Action struct {
 	KeySet keyset.KeySet
 	Client client.Client
 	Type   policy.Type
+	// Expression is the compiled PathPolicy expression, if one is configured
+	Expression expression.Program
+	// Rego holds the compiled Rego modules evaluated by opa policies
+	Rego opa.Policy
+	// TokenValidation holds the time validation overrides of the referenced config
+	TokenValidation v1.TokenValidation
+	// Missing identifies the referenced resource that is not available, if any
+	Missing *policy.Dependency
+	// Resolved holds the config resolved by the accessor of a registered policy type
+	Resolved interface{}
+	// Problems lists the validation problems of the invalid policy protecting the endpoint, if any
+	Problems []string
+}
+
+// Decision holds the actions protecting a target and how their results are combined
+type Decision struct {
+	Mode    policy.Mode
+	Actions []Action
 }
@@ -0,0 +1,127 @@
+package engine
+
+import (
+	"strings"
+	"sync/atomic"
+
+	lru "github.com/hashicorp/golang-lru"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+
+	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/policy"
+	policy2 "github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/policy/store/policy"
+	"github.com/ibm-cloud-security/app-identity-and-access-adapter/config/template"
+)
+
+var (
+	cacheHits = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "appidentityandaccessadapter",
+		Subsystem: "policy_cache",
+		Name:      "hits_total",
+		Help:      "Number of policy decisions served from the decision cache.",
+	})
+	cacheMisses = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "appidentityandaccessadapter",
+		Subsystem: "policy_cache",
+		Name:      "misses_total",
+		Help:      "Number of policy decisions resolved from the policy store.",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(cacheHits, cacheMisses)
+}
+
+// cacheKey identifies a decision. Decisions depend on the request host and scheme,
+// and on the values of the headers matched by policy conditions.
+type cacheKey struct {
+	version   uint64
+	namespace string
+	service   string
+	path      string
+	method    string
+	scheme    string
+	host      string
+	headers   string
+}
+
+// decisionCache is a bounded LRU cache of the decisions resolved for each target.
+// Entries are keyed by the version of the store, and dropped whenever a newer version is read.
+// A nil cache caches nothing.
+type decisionCache struct {
+	decisions *lru.Cache
+	// version holds the newest store version read
+	version uint64
+}
+
+// newDecisionCache creates a cache holding up to size decisions. Sizes below one disable caching.
+func newDecisionCache(size int) *decisionCache {
+	if size < 1 {
+		return nil
+	}
+	decisions, err := lru.New(size)
+	if err != nil {
+		zap.L().Warn("Could not create the policy decision cache", zap.Int("size", size), zap.Error(err))
+		return nil
+	}
+	return &decisionCache{decisions: decisions}
+}
+
+// newCacheKey returns the key of the decision for the target in the given store
+func newCacheKey(store policy2.PolicyStore, target *authnz.TargetMsg, request policy.Request) cacheKey {
+	key := cacheKey{
+		version:   store.Version(),
+		namespace: target.Namespace,
+		service:   target.Service,
+		path:      target.Path,
+		method:    target.Method,
+		scheme:    request.Scheme,
+		host:      request.Host,
+	}
+	if names := store.ConditionHeaders(); len(names) > 0 {
+		var headers strings.Builder
+		for _, name := range names {
+			headers.WriteString(request.Headers[name])
+			headers.WriteByte(0)
+		}
+		key.headers = headers.String()
+	}
+	return key
+}
+
+// get returns a copy of the cached decision for the key
+func (c *decisionCache) get(key cacheKey) (*Decision, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.invalidate(key.version)
+	if value, ok := c.decisions.Get(key); ok {
+		cacheHits.Inc()
+		decision := value.(*Decision)
+		return &Decision{Mode: decision.Mode, Actions: append([]Action(nil), decision.Actions...)}, true
+	}
+	cacheMisses.Inc()
+	return nil, false
+}
+
+// add caches a copy of the decision for the key
+func (c *decisionCache) add(key cacheKey, decision *Decision) {
+	if c == nil {
+		return
+	}
+	c.decisions.Add(key, &Decision{Mode: decision.Mode, Actions: append([]Action(nil), decision.Actions...)})
+}
+
+// invalidate drops every cached decision when a newer version of the store is read
+func (c *decisionCache) invalidate(version uint64) {
+	for {
+		current := atomic.LoadUint64(&c.version)
+		if version <= current {
+			return
+		}
+		if atomic.CompareAndSwapUint64(&c.version, current, version) {
+			c.decisions.Purge()
+			return
+		}
+	}
+}
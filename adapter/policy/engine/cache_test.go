@@ -0,0 +1,133 @@
+package engine
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/pkg/apis/policies/v1"
+	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/policy"
+	policy2 "github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/policy/store/policy"
+	"github.com/ibm-cloud-security/app-identity-and-access-adapter/config/template"
+	"github.com/ibm-cloud-security/app-identity-and-access-adapter/tests/fake"
+)
+
+func TestNewDecisionCache(t *testing.T) {
+	assert.Nil(t, newDecisionCache(0))
+	assert.Nil(t, newDecisionCache(-1))
+	assert.NotNil(t, newDecisionCache(1))
+
+	// A nil cache caches nothing
+	var cache *decisionCache
+	cache.add(cacheKey{}, &Decision{})
+	_, ok := cache.get(cacheKey{})
+	assert.False(t, ok)
+}
+
+func TestEvaluateCache(t *testing.T) {
+	store := policy2.New()
+	store.AddKeySet("namespace/"+defaultJwtConfigName, &fake.KeySet{})
+	store.AddClient("namespace/"+defaultOidcConfigName, fake.NewClient(nil))
+	store.SetPolicies(genEndpoint("namespace", "svc", "/path", "GET"), genJWTPathPolicyArray(defaultJwtConfigName))
+	eng, err := New(store, 10)
+	assert.Nil(t, err)
+
+	hits, misses := testutil.ToFloat64(cacheHits), testutil.ToFloat64(cacheMisses)
+	evaluate := func(path string, request *authnz.RequestMsg) policy.Type {
+		result, err := eng.Evaluate(genActionMessage("namespace", "svc", path, "GET"), request)
+		assert.Nil(t, err)
+		if len(result.Actions) == 0 {
+			return policy.NONE
+		}
+		return result.Actions[0].Type
+	}
+	assertCounts := func(expectedHits float64, expectedMisses float64) {
+		assert.Equal(t, hits+expectedHits, testutil.ToFloat64(cacheHits))
+		assert.Equal(t, misses+expectedMisses, testutil.ToFloat64(cacheMisses))
+	}
+
+	assert.Equal(t, policy.JWT, evaluate("/path", nil))
+	assertCounts(0, 1)
+	assert.Equal(t, policy.JWT, evaluate("/path", nil))
+	assert.Equal(t, policy.NONE, evaluate("/other", nil))
+	assertCounts(1, 2)
+
+	// Cached decisions are copies
+	result, _ := eng.Evaluate(genActionMessage("namespace", "svc", "/path", "GET"), nil)
+	result.Actions[0].Type = policy.DENY
+	assert.Equal(t, policy.JWT, evaluate("/path", nil))
+	assertCounts(3, 2)
+
+	// Store updates invalidate the cache
+	store.SetPolicies(genEndpoint("namespace", "svc", "/path", "GET"), policy.RoutePolicy{
+		Actions: []v1.PathPolicy{{PolicyType: "oidc", Config: defaultOidcConfigName}},
+	})
+	assert.Equal(t, policy.OIDC, evaluate("/path", nil))
+	assertCounts(3, 3)
+
+	// Decisions are cached per value of the headers matched by conditions
+	store.SetPolicies(genEndpoint("namespace", "svc", "/path", "GET"), policy.RoutePolicy{
+		PolicyReference: "namespace/beta",
+		Priority:        1,
+		Actions:         []v1.PathPolicy{{PolicyType: "deny"}},
+		Conditions:      policy.NewConditions(nil, nil, []v1.HeaderMatch{{Name: "x-channel", Value: "beta"}}),
+	})
+	beta := genRequestMessage("https", "api.example.com", map[string]string{"X-Channel": "beta", "X-Request-Id": "1"})
+	stable := genRequestMessage("https", "api.example.com", map[string]string{"X-Channel": "stable", "X-Request-Id": "2"})
+	assert.Equal(t, policy.DENY, evaluate("/path", beta))
+	assert.Equal(t, policy.OIDC, evaluate("/path", stable))
+	assertCounts(3, 5)
+	beta.Headers.Properties["X-Request-Id"] = stable.Headers.Properties["X-Request-Id"]
+	assert.Equal(t, policy.DENY, evaluate("/path", beta))
+	assert.Equal(t, policy.OIDC, evaluate("/path", stable))
+	assertCounts(5, 5)
+}
+
+func TestEvaluateCacheEviction(t *testing.T) {
+	store := policy2.New()
+	store.SetPolicies(genEndpoint("namespace", "svc", "/*", "ALL"), policy.RoutePolicy{Actions: []v1.PathPolicy{{PolicyType: "allow"}}})
+	eng := &engine{store: store, cache: newDecisionCache(2)}
+	for _, path := range []string{"/a", "/b", "/c"} {
+		_, err := eng.Evaluate(genActionMessage("namespace", "svc", path, "GET"), nil)
+		assert.Nil(t, err)
+	}
+	assert.Equal(t, 2, eng.cache.decisions.Len())
+	_, ok := eng.cache.get(newCacheKey(store, genActionMessage("namespace", "svc", "/a", "GET"), newRequest(nil)))
+	assert.False(t, ok)
+	_, ok = eng.cache.get(newCacheKey(store, genActionMessage("namespace", "svc", "/c", "GET"), newRequest(nil)))
+	assert.True(t, ok)
+}
+
+// benchmarkEvaluate evaluates requests spread over the given number of paths
+func benchmarkEvaluate(b *testing.B, cacheSize int, paths int) {
+	store := policy2.New()
+	store.AddKeySet("namespace/"+defaultJwtConfigName, &fake.KeySet{})
+	store.AddClient("namespace/"+defaultOidcConfigName, fake.NewClient(nil))
+	for i := 0; i < 100; i++ {
+		store.SetPolicies(genEndpoint("namespace", "svc", fmt.Sprintf("/api/v1/resource%d/*", i), "GET"), genJWTPathPolicyArray(defaultJwtConfigName))
+	}
+	store.SetPolicies(genEndpoint("namespace", "svc", "/*", "ALL"), policy.RoutePolicy{
+		Actions: []v1.PathPolicy{{PolicyType: "oidc", Config: defaultOidcConfigName}},
+	})
+	eng, _ := New(store, cacheSize)
+	targets := make([]*authnz.TargetMsg, paths)
+	for i := range targets {
+		targets[i] = genActionMessage("namespace", "svc", fmt.Sprintf("/api/v1/resource%d/items/%d", i%100, i), "GET")
+	}
+	request := genRequestMessage("https", "api.example.com", map[string]string{"Authorization": "Bearer token"})
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		target := *targets[i%paths]
+		if _, err := eng.Evaluate(&target, request); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkEvaluate_Uncached(b *testing.B)       { benchmarkEvaluate(b, 0, 100) }
+func BenchmarkEvaluate_Cached(b *testing.B)         { benchmarkEvaluate(b, 1024, 100) }
+func BenchmarkEvaluate_CachedEvicting(b *testing.B) { benchmarkEvaluate(b, 50, 100) }
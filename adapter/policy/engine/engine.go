@@ -3,6 +3,7 @@ package engine
 
 import (
 	"errors"
+	"net/http"
 	"strings"
 
 	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/pkg/apis/policies/v1"
@@ -23,27 +24,30 @@ const (
 
 // PolicyEngine is responsible for making policy decisions
 type PolicyEngine interface {
-	Evaluate(msg *authnz.TargetMsg) (*Action, error)
+	Evaluate(msg *authnz.TargetMsg, request *authnz.RequestMsg) (*Decision, error)
 }
 
 type engine struct {
 	store policy2.PolicyStore
+	// cache holds the decisions resolved for recent targets, if enabled
+	cache *decisionCache
 }
 
-// New creates a PolicyEngine
-func New(store policy2.PolicyStore) (PolicyEngine, error) {
+// New creates a PolicyEngine caching up to cacheSize decisions. A size of zero disables the cache.
+func New(store policy2.PolicyStore, cacheSize int) (PolicyEngine, error) {
 	if store == nil {
 		zap.L().Error("Trying to create PolicyEngine, but no store provided.")
 		return nil, errors.New("could not create policy engine using undefined store")
 	}
-	return &engine{store: store}, nil
+	return &engine{store: store, cache: newDecisionCache(cacheSize)}, nil
 }
 
 ////////////////// interface //////////////////
 
-// Evaluate makes authn/z decision based on authorization action
-// being performed.
-func (m *engine) Evaluate(target *authnz.TargetMsg) (*Action, error) {
+// Evaluate returns every action protecting the target along with
+// the mode used to combine their results. The request is matched
+// against the host, scheme and header conditions of the policies.
+func (m *engine) Evaluate(target *authnz.TargetMsg, request *authnz.RequestMsg) (*Decision, error) {
 	zap.L().Debug("Evaluating policies",
 		zap.String("namespace", target.Namespace),
 		zap.String("service", target.Service),
@@ -58,37 +62,38 @@ func (m *engine) Evaluate(target *authnz.TargetMsg) (*Action, error) {
 		target.Path = strings.Split(target.Path, logoutEndpoint)[0]
 	}
 
-	// Get All policies protecting target
-	policies, err := m.getPolicies(endpointsToCheck(target))
+	// Get All policies protecting target, reading policies and configs from the same snapshot of the store
+	snapshot := &engine{store: m.store.Snapshot()}
+	attributes := newRequest(request)
+	key := newCacheKey(snapshot.store, target, attributes)
+	if decision, ok := m.cache.get(key); ok {
+		zap.L().Debug("Using cached policies", zap.Int("count", len(decision.Actions)), zap.String("mode", decision.Mode.String()))
+		return decision, nil
+	}
+	decision, err := snapshot.getPolicies(endpointsToCheck(target), attributes)
 	if err != nil {
 		zap.L().Error("Could not retrieve configured policies", zap.Error(err))
 		return nil, err
 	}
 
-	zap.L().Debug("Checking policies", zap.Int("count", len(policies)))
-	if len(policies) == 0 {
-		return &Action{
-			Type: policy.NONE,
-		}, nil
-	}
+	zap.L().Debug("Checking policies", zap.Int("count", len(decision.Actions)), zap.String("mode", decision.Mode.String()))
 
 	// Validate and cleanse action policies
-	for i, p := range policies {
+	for i, p := range decision.Actions {
 		if p.Rules == nil {
-			policies[i].Rules = createDefaultRules(p)
-		} else {
-			policies[i].Rules = p.Rules
+			decision.Actions[i].Rules = createDefaultRules(p)
 		}
 	}
 
-	// Temporarily return only 1 policy. When we support multiple and decide on behavior this can be updated.
-	return &policies[0], nil
+	m.cache.add(key, decision)
+	return decision, nil
 }
 
 ////////////////// utils //////////////////
 
-// getPolicies returns policies for the given endpoints
-func (m *engine) getPolicies(endpoints []policy.Endpoint) ([]Action, error) {
+// getPolicies returns policies for the given endpoints.
+// ClusterPolicies selecting the namespace are used if no Policy targets any of the endpoints.
+func (m *engine) getPolicies(endpoints []policy.Endpoint, request policy.Request) (*Decision, error) {
 
 	// Check all possible endpoint variants for policies
 	for _, ep := range endpoints {
@@ -99,39 +104,8 @@ func (m *engine) getPolicies(endpoints []policy.Endpoint) ([]Action, error) {
 			zap.String("method", ep.Method.String()))
 
 		// Get policies for endpoint
-		if routeNode := m.store.GetPolicies(ep); len(routeNode.Actions) > 0 {
-
-			// Convert policy definition into Action
-			endpointActions := make([]Action, len(routeNode.Actions))
-			for i, p := range routeNode.Actions {
-				action := Action{
-					PathPolicy: p,
-					Type:       policy.NewType(p.PolicyType),
-				}
-
-				configName := ep.Service.Namespace + "/" + p.Config
-				zap.L().Debug("Checking for configuration", zap.String("name", configName), zap.String("type", action.PolicyType))
-
-				switch action.Type {
-				case policy.JWT:
-					if set := m.store.GetKeySet(configName); set != nil {
-						action.KeySet = set
-					} else {
-						return nil, errors.New("missing JWK Set : cannot authorize request")
-					}
-				case policy.OIDC:
-					if client := m.store.GetClient(configName); client != nil {
-						action.Client = client
-					} else {
-						return nil, errors.New("missing OIDC client : cannot authenticate user")
-					}
-				default:
-					return nil, errors.New("unexpected policy configuration")
-				}
-				endpointActions[i] = action
-			}
-
-			return endpointActions, nil
+		if routeNode := m.store.GetPolicies(ep, request); len(routeNode.Actions) > 0 {
+			return m.newDecision(ep, routeNode)
 		} else {
 			zap.L().Debug("No policies policies for endpoint",
 				zap.String("namespace", ep.Service.Namespace),
@@ -141,13 +115,105 @@ func (m *engine) getPolicies(endpoints []policy.Endpoint) ([]Action, error) {
 		}
 	}
 
-	return make([]Action, 0), nil
+	// Fall back to the cluster wide defaults
+	for _, ep := range endpoints {
+		if routeNode := m.store.GetClusterPolicies(ep, request); len(routeNode.Actions) > 0 {
+			zap.L().Debug("Using cluster policy for endpoint", zap.String("clusterPolicy", routeNode.PolicyReference),
+				zap.String("namespace", ep.Service.Namespace),
+				zap.String("service", ep.Service.Name),
+				zap.String("path", ep.Path),
+				zap.String("method", ep.Method.String()))
+			return m.newDecision(ep, routeNode)
+		}
+	}
+
+	return &Decision{Mode: policy.FIRSTMATCH, Actions: make([]Action, 0)}, nil
+}
+
+// newDecision converts the policies protecting an endpoint into actions
+func (m *engine) newDecision(ep policy.Endpoint, routeNode policy.RoutePolicy) (*Decision, error) {
+	if len(routeNode.Problems) > 0 {
+		zap.L().Warn("Policy is invalid", zap.String("policy", routeNode.PolicyReference), zap.Strings("problems", routeNode.Problems))
+		// The problems are reported on a deny action, so the request is rejected with 500 Internal Server Error
+		invalid := Action{
+			PathPolicy: v1.PathPolicy{PolicyType: "deny", Status: http.StatusInternalServerError},
+			Type:       policy.DENY,
+			Problems:   routeNode.Problems,
+		}
+		return &Decision{Mode: policy.FIRSTMATCH, Actions: []Action{invalid}}, nil
+	}
+
+	// Convert policy definition into Action
+	endpointActions := make([]Action, len(routeNode.Actions))
+	for i, p := range routeNode.Actions {
+		action := Action{
+			PathPolicy: p,
+			Type:       policy.NewType(p.PolicyType),
+		}
+
+		configName := policy.ConfigKey(ep.Service.Namespace, p.Config)
+		zap.L().Debug("Checking for configuration", zap.String("name", configName), zap.String("type", action.PolicyType))
+
+		// Missing configurations, and those the namespace may not reference, are reported on the action,
+		// causing the request to be denied
+		if kind := action.Type.ConfigKind(); kind != v1.NONE {
+			accessor, ok := configAccessor(action.Type)
+			if !ok {
+				return nil, errors.New("unexpected policy configuration")
+			}
+			resolved := action
+			if accessor(m.store, configName, &resolved) && m.referenceAllowed(kind, configName, ep.Service.Namespace) {
+				action = resolved
+			} else {
+				action.Missing = &policy.Dependency{Kind: kind, Name: configName}
+			}
+		} else if action.Type == policy.NONE {
+			return nil, errors.New("unexpected policy configuration")
+		}
+
+		if action.Type == policy.OPA {
+			regoName := ep.Service.Namespace + "/" + p.RegoModule
+			if rego := m.store.GetRegoPolicy(regoName); rego != nil {
+				action.Rego = rego
+			} else if action.Missing == nil {
+				action.Missing = &policy.Dependency{Kind: v1.CONFIGMAP, Name: regoName}
+			}
+		}
+
+		if action.Missing != nil {
+			zap.L().Warn("Policy references a missing resource", zap.String("policy", routeNode.PolicyReference), zap.String("resource", action.Missing.String()))
+		}
+
+		if p.Expression != "" {
+			if program := routeNode.Expressions[p.Expression]; program != nil {
+				action.Expression = program
+			} else {
+				return nil, errors.New("invalid policy expression: cannot authorize request")
+			}
+		}
+		endpointActions[i] = action
+	}
+
+	return &Decision{Mode: routeNode.Mode, Actions: endpointActions}, nil
+}
+
+// referenceAllowed returns true if a Policy in the given namespace may reference the config
+func (m *engine) referenceAllowed(kind v1.CrdType, configName string, namespace string) bool {
+	config := policy.Dependency{Kind: kind, Name: configName}
+	if m.store.IsReferenceAllowed(config, namespace) {
+		return true
+	}
+	zap.L().Warn("Config does not allow references from the namespace", zap.String("config", config.String()), zap.String("namespace", namespace))
+	return false
 }
 
 // createDefaultRules generates the default JWT validation rules for the given client
 func createDefaultRules(action Action) []v1.Rule {
 	switch action.Type {
 	case policy.OIDC:
+		if action.Client == nil {
+			return []v1.Rule{}
+		}
 		return []v1.Rule{
 			{
 				Claim:  aud,
@@ -160,12 +226,31 @@ func createDefaultRules(action Action) []v1.Rule {
 	}
 }
 
-// endpointsToCheck returns the possible endpoints housing the authn/z policies for the given target
+// newRequest returns the request attributes matched against policy conditions
+func newRequest(request *authnz.RequestMsg) policy.Request {
+	result := policy.Request{Headers: make(map[string]string)}
+	if request == nil {
+		return result
+	}
+	result.Scheme = request.Scheme
+	result.Host = request.Host
+	if request.Headers != nil {
+		for name, value := range request.Headers.Properties {
+			result.Headers[strings.ToLower(name)] = value.GetStringValue()
+		}
+	}
+	return result
+}
+
+// endpointsToCheck returns the possible endpoints housing the authn/z policies for the given target.
+// Regex endpoints are checked after exact and prefix matches.
 func endpointsToCheck(target *authnz.TargetMsg) []policy.Endpoint {
 	service := policy.Service{Namespace: target.Namespace, Name: target.Service}
 	return []policy.Endpoint{
 		{Service: service, Path: target.Path, Method: policy.NewMethod(target.Method)},
 		{Service: service, Path: target.Path, Method: policy.ALL},
+		{Service: service, Path: target.Path, Method: policy.NewMethod(target.Method), Regex: true},
+		{Service: service, Path: target.Path, Method: policy.ALL, Regex: true},
 		{Service: service, Path: "/*", Method: policy.ALL},
 	}
 }
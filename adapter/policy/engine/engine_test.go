@@ -3,13 +3,19 @@ package engine
 import (
 	"errors"
 	"testing"
+	"time"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/expression"
 	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/pkg/apis/policies/v1"
 
 	policy2 "github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/policy/store/policy"
 	"github.com/ibm-cloud-security/app-identity-and-access-adapter/tests/fake"
 
 	"github.com/stretchr/testify/assert"
+	"istio.io/api/policy/v1beta1"
 
 	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/policy"
 	"github.com/ibm-cloud-security/app-identity-and-access-adapter/config/template"
@@ -30,7 +36,7 @@ func TestNew(t *testing.T) {
 		},
 	}
 	for _, test := range tests {
-		result, err := New(test.store)
+		result, err := New(test.store, 0)
 		if test.err != nil {
 			assert.Nil(t, result)
 			assert.Equal(t, test.err, err)
@@ -47,6 +53,8 @@ func TestEvaluateJWTPolicies(t *testing.T) {
 		endpoints         []policy.Endpoint
 		expectedAction    policy.Type
 		expectedRuleCount int
+		missing           *policy.Dependency
+		problems          []string
 		err               error
 	}{
 		{
@@ -175,9 +183,10 @@ func TestEvaluateJWTPolicies(t *testing.T) {
 			endpoints: []policy.Endpoint{
 				genEndpoint("namespace", "svc", "/path", "POST"),
 			},
-			expectedAction:    policy.NONE,
+			expectedAction:    policy.JWT,
 			expectedRuleCount: 0,
-			err:               errors.New("missing JWK Set : cannot authorize request"),
+			missing:           &policy.Dependency{Kind: v1.JWTCONFIG, Name: "namespace/"},
+			err:               nil,
 		},
 		{
 			// 12 - existing rules
@@ -203,6 +212,89 @@ func TestEvaluateJWTPolicies(t *testing.T) {
 			expectedRuleCount: 0, //1, revert when rules exist
 			err:               nil,
 		},
+		{
+			// 13 - compiled expression
+			input: genActionMessage("namespace", "svc", "/path", "POST"),
+			pathPolicy: policy.RoutePolicy{
+				Actions: []v1.PathPolicy{
+					{
+						PolicyType: "jwt",
+						Config:     defaultJwtConfigName,
+						Expression: defaultExpression,
+					},
+				},
+				Expressions: map[string]expression.Program{defaultExpression: genExpression()},
+			},
+			endpoints: []policy.Endpoint{
+				genEndpoint("namespace", "svc", "/path", "POST"),
+			},
+			expectedAction:    policy.JWT,
+			expectedRuleCount: 0,
+			err:               nil,
+		},
+		{
+			// 14 - expression failed to compile
+			input: genActionMessage("namespace", "svc", "/path", "POST"),
+			pathPolicy: policy.RoutePolicy{
+				Actions: []v1.PathPolicy{
+					{
+						PolicyType: "jwt",
+						Config:     defaultJwtConfigName,
+						Expression: "claims.groups +",
+					},
+				},
+				Expressions: map[string]expression.Program{"claims.groups +": nil},
+			},
+			endpoints: []policy.Endpoint{
+				genEndpoint("namespace", "svc", "/path", "POST"),
+			},
+			expectedAction:    policy.NONE,
+			expectedRuleCount: 0,
+			err:               errors.New("invalid policy expression: cannot authorize request"),
+		},
+		{
+			// 15 - config in another namespace allowing the policy namespace
+			input:      genActionMessage("namespace", "svc", "/path", "POST"),
+			pathPolicy: genJWTPathPolicyArray("shared/jwt"),
+			endpoints: []policy.Endpoint{
+				genEndpoint("namespace", "svc", "/path", "POST"),
+			},
+			expectedAction:    policy.JWT,
+			expectedRuleCount: 0,
+			err:               nil,
+		},
+		{
+			// 16 - config in another namespace not allowing the policy namespace
+			input:      genActionMessage("namespace", "svc", "/path", "POST"),
+			pathPolicy: genJWTPathPolicyArray("private/jwt"),
+			endpoints: []policy.Endpoint{
+				genEndpoint("namespace", "svc", "/path", "POST"),
+			},
+			expectedAction:    policy.JWT,
+			expectedRuleCount: 0,
+			missing:           &policy.Dependency{Kind: v1.JWTCONFIG, Name: "private/jwt"},
+			err:               nil,
+		},
+		{
+			// 17 - invalid policy
+			input: genActionMessage("namespace", "svc", "/path", "POST"),
+			pathPolicy: policy.RoutePolicy{
+				Actions: []v1.PathPolicy{
+					{
+						PolicyType: "jwt",
+						Config:     defaultJwtConfigName,
+					},
+				},
+				Problems: []string{"/path: invalid rule rules[0]: unknown source `cookie`"},
+			},
+			endpoints: []policy.Endpoint{
+				genEndpoint("namespace", "svc", "/path", "POST"),
+			},
+			expectedAction:    policy.DENY,
+			expectedRuleCount: 0,
+			problems:          []string{"/path: invalid rule rules[0]: unknown source `cookie`"},
+			err:               nil,
+		},
 	}
 
 	for _, ts := range tests {
@@ -212,6 +304,9 @@ func TestEvaluateJWTPolicies(t *testing.T) {
 			t.Parallel()
 			store := policy2.New().(*policy2.LocalStore)
 			store.AddKeySet("namespace/default-jwt-config", &fake.KeySet{})
+			store.AddKeySet("shared/jwt", &fake.KeySet{})
+			store.SetAllowedNamespaces(policy.Dependency{Kind: v1.JWTCONFIG, Name: "shared/jwt"}, []string{"other", "namespace"})
+			store.AddKeySet("private/jwt", &fake.KeySet{})
 			eng := &engine{store: store}
 
 			if len(test.pathPolicy.Actions) > 0 {
@@ -220,16 +315,22 @@ func TestEvaluateJWTPolicies(t *testing.T) {
 				}
 			}
 
-			result, err := eng.Evaluate(test.input)
+			result, err := eng.Evaluate(test.input, nil)
 
 			// Result
 			if test.err != nil {
 				assert.Equal(t, test.err, err)
 			} else if err != nil {
 				t.Fail()
+			} else if test.expectedAction == policy.NONE {
+				assert.Empty(t, result.Actions)
 			} else {
-				assert.Equal(t, test.expectedRuleCount, len(result.Rules))
-				assert.Equal(t, test.expectedAction, result.Type)
+				assert.Equal(t, 1, len(result.Actions))
+				assert.Equal(t, test.expectedRuleCount, len(result.Actions[0].Rules))
+				assert.Equal(t, test.expectedAction, result.Actions[0].Type)
+				assert.Equal(t, test.missing, result.Actions[0].Missing)
+				assert.Equal(t, test.problems, result.Actions[0].Problems)
+				assert.Equal(t, result.Actions[0].PathPolicy.Expression != "", result.Actions[0].Expression != nil)
 			}
 		})
 	}
@@ -242,6 +343,7 @@ func TestEvaluateOIDCPolicies(t *testing.T) {
 		endpoints         []policy.Endpoint
 		expectedAction    policy.Type
 		expectedRuleCount int
+		missing           *policy.Dependency
 		err               error
 	}{
 		{
@@ -314,9 +416,47 @@ func TestEvaluateOIDCPolicies(t *testing.T) {
 			endpoints: []policy.Endpoint{
 				genEndpoint("namespace", "svc", "/path", "POST"),
 			},
-			expectedAction:    policy.NONE,
+			expectedAction:    policy.OIDC,
+			expectedRuleCount: 0,
+			missing:           &policy.Dependency{Kind: v1.OIDCCONFIG, Name: "namespace/other client"},
+			err:               nil,
+		},
+		{
+			// 4 - client in another namespace allowing every namespace
+			input: genActionMessage("namespace", "svc", "/path", "POST"),
+			pathPolicy: policy.RoutePolicy{
+				Actions: []v1.PathPolicy{
+					{
+						PolicyType: "oidc",
+						Config:     "shared/oidc",
+					},
+				},
+			},
+			endpoints: []policy.Endpoint{
+				genEndpoint("namespace", "svc", "/path", "POST"),
+			},
+			expectedAction:    policy.OIDC,
+			expectedRuleCount: 1,
+			err:               nil,
+		},
+		{
+			// 5 - client in another namespace not allowing references
+			input: genActionMessage("namespace", "svc", "/path", "POST"),
+			pathPolicy: policy.RoutePolicy{
+				Actions: []v1.PathPolicy{
+					{
+						PolicyType: "oidc",
+						Config:     "private/oidc",
+					},
+				},
+			},
+			endpoints: []policy.Endpoint{
+				genEndpoint("namespace", "svc", "/path", "POST"),
+			},
+			expectedAction:    policy.OIDC,
 			expectedRuleCount: 0,
-			err:               errors.New("missing OIDC client : cannot authenticate user"),
+			missing:           &policy.Dependency{Kind: v1.OIDCCONFIG, Name: "private/oidc"},
+			err:               nil,
 		},
 	}
 
@@ -327,6 +467,9 @@ func TestEvaluateOIDCPolicies(t *testing.T) {
 			/// Create new engine
 			store := policy2.New().(*policy2.LocalStore)
 			store.AddClient("namespace/"+defaultOidcConfigName, fake.NewClient(nil))
+			store.AddClient("shared/oidc", fake.NewClient(nil))
+			store.SetAllowedNamespaces(policy.Dependency{Kind: v1.OIDCCONFIG, Name: "shared/oidc"}, []string{"*"})
+			store.AddClient("private/oidc", fake.NewClient(nil))
 			eng := &engine{store: store}
 			if len(test.pathPolicy.Actions) > 0 {
 				for _, ep := range test.endpoints {
@@ -334,14 +477,17 @@ func TestEvaluateOIDCPolicies(t *testing.T) {
 				}
 			}
 			// Result
-			result, err := eng.Evaluate(test.input)
+			result, err := eng.Evaluate(test.input, nil)
 			if test.err != nil {
 				assert.Equal(t, test.err, err)
 			} else if err != nil {
 				t.Fail()
+			} else if test.expectedAction == policy.NONE {
+				assert.Empty(t, result.Actions)
 			} else {
-				assert.Equal(t, test.expectedRuleCount, len(result.Rules))
-				assert.Equal(t, test.expectedAction, result.Type)
+				assert.Equal(t, test.expectedRuleCount, len(result.Actions[0].Rules))
+				assert.Equal(t, test.expectedAction, result.Actions[0].Type)
+				assert.Equal(t, test.missing, result.Actions[0].Missing)
 			}
 		})
 	}
@@ -358,6 +504,304 @@ func genEndpoint(ns string, svc string, path string, method string) policy.Endpo
 	}
 }
 
+func TestEvaluateOPAPolicies(t *testing.T) {
+	tests := []struct {
+		pathPolicy     v1.PathPolicy
+		expectedAction policy.Type
+		missing        *policy.Dependency
+		err            error
+	}{
+		{
+			// 0 - valid keyset and rego module
+			pathPolicy:     v1.PathPolicy{PolicyType: "opa", Config: defaultJwtConfigName, RegoModule: defaultRegoModule},
+			expectedAction: policy.OPA,
+		},
+		{
+			// 1 - missing keyset
+			pathPolicy:     v1.PathPolicy{PolicyType: "opa", Config: "missing", RegoModule: defaultRegoModule},
+			expectedAction: policy.OPA,
+			missing:        &policy.Dependency{Kind: v1.JWTCONFIG, Name: "namespace/missing"},
+		},
+		{
+			// 2 - missing rego module
+			pathPolicy:     v1.PathPolicy{PolicyType: "opa", Config: defaultJwtConfigName, RegoModule: "missing"},
+			expectedAction: policy.OPA,
+			missing:        &policy.Dependency{Kind: v1.CONFIGMAP, Name: "namespace/missing"},
+		},
+	}
+
+	for _, ts := range tests {
+		test := ts
+		t.Run("Engine", func(t *testing.T) {
+			t.Parallel()
+			store := policy2.New()
+			store.AddKeySet("namespace/"+defaultJwtConfigName, &fake.KeySet{})
+			store.AddRegoPolicy("namespace/"+defaultRegoModule, &fake.RegoPolicy{})
+			store.SetPolicies(genEndpoint("namespace", "svc", "/path", "POST"), policy.RoutePolicy{Actions: []v1.PathPolicy{test.pathPolicy}})
+			eng := &engine{store: store}
+
+			result, err := eng.Evaluate(genActionMessage("namespace", "svc", "/path", "POST"), nil)
+			if test.err != nil {
+				assert.Equal(t, test.err, err)
+			} else {
+				assert.Nil(t, err)
+				assert.Equal(t, test.expectedAction, result.Actions[0].Type)
+				assert.Equal(t, test.missing, result.Actions[0].Missing)
+				assert.Equal(t, test.missing == nil || test.missing.Kind != v1.JWTCONFIG, result.Actions[0].KeySet != nil)
+				assert.Equal(t, test.missing == nil || test.missing.Kind != v1.CONFIGMAP, result.Actions[0].Rego != nil)
+			}
+		})
+	}
+}
+
+func TestEvaluateTokenValidation(t *testing.T) {
+	jwtConfig := v1.TokenValidation{Leeway: &metav1.Duration{Duration: time.Minute}}
+	oidcConfig := v1.TokenValidation{MaxAge: &metav1.Duration{Duration: time.Hour}}
+	client := fake.NewClient(nil)
+	client.Validation = oidcConfig
+
+	store := policy2.New()
+	store.AddKeySet("namespace/"+defaultJwtConfigName, &fake.KeySet{})
+	store.AddTokenValidation("namespace/"+defaultJwtConfigName, jwtConfig)
+	store.AddClient("namespace/"+defaultOidcConfigName, client)
+	store.SetPolicies(genEndpoint("namespace", "svc", "/jwt", "GET"), genJWTPathPolicyArray(defaultJwtConfigName))
+	store.SetPolicies(genEndpoint("namespace", "svc", "/oidc", "GET"), policy.RoutePolicy{
+		Actions: []v1.PathPolicy{{PolicyType: "oidc", Config: defaultOidcConfigName}},
+	})
+	eng := &engine{store: store}
+
+	result, err := eng.Evaluate(genActionMessage("namespace", "svc", "/jwt", "GET"), nil)
+	assert.Nil(t, err)
+	assert.Equal(t, jwtConfig, result.Actions[0].TokenValidation)
+
+	result, err = eng.Evaluate(genActionMessage("namespace", "svc", "/oidc", "GET"), nil)
+	assert.Nil(t, err)
+	assert.Equal(t, oidcConfig, result.Actions[0].TokenValidation)
+}
+
+func TestEvaluateMultiplePolicies(t *testing.T) {
+	tests := []struct {
+		mode policy.Mode
+	}{
+		{mode: policy.FIRSTMATCH},
+		{mode: policy.ALLOF},
+		{mode: policy.ANYOF},
+	}
+
+	for _, ts := range tests {
+		test := ts
+		t.Run(test.mode.String(), func(t *testing.T) {
+			t.Parallel()
+			store := policy2.New()
+			store.AddKeySet("namespace/"+defaultJwtConfigName, &fake.KeySet{})
+			store.AddClient("namespace/"+defaultOidcConfigName, fake.NewClient(nil))
+			store.SetPolicies(genEndpoint("namespace", "svc", "/path", "GET"), policy.RoutePolicy{
+				Mode: test.mode,
+				Actions: []v1.PathPolicy{
+					{PolicyType: "jwt", Config: defaultJwtConfigName},
+					{PolicyType: "oidc", Config: defaultOidcConfigName},
+				},
+			})
+			eng := &engine{store: store}
+
+			result, err := eng.Evaluate(genActionMessage("namespace", "svc", "/path", "GET"), nil)
+			assert.Nil(t, err)
+			assert.Equal(t, test.mode, result.Mode)
+			assert.Equal(t, 2, len(result.Actions))
+			assert.Equal(t, policy.JWT, result.Actions[0].Type)
+			assert.Equal(t, policy.OIDC, result.Actions[1].Type)
+			assert.Equal(t, 1, len(result.Actions[1].Rules))
+		})
+	}
+}
+
+func TestEvaluateClusterPolicies(t *testing.T) {
+	store := policy2.New()
+	store.AddKeySet("namespace/"+defaultJwtConfigName, &fake.KeySet{})
+	store.AddClient("namespace/"+defaultOidcConfigName, fake.NewClient(nil))
+	store.SetNamespaceLabels("namespace", map[string]string{"env": "prod"})
+	store.SetNamespaceLabels("other", map[string]string{"env": "dev"})
+	store.SetPolicies(genEndpoint("", policy.AllServices, "/*", "ALL"), policy.RoutePolicy{
+		PolicyReference: "defaults",
+		Actions:         []v1.PathPolicy{{PolicyType: "oidc", Config: defaultOidcConfigName}},
+	})
+	store.SetClusterPolicy("defaults", labels.SelectorFromSet(labels.Set{"env": "prod"}), nil)
+	eng := &engine{store: store}
+
+	// Selected namespace without a Policy uses the ClusterPolicy, resolving configs in its own namespace
+	result, err := eng.Evaluate(genActionMessage("namespace", "svc", "/path", "GET"), nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(result.Actions))
+	assert.Equal(t, policy.OIDC, result.Actions[0].Type)
+	assert.Nil(t, result.Actions[0].Missing)
+
+	// Namespace not selected
+	result, err = eng.Evaluate(genActionMessage("other", "svc", "/path", "GET"), nil)
+	assert.Nil(t, err)
+	assert.Empty(t, result.Actions)
+
+	// Namespace level Policies override the ClusterPolicy
+	store.SetPolicies(genEndpoint("namespace", "svc", "/path", "GET"), genJWTPathPolicyArray(defaultJwtConfigName))
+	result, err = eng.Evaluate(genActionMessage("namespace", "svc", "/path", "GET"), nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(result.Actions))
+	assert.Equal(t, policy.JWT, result.Actions[0].Type)
+}
+
+func TestEvaluateConditions(t *testing.T) {
+	store := policy2.New()
+	store.AddKeySet("namespace/"+defaultJwtConfigName, &fake.KeySet{})
+	store.AddClient("namespace/"+defaultOidcConfigName, fake.NewClient(nil))
+	store.SetPolicies(genEndpoint("namespace", "svc", "/path", "GET"), policy.RoutePolicy{
+		PolicyReference: "namespace/api",
+		Actions:         []v1.PathPolicy{{PolicyType: "jwt", Config: defaultJwtConfigName}},
+		Conditions:      policy.NewConditions([]string{"api.example.com"}, nil, []v1.HeaderMatch{{Name: "x-version"}}),
+	})
+	store.SetPolicies(genEndpoint("namespace", "svc", "/path", "ALL"), policy.RoutePolicy{
+		PolicyReference: "namespace/web",
+		Actions:         []v1.PathPolicy{{PolicyType: "oidc", Config: defaultOidcConfigName}},
+		Conditions:      policy.NewConditions([]string{"*.example.com"}, []string{"https"}, nil),
+	})
+	eng := &engine{store: store}
+
+	tests := []struct {
+		name     string
+		request  *authnz.RequestMsg
+		expected []policy.Type
+	}{
+		{
+			name:     "host and header",
+			request:  genRequestMessage("https", "api.example.com", map[string]string{"X-Version": "2"}),
+			expected: []policy.Type{policy.JWT},
+		},
+		{
+			name:     "missing header falls back to ALL",
+			request:  genRequestMessage("https", "api.example.com", nil),
+			expected: []policy.Type{policy.OIDC},
+		},
+		{
+			name:     "unmatched scheme",
+			request:  genRequestMessage("http", "www.example.com", nil),
+			expected: []policy.Type{},
+		},
+		{
+			name:     "no request",
+			expected: []policy.Type{},
+		},
+	}
+
+	for _, test := range tests {
+		result, err := eng.Evaluate(genActionMessage("namespace", "svc", "/path", "GET"), test.request)
+		assert.Nil(t, err, test.name)
+		types := make([]policy.Type, len(result.Actions))
+		for i, action := range result.Actions {
+			types[i] = action.Type
+		}
+		assert.Equal(t, test.expected, types, test.name)
+	}
+}
+
+func TestEvaluatePathPatterns(t *testing.T) {
+	store := policy2.New()
+	store.AddKeySet("namespace/"+defaultJwtConfigName, &fake.KeySet{})
+	store.AddClient("namespace/"+defaultOidcConfigName, fake.NewClient(nil))
+	store.SetPolicies(genEndpoint("namespace", "svc", "/users/{id}/orders", "GET"), genJWTPathPolicyArray(defaultJwtConfigName))
+	store.SetPolicies(genEndpoint("namespace", "svc", "/users/*", "ALL"), policy.RoutePolicy{
+		Actions: []v1.PathPolicy{{PolicyType: "oidc", Config: defaultOidcConfigName}},
+	})
+	eng := &engine{store: store}
+
+	result, err := eng.Evaluate(genActionMessage("namespace", "svc", "/users/1/orders", "GET"), nil)
+	assert.Nil(t, err)
+	assert.Equal(t, policy.JWT, result.Actions[0].Type)
+
+	result, err = eng.Evaluate(genActionMessage("namespace", "svc", "/users/1/profile", "GET"), nil)
+	assert.Nil(t, err)
+	assert.Equal(t, policy.OIDC, result.Actions[0].Type)
+}
+
+func TestEvaluateRegexPaths(t *testing.T) {
+	store := policy2.New()
+	store.AddKeySet("namespace/"+defaultJwtConfigName, &fake.KeySet{})
+	store.AddClient("namespace/"+defaultOidcConfigName, fake.NewClient(nil))
+	regex := genEndpoint("namespace", "svc", `/v[0-9]+/users`, "GET")
+	regex.Regex = true
+	store.SetPolicies(regex, genJWTPathPolicyArray(defaultJwtConfigName))
+	store.SetPolicies(genEndpoint("namespace", "svc", "/v1/users", "ALL"), policy.RoutePolicy{
+		Actions: []v1.PathPolicy{{PolicyType: "oidc", Config: defaultOidcConfigName}},
+	})
+	eng := &engine{store: store}
+
+	// Exact and prefix matches are checked before regex matches
+	result, err := eng.Evaluate(genActionMessage("namespace", "svc", "/v1/users", "GET"), nil)
+	assert.Nil(t, err)
+	assert.Equal(t, policy.OIDC, result.Actions[0].Type)
+
+	result, err = eng.Evaluate(genActionMessage("namespace", "svc", "/v2/users", "GET"), nil)
+	assert.Nil(t, err)
+	assert.Equal(t, policy.JWT, result.Actions[0].Type)
+
+	result, err = eng.Evaluate(genActionMessage("namespace", "svc", "/v2/users", "POST"), nil)
+	assert.Nil(t, err)
+	assert.Empty(t, result.Actions)
+}
+
+func TestEvaluateAllowDenyPolicies(t *testing.T) {
+	store := policy2.New()
+	store.SetPolicies(genEndpoint("namespace", "svc", "/*", "ALL"), genJWTPathPolicyArray(defaultJwtConfigName))
+	store.SetPolicies(genEndpoint("namespace", "svc", "/healthz", "GET"), policy.RoutePolicy{
+		Actions: []v1.PathPolicy{{PolicyType: "allow"}},
+	})
+	store.SetPolicies(genEndpoint("namespace", "svc", "/admin/*", "ALL"), policy.RoutePolicy{
+		Actions: []v1.PathPolicy{{PolicyType: "deny", Status: 404}},
+	})
+	eng := &engine{store: store}
+
+	result, err := eng.Evaluate(genActionMessage("namespace", "svc", "/healthz", "GET"), nil)
+	assert.Nil(t, err)
+	assert.Equal(t, []Action{{PathPolicy: v1.PathPolicy{PolicyType: "allow", Rules: []v1.Rule{}}, Type: policy.ALLOW}}, result.Actions)
+
+	result, err = eng.Evaluate(genActionMessage("namespace", "svc", "/admin/users", "GET"), nil)
+	assert.Nil(t, err)
+	assert.Equal(t, []Action{{PathPolicy: v1.PathPolicy{PolicyType: "deny", Status: 404, Rules: []v1.Rule{}}, Type: policy.DENY}}, result.Actions)
+
+	result, err = eng.Evaluate(genActionMessage("namespace", "svc", "/other", "GET"), nil)
+	assert.Nil(t, err)
+	assert.Equal(t, policy.JWT, result.Actions[0].Type)
+}
+
+func TestEvaluateMethods(t *testing.T) {
+	store := policy2.New()
+	store.AddKeySet("namespace/"+defaultJwtConfigName, &fake.KeySet{})
+	store.AddClient("namespace/"+defaultOidcConfigName, fake.NewClient(nil))
+	store.SetPolicies(genEndpoint("namespace", "svc", "/path", "HEAD"), genJWTPathPolicyArray(defaultJwtConfigName))
+	store.SetPolicies(genEndpoint("namespace", "svc", "/path", "ALL"), policy.RoutePolicy{
+		Actions: []v1.PathPolicy{{PolicyType: "oidc", Config: defaultOidcConfigName}},
+	})
+	eng := &engine{store: store}
+
+	result, err := eng.Evaluate(genActionMessage("namespace", "svc", "/path", "HEAD"), nil)
+	assert.Nil(t, err)
+	assert.Equal(t, policy.JWT, result.Actions[0].Type)
+
+	// Unknown methods only match policies registered for ALL methods
+	result, err = eng.Evaluate(genActionMessage("namespace", "svc", "/path", "PROPFIND"), nil)
+	assert.Nil(t, err)
+	assert.Equal(t, policy.OIDC, result.Actions[0].Type)
+}
+
+func genRequestMessage(scheme string, host string, headers map[string]string) *authnz.RequestMsg {
+	properties := make(map[string]*v1beta1.Value)
+	for name, value := range headers {
+		properties[name] = &v1beta1.Value{Value: &v1beta1.Value_StringValue{StringValue: value}}
+	}
+	return &authnz.RequestMsg{
+		Scheme:  scheme,
+		Host:    host,
+		Headers: &authnz.HeadersMsg{Properties: properties},
+	}
+}
+
 func genActionMessage(ns string, svc string, path string, method string) *authnz.TargetMsg {
 	return &authnz.TargetMsg{
 		Namespace: ns,
@@ -368,7 +812,9 @@ func genActionMessage(ns string, svc string, path string, method string) *authnz
 }
 
 const defaultOidcConfigName = "default-oidc-config"
+const defaultExpression = `"admin" in claims.groups`
 const defaultJwtConfigName = "default-jwt-config"
+const defaultRegoModule = "default-rego-module"
 
 func genJWTPathPolicyArray(cfg string) policy.RoutePolicy {
 	return policy.RoutePolicy{
@@ -380,3 +826,8 @@ func genJWTPathPolicyArray(cfg string) policy.RoutePolicy {
 		},
 	}
 }
+
+func genExpression() expression.Program {
+	program, _ := expression.Compile(defaultExpression)
+	return program
+}
@@ -0,0 +1,74 @@
+package engine
+
+import (
+	"sync"
+
+	v1 "github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/pkg/apis/policies/v1"
+	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/policy"
+	policy2 "github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/policy/store/policy"
+)
+
+// ConfigAccessor copies the config stored under the namespace/name key onto the action.
+// It returns false if the store does not hold the config.
+// Decisions are cached until the store changes, so accessors should only read from the store.
+type ConfigAccessor func(store policy2.PolicyStore, name string, action *Action) bool
+
+var (
+	accessorsMu sync.RWMutex
+	// accessors resolve the configs referenced by each policy type
+	accessors = map[policy.Type]ConfigAccessor{
+		policy.JWT:  keySetConfig,
+		policy.OPA:  keySetConfig,
+		policy.OIDC: clientConfig,
+	}
+)
+
+// RegisterConfigAccessor sets the accessor resolving the configs referenced by policies of the type
+func RegisterConfigAccessor(t policy.Type, accessor ConfigAccessor) {
+	accessorsMu.Lock()
+	defer accessorsMu.Unlock()
+	accessors[t] = accessor
+}
+
+// configAccessor returns the accessor of the policy type
+func configAccessor(t policy.Type) (ConfigAccessor, bool) {
+	accessorsMu.RLock()
+	defer accessorsMu.RUnlock()
+	accessor, ok := accessors[t]
+	return accessor, ok
+}
+
+// ConfigFound returns true if the store holds the config of the given kind stored under the namespace/name key,
+// as resolved by the accessor of a policy type referencing configs of that kind
+func ConfigFound(store policy2.PolicyStore, kind v1.CrdType, name string) bool {
+	accessorsMu.RLock()
+	defer accessorsMu.RUnlock()
+	for t, accessor := range accessors {
+		if t.ConfigKind() == kind {
+			return accessor(store, name, &Action{Type: t})
+		}
+	}
+	return false
+}
+
+// keySetConfig resolves the key set and token validation of a JwtConfig
+func keySetConfig(store policy2.PolicyStore, name string, action *Action) bool {
+	set := store.GetKeySet(name)
+	if set == nil {
+		return false
+	}
+	action.KeySet = set
+	action.TokenValidation = store.GetTokenValidation(name)
+	return true
+}
+
+// clientConfig resolves the client and token validation of an OidcConfig
+func clientConfig(store policy2.PolicyStore, name string, action *Action) bool {
+	client := store.GetClient(name)
+	if client == nil {
+		return false
+	}
+	action.Client = client
+	action.TokenValidation = client.TokenValidation()
+	return true
+}
@@ -0,0 +1,79 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/pkg/apis/policies/v1"
+	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/policy"
+	policy2 "github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/policy/store/policy"
+	"github.com/ibm-cloud-security/app-identity-and-access-adapter/tests/fake"
+)
+
+var (
+	// hmacType is a registered type signing requests with the keys of a JwtConfig
+	hmacType = registerType(policy.TypeInfo{Name: "hmac", ConfigKind: v1.JWTCONFIG}, func(store policy2.PolicyStore, name string, action *Action) bool {
+		set := store.GetKeySet(name)
+		action.Resolved = set
+		return set != nil
+	})
+	// a registered type referencing a config without an accessor
+	_ = policy.RegisterType(policy.TypeInfo{Name: "unresolved", ConfigKind: v1.OIDCCONFIG})
+	// basicType is a registered type without a config
+	basicType = policy.RegisterType(policy.TypeInfo{Name: "basic", ConfigKind: v1.NONE})
+	// tokenConfigKind is a registered kind of config, of which only namespace/found exists
+	tokenConfigKind = v1.RegisterCrdType("TokenConfig")
+	_               = registerType(policy.TypeInfo{Name: "token", ConfigKind: tokenConfigKind}, func(store policy2.PolicyStore, name string, action *Action) bool {
+		return name == "namespace/found"
+	})
+)
+
+func registerType(info policy.TypeInfo, accessor ConfigAccessor) policy.Type {
+	t := policy.RegisterType(info)
+	RegisterConfigAccessor(t, accessor)
+	return t
+}
+
+func TestEvaluateRegisteredTypes(t *testing.T) {
+	set := &fake.KeySet{}
+	store := policy2.New()
+	store.AddKeySet("namespace/keys", set)
+	store.SetPolicies(genEndpoint("namespace", "svc", "/signed", "ALL"), policy.RoutePolicy{Actions: []v1.PathPolicy{{PolicyType: "hmac", Config: "keys"}}})
+	store.SetPolicies(genEndpoint("namespace", "svc", "/missing", "ALL"), policy.RoutePolicy{Actions: []v1.PathPolicy{{PolicyType: "hmac", Config: "other"}}})
+	store.SetPolicies(genEndpoint("namespace", "svc", "/unresolved", "ALL"), policy.RoutePolicy{Actions: []v1.PathPolicy{{PolicyType: "unresolved", Config: "keys"}}})
+	store.SetPolicies(genEndpoint("namespace", "svc", "/basic", "ALL"), policy.RoutePolicy{Actions: []v1.PathPolicy{{PolicyType: "basic"}}})
+	eng := &engine{store: store}
+
+	result, err := eng.Evaluate(genActionMessage("namespace", "svc", "/signed", "GET"), nil)
+	assert.Nil(t, err)
+	assert.Equal(t, hmacType, result.Actions[0].Type)
+	assert.Equal(t, set, result.Actions[0].Resolved)
+	assert.Nil(t, result.Actions[0].Missing)
+
+	result, err = eng.Evaluate(genActionMessage("namespace", "svc", "/missing", "GET"), nil)
+	assert.Nil(t, err)
+	assert.Nil(t, result.Actions[0].Resolved)
+	assert.Equal(t, &policy.Dependency{Kind: v1.JWTCONFIG, Name: "namespace/other"}, result.Actions[0].Missing)
+
+	_, err = eng.Evaluate(genActionMessage("namespace", "svc", "/unresolved", "GET"), nil)
+	assert.EqualError(t, err, "unexpected policy configuration")
+
+	result, err = eng.Evaluate(genActionMessage("namespace", "svc", "/basic", "GET"), nil)
+	assert.Nil(t, err)
+	assert.Equal(t, []Action{{PathPolicy: v1.PathPolicy{PolicyType: "basic", Rules: []v1.Rule{}}, Type: basicType}}, result.Actions)
+}
+
+func TestConfigFound(t *testing.T) {
+	store := policy2.New()
+	store.AddKeySet("namespace/keys", &fake.KeySet{})
+	store.AddClient("namespace/client", &fake.Client{})
+
+	assert.True(t, ConfigFound(store, v1.JWTCONFIG, "namespace/keys"))
+	assert.False(t, ConfigFound(store, v1.JWTCONFIG, "namespace/other"))
+	assert.True(t, ConfigFound(store, v1.OIDCCONFIG, "namespace/client"))
+	assert.True(t, ConfigFound(store, tokenConfigKind, "namespace/found"))
+	assert.False(t, ConfigFound(store, tokenConfigKind, "namespace/keys"))
+	// Kinds without an accessor are never found
+	assert.False(t, ConfigFound(store, v1.CONFIGMAP, "namespace/keys"))
+}
@@ -1,17 +1,33 @@
 package crdeventhandler
 
 import (
+	"errors"
+	"fmt"
+	"strings"
+
 	"go.uber.org/zap"
+	k8sv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
 
 	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/authserver"
 	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/authserver/keyset"
 	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/client"
+	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/opa"
 	v1 "github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/pkg/apis/policies/v1"
+	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/pkg/client/clientset/versioned"
 	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/policy"
 	storepolicy "github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/policy/store/policy"
 )
 
+// PolicyConflictReason is the reason of the events recorded when several Policies target the same endpoint
+const PolicyConflictReason = "PolicyConflict"
+
+// MissingReferenceReason is the reason of the events recorded when a resource referenced by Policies is deleted
+const MissingReferenceReason = "MissingReference"
+
 type AddUpdateEventHandler interface {
 	HandleAddUpdateEvent()
 }
@@ -19,23 +35,65 @@ type AddUpdateEventHandler interface {
 type JwtConfigAddEventHandler struct {
 	Obj *v1.JwtConfig
 	Store storepolicy.PolicyStore
+	PoliciesClient versioned.Interface
 }
 
 type OidcConfigAddEventHandler struct {
 	Obj *v1.OidcConfig
 	KubeClient kubernetes.Interface
 	Store storepolicy.PolicyStore
+	PoliciesClient versioned.Interface
 }
 
 type PolicyAddEventHandler struct {
 	Obj *v1.Policy
 	Store storepolicy.PolicyStore
+	Recorder record.EventRecorder
+	PoliciesClient versioned.Interface
+}
+
+type ConfigMapAddEventHandler struct {
+	Obj *k8sv1.ConfigMap
+	Store storepolicy.PolicyStore
+	PoliciesClient versioned.Interface
+}
+
+type ClusterPolicyAddEventHandler struct {
+	Obj *v1.ClusterPolicy
+	Store storepolicy.PolicyStore
+	PoliciesClient versioned.Interface
+}
+
+type NamespaceAddEventHandler struct {
+	Obj *k8sv1.Namespace
+	Store storepolicy.PolicyStore
+}
+
+type SecretAddEventHandler struct {
+	Obj *k8sv1.Secret
+	Store storepolicy.PolicyStore
+	KubeClient kubernetes.Interface
+	PoliciesClient versioned.Interface
 }
 
 func (e *JwtConfigAddEventHandler) HandleAddUpdateEvent() {
 	zap.L().Info("Create/Update JwtConfig", zap.String("ID", string(e.Obj.ObjectMeta.UID)), zap.String("name", e.Obj.Name), zap.String("namespace", e.Obj.Namespace))
 	e.Obj.Spec.ClientName = e.Obj.ObjectMeta.Namespace + "/" + e.Obj.ObjectMeta.Name
-	e.Store.AddKeySet(e.Obj.Spec.ClientName, keyset.New(e.Obj.Spec.JwksURL, nil))
+	jwks := keyset.New(e.Obj.Spec.JwksURL, nil)
+	// Keys are synced again when tokens are signed by unknown keys, which may change the status of the JwtConfig
+	namespace, name := e.Obj.Namespace, e.Obj.Name
+	jwks.OnSync(func(error) {
+		go refreshJwtConfigStatus(e.Store, e.PoliciesClient, namespace, name, jwks)
+	})
+	e.Store.Update(func(store storepolicy.PolicyStore) {
+		store.AddKeySet(e.Obj.Spec.ClientName, jwks)
+		store.AddTokenValidation(e.Obj.Spec.ClientName, e.Obj.Spec.TokenValidation)
+		store.SetAllowedNamespaces(policy.Dependency{Kind: v1.JWTCONFIG, Name: e.Obj.Spec.ClientName}, e.Obj.Spec.AllowedNamespaces)
+	})
+	updateJwtConfigStatus(e.PoliciesClient, e.Obj,
+		newCondition(v1.KeysSynced, jwks.SyncError(), reasonSynced, reasonSyncFailed),
+	)
+	refreshDependentPolicies(e.Store, e.PoliciesClient, policy.Dependency{Kind: v1.JWTCONFIG, Name: e.Obj.Spec.ClientName})
 	zap.L().Info("JwtConfig created/updated", zap.String("ID", string(e.Obj.ObjectMeta.UID)), zap.String("name", e.Obj.ObjectMeta.Name), zap.String("namespace", e.Obj.ObjectMeta.Namespace))
 }
 
@@ -48,10 +106,32 @@ func (e *OidcConfigAddEventHandler) HandleAddUpdateEvent() {
 	authorizationServer := authserver.New(e.Obj.Spec.DiscoveryURL)
 	keySets := keyset.New(authorizationServer.JwksEndpoint(), nil)
 	authorizationServer.SetKeySet(keySets)
-	e.Obj.Spec.ClientSecret = GetClientSecret(e.Obj, e.KubeClient)
+	// The discovery document and keys are synced again as requests use them, which may change the status of the OidcConfig
+	namespace, name := e.Obj.Namespace, e.Obj.Name
+	refresh := func(error) {
+		go refreshOidcConfigStatus(e.Store, e.PoliciesClient, namespace, name, authorizationServer, keySets)
+	}
+	authorizationServer.OnSync(refresh)
+	keySets.OnSync(refresh)
+	secret, secretErr := resolveClientSecret(e.Obj, e.KubeClient)
+	e.Obj.Spec.ClientSecret = secret
 	// Create and store OIDC Client
 	oidcClient := client.New(e.Obj.Spec, authorizationServer)
-	e.Store.AddClient(oidcClient.Name(), oidcClient)
+	e.Store.Update(func(store storepolicy.PolicyStore) {
+		if e.Obj.Spec.ClientSecretRef.Name != "" && e.Obj.Spec.ClientSecretRef.Key != "" {
+			store.SetSecretReference(e.Obj.Spec.ClientName, e.Obj.ObjectMeta.Namespace+"/"+e.Obj.Spec.ClientSecretRef.Name)
+		} else {
+			store.SetSecretReference(e.Obj.Spec.ClientName, "")
+		}
+		store.AddClient(oidcClient.Name(), oidcClient)
+		store.SetAllowedNamespaces(policy.Dependency{Kind: v1.OIDCCONFIG, Name: e.Obj.Spec.ClientName}, e.Obj.Spec.AllowedNamespaces)
+	})
+	updateOidcConfigStatus(e.PoliciesClient, e.Obj,
+		newCondition(v1.DiscoverySynced, authorizationServer.SyncError(), reasonSynced, reasonSyncFailed),
+		newCondition(v1.KeysSynced, keySets.SyncError(), reasonSynced, reasonSyncFailed),
+		newCondition(v1.SecretResolved, secretErr, reasonResolved, reasonSecretMissing),
+	)
+	refreshDependentPolicies(e.Store, e.PoliciesClient, policy.Dependency{Kind: v1.OIDCCONFIG, Name: e.Obj.Spec.ClientName})
 	zap.L().Info("OidcConfig created/updated", zap.String("ID", string(e.Obj.ObjectMeta.UID)), zap.String("name", e.Obj.Name), zap.String("namespace", e.Obj.Namespace))
 }
 
@@ -59,49 +139,227 @@ func (e *PolicyAddEventHandler) HandleAddUpdateEvent() {
 	zap.L().Debug("Create/Update Policy", zap.String("ID", string(e.Obj.ObjectMeta.UID)), zap.String("name", e.Obj.ObjectMeta.Name), zap.String("namespace", e.Obj.ObjectMeta.Namespace))
 	mappingId := e.Obj.ObjectMeta.Namespace + "/" +e.Obj.ObjectMeta.Name
 	parsedPolicies := ParseTarget(e.Obj.Spec.Target, e.Obj.ObjectMeta.Namespace)
+	problems, expressions := validatePolicy(e.Obj)
+	for _, problem := range problems {
+		zap.L().Error("Policy is invalid", zap.String("policy", mappingId), zap.String("problem", problem))
+	}
+	mode := policy.NewMode(e.Obj.Spec.Mode)
+	// Replace the endpoints of the previous version of the policy in a single update,
+	// so requests are never evaluated against a partially applied policy.
+	// The endpoints of an invalid policy are stored with its problems, so requests to them are denied.
+	e.Store.Update(func(store storepolicy.PolicyStore) {
+		for _, policies := range store.GetPolicyMapping(mappingId) {
+			store.DeletePolicies(policies.Endpoint, mappingId)
+		}
+		for _, policies := range parsedPolicies {
+			zap.S().Debug("Adding policy for endpoint", policies.Endpoint)
+			store.SetPolicies(policies.Endpoint, policy.RoutePolicy{PolicyReference: mappingId, Priority: e.Obj.Spec.Priority, Mode: mode, Actions: policies.Actions, Expressions: expressions, Conditions: policies.Conditions, Problems: storedProblems(problems)})
+		}
+		store.AddPolicyMapping(mappingId, parsedPolicies)
+	})
 	for _, policies := range parsedPolicies {
-		zap.S().Debug("Adding policy for endpoint", policies.Endpoint)
-		e.Store.SetPolicies(policies.Endpoint, policy.RoutePolicy{ PolicyReference: mappingId, Actions: policies.Actions})
+		e.reportConflicts(policies.Endpoint, policies.Conditions)
 	}
-	e.Store.AddPolicyMapping(mappingId, parsedPolicies)
+	updatePolicyStatus(e.PoliciesClient, e.Obj, problems, missingDependencies(e.Store, parsedPolicies), forbiddenDependencies(e.Store, e.Obj.Namespace, parsedPolicies), unsyncedDependencies(e.Store, parsedPolicies))
 	zap.L().Info("Policy created/updated", zap.String("ID", string(e.Obj.ObjectMeta.UID)))
 }
 
+// storedProblems returns the problems stored with the endpoints of a policy, nil if it is valid
+func storedProblems(problems []string) []string {
+	if len(problems) == 0 {
+		return nil
+	}
+	return problems
+}
+
+// reportConflicts records an event on every Policy targeting the endpoint with the same conditions when more than one does
+func (e *PolicyAddEventHandler) reportConflicts(endpoint policy.Endpoint, conditions policy.Conditions) {
+	references := make([]string, 0)
+	for _, p := range e.Store.ListPolicies(endpoint) {
+		if p.Conditions.String() == conditions.String() {
+			references = append(references, p.PolicyReference)
+		}
+	}
+	if len(references) < 2 {
+		return
+	}
+	message := fmt.Sprintf("Policies %s target %s/%s %s %s; %s takes precedence",
+		strings.Join(references, ", "), endpoint.Service.Namespace, endpoint.Service.Name, endpoint.Method, endpoint.Path, references[0])
+	zap.L().Warn("Conflicting policies", zap.Strings("policies", references), zap.String("service", endpoint.Service.Name), zap.String("path", endpoint.Path), zap.String("method", endpoint.Method.String()))
+	if e.Recorder == nil {
+		return
+	}
+	for _, reference := range references {
+		e.Recorder.Event(policyReference(reference), k8sv1.EventTypeWarning, PolicyConflictReason, message)
+	}
+}
+
+func (e *ConfigMapAddEventHandler) HandleAddUpdateEvent() {
+	zap.L().Debug("Create/Update Rego ConfigMap", zap.String("ID", string(e.Obj.ObjectMeta.UID)), zap.String("name", e.Obj.ObjectMeta.Name), zap.String("namespace", e.Obj.ObjectMeta.Namespace))
+	name := e.Obj.ObjectMeta.Namespace + "/" + e.Obj.ObjectMeta.Name
+	regoPolicy, err := opa.Compile(name, e.Obj.Data)
+	if err != nil {
+		// Remove the previous modules so that requests are denied until the ConfigMap is fixed
+		zap.L().Error("Could not compile Rego modules", zap.String("configMap", name), zap.Error(err))
+		e.Store.DeleteRegoPolicy(name)
+		refreshDependentPolicies(e.Store, e.PoliciesClient, policy.Dependency{Kind: v1.CONFIGMAP, Name: name})
+		return
+	}
+	e.Store.AddRegoPolicy(name, regoPolicy)
+	refreshDependentPolicies(e.Store, e.PoliciesClient, policy.Dependency{Kind: v1.CONFIGMAP, Name: name})
+	zap.L().Info("Rego ConfigMap created/updated", zap.String("ID", string(e.Obj.ObjectMeta.UID)), zap.String("name", e.Obj.Name), zap.String("namespace", e.Obj.Namespace))
+}
+
+func (e *ClusterPolicyAddEventHandler) HandleAddUpdateEvent() {
+	zap.L().Debug("Create/Update ClusterPolicy", zap.String("ID", string(e.Obj.ObjectMeta.UID)), zap.String("name", e.Obj.ObjectMeta.Name))
+	name := e.Obj.ObjectMeta.Name
+	// Cluster policy endpoints are parsed without a namespace, storing them under their ClusterService
+	parsedPolicies := ParseTarget(e.Obj.Spec.Target, "")
+	problems, expressions := validateClusterPolicy(e.Obj)
+	for _, problem := range problems {
+		zap.L().Error("ClusterPolicy is invalid", zap.String("clusterPolicy", name), zap.String("problem", problem))
+	}
+	selector, err := namespaceSelector(e.Obj)
+	if err != nil {
+		// An invalid selector selects no namespace
+		selector = labels.Nothing()
+	}
+	mode := policy.NewMode(e.Obj.Spec.Mode)
+	// Replace the endpoints of the previous version of the policy in a single update.
+	// Requests to the endpoints of an invalid policy are denied.
+	e.Store.Update(func(store storepolicy.PolicyStore) {
+		for _, policies := range store.GetClusterPolicyMapping(name) {
+			store.DeletePolicies(policies.Endpoint, name)
+		}
+		for _, policies := range parsedPolicies {
+			store.SetPolicies(policies.Endpoint, policy.RoutePolicy{PolicyReference: name, Priority: e.Obj.Spec.Priority, Mode: mode, Actions: policies.Actions, Expressions: expressions, Conditions: policies.Conditions, Problems: storedProblems(problems)})
+		}
+		store.SetClusterPolicy(name, selector, parsedPolicies)
+	})
+	updateClusterPolicyStatus(e.PoliciesClient, e.Obj, problems)
+	zap.L().Info("ClusterPolicy created/updated", zap.String("ID", string(e.Obj.ObjectMeta.UID)), zap.String("name", name))
+}
+
+func (e *NamespaceAddEventHandler) HandleAddUpdateEvent() {
+	zap.L().Debug("Create/Update Namespace", zap.String("name", e.Obj.ObjectMeta.Name))
+	namespaceLabels := e.Obj.ObjectMeta.Labels
+	if namespaceLabels == nil {
+		namespaceLabels = map[string]string{}
+	}
+	e.Store.SetNamespaceLabels(e.Obj.ObjectMeta.Name, namespaceLabels)
+}
+
+func (e *SecretAddEventHandler) HandleAddUpdateEvent() {
+	name := e.Obj.ObjectMeta.Namespace + "/" + e.Obj.ObjectMeta.Name
+	zap.L().Debug("Create/Update Secret", zap.String("name", name))
+	refreshSecretReferences(e.Store, e.KubeClient, e.PoliciesClient, name)
+}
+
+// refreshSecretReferences rebuilds the OIDC clients reading their client secret from a secret that was added, updated or deleted
+func refreshSecretReferences(store storepolicy.PolicyStore, kubeClient kubernetes.Interface, policiesClient versioned.Interface, secret string) {
+	for _, key := range store.GetSecretReferences(secret) {
+		zap.L().Info("Reloading client secret of OidcConfig", zap.String("oidcConfig", key), zap.String("secret", secret))
+		if policiesClient == nil {
+			continue
+		}
+		namespace, name := splitKey(key)
+		obj, err := policiesClient.AppidV1().OidcConfigs(namespace).Get(name, metav1.GetOptions{})
+		if err != nil {
+			zap.L().Warn("Could not get OidcConfig referencing secret", zap.String("oidcConfig", key), zap.Error(err))
+			continue
+		}
+		handler := &OidcConfigAddEventHandler{
+			Obj:            obj,
+			Store:          store,
+			KubeClient:     kubeClient,
+			PoliciesClient: policiesClient,
+		}
+		handler.HandleAddUpdateEvent()
+	}
+}
+
 func GetClientSecret(crd *v1.OidcConfig, kubeClient kubernetes.Interface) string {
+	secret, _ := resolveClientSecret(crd, kubeClient)
+	return secret
+}
+
+// resolveClientSecret returns the client secret of the OidcConfig.
+// An error is returned if the referenced secret could not be read or no secret is configured.
+func resolveClientSecret(crd *v1.OidcConfig, kubeClient kubernetes.Interface) (string, error) {
 	// Return kube secret from reference if present, else try clientSecret
 	if crd.Spec.ClientSecretRef.Name != "" && crd.Spec.ClientSecretRef.Key != "" {
+		// The plaintext secret is not used when the referenced secret cannot be read
 		secret, err := GetKubeSecret(kubeClient, crd.ObjectMeta.Namespace, crd.Spec.ClientSecretRef)
 		if err != nil || string(secret.Data[crd.Spec.ClientSecretRef.Key]) == "" {
-			zap.S().Warn("Failed to get kube secret: ", err)
-			return crd.Spec.ClientSecret
+			if err == nil {
+				err = fmt.Errorf("secret %s does not contain key %s", crd.Spec.ClientSecretRef.Name, crd.Spec.ClientSecretRef.Key)
+			}
+			zap.L().Error("Failed to get kube secret", zap.String("oidcConfig", crd.Namespace+"/"+crd.Name), zap.Error(err))
+			return "", err
 		} else {
-			return string(secret.Data[crd.Spec.ClientSecretRef.Key])
+			return string(secret.Data[crd.Spec.ClientSecretRef.Key]), nil
 		}
 	} else if crd.Spec.ClientSecret != "" {
-		return crd.Spec.ClientSecret
+		return crd.Spec.ClientSecret, nil
 	}
-	return ""
+	return "", errors.New("no client secret configured")
 }
 
-func GetAddEventHandler(obj interface{}, store storepolicy.PolicyStore, kubeClient kubernetes.Interface) AddUpdateEventHandler {
+func GetAddEventHandler(obj interface{}, store storepolicy.PolicyStore, kubeClient kubernetes.Interface, policiesClient versioned.Interface, recorder record.EventRecorder) AddUpdateEventHandler {
 	switch crd := obj.(type) {
 	case *v1.JwtConfig:
 		return &JwtConfigAddEventHandler{
-			Obj:   crd,
-			Store: store,
+			Obj:            crd,
+			Store:          store,
+			PoliciesClient: policiesClient,
 		}
 	case *v1.OidcConfig:
 		return &OidcConfigAddEventHandler{
-			Obj:        crd,
-			Store:      store,
-			KubeClient: kubeClient,
+			Obj:            crd,
+			Store:          store,
+			KubeClient:     kubeClient,
+			PoliciesClient: policiesClient,
 		}
 	case *v1.Policy:
 		return &PolicyAddEventHandler{
+			Obj:            crd,
+			Store:          store,
+			Recorder:       recorder,
+			PoliciesClient: policiesClient,
+		}
+	case *k8sv1.ConfigMap:
+		return &ConfigMapAddEventHandler{
+			Obj:            crd,
+			Store:          store,
+			PoliciesClient: policiesClient,
+		}
+	case *v1.ClusterPolicy:
+		return &ClusterPolicyAddEventHandler{
+			Obj:            crd,
+			Store:          store,
+			PoliciesClient: policiesClient,
+		}
+	case *k8sv1.Namespace:
+		return &NamespaceAddEventHandler{
 			Obj:   crd,
 			Store: store,
 		}
+	case *k8sv1.Secret:
+		return &SecretAddEventHandler{
+			Obj:            crd,
+			Store:          store,
+			KubeClient:     kubeClient,
+			PoliciesClient: policiesClient,
+		}
 	default:
+		if kind, ok := configKindOf(obj); ok {
+			return &ConfigAddEventHandler{
+				Obj:            obj,
+				Kind:           kind,
+				Store:          store,
+				PoliciesClient: policiesClient,
+			}
+		}
 		return nil
 	}
 }
@@ -2,14 +2,20 @@ package crdeventhandler
 
 
 import (
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	k8sV1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
 
+	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/expression"
+	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/opa"
 	v1 "github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/pkg/apis/policies/v1"
+	policiesFake "github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/pkg/client/clientset/versioned/fake"
 	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/policy"
 	storePolicy "github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/policy/store/policy"
 )
@@ -30,6 +36,13 @@ func getDefaultPathPolicy() []v1.PathPolicy{
 	return []v1.PathPolicy{}
 }
 
+func configMapGenerator(name string, module string) *k8sV1.ConfigMap {
+	return &k8sV1.ConfigMap{
+		ObjectMeta: getObjectMetaWithName(name),
+		Data:       map[string]string{"authz.rego": module},
+	}
+}
+
 func getObjectMetaWithName(name string) metav1.ObjectMeta {
 	return metav1.ObjectMeta{Namespace: ns, Name: name}
 }
@@ -106,6 +119,7 @@ func getRoutePolicy(policyReference string) policy.RoutePolicy{
 	return policy.RoutePolicy{
 		PolicyReference: policyReference,
 		Actions: getPathPolicy(),
+		Expressions: map[string]expression.Program{},
 	}
 }
 
@@ -179,6 +193,17 @@ func TestGetClientSecret(t *testing.T) {
 					ClientSecretRef: v1.ClientSecretRef{Name: "mysecret", Key: ""}}, getObjectMeta(), getTypeMeta()),
 			secret: secretFromPlainText,
 		},
+		{
+			name: "plaintext secret w/ missing ref secret",
+			obj: getOidcConfig(
+				v1.OidcConfigSpec{
+					ClientName:      "name",
+					ClientID:        "id",
+					DiscoveryURL:    "url",
+					ClientSecret:    secretFromPlainText,
+					ClientSecretRef: v1.ClientSecretRef{Name: "invalidName", Key: "secretKey"}}, getObjectMeta(), getTypeMeta()),
+			secret: "",
+		},
 	}
 	kubeclient := fake.NewSimpleClientset()
 	kubeclient.CoreV1().Secrets(ns).Create(mockKubeSecret())
@@ -194,35 +219,295 @@ func TestGetClientSecret(t *testing.T) {
 
 func TestHandler_JwtConfigAddEventHandler(t *testing.T) {
 	store:= storePolicy.New()
-	handler := GetAddEventHandler(jwtConfigGenerator(), store, fake.NewSimpleClientset())
+	handler := GetAddEventHandler(jwtConfigGenerator(), store, fake.NewSimpleClientset(), nil, nil)
 	handler.HandleAddUpdateEvent()
 	key := "ns/sample"
 	assert.Equal(t, store.GetKeySet(key).PublicKeyURL(), jwksUrl)
+	assert.Equal(t, v1.TokenValidation{}, store.GetTokenValidation(key))
+
+	config := jwtConfigGenerator()
+	config.Spec.Leeway = &metav1.Duration{Duration: time.Minute}
+	GetAddEventHandler(config, store, fake.NewSimpleClientset(), nil, nil).HandleAddUpdateEvent()
+	assert.Equal(t, time.Minute, store.GetTokenValidation(key).Leeway.Duration)
 }
 
 func TestHandler_OidcConfigAddEventHandler(t *testing.T) {
 	store:= storePolicy.New()
 	policyName := "oidcconfig"
 	key := "ns/" + policyName
-	handler := GetAddEventHandler(oidcConfigGenerator(policyName, "oidc", jwksUrl), store, fake.NewSimpleClientset())
+	handler := GetAddEventHandler(oidcConfigGenerator(policyName, "oidc", jwksUrl), store, fake.NewSimpleClientset(), nil, nil)
 	handler.HandleAddUpdateEvent()
 	assert.Equal(t, store.GetClient(key).Secret(), secretFromPlainText)
 }
 
+func TestHandler_SecretEventHandlers(t *testing.T) {
+	store := storePolicy.New()
+	secret := mockKubeSecret()
+	secret.Namespace = ns
+	kubeClient := fake.NewSimpleClientset(secret)
+	obj := oidcConfigWithRef("oidc", "id", jwksUrl, v1.ClientSecretRef{Name: "mysecret", Key: "secretKey"})
+	policiesClient := policiesFake.NewSimpleClientset(obj)
+	GetAddEventHandler(obj, store, kubeClient, policiesClient, nil).HandleAddUpdateEvent()
+	assert.Equal(t, secretFromRef, store.GetClient("ns/sample").Secret())
+	assert.Equal(t, []string{"ns/sample"}, store.GetSecretReferences("ns/mysecret"))
+
+	// Rotated secret
+	secret.Data["secretKey"] = []byte("rotated")
+	_, _ = kubeClient.CoreV1().Secrets(ns).Update(secret)
+	GetAddEventHandler(secret, store, kubeClient, policiesClient, nil).HandleAddUpdateEvent()
+	assert.Equal(t, "rotated", store.GetClient("ns/sample").Secret())
+
+	// Deleted secret
+	_ = kubeClient.CoreV1().Secrets(ns).Delete(secret.Name, &metav1.DeleteOptions{})
+	GetDeleteEventHandler(policy.CrdKey{Id: "ns/mysecret", CrdType: v1.SECRET}, store, kubeClient, policiesClient, nil).HandleDeleteEvent()
+	assert.Equal(t, "", store.GetClient("ns/sample").Secret())
+	result, err := policiesClient.AppidV1().OidcConfigs(ns).Get(obj.Name, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, reasonSecretMissing, result.Status.GetCondition(v1.SecretResolved).Reason)
+
+	// Unreferenced secret
+	other := &k8sV1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: "other"}}
+	GetAddEventHandler(other, store, kubeClient, policiesClient, nil).HandleAddUpdateEvent()
+	assert.Equal(t, "", store.GetClient("ns/sample").Secret())
+}
+
 func TestHandler_PolicyAddEventHandler(t *testing.T) {
 	store:= storePolicy.New()
 	key := "ns/sample"
 	targets := []v1.TargetElement{
 		getTargetElements(service, getPathConfigs(getPathConfig("/path", "/paths", "GET", getPathPolicy()))),
 	}
-	handler := GetAddEventHandler(policyGenerator(targets), store, fake.NewSimpleClientset())
+	handler := GetAddEventHandler(policyGenerator(targets), store, fake.NewSimpleClientset(), nil, nil)
 	handler.HandleAddUpdateEvent()
-	assert.Equal(t, store.GetPolicies(getEndpoint(getDefaultService(), policy.GET,"/path")), getRoutePolicy(key))
-	assert.Equal(t, store.GetPolicies(getEndpoint(getDefaultService(), policy.GET,"/paths/*")), getRoutePolicy(key))
+	assert.Equal(t, store.GetPolicies(getEndpoint(getDefaultService(), policy.GET,"/path"), policy.Request{}), getRoutePolicy(key))
+	assert.Equal(t, store.GetPolicies(getEndpoint(getDefaultService(), policy.GET,"/paths/*"), policy.Request{}), getRoutePolicy(key))
+}
+
+func TestHandler_PolicyAddEventHandlerExpressions(t *testing.T) {
+	store:= storePolicy.New()
+	valid := `"admin" in claims.groups && request.method == "GET"`
+	invalid := `claims.groups +`
+	pathPolicies := []v1.PathPolicy{
+		{PolicyType: "jwt", Config: "samplejwt", Expression: valid},
+		{PolicyType: "jwt", Config: "samplejwt", Expression: invalid},
+	}
+	targets := []v1.TargetElement{
+		getTargetElements(service, getPathConfigs(getPathConfig("/path", "", "GET", pathPolicies))),
+	}
+	handler := GetAddEventHandler(policyGenerator(targets), store, fake.NewSimpleClientset(), nil, nil)
+	handler.HandleAddUpdateEvent()
+	routePolicy := store.GetPolicies(getEndpoint(getDefaultService(), policy.GET,"/path"), policy.Request{})
+	assert.Equal(t, 2, len(routePolicy.Expressions))
+	assert.NotNil(t, routePolicy.Expressions[valid])
+	assert.Nil(t, routePolicy.Expressions[invalid])
+	assert.Equal(t, 1, len(routePolicy.Problems))
+}
+
+func TestHandler_PolicyAddEventHandlerInvalid(t *testing.T) {
+	store := storePolicy.New()
+	invalid := []v1.PathPolicy{{PolicyType: "jwt", Config: "samplejwt", Rules: []v1.Rule{{AllOf: []v1.Rule{{Claim: "aud", Values: []string{"app"}, Source: "cookie"}}}}}}
+	obj := policyGenerator([]v1.TargetElement{
+		getTargetElements(service, getPathConfigs(getPathConfig("/path", "", "GET", invalid))),
+	})
+	client := policiesFake.NewSimpleClientset(obj)
+	ep := getEndpoint(getDefaultService(), policy.GET, "/path")
+
+	// Invalid policies are stored with their problems, so requests to their endpoints are denied
+	GetAddEventHandler(obj, store, fake.NewSimpleClientset(), client, nil).HandleAddUpdateEvent()
+	assert.Equal(t, "ns/sample", store.GetPolicies(ep, policy.Request{}).PolicyReference)
+	assert.Equal(t, []string{"/path: invalid rule rules[0].allOf[0]: unknown source `cookie`"}, store.GetPolicies(ep, policy.Request{}).Problems)
+	result, err := client.AppidV1().Policies(ns).Get(obj.Name, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, reasonInvalidPolicy, result.Status.GetCondition(v1.Ready).Reason)
+
+	// Fixing the policy enforces it
+	obj.Spec.Target = []v1.TargetElement{
+		getTargetElements(service, getPathConfigs(getPathConfig("/path", "", "GET", getPathPolicy()))),
+	}
+	GetAddEventHandler(obj, store, fake.NewSimpleClientset(), client, nil).HandleAddUpdateEvent()
+	assert.Empty(t, store.GetPolicies(ep, policy.Request{}).Problems)
+}
+
+func TestHandler_PolicyAddEventHandlerUnknownMethods(t *testing.T) {
+	store := storePolicy.New()
+	path := getPathConfig("/path", "", "GETT", getPathPolicy())
+	path.Methods = []string{"POSTT"}
+	GetAddEventHandler(policyGenerator([]v1.TargetElement{getTargetElements(service, getPathConfigs(path))}), store, fake.NewSimpleClientset(), nil, nil).HandleAddUpdateEvent()
+
+	// Requests to the path are denied rather than left unprotected
+	for _, method := range []policy.Method{policy.GET, policy.POST, policy.DELETE} {
+		routePolicy := store.GetPolicies(getEndpoint(getDefaultService(), method, "/path"), policy.Request{})
+		assert.Equal(t, "ns/sample", routePolicy.PolicyReference)
+		assert.NotEmpty(t, routePolicy.Problems)
+	}
+}
+
+func TestHandler_PolicyAddEventHandlerMode(t *testing.T) {
+	tests := []struct {
+		mode     string
+		expected policy.Mode
+	}{
+		{mode: "", expected: policy.FIRSTMATCH},
+		{mode: "firstMatch", expected: policy.FIRSTMATCH},
+		{mode: "all", expected: policy.ALLOF},
+		{mode: "any", expected: policy.ANYOF},
+		{mode: "unknown", expected: policy.FIRSTMATCH},
+	}
+	for _, test := range tests {
+		store := storePolicy.New()
+		targets := []v1.TargetElement{
+			getTargetElements(service, getPathConfigs(getPathConfig("/path", "", "GET", getPathPolicy()))),
+		}
+		obj := policyGenerator(targets)
+		obj.Spec.Mode = test.mode
+		handler := GetAddEventHandler(obj, store, fake.NewSimpleClientset(), nil, nil)
+		handler.HandleAddUpdateEvent()
+		assert.Equal(t, test.expected, store.GetPolicies(getEndpoint(getDefaultService(), policy.GET, "/path"), policy.Request{}).Mode)
+	}
+}
+
+func TestHandler_PolicyAddEventHandlerConflicts(t *testing.T) {
+	store := storePolicy.New()
+	recorder := record.NewFakeRecorder(10)
+	targets := []v1.TargetElement{
+		getTargetElements(service, getPathConfigs(getPathConfig("/path", "", "GET", getPathPolicy()))),
+	}
+	low := getPolicy(getPolicySpec(targets), getObjectMetaWithName("low"), getTypeMeta())
+	high := getPolicy(getPolicySpec(targets), getObjectMetaWithName("high"), getTypeMeta())
+	high.Spec.Priority = 10
+
+	GetAddEventHandler(low, store, fake.NewSimpleClientset(), nil, recorder).HandleAddUpdateEvent()
+	assert.Empty(t, recorder.Events)
+	GetAddEventHandler(high, store, fake.NewSimpleClientset(), nil, recorder).HandleAddUpdateEvent()
+
+	ep := getEndpoint(getDefaultService(), policy.GET, "/path")
+	assert.Equal(t, "ns/high", store.GetPolicies(ep, policy.Request{}).PolicyReference)
+	assert.Equal(t, 2, len(recorder.Events))
+	for i := 0; i < 2; i++ {
+		assert.Equal(t, "Warning PolicyConflict Policies ns/high, ns/low target ns/service GET /path; ns/high takes precedence", <-recorder.Events)
+	}
+
+	// Updating a policy removes the endpoints it no longer targets
+	high.Spec.Target = []v1.TargetElement{
+		getTargetElements(service, getPathConfigs(getPathConfig("/other", "", "GET", getPathPolicy()))),
+	}
+	GetAddEventHandler(high, store, fake.NewSimpleClientset(), nil, recorder).HandleAddUpdateEvent()
+	assert.Equal(t, "ns/low", store.GetPolicies(ep, policy.Request{}).PolicyReference)
+	assert.Equal(t, "ns/high", store.GetPolicies(getEndpoint(getDefaultService(), policy.GET, "/other"), policy.Request{}).PolicyReference)
+	assert.Empty(t, recorder.Events)
+}
+
+func clusterPolicyGenerator(name string, selector *metav1.LabelSelector, targets []v1.TargetElement) *v1.ClusterPolicy {
+	return &v1.ClusterPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Generation: 1},
+		Spec:       v1.ClusterPolicySpec{NamespaceSelector: selector, PolicySpec: getPolicySpec(targets)},
+	}
+}
+
+func TestHandler_ClusterPolicyAddEventHandler(t *testing.T) {
+	store := storePolicy.New()
+	selector := &metav1.LabelSelector{MatchLabels: map[string]string{"env": "prod"}}
+	obj := clusterPolicyGenerator("defaults", selector, []v1.TargetElement{
+		getTargetElements(policy.AllServices, getPathConfigs(getPathConfig("/path", "", "GET", []v1.PathPolicy{{PolicyType: "oidc", Config: "oidc"}}))),
+	})
+	client := policiesFake.NewSimpleClientset(obj)
+	GetAddEventHandler(obj, store, fake.NewSimpleClientset(), client, nil).HandleAddUpdateEvent()
+	ep := getEndpoint(getDefaultService(), policy.GET, "/path")
+	assert.Equal(t, getDefaultRoutePolicy(), store.GetClusterPolicies(ep, policy.Request{}))
+
+	// Labeled namespace
+	namespace := &k8sV1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: ns, Labels: map[string]string{"env": "prod"}}}
+	GetAddEventHandler(namespace, store, nil, nil, nil).HandleAddUpdateEvent()
+	assert.Equal(t, "defaults", store.GetClusterPolicies(ep, policy.Request{}).PolicyReference)
+	assert.Equal(t, getDefaultRoutePolicy(), store.GetPolicies(ep, policy.Request{}))
+
+	result, err := client.AppidV1().ClusterPolicies().Get(obj.Name, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, k8sV1.ConditionTrue, result.Status.GetCondition(v1.Ready).Status)
+
+	// Updated paths replace the previous ones
+	obj.Spec.Target = []v1.TargetElement{
+		getTargetElements(policy.AllServices, getPathConfigs(getPathConfig("/other", "", "GET", []v1.PathPolicy{{PolicyType: "oidc", Config: "oidc"}}))),
+	}
+	GetAddEventHandler(obj, store, fake.NewSimpleClientset(), client, nil).HandleAddUpdateEvent()
+	assert.Equal(t, getDefaultRoutePolicy(), store.GetClusterPolicies(ep, policy.Request{}))
+	assert.Equal(t, "defaults", store.GetClusterPolicies(getEndpoint(getDefaultService(), policy.GET, "/other"), policy.Request{}).PolicyReference)
+
+	// Invalid selector selects no namespace
+	obj.Spec.NamespaceSelector = &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "env", Operator: "Among"}}}
+	GetAddEventHandler(obj, store, fake.NewSimpleClientset(), client, nil).HandleAddUpdateEvent()
+	assert.Equal(t, getDefaultRoutePolicy(), store.GetClusterPolicies(getEndpoint(getDefaultService(), policy.GET, "/other"), policy.Request{}))
+	result, err = client.AppidV1().ClusterPolicies().Get(obj.Name, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, reasonInvalidPolicy, result.Status.GetCondition(v1.Ready).Reason)
+}
+
+func TestHandler_ConfigMapAddEventHandler(t *testing.T) {
+	store:= storePolicy.New()
+	key := "ns/rego"
+	handler := GetAddEventHandler(configMapGenerator("rego", "package authz\n\ndefault allow = true"), store, fake.NewSimpleClientset(), nil, nil)
+	handler.HandleAddUpdateEvent()
+	regoPolicy := store.GetRegoPolicy(key)
+	assert.NotNil(t, regoPolicy)
+	allowed, err := regoPolicy.Eval("", opa.Input{})
+	assert.Nil(t, err)
+	assert.True(t, allowed)
+
+	// Invalid modules replace the previous version
+	handler = GetAddEventHandler(configMapGenerator("rego", "package authz\n\nallow {"), store, fake.NewSimpleClientset(), nil, nil)
+	handler.HandleAddUpdateEvent()
+	assert.Nil(t, store.GetRegoPolicy(key))
 }
 
 func TestHandler_InvalidObject(t *testing.T) {
 	store:= storePolicy.New()
-	handler := GetAddEventHandler(1, store, fake.NewSimpleClientset())
+	handler := GetAddEventHandler(1, store, fake.NewSimpleClientset(), nil, nil)
 	assert.Nil(t, handler)
-}
\ No newline at end of file
+}
+func TestHandler_PolicyEventHandlersConcurrentReads(t *testing.T) {
+	store := storePolicy.New()
+	// version returns the Policy protecting /path and /paths with config "a", or only /path with config "b"
+	version := func(config string) *v1.Policy {
+		prefix := ""
+		if config == "a" {
+			prefix = "/paths"
+		}
+		return policyGenerator([]v1.TargetElement{
+			getTargetElements(service, getPathConfigs(getPathConfig("/path", prefix, "GET", []v1.PathPolicy{{PolicyType: "jwt", Config: config}}))),
+		})
+	}
+
+	done := make(chan struct{})
+	var readers sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				// Both endpoints of a snapshot are protected by the same version of the Policy
+				snapshot := store.Snapshot()
+				exact := snapshot.GetPolicies(getEndpoint(getDefaultService(), policy.GET, "/path"), policy.Request{})
+				prefix := snapshot.GetPolicies(getEndpoint(getDefaultService(), policy.GET, "/paths/resource"), policy.Request{})
+				if len(exact.Actions) > 0 && exact.Actions[0].Config == "a" {
+					assert.Equal(t, exact, prefix)
+				} else {
+					assert.Equal(t, getDefaultRoutePolicy(), prefix)
+				}
+			}
+		}()
+	}
+
+	for i := 0; i < 100; i++ {
+		GetAddEventHandler(version("a"), store, fake.NewSimpleClientset(), nil, nil).HandleAddUpdateEvent()
+		GetAddEventHandler(version("b"), store, fake.NewSimpleClientset(), nil, nil).HandleAddUpdateEvent()
+		GetAddEventHandler(version("a"), store, fake.NewSimpleClientset(), nil, nil).HandleAddUpdateEvent()
+		GetDeleteEventHandler(policy.CrdKey{Id: "ns/sample", CrdType: v1.POLICY}, store, nil, nil, nil).HandleDeleteEvent()
+	}
+	close(done)
+	readers.Wait()
+}
@@ -2,8 +2,11 @@ package crdeventhandler
 
 import (
 	"go.uber.org/zap"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
 
 	v1 "github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/pkg/apis/policies/v1"
+	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/pkg/client/clientset/versioned"
 	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/policy"
 	storepolicy "github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/policy/store/policy"
 )
@@ -15,11 +18,15 @@ type DeleteEventHandler interface {
 type JwtConfigDeleteEventHandler struct {
 	Key string
 	Store storepolicy.PolicyStore
+	Recorder record.EventRecorder
+	PoliciesClient versioned.Interface
 }
 
 type OidcConfigDeleteEventHandler struct {
 	Key string
 	Store storepolicy.PolicyStore
+	Recorder record.EventRecorder
+	PoliciesClient versioned.Interface
 }
 
 type PolicyDeleteEventHandler struct {
@@ -27,46 +34,145 @@ type PolicyDeleteEventHandler struct {
 	Store storepolicy.PolicyStore
 }
 
+type ConfigMapDeleteEventHandler struct {
+	Key string
+	Store storepolicy.PolicyStore
+	Recorder record.EventRecorder
+	PoliciesClient versioned.Interface
+}
+
+type ClusterPolicyDeleteEventHandler struct {
+	Key string
+	Store storepolicy.PolicyStore
+}
+
+type NamespaceDeleteEventHandler struct {
+	Key string
+	Store storepolicy.PolicyStore
+}
+
+type SecretDeleteEventHandler struct {
+	Key string
+	Store storepolicy.PolicyStore
+	KubeClient kubernetes.Interface
+	PoliciesClient versioned.Interface
+}
+
 func (e *JwtConfigDeleteEventHandler) HandleDeleteEvent() {
-	e.Store.DeleteKeySet(e.Key)
+	dependency := policy.Dependency{Kind: v1.JWTCONFIG, Name: e.Key}
+	e.Store.Update(func(store storepolicy.PolicyStore) {
+		store.DeleteKeySet(e.Key)
+		store.DeleteTokenValidation(e.Key)
+		store.SetAllowedNamespaces(dependency, nil)
+	})
+	warnDependentPolicies(e.Store, e.Recorder, dependency)
+	refreshDependentPolicies(e.Store, e.PoliciesClient, dependency)
 }
 
 func (e *OidcConfigDeleteEventHandler) HandleDeleteEvent() {
-	e.Store.DeleteClient(e.Key)
+	dependency := policy.Dependency{Kind: v1.OIDCCONFIG, Name: e.Key}
+	e.Store.Update(func(store storepolicy.PolicyStore) {
+		store.DeleteClient(e.Key)
+		store.SetAllowedNamespaces(dependency, nil)
+	})
+	warnDependentPolicies(e.Store, e.Recorder, dependency)
+	refreshDependentPolicies(e.Store, e.PoliciesClient, dependency)
+}
+
+func (e *ConfigMapDeleteEventHandler) HandleDeleteEvent() {
+	e.Store.DeleteRegoPolicy(e.Key)
+	dependency := policy.Dependency{Kind: v1.CONFIGMAP, Name: e.Key}
+	warnDependentPolicies(e.Store, e.Recorder, dependency)
+	refreshDependentPolicies(e.Store, e.PoliciesClient, dependency)
+}
+
+func (e *ClusterPolicyDeleteEventHandler) HandleDeleteEvent() {
+	e.Store.Update(func(store storepolicy.PolicyStore) {
+		for _, policies := range store.GetClusterPolicyMapping(e.Key) {
+			store.DeletePolicies(policies.Endpoint, e.Key)
+		}
+		store.DeleteClusterPolicy(e.Key)
+	})
+	zap.L().Debug("Delete cluster policy completed", zap.String("name", e.Key))
+}
+
+func (e *NamespaceDeleteEventHandler) HandleDeleteEvent() {
+	e.Store.SetNamespaceLabels(e.Key, nil)
+}
+
+func (e *SecretDeleteEventHandler) HandleDeleteEvent() {
+	// The OIDC clients referencing the secret are rebuilt without a client secret
+	refreshSecretReferences(e.Store, e.KubeClient, e.PoliciesClient, e.Key)
 }
 
 func (e *PolicyDeleteEventHandler) HandleDeleteEvent() {
-	parsedPolicies := e.Store.GetPolicyMapping(e.Key)
-	for _, policies := range parsedPolicies {
-		zap.S().Debug("Getting policy for endpoint", policies.Endpoint)
-		storedPolicy := e.Store.GetPolicies(policies.Endpoint)
-		if storedPolicy.PolicyReference == e.Key {
-			e.Store.SetPolicies(policies.Endpoint, policy.NewRoutePolicy())
+	e.Store.Update(func(store storepolicy.PolicyStore) {
+		for _, policies := range store.GetPolicyMapping(e.Key) {
+			// Policies contributed to the endpoint by other Policy objects take over
+			zap.S().Debug("Deleting policy for endpoint", policies.Endpoint)
+			store.DeletePolicies(policies.Endpoint, e.Key)
 		}
-	}
-	// remove entry from policyMapping
-	e.Store.DeletePolicyMapping(e.Key)
+		// remove entry from policyMapping
+		store.DeletePolicyMapping(e.Key)
+	})
 	zap.S().Debug("Delete policy completed")
 }
 
-func GetDeleteEventHandler(crd policy.CrdKey, store storepolicy.PolicyStore) DeleteEventHandler {
+func GetDeleteEventHandler(crd policy.CrdKey, store storepolicy.PolicyStore, kubeClient kubernetes.Interface, policiesClient versioned.Interface, recorder record.EventRecorder) DeleteEventHandler {
 	switch crd.CrdType {
 	case v1.JWTCONFIG:
 		return &JwtConfigDeleteEventHandler{
-			Key:   crd.Id,
-			Store: store,
+			Key:            crd.Id,
+			Store:          store,
+			Recorder:       recorder,
+			PoliciesClient: policiesClient,
 		}
 	case v1.OIDCCONFIG:
 		return &OidcConfigDeleteEventHandler{
-			Key:   crd.Id,
-			Store: store,
+			Key:            crd.Id,
+			Store:          store,
+			Recorder:       recorder,
+			PoliciesClient: policiesClient,
 		}
 	case v1.POLICY:
 		return &PolicyDeleteEventHandler{
 			Key:   crd.Id,
 			Store: store,
 		}
+	case v1.CONFIGMAP:
+		return &ConfigMapDeleteEventHandler{
+			Key:            crd.Id,
+			Store:          store,
+			Recorder:       recorder,
+			PoliciesClient: policiesClient,
+		}
+	case v1.CLUSTERPOLICY:
+		return &ClusterPolicyDeleteEventHandler{
+			Key:   crd.Id,
+			Store: store,
+		}
+	case v1.NAMESPACE:
+		return &NamespaceDeleteEventHandler{
+			Key:   crd.Id,
+			Store: store,
+		}
+	case v1.SECRET:
+		return &SecretDeleteEventHandler{
+			Key:            crd.Id,
+			Store:          store,
+			KubeClient:     kubeClient,
+			PoliciesClient: policiesClient,
+		}
 	default:
+		if kind, ok := registeredConfigKind(crd.CrdType); ok {
+			return &ConfigDeleteEventHandler{
+				Key:            crd.Id,
+				Kind:           kind,
+				Store:          store,
+				Recorder:       recorder,
+				PoliciesClient: policiesClient,
+			}
+		}
 		zap.S().Warn("Could not delete object. Unknown type: %f", crd)
 		return nil
 	}
@@ -4,6 +4,8 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	k8sV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes/fake"
 
 	v1 "github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/pkg/apis/policies/v1"
@@ -13,23 +15,24 @@ import (
 
 func TestHandler_JwtConfigDeleteEventHandler(t *testing.T) {
 	store:= storePolicy.New()
-	handler := GetAddEventHandler(jwtConfigGenerator(), store, fake.NewSimpleClientset())
+	handler := GetAddEventHandler(jwtConfigGenerator(), store, fake.NewSimpleClientset(), nil, nil)
 	handler.HandleAddUpdateEvent()
 	key := "ns/sample"
 	assert.Equal(t, store.GetKeySet(key).PublicKeyURL(), jwksUrl)
-	deleteHandler := GetDeleteEventHandler(policy.CrdKey{ Id: key, CrdType: v1.JWTCONFIG}, store)
+	deleteHandler := GetDeleteEventHandler(policy.CrdKey{ Id: key, CrdType: v1.JWTCONFIG}, store, nil, nil, nil)
 	deleteHandler.HandleDeleteEvent()
 	assert.Nil(t, store.GetKeySet(key))
+	assert.Equal(t, v1.TokenValidation{}, store.GetTokenValidation(key))
 }
 
 func TestHandler_OidcConfigDeleteEventHandler(t *testing.T) {
 	store:= storePolicy.New()
 	policyName := "oidcconfig"
 	key := "ns/" + policyName
-	handler := GetAddEventHandler(oidcConfigGenerator(policyName, "oidc", jwksUrl), store, fake.NewSimpleClientset())
+	handler := GetAddEventHandler(oidcConfigGenerator(policyName, "oidc", jwksUrl), store, fake.NewSimpleClientset(), nil, nil)
 	handler.HandleAddUpdateEvent()
 	assert.Equal(t, store.GetClient(key).Secret(), secretFromPlainText)
-	deleteHandler := GetDeleteEventHandler(policy.CrdKey{ Id: key, CrdType: v1.OIDCCONFIG}, store)
+	deleteHandler := GetDeleteEventHandler(policy.CrdKey{ Id: key, CrdType: v1.OIDCCONFIG}, store, nil, nil, nil)
 	deleteHandler.HandleDeleteEvent()
 	assert.Nil(t, store.GetClient(key))
 }
@@ -40,19 +43,78 @@ func TestHandler_PolicyDeleteEventHandler(t *testing.T) {
 	targets := []v1.TargetElement{
 		getTargetElements(service, getPathConfigs(getPathConfig("/path", "/paths", "GET", getPathPolicy()))),
 	}
-	handler := GetAddEventHandler(policyGenerator(targets), store, fake.NewSimpleClientset())
+	handler := GetAddEventHandler(policyGenerator(targets), store, fake.NewSimpleClientset(), nil, nil)
 	handler.HandleAddUpdateEvent()
-	assert.Equal(t, store.GetPolicies(getEndpoint(getDefaultService(), policy.GET,"/path")), getRoutePolicy(key))
-	assert.Equal(t, store.GetPolicies(getEndpoint(getDefaultService(), policy.GET,"/paths/*")), getRoutePolicy(key))
-	deleteHandler := GetDeleteEventHandler(policy.CrdKey{ Id: key, CrdType: v1.POLICY}, store)
+	assert.Equal(t, store.GetPolicies(getEndpoint(getDefaultService(), policy.GET,"/path"), policy.Request{}), getRoutePolicy(key))
+	assert.Equal(t, store.GetPolicies(getEndpoint(getDefaultService(), policy.GET,"/paths/*"), policy.Request{}), getRoutePolicy(key))
+	deleteHandler := GetDeleteEventHandler(policy.CrdKey{ Id: key, CrdType: v1.POLICY}, store, nil, nil, nil)
 	deleteHandler.HandleDeleteEvent()
-	assert.Equal(t, store.GetPolicies(getEndpoint(getDefaultService(),policy.GET,"/path")), getDefaultRoutePolicy())
-	assert.Equal(t, store.GetPolicies(getEndpoint(getDefaultService(), policy.GET, "/paths/*")), getDefaultRoutePolicy())
+	assert.Equal(t, store.GetPolicies(getEndpoint(getDefaultService(),policy.GET,"/path"), policy.Request{}), getDefaultRoutePolicy())
+	assert.Equal(t, store.GetPolicies(getEndpoint(getDefaultService(), policy.GET, "/paths/*"), policy.Request{}), getDefaultRoutePolicy())
 
 }
 
+func TestHandler_PolicyDeleteEventHandlerRestoresNextPolicy(t *testing.T) {
+	store := storePolicy.New()
+	targets := []v1.TargetElement{
+		getTargetElements(service, getPathConfigs(getPathConfig("/path", "", "GET", getPathPolicy()))),
+	}
+	low := getPolicy(getPolicySpec(targets), getObjectMetaWithName("low"), getTypeMeta())
+	high := getPolicy(getPolicySpec(targets), getObjectMetaWithName("high"), getTypeMeta())
+	high.Spec.Priority = 1
+	GetAddEventHandler(low, store, fake.NewSimpleClientset(), nil, nil).HandleAddUpdateEvent()
+	GetAddEventHandler(high, store, fake.NewSimpleClientset(), nil, nil).HandleAddUpdateEvent()
+
+	ep := getEndpoint(getDefaultService(), policy.GET, "/path")
+	assert.Equal(t, "ns/high", store.GetPolicies(ep, policy.Request{}).PolicyReference)
+	GetDeleteEventHandler(policy.CrdKey{Id: "ns/high", CrdType: v1.POLICY}, store, nil, nil, nil).HandleDeleteEvent()
+	assert.Equal(t, "ns/low", store.GetPolicies(ep, policy.Request{}).PolicyReference)
+	GetDeleteEventHandler(policy.CrdKey{Id: "ns/low", CrdType: v1.POLICY}, store, nil, nil, nil).HandleDeleteEvent()
+	assert.Equal(t, getDefaultRoutePolicy(), store.GetPolicies(ep, policy.Request{}))
+}
+
+func TestHandler_ClusterPolicyDeleteEventHandler(t *testing.T) {
+	store := storePolicy.New()
+	obj := clusterPolicyGenerator("defaults", nil, []v1.TargetElement{
+		getTargetElements(service, getPathConfigs(getPathConfig("/path", "", "GET", getPathPolicy()))),
+	})
+	GetAddEventHandler(obj, store, fake.NewSimpleClientset(), nil, nil).HandleAddUpdateEvent()
+	GetAddEventHandler(&k8sV1.Namespace{ObjectMeta: getObjectMetaWithName(ns)}, store, nil, nil, nil).HandleAddUpdateEvent()
+	ep := getEndpoint(getDefaultService(), policy.GET, "/path")
+	assert.Equal(t, "defaults", store.GetClusterPolicies(ep, policy.Request{}).PolicyReference)
+
+	GetDeleteEventHandler(policy.CrdKey{Id: "defaults", CrdType: v1.CLUSTERPOLICY}, store, nil, nil, nil).HandleDeleteEvent()
+	assert.Equal(t, getDefaultRoutePolicy(), store.GetClusterPolicies(ep, policy.Request{}))
+	assert.Nil(t, store.GetClusterPolicyMapping("defaults"))
+}
+
+func TestHandler_NamespaceDeleteEventHandler(t *testing.T) {
+	store := storePolicy.New()
+	selector := &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "env", Operator: metav1.LabelSelectorOpDoesNotExist}}}
+	GetAddEventHandler(clusterPolicyGenerator("defaults", selector, []v1.TargetElement{
+		getTargetElements(service, getPathConfigs(getPathConfig("/path", "", "GET", getPathPolicy()))),
+	}), store, fake.NewSimpleClientset(), nil, nil).HandleAddUpdateEvent()
+	GetAddEventHandler(&k8sV1.Namespace{ObjectMeta: getObjectMetaWithName(ns)}, store, nil, nil, nil).HandleAddUpdateEvent()
+	ep := getEndpoint(getDefaultService(), policy.GET, "/path")
+	assert.Equal(t, "defaults", store.GetClusterPolicies(ep, policy.Request{}).PolicyReference)
+
+	GetDeleteEventHandler(policy.CrdKey{Id: ns, CrdType: v1.NAMESPACE}, store, nil, nil, nil).HandleDeleteEvent()
+	assert.Equal(t, "defaults", store.GetClusterPolicies(ep, policy.Request{}).PolicyReference)
+}
+
+func TestHandler_ConfigMapDeleteEventHandler(t *testing.T) {
+	store:= storePolicy.New()
+	key := "ns/rego"
+	handler := GetAddEventHandler(configMapGenerator("rego", "package authz\n\ndefault allow = true"), store, fake.NewSimpleClientset(), nil, nil)
+	handler.HandleAddUpdateEvent()
+	assert.NotNil(t, store.GetRegoPolicy(key))
+	deleteHandler := GetDeleteEventHandler(policy.CrdKey{ Id: key, CrdType: v1.CONFIGMAP}, store, nil, nil, nil)
+	deleteHandler.HandleDeleteEvent()
+	assert.Nil(t, store.GetRegoPolicy(key))
+}
+
 func TestHandler_InvalidInputObject(t *testing.T) {
 	store:= storePolicy.New()
-	handler :=  GetDeleteEventHandler(policy.CrdKey{ Id: "key", CrdType: 5}, store)
+	handler :=  GetDeleteEventHandler(policy.CrdKey{ Id: "key", CrdType: v1.NONE}, store, nil, nil, nil)
 	assert.Nil(t, handler)
 }
\ No newline at end of file
@@ -0,0 +1,128 @@
+package crdeventhandler
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+
+	"go.uber.org/zap"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+
+	v1 "github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/pkg/apis/policies/v1"
+	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/pkg/client/clientset/versioned"
+	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/policy"
+	storepolicy "github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/policy/store/policy"
+)
+
+// ConfigKind describes a kind of config provided by a plugin, such as the custom resource configuring a new policy type.
+// Configs of registered kinds are watched and stored without changes to the handlers or the initializer,
+// and the Policies referencing them are refreshed as they are added and deleted.
+type ConfigKind struct {
+	// Kind identifies the configs, as returned by v1.RegisterCrdType
+	Kind v1.CrdType
+	// Object is an instance of the type of the objects returned by the informer.
+	// Each kind must be watched as a distinct type.
+	Object interface{}
+	// Informer creates the informer watching the configs
+	Informer func(config *rest.Config) (cache.SharedIndexInformer, error)
+	// Add stores a config that was added or updated under its namespace/name key.
+	// Configs that cannot be used should be removed from the store, so that requests are denied.
+	Add func(obj interface{}, key string, store storepolicy.PolicyStore) error
+	// Delete removes the config stored under the namespace/name key
+	Delete func(key string, store storepolicy.PolicyStore)
+}
+
+var (
+	configKindsMu sync.RWMutex
+	// configKinds holds the registered kinds of config
+	configKinds = make(map[v1.CrdType]ConfigKind)
+)
+
+// RegisterConfigKind adds a kind of config to watch. Plugins usually register from an init function.
+// It panics if the kind is a built-in one or is already registered.
+func RegisterConfigKind(kind ConfigKind) {
+	if kind.Kind <= v1.NONE || kind.Object == nil || kind.Informer == nil || kind.Add == nil || kind.Delete == nil {
+		panic(fmt.Sprintf("crdeventhandler: invalid config kind %s", kind.Kind))
+	}
+	configKindsMu.Lock()
+	defer configKindsMu.Unlock()
+	if _, ok := configKinds[kind.Kind]; ok {
+		panic(fmt.Sprintf("crdeventhandler: config kind %s is already registered", kind.Kind))
+	}
+	configKinds[kind.Kind] = kind
+}
+
+// ConfigKinds returns the registered kinds of config
+func ConfigKinds() []ConfigKind {
+	configKindsMu.RLock()
+	defer configKindsMu.RUnlock()
+	kinds := make([]ConfigKind, 0, len(configKinds))
+	for _, kind := range configKinds {
+		kinds = append(kinds, kind)
+	}
+	return kinds
+}
+
+// configKindOf returns the registered kind of config of the object
+func configKindOf(obj interface{}) (ConfigKind, bool) {
+	configKindsMu.RLock()
+	defer configKindsMu.RUnlock()
+	for _, kind := range configKinds {
+		if reflect.TypeOf(kind.Object) == reflect.TypeOf(obj) {
+			return kind, true
+		}
+	}
+	return ConfigKind{}, false
+}
+
+// registeredConfigKind returns the registered kind of config with the given type
+func registeredConfigKind(t v1.CrdType) (ConfigKind, bool) {
+	configKindsMu.RLock()
+	defer configKindsMu.RUnlock()
+	kind, ok := configKinds[t]
+	return kind, ok
+}
+
+type ConfigAddEventHandler struct {
+	Obj            interface{}
+	Kind           ConfigKind
+	Store          storepolicy.PolicyStore
+	PoliciesClient versioned.Interface
+}
+
+type ConfigDeleteEventHandler struct {
+	Key            string
+	Kind           ConfigKind
+	Store          storepolicy.PolicyStore
+	Recorder       record.EventRecorder
+	PoliciesClient versioned.Interface
+}
+
+func (e *ConfigAddEventHandler) HandleAddUpdateEvent() {
+	key, err := cache.MetaNamespaceKeyFunc(e.Obj)
+	if err != nil {
+		zap.L().Warn("Could not get the key of config", zap.String("kind", e.Kind.Kind.String()), zap.Error(err))
+		return
+	}
+	zap.L().Debug("Create/Update config", zap.String("kind", e.Kind.Kind.String()), zap.String("key", key))
+	e.Store.Update(func(store storepolicy.PolicyStore) {
+		err = e.Kind.Add(e.Obj, key, store)
+	})
+	if err != nil {
+		zap.L().Error("Could not add config", zap.String("kind", e.Kind.Kind.String()), zap.String("key", key), zap.Error(err))
+	}
+	refreshDependentPolicies(e.Store, e.PoliciesClient, policy.Dependency{Kind: e.Kind.Kind, Name: key})
+	zap.L().Info("Config created/updated", zap.String("kind", e.Kind.Kind.String()), zap.String("key", key))
+}
+
+func (e *ConfigDeleteEventHandler) HandleDeleteEvent() {
+	dependency := policy.Dependency{Kind: e.Kind.Kind, Name: e.Key}
+	e.Store.Update(func(store storepolicy.PolicyStore) {
+		e.Kind.Delete(e.Key, store)
+		store.SetAllowedNamespaces(dependency, nil)
+	})
+	warnDependentPolicies(e.Store, e.Recorder, dependency)
+	refreshDependentPolicies(e.Store, e.PoliciesClient, dependency)
+}
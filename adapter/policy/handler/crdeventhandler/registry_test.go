@@ -0,0 +1,121 @@
+package crdeventhandler
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	k8sv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+
+	v1 "github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/pkg/apis/policies/v1"
+	policiesFake "github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/pkg/client/clientset/versioned/fake"
+	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/policy"
+	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/policy/engine"
+	storePolicy "github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/policy/store/policy"
+)
+
+// apiKeyConfig is the config of a policy type provided by a plugin
+type apiKeyConfig struct {
+	metav1.ObjectMeta
+	Keys []string
+}
+
+// A policy type referencing configs of a kind provided by a plugin
+var (
+	apiKeyConfigKind = v1.RegisterCrdType("ApiKeyConfig")
+	apiKeysType      = policy.RegisterType(policy.TypeInfo{Name: "apikeys", ConfigKind: apiKeyConfigKind})
+	_                = registerAPIKeyConfig()
+)
+
+func registerAPIKeyConfig() bool {
+	engine.RegisterConfigAccessor(apiKeysType, func(store storePolicy.PolicyStore, name string, action *engine.Action) bool {
+		action.Resolved = store.GetConfig(policy.Dependency{Kind: apiKeyConfigKind, Name: name})
+		return action.Resolved != nil
+	})
+	RegisterConfigKind(ConfigKind{
+		Kind:   apiKeyConfigKind,
+		Object: &apiKeyConfig{},
+		Informer: func(*rest.Config) (cache.SharedIndexInformer, error) {
+			return nil, errors.New("not watched in tests")
+		},
+		Add: func(obj interface{}, key string, store storePolicy.PolicyStore) error {
+			config := obj.(*apiKeyConfig)
+			if len(config.Keys) == 0 {
+				store.DeleteConfig(policy.Dependency{Kind: apiKeyConfigKind, Name: key})
+				return errors.New("no keys")
+			}
+			store.AddConfig(policy.Dependency{Kind: apiKeyConfigKind, Name: key}, config.Keys)
+			return nil
+		},
+		Delete: func(key string, store storePolicy.PolicyStore) {
+			store.DeleteConfig(policy.Dependency{Kind: apiKeyConfigKind, Name: key})
+		},
+	})
+	return true
+}
+
+func TestRegisterConfigKind(t *testing.T) {
+	kind := ConfigKind{
+		Kind:     apiKeyConfigKind,
+		Object:   &apiKeyConfig{},
+		Informer: func(*rest.Config) (cache.SharedIndexInformer, error) { return nil, nil },
+		Add:      func(interface{}, string, storePolicy.PolicyStore) error { return nil },
+		Delete:   func(string, storePolicy.PolicyStore) {},
+	}
+	// Each kind is registered once
+	assert.Panics(t, func() { RegisterConfigKind(kind) })
+	// Built-in kinds are handled by the built-in handlers
+	kind.Kind = v1.JWTCONFIG
+	assert.Panics(t, func() { RegisterConfigKind(kind) })
+	kind.Kind, kind.Add = v1.NONE+1, nil
+	assert.Panics(t, func() { RegisterConfigKind(kind) })
+
+	kinds := make([]v1.CrdType, 0)
+	for _, k := range ConfigKinds() {
+		kinds = append(kinds, k.Kind)
+	}
+	assert.Equal(t, []v1.CrdType{apiKeyConfigKind}, kinds)
+}
+
+func TestHandler_ConfigKindEventHandlers(t *testing.T) {
+	obj := policyGenerator([]v1.TargetElement{
+		getTargetElements(service, getPathConfigs(getPathConfig("/path", "", "GET", []v1.PathPolicy{{PolicyType: "apikeys", Config: "keys"}}))),
+	})
+	client := policiesFake.NewSimpleClientset(obj)
+	store := storePolicy.New()
+	recorder := record.NewFakeRecorder(10)
+	resolved := func() *v1.Condition {
+		result, err := client.AppidV1().Policies(ns).Get(obj.Name, metav1.GetOptions{})
+		assert.Nil(t, err)
+		return result.Status.GetCondition(v1.ReferencesResolved)
+	}
+	config := &apiKeyConfig{ObjectMeta: metav1.ObjectMeta{Name: "keys", Namespace: ns}, Keys: []string{"key"}}
+	dependency := policy.Dependency{Kind: apiKeyConfigKind, Name: ns + "/keys"}
+
+	// Policy is added before the config it references
+	GetAddEventHandler(obj, store, fake.NewSimpleClientset(), client, recorder).HandleAddUpdateEvent()
+	assert.Equal(t, k8sv1.ConditionFalse, resolved().Status)
+	assert.Equal(t, "ApiKeyConfig ns/keys not found", resolved().Message)
+
+	// Adding the config stores it and resolves the Policy
+	GetAddEventHandler(config, store, fake.NewSimpleClientset(), client, recorder).HandleAddUpdateEvent()
+	assert.Equal(t, []string{"key"}, store.GetConfig(dependency))
+	assert.Equal(t, k8sv1.ConditionTrue, resolved().Status)
+
+	// Configs that cannot be used are removed
+	GetAddEventHandler(&apiKeyConfig{ObjectMeta: config.ObjectMeta}, store, fake.NewSimpleClientset(), client, recorder).HandleAddUpdateEvent()
+	assert.Nil(t, store.GetConfig(dependency))
+	assert.Equal(t, k8sv1.ConditionFalse, resolved().Status)
+
+	// Deleting the config warns the Policy owner
+	GetAddEventHandler(config, store, fake.NewSimpleClientset(), client, recorder).HandleAddUpdateEvent()
+	GetDeleteEventHandler(policy.CrdKey{Id: ns + "/keys", CrdType: apiKeyConfigKind}, store, nil, client, recorder).HandleDeleteEvent()
+	assert.Nil(t, store.GetConfig(dependency))
+	assert.Equal(t, k8sv1.ConditionFalse, resolved().Status)
+	assert.Equal(t, "Warning MissingReference ApiKeyConfig ns/keys was deleted; requests protected by it are denied until it is restored", <-recorder.Events)
+}
@@ -0,0 +1,306 @@
+package crdeventhandler
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+	k8sv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+
+	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/authserver"
+	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/authserver/keyset"
+	v1 "github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/pkg/apis/policies/v1"
+	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/pkg/client/clientset/versioned"
+	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/policy"
+	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/policy/engine"
+	storepolicy "github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/policy/store/policy"
+)
+
+// Condition reasons
+const (
+	reasonAccepted      = "Accepted"
+	reasonInvalidPolicy = "InvalidPolicy"
+	reasonNotReady      = "NotReady"
+	reasonNotAllowed    = "NotAllowed"
+	reasonNotFound      = "NotFound"
+	reasonResolved      = "Resolved"
+	reasonSecretMissing = "SecretNotFound"
+	reasonSynced        = "Synced"
+	reasonSyncFailed    = "SyncFailed"
+)
+
+// newCondition returns a condition that is true if err is nil, using failureReason otherwise
+func newCondition(t v1.ConditionType, err error, successReason string, failureReason string) v1.Condition {
+	if err != nil {
+		return v1.Condition{Type: t, Status: k8sv1.ConditionFalse, Reason: failureReason, Message: err.Error()}
+	}
+	return v1.Condition{Type: t, Status: k8sv1.ConditionTrue, Reason: successReason}
+}
+
+// readyCondition returns a Ready condition that is true if all of the given conditions are true
+func readyCondition(conditions []v1.Condition) v1.Condition {
+	failed := make([]string, 0)
+	for _, c := range conditions {
+		if c.Status != k8sv1.ConditionTrue {
+			failed = append(failed, string(c.Type))
+		}
+	}
+	if len(failed) > 0 {
+		return v1.Condition{Type: v1.Ready, Status: k8sv1.ConditionFalse, Reason: reasonNotReady, Message: fmt.Sprintf("%s not satisfied", strings.Join(failed, ", "))}
+	}
+	return v1.Condition{Type: v1.Ready, Status: k8sv1.ConditionTrue, Reason: reasonAccepted}
+}
+
+// policyConditions returns the conditions of a Policy with the given validation problems, missing references,
+// references to configs that do not allow the namespace of the Policy and references to configs that failed to sync
+func policyConditions(problems []string, missing []policy.Dependency, forbidden []policy.Dependency, unsynced []policy.Dependency) []v1.Condition {
+	unresolved := make([]string, 0, 2)
+	reason := reasonNotFound
+	if len(missing) > 0 {
+		unresolved = append(unresolved, fmt.Sprintf("%s not found", joinDependencies(missing)))
+	} else if len(forbidden) > 0 {
+		reason = reasonNotAllowed
+	}
+	if len(forbidden) > 0 {
+		unresolved = append(unresolved, fmt.Sprintf("%s not allowed to be referenced from this namespace", joinDependencies(forbidden)))
+	}
+	var err error
+	if len(unresolved) > 0 {
+		err = errors.New(strings.Join(unresolved, "; "))
+	}
+	resolved := newCondition(v1.ReferencesResolved, err, reasonResolved, reason)
+	err = nil
+	if len(unsynced) > 0 {
+		err = fmt.Errorf("%s not synced", joinDependencies(unsynced))
+	}
+	synced := newCondition(v1.ReferencesSynced, err, reasonSynced, reasonSyncFailed)
+	if len(problems) > 0 {
+		return []v1.Condition{resolved, synced, {Type: v1.Ready, Status: k8sv1.ConditionFalse, Reason: reasonInvalidPolicy, Message: strings.Join(problems, "; ")}}
+	}
+	return []v1.Condition{resolved, synced, readyCondition([]v1.Condition{resolved, synced})}
+}
+
+// missingDependencies returns the resources referenced by the mappings of a Policy that are not in the store
+func missingDependencies(store storepolicy.PolicyStore, mappings []policy.PolicyMapping) []policy.Dependency {
+	missing := make([]policy.Dependency, 0)
+	for _, dependency := range policy.Dependencies(mappings) {
+		if !dependencyFound(store, dependency) {
+			missing = append(missing, dependency)
+		}
+	}
+	return missing
+}
+
+// dependencyFound returns true if the referenced resource is in the store.
+// Configs are resolved by the accessors of the policy types referencing them, including those of plugins.
+func dependencyFound(store storepolicy.PolicyStore, dependency policy.Dependency) bool {
+	if dependency.Kind == v1.CONFIGMAP {
+		return store.GetRegoPolicy(dependency.Name) != nil
+	}
+	return engine.ConfigFound(store, dependency.Kind, dependency.Name)
+}
+
+// forbiddenDependencies returns the configs in the store referenced by the mappings of a Policy in the given namespace
+// that do not allow references from it
+func forbiddenDependencies(store storepolicy.PolicyStore, namespace string, mappings []policy.PolicyMapping) []policy.Dependency {
+	forbidden := make([]policy.Dependency, 0)
+	for _, dependency := range policy.Dependencies(mappings) {
+		if dependency.Kind != v1.CONFIGMAP && dependencyFound(store, dependency) && !store.IsReferenceAllowed(dependency, namespace) {
+			forbidden = append(forbidden, dependency)
+		}
+	}
+	return forbidden
+}
+
+// unsyncedDependencies returns the JwtConfigs and OidcConfigs in the store referenced by the mappings of a Policy
+// that could not retrieve their discovery document or keys
+func unsyncedDependencies(store storepolicy.PolicyStore, mappings []policy.PolicyMapping) []policy.Dependency {
+	unsynced := make([]policy.Dependency, 0)
+	for _, dependency := range policy.Dependencies(mappings) {
+		if syncError(store, dependency) != nil {
+			unsynced = append(unsynced, dependency)
+		}
+	}
+	return unsynced
+}
+
+// syncError returns the error of the last attempt of a JwtConfig or OidcConfig in the store to retrieve
+// its discovery document or keys
+func syncError(store storepolicy.PolicyStore, dependency policy.Dependency) error {
+	switch dependency.Kind {
+	case v1.JWTCONFIG:
+		if jwks := store.GetKeySet(dependency.Name); jwks != nil {
+			return jwks.SyncError()
+		}
+	case v1.OIDCCONFIG:
+		if c := store.GetClient(dependency.Name); c != nil && c.AuthorizationServer() != nil {
+			server := c.AuthorizationServer()
+			if err := server.SyncError(); err != nil {
+				return err
+			}
+			if jwks := server.KeySet(); jwks != nil {
+				return jwks.SyncError()
+			}
+		}
+	}
+	return nil
+}
+
+// joinDependencies returns a comma separated list of the given resources
+func joinDependencies(dependencies []policy.Dependency) string {
+	names := make([]string, len(dependencies))
+	for i, dependency := range dependencies {
+		names[i] = dependency.String()
+	}
+	return strings.Join(names, ", ")
+}
+
+// refreshDependentPolicies updates the status of the Policies referencing a resource that was added, deleted or synced
+func refreshDependentPolicies(store storepolicy.PolicyStore, client versioned.Interface, dependency policy.Dependency) {
+	for _, key := range store.GetDependentPolicies(dependency) {
+		zap.L().Debug("Re-evaluating dependent policy", zap.String("policy", key), zap.String("resource", dependency.String()))
+		if client == nil {
+			continue
+		}
+		namespace, name := splitKey(key)
+		obj, err := client.AppidV1().Policies(namespace).Get(name, metav1.GetOptions{})
+		if err != nil {
+			zap.L().Warn("Could not get dependent Policy", zap.String("policy", key), zap.Error(err))
+			continue
+		}
+		mappings := store.GetPolicyMapping(key)
+		updatePolicyStatus(client, obj, ValidatePolicy(obj), missingDependencies(store, mappings), forbiddenDependencies(store, namespace, mappings), unsyncedDependencies(store, mappings))
+	}
+}
+
+// refreshJwtConfigStatus updates the status of a JwtConfig after its key set synced again, and of the Policies referencing it.
+// Key sets replaced by an update of the JwtConfig are ignored.
+func refreshJwtConfigStatus(store storepolicy.PolicyStore, client versioned.Interface, namespace string, name string, jwks keyset.KeySet) {
+	key := namespace + "/" + name
+	if store.GetKeySet(key) != jwks {
+		return
+	}
+	if client != nil {
+		obj, err := client.AppidV1().JwtConfigs(namespace).Get(name, metav1.GetOptions{})
+		if err != nil {
+			zap.L().Warn("Could not get synced JwtConfig", zap.String("config", key), zap.Error(err))
+		} else {
+			updateJwtConfigStatus(client, obj, newCondition(v1.KeysSynced, jwks.SyncError(), reasonSynced, reasonSyncFailed))
+		}
+	}
+	refreshDependentPolicies(store, client, policy.Dependency{Kind: v1.JWTCONFIG, Name: key})
+}
+
+// refreshOidcConfigStatus updates the status of an OidcConfig after its discovery document or key set synced again,
+// and of the Policies referencing it. Servers replaced by an update of the OidcConfig are ignored.
+func refreshOidcConfigStatus(store storepolicy.PolicyStore, client versioned.Interface, namespace string, name string, server authserver.AuthorizationServerService, jwks keyset.KeySet) {
+	key := namespace + "/" + name
+	if c := store.GetClient(key); c == nil || c.AuthorizationServer() != server {
+		return
+	}
+	if client != nil {
+		obj, err := client.AppidV1().OidcConfigs(namespace).Get(name, metav1.GetOptions{})
+		if err != nil {
+			zap.L().Warn("Could not get synced OidcConfig", zap.String("config", key), zap.Error(err))
+		} else {
+			conditions := []v1.Condition{
+				newCondition(v1.DiscoverySynced, server.SyncError(), reasonSynced, reasonSyncFailed),
+				newCondition(v1.KeysSynced, jwks.SyncError(), reasonSynced, reasonSyncFailed),
+			}
+			// The client secret is resolved when the OidcConfig or its Secret changes
+			if secret := obj.Status.GetCondition(v1.SecretResolved); secret != nil {
+				conditions = append(conditions, *secret)
+			}
+			updateOidcConfigStatus(client, obj, conditions...)
+		}
+	}
+	refreshDependentPolicies(store, client, policy.Dependency{Kind: v1.OIDCCONFIG, Name: key})
+}
+
+// warnDependentPolicies records an event on the Policies referencing a resource that was deleted
+func warnDependentPolicies(store storepolicy.PolicyStore, recorder record.EventRecorder, dependency policy.Dependency) {
+	dependents := store.GetDependentPolicies(dependency)
+	if len(dependents) == 0 {
+		return
+	}
+	zap.L().Warn("Deleted resource is referenced by policies", zap.String("resource", dependency.String()), zap.Strings("policies", dependents))
+	if recorder == nil {
+		return
+	}
+	message := fmt.Sprintf("%s was deleted; requests protected by it are denied until it is restored", dependency)
+	for _, key := range dependents {
+		recorder.Event(policyReference(key), k8sv1.EventTypeWarning, MissingReferenceReason, message)
+	}
+}
+
+// newStatus returns a copy of the current status updated with the given conditions.
+// The second return value is false if the status did not change.
+func newStatus(current v1.Status, generation int64, conditions []v1.Condition) (v1.Status, bool) {
+	status := current.DeepCopy()
+	status.ObservedGeneration = generation
+	for _, c := range conditions {
+		status.SetCondition(c)
+	}
+	return *status, !equality.Semantic.DeepEqual(current, *status)
+}
+
+// updateJwtConfigStatus writes the conditions of a JwtConfig through the status subresource
+func updateJwtConfigStatus(client versioned.Interface, obj *v1.JwtConfig, conditions ...v1.Condition) {
+	status, changed := newStatus(obj.Status, obj.Generation, append(conditions, readyCondition(conditions)))
+	if client == nil || !changed {
+		return
+	}
+	updated := obj.DeepCopy()
+	updated.Status = status
+	if _, err := client.AppidV1().JwtConfigs(obj.Namespace).UpdateStatus(updated); err != nil {
+		zap.L().Warn("Could not update JwtConfig status", zap.String("name", obj.Name), zap.String("namespace", obj.Namespace), zap.Error(err))
+	}
+}
+
+// updateOidcConfigStatus writes the conditions of an OidcConfig through the status subresource
+func updateOidcConfigStatus(client versioned.Interface, obj *v1.OidcConfig, conditions ...v1.Condition) {
+	status, changed := newStatus(obj.Status, obj.Generation, append(conditions, readyCondition(conditions)))
+	if client == nil || !changed {
+		return
+	}
+	updated := obj.DeepCopy()
+	updated.Status = status
+	if _, err := client.AppidV1().OidcConfigs(obj.Namespace).UpdateStatus(updated); err != nil {
+		zap.L().Warn("Could not update OidcConfig status", zap.String("name", obj.Name), zap.String("namespace", obj.Namespace), zap.Error(err))
+	}
+}
+
+// updatePolicyStatus writes the conditions of a Policy through the status subresource
+func updatePolicyStatus(client versioned.Interface, obj *v1.Policy, problems []string, missing []policy.Dependency, forbidden []policy.Dependency, unsynced []policy.Dependency) {
+	status, changed := newStatus(obj.Status, obj.Generation, policyConditions(problems, missing, forbidden, unsynced))
+	if client == nil || !changed {
+		return
+	}
+	updated := obj.DeepCopy()
+	updated.Status = status
+	if _, err := client.AppidV1().Policies(obj.Namespace).UpdateStatus(updated); err != nil {
+		zap.L().Warn("Could not update Policy status", zap.String("name", obj.Name), zap.String("namespace", obj.Namespace), zap.Error(err))
+	}
+}
+
+// updateClusterPolicyStatus writes the conditions of a ClusterPolicy through the status subresource.
+// The references of a ClusterPolicy are resolved in each selected namespace and are not reported.
+func updateClusterPolicyStatus(client versioned.Interface, obj *v1.ClusterPolicy, problems []string) {
+	ready := v1.Condition{Type: v1.Ready, Status: k8sv1.ConditionTrue, Reason: reasonAccepted}
+	if len(problems) > 0 {
+		ready = v1.Condition{Type: v1.Ready, Status: k8sv1.ConditionFalse, Reason: reasonInvalidPolicy, Message: strings.Join(problems, "; ")}
+	}
+	status, changed := newStatus(obj.Status, obj.Generation, []v1.Condition{ready})
+	if client == nil || !changed {
+		return
+	}
+	updated := obj.DeepCopy()
+	updated.Status = status
+	if _, err := client.AppidV1().ClusterPolicies().UpdateStatus(updated); err != nil {
+		zap.L().Warn("Could not update ClusterPolicy status", zap.String("name", obj.Name), zap.Error(err))
+	}
+}
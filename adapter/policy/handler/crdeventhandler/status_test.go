@@ -0,0 +1,277 @@
+package crdeventhandler
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	k8sv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+
+	v1 "github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/pkg/apis/policies/v1"
+	policiesFake "github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/pkg/client/clientset/versioned/fake"
+	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/policy"
+	storePolicy "github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/policy/store/policy"
+	fakes "github.com/ibm-cloud-security/app-identity-and-access-adapter/tests/fake"
+)
+
+func TestNewStatus(t *testing.T) {
+	synced := newCondition(v1.KeysSynced, nil, reasonSynced, reasonSyncFailed)
+	failed := newCondition(v1.KeysSynced, errors.New("timeout"), reasonSynced, reasonSyncFailed)
+	assert.Equal(t, k8sv1.ConditionTrue, synced.Status)
+	assert.Equal(t, v1.Condition{Type: v1.KeysSynced, Status: k8sv1.ConditionFalse, Reason: reasonSyncFailed, Message: "timeout"}, failed)
+
+	status, changed := newStatus(v1.Status{}, 2, []v1.Condition{synced})
+	assert.True(t, changed)
+	assert.Equal(t, int64(2), status.ObservedGeneration)
+	transition := status.GetCondition(v1.KeysSynced).LastTransitionTime
+	assert.False(t, transition.IsZero())
+
+	// Unchanged conditions keep their transition time
+	unchanged, changed := newStatus(status, 2, []v1.Condition{synced})
+	assert.False(t, changed)
+	assert.Equal(t, status, unchanged)
+
+	// A new generation is recorded even if the conditions did not change
+	_, changed = newStatus(status, 3, []v1.Condition{synced})
+	assert.True(t, changed)
+
+	// Changed conditions are replaced
+	status.Conditions[0].LastTransitionTime = metav1.NewTime(transition.Add(-1))
+	updated, changed := newStatus(status, 2, []v1.Condition{failed})
+	assert.True(t, changed)
+	assert.Equal(t, 1, len(updated.Conditions))
+	assert.Equal(t, k8sv1.ConditionFalse, updated.Conditions[0].Status)
+	assert.NotEqual(t, status.Conditions[0].LastTransitionTime, updated.Conditions[0].LastTransitionTime)
+	assert.Nil(t, updated.GetCondition(v1.Ready))
+}
+
+func TestReadyCondition(t *testing.T) {
+	assert.Equal(t, v1.Condition{Type: v1.Ready, Status: k8sv1.ConditionTrue, Reason: reasonAccepted}, readyCondition(nil))
+	ready := readyCondition([]v1.Condition{
+		newCondition(v1.DiscoverySynced, errors.New("not found"), reasonSynced, reasonSyncFailed),
+		newCondition(v1.KeysSynced, nil, reasonSynced, reasonSyncFailed),
+		newCondition(v1.SecretResolved, errors.New("missing"), reasonResolved, reasonSecretMissing),
+	})
+	assert.Equal(t, v1.Condition{Type: v1.Ready, Status: k8sv1.ConditionFalse, Reason: reasonNotReady, Message: "DiscoverySynced, SecretResolved not satisfied"}, ready)
+}
+
+func TestHandler_JwtConfigStatus(t *testing.T) {
+	obj := jwtConfigGenerator()
+	obj.Generation = 1
+	client := policiesFake.NewSimpleClientset(obj)
+	GetAddEventHandler(obj, storePolicy.New(), fake.NewSimpleClientset(), client, nil).HandleAddUpdateEvent()
+
+	result, err := client.AppidV1().JwtConfigs(ns).Get(obj.Name, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), result.Status.ObservedGeneration)
+	assert.Equal(t, k8sv1.ConditionFalse, result.Status.GetCondition(v1.KeysSynced).Status)
+	assert.Equal(t, k8sv1.ConditionFalse, result.Status.GetCondition(v1.Ready).Status)
+}
+
+func TestHandler_OidcConfigStatus(t *testing.T) {
+	obj := oidcConfigWithRef("oidc", "id", "url", v1.ClientSecretRef{Name: "missing", Key: "key"})
+	client := policiesFake.NewSimpleClientset(obj)
+	GetAddEventHandler(obj, storePolicy.New(), fake.NewSimpleClientset(), client, nil).HandleAddUpdateEvent()
+
+	result, err := client.AppidV1().OidcConfigs(ns).Get(obj.Name, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, k8sv1.ConditionFalse, result.Status.GetCondition(v1.DiscoverySynced).Status)
+	assert.Equal(t, k8sv1.ConditionFalse, result.Status.GetCondition(v1.KeysSynced).Status)
+	assert.Equal(t, reasonSecretMissing, result.Status.GetCondition(v1.SecretResolved).Reason)
+	assert.Equal(t, k8sv1.ConditionFalse, result.Status.GetCondition(v1.Ready).Status)
+}
+
+func TestHandler_PolicyStatus(t *testing.T) {
+	tests := []struct {
+		name     string
+		policies []v1.PathPolicy
+		mode     string
+		status   k8sv1.ConditionStatus
+		message  string
+	}{
+		{
+			name:     "valid",
+			policies: []v1.PathPolicy{{PolicyType: "oidc", Config: "sampleoidc"}},
+			status:   k8sv1.ConditionTrue,
+		},
+		{
+			name:     "missing config",
+			policies: []v1.PathPolicy{{PolicyType: "jwt", Config: "missing"}},
+			status:   k8sv1.ConditionFalse,
+			message:  "ReferencesResolved not satisfied",
+		},
+		{
+			name:     "unknown policy type",
+			policies: []v1.PathPolicy{{PolicyType: "saml", Config: "config"}},
+			status:   k8sv1.ConditionFalse,
+			message:  `/path: unknown policyType "saml"`,
+		},
+		{
+			name:     "unknown mode and missing rego module",
+			policies: []v1.PathPolicy{{PolicyType: "opa", Config: "config"}},
+			mode:     "some",
+			status:   k8sv1.ConditionFalse,
+			message:  `/path: policy of type opa does not reference a Rego module; unknown mode "some"`,
+		},
+	}
+
+	for _, test := range tests {
+		obj := policyGenerator([]v1.TargetElement{
+			getTargetElements(service, getPathConfigs(getPathConfig("/path", "", "GET", test.policies))),
+		})
+		obj.Spec.Mode = test.mode
+		client := policiesFake.NewSimpleClientset(obj)
+		store := storePolicy.New()
+		store.AddClient(ns+"/sampleoidc", fakes.NewClient(nil))
+		GetAddEventHandler(obj, store, fake.NewSimpleClientset(), client, nil).HandleAddUpdateEvent()
+
+		result, err := client.AppidV1().Policies(ns).Get(obj.Name, metav1.GetOptions{})
+		assert.Nil(t, err, test.name)
+		ready := result.Status.GetCondition(v1.Ready)
+		assert.Equal(t, test.status, ready.Status, test.name)
+		assert.Equal(t, test.message, ready.Message, test.name)
+	}
+}
+
+func TestPolicyConditions(t *testing.T) {
+	missing := []policy.Dependency{{Kind: v1.JWTCONFIG, Name: "ns/jwt"}, {Kind: v1.CONFIGMAP, Name: "ns/rego"}}
+	conditions := policyConditions([]string{"invalid"}, missing, nil, nil)
+	assert.Equal(t, v1.Condition{Type: v1.ReferencesResolved, Status: k8sv1.ConditionFalse, Reason: reasonNotFound, Message: "JwtConfig ns/jwt, ConfigMap ns/rego not found"}, conditions[0])
+	assert.Equal(t, v1.Condition{Type: v1.Ready, Status: k8sv1.ConditionFalse, Reason: reasonInvalidPolicy, Message: "invalid"}, conditions[2])
+
+	forbidden := []policy.Dependency{{Kind: v1.OIDCCONFIG, Name: "shared/oidc"}}
+	conditions = policyConditions(nil, nil, forbidden, nil)
+	assert.Equal(t, v1.Condition{Type: v1.ReferencesResolved, Status: k8sv1.ConditionFalse, Reason: reasonNotAllowed, Message: "OidcConfig shared/oidc not allowed to be referenced from this namespace"}, conditions[0])
+	assert.Equal(t, k8sv1.ConditionFalse, conditions[2].Status)
+
+	conditions = policyConditions(nil, missing[:1], forbidden, nil)
+	assert.Equal(t, reasonNotFound, conditions[0].Reason)
+	assert.Equal(t, "JwtConfig ns/jwt not found; OidcConfig shared/oidc not allowed to be referenced from this namespace", conditions[0].Message)
+
+	conditions = policyConditions(nil, nil, nil, forbidden)
+	assert.Equal(t, k8sv1.ConditionTrue, conditions[0].Status)
+	assert.Equal(t, v1.Condition{Type: v1.ReferencesSynced, Status: k8sv1.ConditionFalse, Reason: reasonSyncFailed, Message: "OidcConfig shared/oidc not synced"}, conditions[1])
+	assert.Equal(t, v1.Condition{Type: v1.Ready, Status: k8sv1.ConditionFalse, Reason: reasonNotReady, Message: "ReferencesSynced not satisfied"}, conditions[2])
+
+	conditions = policyConditions(nil, nil, nil, nil)
+	assert.Equal(t, k8sv1.ConditionTrue, conditions[0].Status)
+	assert.Equal(t, k8sv1.ConditionTrue, conditions[1].Status)
+	assert.Equal(t, k8sv1.ConditionTrue, conditions[2].Status)
+}
+
+func TestHandler_SyncedConfigStatus(t *testing.T) {
+	obj := policyGenerator([]v1.TargetElement{
+		getTargetElements(service, getPathConfigs(getPathConfig("/path", "", "GET", []v1.PathPolicy{{PolicyType: "jwt", Config: "sample"}, {PolicyType: "oidc", Config: "oidc"}}))),
+	})
+	jwtConfig := jwtConfigGenerator()
+	oidcConfig := oidcConfigWithRef("oidc", "id", "url", v1.ClientSecretRef{})
+	oidcConfig.Name = "oidc"
+	client := policiesFake.NewSimpleClientset(obj, jwtConfig, oidcConfig)
+	jwks := &fakes.KeySet{Err: errors.New("timeout")}
+	oidcClient := fakes.NewClient(nil)
+	server := oidcClient.Server.(*fakes.AuthServer)
+	server.Err = errors.New("not found")
+	store := storePolicy.New()
+	store.AddKeySet(ns+"/sample", jwks)
+	store.AddClient(ns+"/oidc", oidcClient)
+	GetAddEventHandler(obj, store, fake.NewSimpleClientset(), client, nil).HandleAddUpdateEvent()
+	synced := func() *v1.Condition {
+		result, err := client.AppidV1().Policies(ns).Get(obj.Name, metav1.GetOptions{})
+		assert.Nil(t, err)
+		return result.Status.GetCondition(v1.ReferencesSynced)
+	}
+	assert.Equal(t, "JwtConfig ns/sample, OidcConfig ns/oidc not synced", synced().Message)
+
+	// Syncing the keys of the JwtConfig updates its status and the Policy
+	jwks.Err = nil
+	refreshJwtConfigStatus(store, client, ns, jwtConfig.Name, jwks)
+	result, err := client.AppidV1().JwtConfigs(ns).Get(jwtConfig.Name, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, k8sv1.ConditionTrue, result.Status.GetCondition(v1.KeysSynced).Status)
+	assert.Equal(t, "OidcConfig ns/oidc not synced", synced().Message)
+
+	// Syncing the discovery document of the OidcConfig keeps its resolved secret
+	updateOidcConfigStatus(client, oidcConfig, newCondition(v1.SecretResolved, errors.New("missing"), reasonResolved, reasonSecretMissing))
+	server.Err = nil
+	refreshOidcConfigStatus(store, client, ns, oidcConfig.Name, server, server.Keys)
+	oidcResult, err := client.AppidV1().OidcConfigs(ns).Get(oidcConfig.Name, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, k8sv1.ConditionTrue, oidcResult.Status.GetCondition(v1.DiscoverySynced).Status)
+	assert.Equal(t, k8sv1.ConditionFalse, oidcResult.Status.GetCondition(v1.SecretResolved).Status)
+	assert.Equal(t, k8sv1.ConditionFalse, oidcResult.Status.GetCondition(v1.Ready).Status)
+	assert.Equal(t, k8sv1.ConditionTrue, synced().Status)
+
+	// Key sets replaced by an update of the JwtConfig are ignored
+	jwks.Err = errors.New("timeout")
+	store.AddKeySet(ns+"/sample", &fakes.KeySet{})
+	refreshJwtConfigStatus(store, client, ns, jwtConfig.Name, jwks)
+	assert.Equal(t, k8sv1.ConditionTrue, synced().Status)
+}
+
+func TestHandler_CrossNamespacePolicy(t *testing.T) {
+	obj := policyGenerator([]v1.TargetElement{
+		getTargetElements(service, getPathConfigs(getPathConfig("/path", "", "GET", []v1.PathPolicy{{PolicyType: "jwt", Config: "shared/sample"}}))),
+	})
+	config := jwtConfigGenerator()
+	config.Namespace = "shared"
+	client := policiesFake.NewSimpleClientset(obj)
+	store := storePolicy.New()
+	resolved := func() *v1.Condition {
+		result, err := client.AppidV1().Policies(ns).Get(obj.Name, metav1.GetOptions{})
+		assert.Nil(t, err)
+		return result.Status.GetCondition(v1.ReferencesResolved)
+	}
+
+	// Config does not opt in
+	GetAddEventHandler(config, store, fake.NewSimpleClientset(), client, nil).HandleAddUpdateEvent()
+	GetAddEventHandler(obj, store, fake.NewSimpleClientset(), client, nil).HandleAddUpdateEvent()
+	assert.Equal(t, reasonNotAllowed, resolved().Reason)
+	assert.Equal(t, "JwtConfig shared/sample not allowed to be referenced from this namespace", resolved().Message)
+
+	// Allowing the namespace resolves the Policy
+	config.Spec.AllowedNamespaces = []string{ns}
+	GetAddEventHandler(config, store, fake.NewSimpleClientset(), client, nil).HandleAddUpdateEvent()
+	assert.Equal(t, k8sv1.ConditionTrue, resolved().Status)
+	assert.True(t, store.IsReferenceAllowed(policy.Dependency{Kind: v1.JWTCONFIG, Name: "shared/sample"}, ns))
+
+	// Deleting the config removes the permission
+	GetDeleteEventHandler(policy.CrdKey{Id: "shared/sample", CrdType: v1.JWTCONFIG}, store, nil, client, nil).HandleDeleteEvent()
+	assert.Equal(t, reasonNotFound, resolved().Reason)
+	assert.False(t, store.IsReferenceAllowed(policy.Dependency{Kind: v1.JWTCONFIG, Name: "shared/sample"}, ns))
+}
+
+func TestHandler_DependentPolicies(t *testing.T) {
+	obj := policyGenerator([]v1.TargetElement{
+		getTargetElements(service, getPathConfigs(getPathConfig("/path", "", "GET", []v1.PathPolicy{{PolicyType: "jwt", Config: "sample"}}))),
+	})
+	client := policiesFake.NewSimpleClientset(obj)
+	store := storePolicy.New()
+	recorder := record.NewFakeRecorder(10)
+	resolved := func() *v1.Condition {
+		result, err := client.AppidV1().Policies(ns).Get(obj.Name, metav1.GetOptions{})
+		assert.Nil(t, err)
+		return result.Status.GetCondition(v1.ReferencesResolved)
+	}
+
+	// Policy is added before the config it references
+	GetAddEventHandler(obj, store, fake.NewSimpleClientset(), client, recorder).HandleAddUpdateEvent()
+	assert.Equal(t, k8sv1.ConditionFalse, resolved().Status)
+	assert.Equal(t, "JwtConfig ns/sample not found", resolved().Message)
+	assert.Equal(t, []string{"ns/sample"}, store.GetDependentPolicies(policy.Dependency{Kind: v1.JWTCONFIG, Name: "ns/sample"}))
+
+	// Adding the config resolves the Policy
+	GetAddEventHandler(jwtConfigGenerator(), store, fake.NewSimpleClientset(), client, recorder).HandleAddUpdateEvent()
+	assert.Equal(t, k8sv1.ConditionTrue, resolved().Status)
+
+	// Deleting the config warns the Policy owner
+	GetDeleteEventHandler(policy.CrdKey{Id: "ns/sample", CrdType: v1.JWTCONFIG}, store, nil, client, recorder).HandleDeleteEvent()
+	assert.Equal(t, k8sv1.ConditionFalse, resolved().Status)
+	assert.Equal(t, "Warning MissingReference JwtConfig ns/sample was deleted; requests protected by it are denied until it is restored", <-recorder.Events)
+
+	// Deleted Policies are no longer re-evaluated
+	GetDeleteEventHandler(policy.CrdKey{Id: "ns/sample", CrdType: v1.POLICY}, store, nil, client, recorder).HandleDeleteEvent()
+	assert.Empty(t, store.GetDependentPolicies(policy.Dependency{Kind: v1.JWTCONFIG, Name: "ns/sample"}))
+}
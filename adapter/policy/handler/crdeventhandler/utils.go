@@ -23,6 +23,38 @@ func getParsedPolicy(service policy.Service, method policy.Method, path string,
 	return policy.NewPolicyMapping(getEndpoint(service, method, path), policies)
 }
 
+// withConditions restricts a parsed policy to requests matching the conditions
+func withConditions(mapping policy.PolicyMapping, conditions policy.Conditions) policy.PolicyMapping {
+	mapping.Conditions = conditions
+	return mapping
+}
+
+// pathMethods returns the distinct methods of a path, or ALL if none is set.
+// A path with an unknown method protects ALL methods, so that it is not left unprotected;
+// the method is reported by validatePolicy and requests to the path are denied until it is fixed.
+func pathMethods(path v1.PathConfig) []policy.Method {
+	methods := make([]policy.Method, 0, len(path.Methods)+1)
+	seen := make(map[policy.Method]struct{})
+	names := path.Methods
+	if path.Method != "" {
+		names = append([]string{path.Method}, names...)
+	}
+	for _, name := range names {
+		method, err := policy.ParseMethod(name)
+		if err != nil {
+			return []policy.Method{policy.ALL}
+		}
+		if _, ok := seen[method]; !ok {
+			seen[method] = struct{}{}
+			methods = append(methods, method)
+		}
+	}
+	if len(names) == 0 {
+		methods = append(methods, policy.ALL)
+	}
+	return methods
+}
+
 func ParseTarget(target []v1.TargetElement, namespace string) []policy.PolicyMapping {
 	targets := make([]policy.PolicyMapping, 0)
 	if len(target) > 0 {
@@ -33,12 +65,9 @@ func ParseTarget(target []v1.TargetElement, namespace string) []policy.PolicyMap
 			}
 			if items.Paths != nil && len(items.Paths) > 0 {
 				for _, path := range items.Paths {
-					method := policy.NewMethod(path.Method)
-					if path.Exact != "" {
-						if path.Exact != "/" {
-							path.Exact = strings.TrimRight(path.Exact, "/")
-						}
-						targets = append(targets, getParsedPolicy(service, method, path.Exact, path.Policies))
+					conditions := policy.NewConditions(items.Hosts, path.Schemes, path.Headers)
+					if path.Exact != "" && path.Exact != "/" {
+						path.Exact = strings.TrimRight(path.Exact, "/")
 					}
 
 					if path.Prefix != "" {
@@ -49,11 +78,26 @@ func ParseTarget(target []v1.TargetElement, namespace string) []policy.PolicyMap
 								path.Prefix = path.Prefix + "/*"
 							}
 						}
-						targets = append(targets, getParsedPolicy(service, method, path.Prefix, path.Policies))
 					}
 
-					if path.Exact == "" && path.Prefix == "" {
-						targets = append(targets, getParsedPolicy(service, method, "/*", path.Policies))
+					for _, method := range pathMethods(path) {
+						if path.Exact != "" {
+							targets = append(targets, withConditions(getParsedPolicy(service, method, path.Exact, path.Policies), conditions))
+						}
+
+						if path.Prefix != "" {
+							targets = append(targets, withConditions(getParsedPolicy(service, method, path.Prefix, path.Policies), conditions))
+						}
+
+						if path.Regex != "" {
+							mapping := getParsedPolicy(service, method, path.Regex, path.Policies)
+							mapping.Endpoint.Regex = true
+							targets = append(targets, withConditions(mapping, conditions))
+						}
+
+						if path.Exact == "" && path.Prefix == "" && path.Regex == "" {
+							targets = append(targets, withConditions(getParsedPolicy(service, method, "/*", path.Policies), conditions))
+						}
 					}
 				}
 			}
@@ -64,4 +108,19 @@ func ParseTarget(target []v1.TargetElement, namespace string) []policy.PolicyMap
 
 func GetKubeSecret(kubeClient kubernetes.Interface, namespace string, ref v1.ClientSecretRef) (*k8sv1.Secret, error) {
 	return kubeClient.CoreV1().Secrets(namespace).Get(ref.Name, metav1.GetOptions{})
+}
+
+// policyReference returns a reference to the Policy identified by namespace/name
+func policyReference(key string) *k8sv1.ObjectReference {
+	namespace, name := splitKey(key)
+	return &k8sv1.ObjectReference{Kind: v1.POLICY.String(), APIVersion: v1.SchemeGroupVersion.String(), Namespace: namespace, Name: name}
+}
+
+// splitKey returns the namespace and name of a namespace/name key
+func splitKey(key string) (string, string) {
+	parts := strings.SplitN(key, "/", 2)
+	if len(parts) == 2 {
+		return parts[0], parts[1]
+	}
+	return "", key
 }
\ No newline at end of file
@@ -113,6 +113,106 @@ func TestParsedTarget(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "request conditions",
+			targets: []v1.TargetElement{
+				{
+					ServiceName: service,
+					Hosts:       []string{"API.example.com", "*.example.org"},
+					Paths: []v1.PathConfig{{
+						Exact:    "/path",
+						Schemes:  []string{"HTTPS"},
+						Headers:  []v1.HeaderMatch{{Name: "X-Tenant", Value: "a"}},
+						Policies: getDefaultPathPolicy(),
+					}},
+				},
+			},
+			output: output{
+				total: 1,
+				policies: []policy.PolicyMapping{
+					{
+						Endpoint: getEndpoint(getDefaultService(), policy.ALL, "/path"),
+						Actions:  getDefaultPathPolicy(),
+						Conditions: policy.Conditions{
+							Hosts:   []string{"*.example.org", "api.example.com"},
+							Schemes: []string{"https"},
+							Headers: []v1.HeaderMatch{{Name: "x-tenant", Value: "a"}},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "method list",
+			targets: []v1.TargetElement{
+				{
+					ServiceName: service,
+					Paths: []v1.PathConfig{
+						{Exact: "/path", Method: "GET", Methods: []string{"HEAD", "GET"}, Policies: getDefaultPathPolicy()},
+					},
+				},
+			},
+			output: output{
+				total: 2,
+				policies: []policy.PolicyMapping{
+					{
+						Endpoint: getEndpoint(getDefaultService(), policy.GET, "/path"),
+						Actions:  getDefaultPathPolicy(),
+					},
+					{
+						Endpoint: getEndpoint(getDefaultService(), policy.HEAD, "/path"),
+						Actions:  getDefaultPathPolicy(),
+					},
+				},
+			},
+		},
+		{
+			name: "unknown methods",
+			targets: []v1.TargetElement{
+				{
+					ServiceName: service,
+					Paths: []v1.PathConfig{
+						{Exact: "/path", Method: "GET", Methods: []string{"HEAD", "FETCH"}, Policies: getDefaultPathPolicy()},
+						{Exact: "/other", Method: "get", Policies: getDefaultPathPolicy()},
+					},
+				},
+			},
+			output: output{
+				total: 2,
+				policies: []policy.PolicyMapping{
+					{
+						Endpoint: getEndpoint(getDefaultService(), policy.ALL, "/path"),
+						Actions:  getDefaultPathPolicy(),
+					},
+					{
+						Endpoint: getEndpoint(getDefaultService(), policy.ALL, "/other"),
+						Actions:  getDefaultPathPolicy(),
+					},
+				},
+			},
+		},
+		{
+			name: "regex path",
+			targets: []v1.TargetElement{
+				{
+					ServiceName: service,
+					Paths: []v1.PathConfig{{
+						Regex:    `/users/[0-9]+`,
+						Method:   "GET",
+						Policies: getDefaultPathPolicy(),
+					}},
+				},
+			},
+			output: output{
+				total: 1,
+				policies: []policy.PolicyMapping{
+					{
+						Endpoint: policy.Endpoint{Service: getDefaultService(), Path: `/users/[0-9]+`, Method: policy.GET, Regex: true},
+						Actions:  getDefaultPathPolicy(),
+					},
+				},
+			},
+		},
 	}
 
 	for _, test := range tests {
@@ -0,0 +1,257 @@
+package crdeventhandler
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/expression"
+	v1 "github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/pkg/apis/policies/v1"
+	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/pkg/client/clientset/versioned"
+	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/policy"
+	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/validator"
+)
+
+// ValidatePolicy returns the problems found in the spec of a Policy
+func ValidatePolicy(obj *v1.Policy) []string {
+	problems, _ := validatePolicy(obj)
+	return problems
+}
+
+// validatePolicy returns the problems found in the spec of a Policy and its compiled expressions.
+// Expressions that fail to compile are stored as nil, causing requests to be denied.
+func validatePolicy(obj *v1.Policy) ([]string, map[string]expression.Program) {
+	problems := make([]string, 0)
+	for _, target := range obj.Spec.Target {
+		for _, host := range target.Hosts {
+			if !validHost(host) {
+				problems = append(problems, fmt.Sprintf("%s: invalid host %q, wildcards are only allowed as a leading *.", target.ServiceName, host))
+			}
+		}
+		for _, path := range target.Paths {
+			methods := path.Methods
+			if path.Method != "" {
+				methods = append([]string{path.Method}, methods...)
+			}
+			for _, method := range methods {
+				if _, err := policy.ParseMethod(method); err != nil {
+					problems = append(problems, fmt.Sprintf("%s: %v", target.ServiceName, err))
+				}
+			}
+			for _, pattern := range []string{path.Exact, path.Prefix} {
+				if !validPathPattern(pattern) {
+					problems = append(problems, fmt.Sprintf("%s: invalid path %q, * and {param} must span a whole segment", target.ServiceName, pattern))
+				}
+			}
+			if path.Regex != "" {
+				if _, err := regexp.Compile(path.Regex); err != nil {
+					problems = append(problems, fmt.Sprintf("%s: invalid regex %q: %v", target.ServiceName, path.Regex, err))
+				}
+			}
+			for _, scheme := range path.Schemes {
+				if s := strings.ToLower(scheme); s != "http" && s != "https" {
+					problems = append(problems, fmt.Sprintf("%s: unknown scheme %q", target.ServiceName, scheme))
+				}
+			}
+			if err := validator.ValidateHeaderMatches(path.Headers); err != nil {
+				problems = append(problems, fmt.Sprintf("%s: %v", target.ServiceName, err))
+			}
+		}
+	}
+	expressions := make(map[string]expression.Program)
+	for _, policies := range ParseTarget(obj.Spec.Target, obj.Namespace) {
+		for _, action := range policies.Actions {
+			if policy.NewType(action.PolicyType) == policy.NONE {
+				problems = append(problems, fmt.Sprintf("%s: unknown policyType %q", policies.Endpoint.Path, action.PolicyType))
+			}
+			if err := validator.ValidateRules(action.Rules); err != nil {
+				problems = append(problems, fmt.Sprintf("%s: %v", policies.Endpoint.Path, err))
+			}
+			if err := validator.ValidateHeaders(action.Headers); err != nil {
+				problems = append(problems, fmt.Sprintf("%s: %v", policies.Endpoint.Path, err))
+			}
+			if parts := strings.Split(action.Config, "/"); len(parts) > 2 || (len(parts) == 2 && (parts[0] == "" || parts[1] == "")) {
+				problems = append(problems, fmt.Sprintf("%s: invalid config reference %q, expected name or namespace/name", policies.Endpoint.Path, action.Config))
+			}
+			switch t := policy.NewType(action.PolicyType); {
+			case t == policy.ALLOW, t == policy.DENY:
+				if action.Config != "" || len(action.Rules) > 0 || action.Expression != "" || len(action.Headers) > 0 {
+					problems = append(problems, fmt.Sprintf("%s: policy of type %s does not take a config, rules, expression or headers", policies.Endpoint.Path, action.PolicyType))
+				}
+			case t.ConfigKind() != v1.NONE:
+				if action.Config == "" {
+					problems = append(problems, fmt.Sprintf("%s: policy of type %s does not reference a config", policies.Endpoint.Path, action.PolicyType))
+				}
+			}
+			if action.Status != 0 && policy.NewType(action.PolicyType) != policy.DENY {
+				problems = append(problems, fmt.Sprintf("%s: status is only used by policies of type deny", policies.Endpoint.Path))
+			} else if action.Status != 0 && (action.Status < 400 || action.Status > 599) {
+				problems = append(problems, fmt.Sprintf("%s: invalid status %d, expected a 4xx or 5xx code", policies.Endpoint.Path, action.Status))
+			}
+			if policy.NewType(action.PolicyType) == policy.OPA && action.RegoModule == "" {
+				problems = append(problems, fmt.Sprintf("%s: policy of type opa does not reference a Rego module", policies.Endpoint.Path))
+			}
+			if _, ok := expressions[action.Expression]; action.Expression == "" || ok {
+				continue
+			}
+			program, err := expression.Compile(action.Expression)
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("%s: invalid expression: %v", policies.Endpoint.Path, err))
+			}
+			expressions[action.Expression] = program
+		}
+	}
+	if mode := policy.NewMode(obj.Spec.Mode); mode.String() != obj.Spec.Mode && obj.Spec.Mode != "" {
+		problems = append(problems, fmt.Sprintf("unknown mode %q", obj.Spec.Mode))
+	}
+	return problems, expressions
+}
+
+// ValidateClusterPolicy returns the problems found in the spec of a ClusterPolicy
+func ValidateClusterPolicy(obj *v1.ClusterPolicy) []string {
+	problems, _ := validateClusterPolicy(obj)
+	return problems
+}
+
+// validateClusterPolicy returns the problems found in the spec of a ClusterPolicy and its compiled expressions
+func validateClusterPolicy(obj *v1.ClusterPolicy) ([]string, map[string]expression.Program) {
+	problems, expressions := validatePolicy(&v1.Policy{ObjectMeta: obj.ObjectMeta, Spec: obj.Spec.PolicySpec})
+	if _, err := namespaceSelector(obj); err != nil {
+		problems = append(problems, fmt.Sprintf("namespaceSelector: %v", err))
+	}
+	return problems, expressions
+}
+
+// namespaceSelector returns the selector of the namespaces a ClusterPolicy applies to
+func namespaceSelector(obj *v1.ClusterPolicy) (labels.Selector, error) {
+	if obj.Spec.NamespaceSelector == nil {
+		return labels.Everything(), nil
+	}
+	return metav1.LabelSelectorAsSelector(obj.Spec.NamespaceSelector)
+}
+
+// ValidatePolicyReferences returns the configurations and Rego modules referenced by a Policy that do not exist,
+// and the configurations that do not allow references from the namespace of the Policy
+func ValidatePolicyReferences(obj *v1.Policy, kubeClient kubernetes.Interface, policiesClient versioned.Interface) []string {
+	problems := make([]string, 0)
+	checked := make(map[string]struct{})
+	// get returns the namespaces allowed to reference the resource
+	check := func(kind v1.CrdType, key string, get func(namespace string, name string) ([]string, error)) {
+		if _, ok := checked[kind.String()+" "+key]; ok {
+			return
+		}
+		checked[kind.String()+" "+key] = struct{}{}
+		namespace, name := splitKey(key)
+		allowed, err := get(namespace, name)
+		if apierrors.IsNotFound(err) {
+			problems = append(problems, fmt.Sprintf("%s %s does not exist", kind, key))
+		} else if err != nil {
+			problems = append(problems, fmt.Sprintf("could not get %s %s: %v", kind, key, err))
+		} else if kind != v1.CONFIGMAP && !policy.NamespaceAllowed(allowed, namespace, obj.Namespace) {
+			problems = append(problems, fmt.Sprintf("%s %s does not allow references from namespace %s", kind, key, obj.Namespace))
+		}
+	}
+	for _, policies := range ParseTarget(obj.Spec.Target, obj.Namespace) {
+		for _, action := range policies.Actions {
+			configKey := policy.ConfigKey(obj.Namespace, action.Config)
+			// Configs of other kinds are provided by strategy plugins and are not checked
+			switch policy.NewType(action.PolicyType).ConfigKind() {
+			case v1.JWTCONFIG:
+				check(v1.JWTCONFIG, configKey, func(namespace string, name string) ([]string, error) {
+					config, err := policiesClient.AppidV1().JwtConfigs(namespace).Get(name, metav1.GetOptions{})
+					if err != nil {
+						return nil, err
+					}
+					return config.Spec.AllowedNamespaces, nil
+				})
+			case v1.OIDCCONFIG:
+				check(v1.OIDCCONFIG, configKey, func(namespace string, name string) ([]string, error) {
+					config, err := policiesClient.AppidV1().OidcConfigs(namespace).Get(name, metav1.GetOptions{})
+					if err != nil {
+						return nil, err
+					}
+					return config.Spec.AllowedNamespaces, nil
+				})
+			}
+			if policy.NewType(action.PolicyType) == policy.OPA && action.RegoModule != "" {
+				check(v1.CONFIGMAP, obj.Namespace+"/"+action.RegoModule, func(namespace string, name string) ([]string, error) {
+					_, err := kubeClient.CoreV1().ConfigMaps(namespace).Get(name, metav1.GetOptions{})
+					return nil, err
+				})
+			}
+		}
+	}
+	return problems
+}
+
+// ValidateJwtConfig returns the problems found in the spec of a JwtConfig
+func ValidateJwtConfig(obj *v1.JwtConfig) []string {
+	problems := make([]string, 0)
+	if err := validateURL(obj.Spec.JwksURL); err != nil {
+		problems = append(problems, fmt.Sprintf("jwksUrl: %v", err))
+	}
+	return problems
+}
+
+// ValidateOidcConfig returns the problems found in the spec of an OidcConfig
+func ValidateOidcConfig(obj *v1.OidcConfig) []string {
+	problems := make([]string, 0)
+	if obj.Spec.ClientID == "" {
+		problems = append(problems, "clientId: must be set")
+	}
+	if err := validateURL(obj.Spec.DiscoveryURL); err != nil {
+		problems = append(problems, fmt.Sprintf("discoveryUrl: %v", err))
+	}
+	switch obj.Spec.AuthMethod {
+	case "", "client_secret_basic", "client_secret_post":
+	default:
+		problems = append(problems, fmt.Sprintf("authMethod: unknown method %q", obj.Spec.AuthMethod))
+	}
+	if obj.Spec.ClientSecret == "" && (obj.Spec.ClientSecretRef.Name == "" || obj.Spec.ClientSecretRef.Key == "") {
+		problems = append(problems, "clientSecret: either clientSecret or clientSecretRef name and key must be set")
+	}
+	return problems
+}
+
+// validPathPattern returns true if every * and {param} of the path spans a whole segment
+func validPathPattern(path string) bool {
+	for _, segment := range strings.Split(path, "/") {
+		if segment == "*" {
+			continue
+		}
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			segment = segment[1 : len(segment)-1]
+		}
+		if strings.ContainsAny(segment, "*{}") {
+			return false
+		}
+	}
+	return true
+}
+
+// validHost returns true if host is *, a host name, or a host name prefixed with *. to match its subdomains
+func validHost(host string) bool {
+	if host == "*" {
+		return true
+	}
+	host = strings.TrimPrefix(host, "*.")
+	return host != "" && !strings.ContainsAny(host, "*/: ")
+}
+
+// validateURL returns an error if value is not an absolute http or https URL
+func validateURL(value string) error {
+	u, err := url.Parse(value)
+	if err != nil {
+		return err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%q is not an absolute http or https URL", value)
+	}
+	return nil
+}
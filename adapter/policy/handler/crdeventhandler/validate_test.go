@@ -0,0 +1,236 @@
+package crdeventhandler
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/client-go/kubernetes/fake"
+
+	v1 "github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/pkg/apis/policies/v1"
+	policiesFake "github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/pkg/client/clientset/versioned/fake"
+	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/policy"
+)
+
+// Types registered by strategy plugins
+var (
+	_ = policy.RegisterType(policy.TypeInfo{Name: "hmac", ConfigKind: v1.JWTCONFIG})
+	_ = policy.RegisterType(policy.TypeInfo{Name: "apikey", ConfigKind: v1.NONE})
+)
+
+func TestValidatePolicy(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   string
+		policies []v1.PathPolicy
+		problems []string
+	}{
+		{
+			name:     "valid",
+			method:   "GET",
+			policies: []v1.PathPolicy{{PolicyType: "jwt", Config: "jwt"}},
+			problems: []string{},
+		},
+		{
+			name:     "default method",
+			policies: []v1.PathPolicy{{PolicyType: "oidc", Config: "oidc"}},
+			problems: []string{},
+		},
+		{
+			name:     "unknown method",
+			method:   "get",
+			policies: []v1.PathPolicy{{PolicyType: "jwt", Config: "jwt"}},
+			problems: []string{`service: unknown method "get"`},
+		},
+		{
+			name:     "invalid rule match",
+			method:   "GET",
+			policies: []v1.PathPolicy{{PolicyType: "jwt", Config: "jwt", Rules: []v1.Rule{{Claim: "scope", Values: []string{"read"}, Match: "SOME"}}}},
+			problems: []string{"/path: invalid rule rules[0]: unknown match `SOME`"},
+		},
+		{
+			name:     "config in another namespace",
+			method:   "GET",
+			policies: []v1.PathPolicy{{PolicyType: "jwt", Config: "shared/jwt"}},
+			problems: []string{},
+		},
+		{
+			name:     "invalid config reference",
+			method:   "GET",
+			policies: []v1.PathPolicy{{PolicyType: "jwt", Config: "shared/jwt/extra"}},
+			problems: []string{`/path: invalid config reference "shared/jwt/extra", expected name or namespace/name`},
+		},
+		{
+			name:     "allow and deny",
+			method:   "GET",
+			policies: []v1.PathPolicy{{PolicyType: "allow"}, {PolicyType: "deny", Status: 404}},
+			problems: []string{},
+		},
+		{
+			name:     "allow with a config and status",
+			method:   "GET",
+			policies: []v1.PathPolicy{{PolicyType: "allow", Config: "jwt", Status: 403}},
+			problems: []string{"/path: policy of type allow does not take a config, rules, expression or headers", "/path: status is only used by policies of type deny"},
+		},
+		{
+			name:     "deny with an invalid status",
+			method:   "GET",
+			policies: []v1.PathPolicy{{PolicyType: "deny", Status: 200}},
+			problems: []string{"/path: invalid status 200, expected a 4xx or 5xx code"},
+		},
+		{
+			name:     "missing config",
+			method:   "GET",
+			policies: []v1.PathPolicy{{PolicyType: "jwt"}},
+			problems: []string{"/path: policy of type jwt does not reference a config"},
+		},
+		{
+			name:     "invalid expression",
+			method:   "GET",
+			policies: []v1.PathPolicy{{PolicyType: "jwt", Config: "jwt", Expression: "claims.sub =="}},
+		},
+		{
+			name:     "registered type",
+			method:   "GET",
+			policies: []v1.PathPolicy{{PolicyType: "hmac", Config: "keys"}, {PolicyType: "apikey"}},
+			problems: []string{},
+		},
+		{
+			name:     "registered type missing config",
+			method:   "GET",
+			policies: []v1.PathPolicy{{PolicyType: "hmac"}},
+			problems: []string{"/path: policy of type hmac does not reference a config"},
+		},
+	}
+
+	for _, test := range tests {
+		obj := policyGenerator([]v1.TargetElement{
+			getTargetElements(service, getPathConfigs(getPathConfig("/path", "", test.method, test.policies))),
+		})
+		problems := ValidatePolicy(obj)
+		if test.problems == nil {
+			assert.Equal(t, 1, len(problems), test.name)
+			continue
+		}
+		assert.Equal(t, test.problems, problems, test.name)
+	}
+}
+
+func TestValidatePolicyConditions(t *testing.T) {
+	path := getPathConfig("/path", "", "GET", []v1.PathPolicy{{PolicyType: "jwt", Config: "jwt"}})
+	path.Methods = []string{"OPTIONS", "HEAD", "FETCH"}
+	path.Schemes = []string{"HTTPS", "ftp"}
+	path.Headers = []v1.HeaderMatch{{Name: "x-tenant", Value: "a"}, {Name: "x tenant"}}
+	target := getTargetElements(service, []v1.PathConfig{path})
+	target.Hosts = []string{"*", "*.example.com", "api.example.com:8080", "api.*.com", "*."}
+	problems := ValidatePolicy(policyGenerator([]v1.TargetElement{target}))
+	assert.Equal(t, []string{
+		`service: invalid host "api.example.com:8080", wildcards are only allowed as a leading *.`,
+		`service: invalid host "api.*.com", wildcards are only allowed as a leading *.`,
+		`service: invalid host "*.", wildcards are only allowed as a leading *.`,
+		`service: unknown method "FETCH"`,
+		`service: unknown scheme "ftp"`,
+		"service: invalid header match headers[1]: `x tenant` is not a valid header name",
+	}, problems)
+}
+
+func TestValidatePolicyPaths(t *testing.T) {
+	policies := []v1.PathPolicy{{PolicyType: "jwt", Config: "jwt"}}
+	obj := policyGenerator([]v1.TargetElement{
+		getTargetElements(service, []v1.PathConfig{
+			getPathConfig("/users/{id}/orders", "/api/*/admin", "GET", policies),
+			getPathConfig("/users/{id", "", "GET", policies),
+			getPathConfig("", "/api/v*", "GET", policies),
+		}),
+	})
+	assert.Equal(t, []string{
+		`service: invalid path "/users/{id", * and {param} must span a whole segment`,
+		`service: invalid path "/api/v*", * and {param} must span a whole segment`,
+	}, ValidatePolicy(obj))
+
+	obj.Spec.Target[0].Paths = []v1.PathConfig{
+		{Regex: `/users/[0-9]+/orders`, Method: "GET", Policies: policies},
+		{Regex: `/users/(?P<id`, Method: "GET", Policies: policies},
+	}
+	assert.Equal(t, []string{
+		"service: invalid regex \"/users/(?P<id\": error parsing regexp: invalid named capture: `(?P<id`",
+	}, ValidatePolicy(obj))
+}
+
+func TestValidatePolicyReferences(t *testing.T) {
+	obj := policyGenerator([]v1.TargetElement{
+		getTargetElements(service, []v1.PathConfig{
+			getPathConfig("/jwt", "", "GET", []v1.PathPolicy{{PolicyType: "jwt", Config: "sample"}}),
+			getPathConfig("/oidc", "", "GET", []v1.PathPolicy{{PolicyType: "oidc", Config: "missing"}}),
+			getPathConfig("/opa", "", "GET", []v1.PathPolicy{{PolicyType: "opa", Config: "sample", RegoModule: "rego"}}),
+			getPathConfig("/other", "", "GET", []v1.PathPolicy{{PolicyType: "oidc", Config: "missing"}}),
+		}),
+	})
+	policiesClient := policiesFake.NewSimpleClientset(jwtConfigGenerator())
+
+	problems := ValidatePolicyReferences(obj, fake.NewSimpleClientset(), policiesClient)
+	assert.ElementsMatch(t, []string{"OidcConfig ns/missing does not exist", "ConfigMap ns/rego does not exist"}, problems)
+
+	problems = ValidatePolicyReferences(obj, fake.NewSimpleClientset(configMapGenerator("rego", "")), policiesClient)
+	assert.Equal(t, []string{"OidcConfig ns/missing does not exist"}, problems)
+
+	// Configs in other namespaces must allow the namespace of the Policy
+	obj = policyGenerator([]v1.TargetElement{
+		getTargetElements(service, []v1.PathConfig{
+			getPathConfig("/jwt", "", "GET", []v1.PathPolicy{{PolicyType: "jwt", Config: "shared/sample"}}),
+			getPathConfig("/oidc", "", "GET", []v1.PathPolicy{{PolicyType: "oidc", Config: "shared/sample"}}),
+		}),
+	})
+	jwtConfig := jwtConfigGenerator()
+	jwtConfig.Namespace = "shared"
+	oidcConfig := oidcConfigGenerator("sample", "id", "https://localhost")
+	oidcConfig.Namespace = "shared"
+	oidcConfig.Spec.AllowedNamespaces = []string{"*"}
+	problems = ValidatePolicyReferences(obj, fake.NewSimpleClientset(), policiesFake.NewSimpleClientset(jwtConfig, oidcConfig))
+	assert.Equal(t, []string{"JwtConfig shared/sample does not allow references from namespace ns"}, problems)
+
+	jwtConfig.Spec.AllowedNamespaces = []string{"other", ns}
+	problems = ValidatePolicyReferences(obj, fake.NewSimpleClientset(), policiesFake.NewSimpleClientset(jwtConfig, oidcConfig))
+	assert.Empty(t, problems)
+}
+
+func TestValidateJwtConfig(t *testing.T) {
+	assert.Empty(t, ValidateJwtConfig(jwtConfigGenerator()))
+	assert.Equal(t, []string{`jwksUrl: "sampleurl" is not an absolute http or https URL`}, ValidateJwtConfig(getJwtConfig(getJwtConfigSpec("sampleurl"), getObjectMeta(), getTypeMeta())))
+}
+
+func TestValidateOidcConfig(t *testing.T) {
+	tests := []struct {
+		name     string
+		obj      *v1.OidcConfig
+		problems []string
+	}{
+		{
+			name:     "valid",
+			obj:      oidcConfigWithRef("oidc", "id", "https://localhost/.well-known/openid-configuration", v1.ClientSecretRef{Name: "secret", Key: "key"}),
+			problems: []string{},
+		},
+		{
+			name:     "malformed discovery url",
+			obj:      oidcConfigGenerator("oidc", "id", "localhost:8080"),
+			problems: []string{`discoveryUrl: "localhost:8080" is not an absolute http or https URL`},
+		},
+		{
+			name:     "missing secret",
+			obj:      oidcConfigNoSecret("oidc", "id", "https://localhost"),
+			problems: []string{"clientSecret: either clientSecret or clientSecretRef name and key must be set"},
+		},
+		{
+			name: "missing client id and unknown auth method",
+			obj: getOidcConfig(v1.OidcConfigSpec{
+				DiscoveryURL: "https://localhost",
+				ClientSecret: "secret",
+				AuthMethod:   "private_key_jwt",
+			}, getObjectMeta(), getTypeMeta()),
+			problems: []string{"clientId: must be set", `authMethod: unknown method "private_key_jwt"`},
+		},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.problems, ValidateOidcConfig(test.obj), test.name)
+	}
+}
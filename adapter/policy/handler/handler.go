@@ -3,8 +3,13 @@ package handler
 
 import (
 	"go.uber.org/zap"
+	k8sv1 "k8s.io/api/core/v1"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedv1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
 
+	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/pkg/client/clientset/versioned"
 	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/policy"
 	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/policy/handler/crdeventhandler"
 	policystore "github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/policy/store/policy"
@@ -18,17 +23,21 @@ type PolicyHandler interface {
 
 // CrdHandler is responsible for storing and managing policy/client data
 type CrdHandler struct {
-	store      policystore.PolicyStore
-	kubeClient kubernetes.Interface
+	store          policystore.PolicyStore
+	kubeClient     kubernetes.Interface
+	policiesClient versioned.Interface
+	recorder       record.EventRecorder
 }
 
 // //////////////// constructor //////////////////
 
 // New creates a PolicyManager
-func New(store policystore.PolicyStore, kubeClient kubernetes.Interface) PolicyHandler {
+func New(store policystore.PolicyStore, kubeClient kubernetes.Interface, policiesClient versioned.Interface) PolicyHandler {
 	return &CrdHandler{
-		store:      store,
-		kubeClient: kubeClient,
+		store:          store,
+		kubeClient:     kubeClient,
+		policiesClient: policiesClient,
+		recorder:       newRecorder(kubeClient),
 	}
 }
 
@@ -36,7 +45,7 @@ func New(store policystore.PolicyStore, kubeClient kubernetes.Interface) PolicyH
 
 // HandleAddUpdateEvent updates the store after a CRD has been added
 func (c *CrdHandler) HandleAddUpdateEvent(obj interface{}) {
-	crdhandler := crdeventhandler.GetAddEventHandler(obj, c.store, c.kubeClient)
+	crdhandler := crdeventhandler.GetAddEventHandler(obj, c.store, c.kubeClient, c.policiesClient, c.recorder)
 	if crdhandler != nil {
 		crdhandler.HandleAddUpdateEvent()
 	}
@@ -51,8 +60,15 @@ func (c *CrdHandler) HandleDeleteEvent(obj interface{}) {
 	}
 	zap.S().Debugf("crdKey : %s", crdKey.Id)
 	zap.S().Debugf("crdType : %s", crdKey.CrdType)
-	handler := crdeventhandler.GetDeleteEventHandler(crdKey, c.store)
+	handler := crdeventhandler.GetDeleteEventHandler(crdKey, c.store, c.kubeClient, c.policiesClient, c.recorder)
 	if handler != nil {
 		handler.HandleDeleteEvent()
 	}
+}
+
+// newRecorder creates an EventRecorder publishing events on the observed objects
+func newRecorder(kubeClient kubernetes.Interface) record.EventRecorder {
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartRecordingToSink(&typedv1.EventSinkImpl{Interface: kubeClient.CoreV1().Events("")})
+	return broadcaster.NewRecorder(scheme.Scheme, k8sv1.EventSource{Component: "appidentityandaccessadapter"})
 }
\ No newline at end of file
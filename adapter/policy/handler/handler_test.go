@@ -8,6 +8,7 @@ import (
 	"k8s.io/client-go/kubernetes/fake"
 
 	v1 "github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/pkg/apis/policies/v1"
+	policiesFake "github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/pkg/client/clientset/versioned/fake"
 	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/policy"
 	storePolicy "github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/policy/store/policy"
 )
@@ -40,7 +41,7 @@ func jwtConfigGenerator() *v1.JwtConfig {
 }
 
 func TestNew(t *testing.T) {
-	assert.NotNil(t, New(storePolicy.New(), fake.NewSimpleClientset()))
+	assert.NotNil(t, New(storePolicy.New(), fake.NewSimpleClientset(), policiesFake.NewSimpleClientset()))
 }
 
 func TestHandler_HandleEventTest(t *testing.T) {
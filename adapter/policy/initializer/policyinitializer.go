@@ -9,6 +9,9 @@ import (
 
 	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/policy/store/policy"
 
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/oidc"
 	"k8s.io/client-go/rest"
@@ -21,23 +24,36 @@ import (
 	policiesInformer "github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/pkg/client/informers/externalversions"
 	policyController "github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/policy/controller"
 	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/policy/handler"
+	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/policy/handler/crdeventhandler"
 )
 
+// regoConfigMapSelector selects the ConfigMaps holding Rego modules for opa policies
+const regoConfigMapSelector = "security.cloud.ibm.com/rego=true"
+
+// secretFieldSelector excludes the service account tokens, which are never referenced by an OidcConfig
+const secretFieldSelector = "type!=kubernetes.io/service-account-token"
+
 // Initializer interface contains the methods that are required
 type Initializer interface {
 	GetHandler() handler.PolicyHandler
 	GetKubeClient() kubernetes.Interface
+	GetPoliciesClient() policiesClientSet.Interface
 }
 
 type PolicyInitializer struct {
-	KubeClient kubernetes.Interface
-	Handler    handler.PolicyHandler
+	KubeClient     kubernetes.Interface
+	PoliciesClient policiesClientSet.Interface
+	Handler        handler.PolicyHandler
 }
 
 func (pi *PolicyInitializer) GetKubeClient() kubernetes.Interface {
 	return pi.KubeClient
 }
 
+func (pi *PolicyInitializer) GetPoliciesClient() policiesClientSet.Interface {
+	return pi.PoliciesClient
+}
+
 func (pi *PolicyInitializer) GetHandler() handler.PolicyHandler {
 	return pi.Handler
 }
@@ -48,13 +64,45 @@ func New(store policy.PolicyStore) (Initializer, error) {
 		return nil, err
 	}
 
-	handler := handler.New(store, client)
-	policyInitializer := &PolicyInitializer{Handler: handler, KubeClient: client}
+	handler := handler.New(store, client, myresourceClient)
+	policyInitializer := &PolicyInitializer{Handler: handler, KubeClient: client, PoliciesClient: myresourceClient}
 	informerlist := policiesInformer.NewSharedInformerFactory(myresourceClient, 0)
 
 	go initPolicyController(informerlist.Appid().V1().JwtConfigs().Informer(), client, policyInitializer.Handler, v1.JWTCONFIG)
 	go initPolicyController(informerlist.Appid().V1().OidcConfigs().Informer(), client, policyInitializer.Handler, v1.OIDCCONFIG)
 	go initPolicyController(informerlist.Appid().V1().Policies().Informer(), client, policyInitializer.Handler, v1.POLICY)
+	go initPolicyController(informerlist.Appid().V1().ClusterPolicies().Informer(), client, policyInitializer.Handler, v1.CLUSTERPOLICY)
+
+	// Watch Namespaces so that ClusterPolicies are selected by the current namespace labels
+	go initPolicyController(informers.NewSharedInformerFactory(client, 0).Core().V1().Namespaces().Informer(), client, policyInitializer.Handler, v1.NAMESPACE)
+
+	// Watch labeled ConfigMaps so that Rego modules are reloaded when they change
+	configMapInformers := informers.NewSharedInformerFactoryWithOptions(client, 0, informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+		options.LabelSelector = regoConfigMapSelector
+	}))
+	go initPolicyController(configMapInformers.Core().V1().ConfigMaps().Informer(), client, policyInitializer.Handler, v1.CONFIGMAP)
+
+	// Watch Secrets so that OIDC clients are rebuilt when a referenced client secret is rotated
+	secretInformers := informers.NewSharedInformerFactoryWithOptions(client, 0, informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+		options.FieldSelector = secretFieldSelector
+	}))
+	go initPolicyController(secretInformers.Core().V1().Secrets().Informer(), client, policyInitializer.Handler, v1.SECRET)
+
+	// Watch the configs of the policy types provided by plugins
+	if kinds := crdeventhandler.ConfigKinds(); len(kinds) > 0 {
+		config, err := getKubeConfig()
+		if err != nil {
+			return nil, err
+		}
+		for _, kind := range kinds {
+			informer, err := kind.Informer(config)
+			if err != nil {
+				zap.L().Error("Could not create informer", zap.String("kind", kind.Kind.String()), zap.Error(err))
+				return nil, err
+			}
+			go initPolicyController(informer, client, policyInitializer.Handler, kind.Kind)
+		}
+	}
 
 	return policyInitializer, nil
 }
@@ -135,6 +183,10 @@ func initPolicyController(informer cache.SharedIndexInformer, client kubernetes.
 			}
 		},
 		UpdateFunc: func(oldObj, newObj interface{}) {
+			// skip the updates caused by the adapter writing the status subresource
+			if crdType != v1.CONFIGMAP && !specChanged(oldObj, newObj) {
+				return
+			}
 			key, err := cache.MetaNamespaceKeyFunc(newObj)
 			zap.L().Debug("Updating resource", zap.String("key", key))
 			if err == nil {
@@ -179,3 +231,17 @@ func initPolicyController(informer cache.SharedIndexInformer, client kubernetes.
 	signal.Notify(sigTerm, syscall.SIGINT)
 	<-sigTerm
 }
+
+// specChanged returns false if the generation of the object did not change.
+// The generation of custom resources with a status subresource only changes when their spec does.
+func specChanged(oldObj, newObj interface{}) bool {
+	oldMeta, err := meta.Accessor(oldObj)
+	if err != nil {
+		return true
+	}
+	newMeta, err := meta.Accessor(newObj)
+	if err != nil {
+		return true
+	}
+	return newMeta.GetGeneration() == 0 || oldMeta.GetGeneration() != newMeta.GetGeneration()
+}
@@ -1,12 +1,14 @@
 package policy
 
 import (
-	"go.uber.org/zap"
+	"strings"
 
+	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/expression"
 	v1 "github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/pkg/apis/policies/v1"
 )
 
-// Type represents a policy types (WEB/API)
+// Type represents a policy types (WEB/API).
+// Types following NONE are added by RegisterType.
 type Type int
 
 const (
@@ -14,10 +16,28 @@ const (
 	JWT Type = iota
 	// OIDC policy types specifies requests protected by WEB strategy
 	OIDC
+	// OPA policy types specifies requests protected by API strategy and a Rego policy
+	OPA
+	// ALLOW policy types specifies requests that are always allowed
+	ALLOW
+	// DENY policy types specifies requests that are always rejected
+	DENY
 	// NONE policy specifies requests without protection
 	NONE
 )
 
+// Mode represents how the results of multiple policies protecting an endpoint are combined
+type Mode int
+
+const (
+	// FIRSTMATCH enforces the first policy applicable to the request
+	FIRSTMATCH Mode = iota
+	// ALLOF requires every policy to pass
+	ALLOF
+	// ANYOF requires at least one policy to pass
+	ANYOF
+)
+
 // Endpoint captures a request endpoint
 type Endpoint struct {
 	Service Service
@@ -25,6 +45,8 @@ type Endpoint struct {
 	Path string
 	// Method holds an HTTP Method
 	Method Method
+	// Regex is true if Path holds an RE2 pattern matched against the whole request path
+	Regex bool
 }
 
 // CrdKey represents a CustomResourceDefinition ID
@@ -37,18 +59,47 @@ type CrdKey struct {
 type PolicyMapping struct {
 	Actions  []v1.PathPolicy
 	Endpoint Endpoint
+	// Conditions restrict the actions to matching requests
+	Conditions Conditions
+	// Problems found validating the Policy. Requests to the endpoints of an invalid Policy are denied.
+	Problems []string
 }
 
 type RoutePolicy struct {
 	PolicyReference string
-	Actions         []v1.PathPolicy
+	// Priority orders the policies contributed to the same endpoint by different Policy objects
+	Priority int32
+	// Mode combines the results of the actions
+	Mode    Mode
+	Actions []v1.PathPolicy
+	// Expressions maps expression source -> compiled expression for the actions
+	Expressions map[string]expression.Program
+	// Conditions restrict the actions to matching requests
+	Conditions Conditions
+	// Problems found validating the Policy. Requests to the endpoints of an invalid Policy are denied.
+	Problems []string
 }
 
 func NewRoutePolicy() RoutePolicy {
 	return RoutePolicy{
 		PolicyReference: "",
+		Mode:            FIRSTMATCH,
 		Actions:         make([]v1.PathPolicy, 0),
+		Expressions:     make(map[string]expression.Program),
+	}
+}
+
+// Precedes returns true if p takes precedence over other.
+// Higher priorities win and ties are broken by policy reference.
+// Within a policy, policies with more conditions are checked first.
+func (p RoutePolicy) Precedes(other RoutePolicy) bool {
+	if p.Priority != other.Priority {
+		return p.Priority > other.Priority
+	}
+	if p.PolicyReference != other.PolicyReference {
+		return p.PolicyReference < other.PolicyReference
 	}
+	return p.Conditions.Specificity() > other.Conditions.Specificity()
 }
 
 // New creates a new ParsedPolicies
@@ -59,20 +110,36 @@ func NewPolicyMapping(service Endpoint, actions []v1.PathPolicy) PolicyMapping {
 	}
 }
 
-var typeNames = [...]string{"JWT", "OIDC", "NONE"}
-
+// String returns the upper case name of the type
 func (t Type) String() string {
-	return typeNames[t]
+	return strings.ToUpper(t.Info().Name)
 }
 
+// NewType returns the type registered under the policyType name, or NONE.
+// Names are matched case-insensitively, so NewType(t.String()) returns t.
 func NewType(t string) Type {
-	zap.S().Info("Type: ", t)
-	switch t {
-	case "jwt":
-		return JWT
-	case "oidc":
-		return OIDC
+	typesMu.RLock()
+	defer typesMu.RUnlock()
+	if result, ok := typesByName[strings.ToLower(t)]; ok {
+		return result
+	}
+	return NONE
+}
+
+var modeNames = [...]string{"firstMatch", "all", "any"}
+
+func (m Mode) String() string {
+	return modeNames[m]
+}
+
+// NewMode parses a Policy combination mode. Unknown modes default to FIRSTMATCH.
+func NewMode(m string) Mode {
+	switch m {
+	case "all":
+		return ALLOF
+	case "any":
+		return ANYOF
 	default:
-		return NONE
+		return FIRSTMATCH
 	}
 }
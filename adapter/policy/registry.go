@@ -0,0 +1,74 @@
+package policy
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	v1 "github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/pkg/apis/policies/v1"
+)
+
+// TypeInfo describes a policy type
+type TypeInfo struct {
+	// Name is the policyType used by Policy resources. Names are matched case-insensitively.
+	Name string
+	// ConfigKind is the kind of config referenced by policies of the type, or v1.NONE if they take no config
+	ConfigKind v1.CrdType
+	// Authorization is true if the type only applies to requests with an authorization header.
+	// Under the firstMatch mode, such policies are skipped for requests without one.
+	Authorization bool
+	// References returns the resources referenced by an action of the type besides its config, if any,
+	// such as the ConfigMap holding the Rego modules of an opa policy in the given namespace.
+	References func(namespace string, action v1.PathPolicy) []Dependency
+}
+
+var (
+	typesMu sync.RWMutex
+	// types holds the registered types, indexed by Type
+	types = []TypeInfo{
+		JWT:   {Name: "jwt", ConfigKind: v1.JWTCONFIG, Authorization: true},
+		OIDC:  {Name: "oidc", ConfigKind: v1.OIDCCONFIG},
+		OPA:   {Name: "opa", ConfigKind: v1.JWTCONFIG, Authorization: true, References: regoModules},
+		ALLOW: {Name: "allow", ConfigKind: v1.NONE},
+		DENY:  {Name: "deny", ConfigKind: v1.NONE},
+		NONE:  {Name: "none", ConfigKind: v1.NONE},
+	}
+	// typesByName maps lower case policyType names to types
+	typesByName = map[string]Type{"jwt": JWT, "oidc": OIDC, "opa": OPA, "allow": ALLOW, "deny": DENY}
+)
+
+// RegisterType adds a policy type, allowing Policy resources to use its name as policyType.
+// Types are registered during initialization, usually by strategy.Register.
+// It panics if the name is already registered.
+func RegisterType(info TypeInfo) Type {
+	typesMu.Lock()
+	defer typesMu.Unlock()
+	name := strings.ToLower(info.Name)
+	if _, ok := typesByName[name]; ok || name == "" || name == types[NONE].Name {
+		panic(fmt.Sprintf("policy: type %q is already registered", info.Name))
+	}
+	t := Type(len(types))
+	types = append(types, info)
+	typesByName[name] = t
+	return t
+}
+
+// Info returns the description of the type. Unknown types are described as NONE.
+func (t Type) Info() TypeInfo {
+	typesMu.RLock()
+	defer typesMu.RUnlock()
+	if t < 0 || int(t) >= len(types) {
+		return types[NONE]
+	}
+	return types[t]
+}
+
+// ConfigKind returns the kind of config referenced by policies of the type, or v1.NONE
+func (t Type) ConfigKind() v1.CrdType {
+	return t.Info().ConfigKind
+}
+
+// regoModules returns the ConfigMap holding the Rego modules of an opa policy
+func regoModules(namespace string, action v1.PathPolicy) []Dependency {
+	return []Dependency{{Kind: v1.CONFIGMAP, Name: namespace + "/" + action.RegoModule}}
+}
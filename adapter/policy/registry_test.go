@@ -0,0 +1,62 @@
+package policy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	v1 "github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/pkg/apis/policies/v1"
+)
+
+var (
+	apiKeyType = RegisterType(TypeInfo{Name: "apikey", ConfigKind: v1.NONE, Authorization: true})
+	// scriptConfigKind holds the configs of the script type, whose policies also reference a Secret
+	scriptConfigKind = v1.RegisterCrdType("ScriptConfig")
+	_                = RegisterType(TypeInfo{Name: "script", ConfigKind: scriptConfigKind, References: func(namespace string, action v1.PathPolicy) []Dependency {
+		return []Dependency{{Kind: v1.SECRET, Name: namespace + "/script-token"}}
+	}})
+)
+
+func TestNewType(t *testing.T) {
+	for _, policyType := range []Type{JWT, OIDC, OPA, ALLOW, DENY, apiKeyType} {
+		assert.Equal(t, policyType, NewType(policyType.Info().Name))
+		assert.Equal(t, policyType, NewType(policyType.String()))
+	}
+	assert.Equal(t, "JWT", JWT.String())
+	assert.Equal(t, "DENY", DENY.String())
+	assert.Equal(t, NONE, NewType("none"))
+	assert.Equal(t, JWT, NewType("Jwt"))
+	assert.Equal(t, NONE, NewType("NONE"))
+	assert.Equal(t, NONE, NewType("unknown"))
+}
+
+func TestRegisterType(t *testing.T) {
+	assert.True(t, apiKeyType > NONE)
+	assert.Equal(t, apiKeyType, NewType("apikey"))
+	assert.Equal(t, "APIKEY", apiKeyType.String())
+	assert.Equal(t, TypeInfo{Name: "apikey", ConfigKind: v1.NONE, Authorization: true}, apiKeyType.Info())
+	assert.Equal(t, v1.JWTCONFIG, OPA.ConfigKind())
+	assert.Equal(t, v1.NONE, Type(1000).ConfigKind())
+
+	assert.Panics(t, func() { RegisterType(TypeInfo{Name: "jwt"}) })
+	assert.Panics(t, func() { RegisterType(TypeInfo{Name: "apikey"}) })
+	assert.Panics(t, func() { RegisterType(TypeInfo{Name: "APIKEY"}) })
+	assert.Panics(t, func() { RegisterType(TypeInfo{Name: "none"}) })
+	assert.Panics(t, func() { RegisterType(TypeInfo{Name: "None"}) })
+	assert.Panics(t, func() { RegisterType(TypeInfo{}) })
+}
+
+func TestDependencies(t *testing.T) {
+	mappings := []PolicyMapping{NewPolicyMapping(Endpoint{Service: Service{Namespace: "ns", Name: "svc"}, Path: "/path", Method: GET}, []v1.PathPolicy{
+		{PolicyType: "opa", Config: "jwt", RegoModule: "rego"},
+		{PolicyType: "script", Config: "shared/script"},
+		{PolicyType: "apikey"},
+	})}
+	assert.Equal(t, []Dependency{
+		{Kind: v1.CONFIGMAP, Name: "ns/rego"},
+		{Kind: v1.JWTCONFIG, Name: "ns/jwt"},
+		{Kind: scriptConfigKind, Name: "shared/script"},
+		{Kind: v1.SECRET, Name: "ns/script-token"},
+	}, Dependencies(mappings))
+	assert.Equal(t, "ScriptConfig shared/script", Dependency{Kind: scriptConfigKind, Name: "shared/script"}.String())
+}
@@ -7,3 +7,12 @@ type Service struct {
 	// Name is the name of the service
 	Name string
 }
+
+// AllServices is the service name of the ClusterPolicy targets applying to every service
+const AllServices = "*"
+
+// ClusterService returns the service the endpoints of ClusterPolicies targeting the named service are stored under.
+// ClusterPolicies are not namespaced, so their services have no namespace.
+func ClusterService(name string) Service {
+	return Service{Name: name}
+}
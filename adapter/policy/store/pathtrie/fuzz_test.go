@@ -0,0 +1,113 @@
+//go:build go1.18
+// +build go1.18
+
+package pathtrie
+
+import (
+	"strings"
+	"testing"
+)
+
+var fuzzRoutes = []string{
+	"/*",
+	"/a",
+	"/a/*",
+	"/a/b",
+	"/a/{id}",
+	"/a/{id}/c",
+	"/a/*/c",
+	"/a/*/c/*",
+	"/a/b/c/d",
+	"/{x}/b",
+	"/{x}/{y}/*",
+}
+
+// FuzzPathTrie_GetActions checks that lookups agree with a linear scan of the routes
+func FuzzPathTrie_GetActions(f *testing.F) {
+	for _, seed := range []string{"/", "", "/a", "/a/b", "/a/x/c", "/a/x/c/d/e", "/z/b", "//b", "/a//c", "/a/{id}", "/a/*/c", "/a/{}"} {
+		f.Add(seed)
+	}
+	trie := NewPathTrie()
+	for _, route := range fuzzRoutes {
+		trie.Put(route, route)
+	}
+
+	f.Fuzz(func(t *testing.T, path string) {
+		if !strings.HasPrefix(path, "/") {
+			return
+		}
+		expected := mostSpecific(fuzzRoutes, path)
+		if actual := trie.GetActions(path); actual != expected {
+			t.Errorf("GetActions(%q) = %v, expected %v", path, actual, expected)
+		}
+	})
+}
+
+// mostSpecific returns the route matching the path with the highest rank, or nil
+func mostSpecific(routes []string, path string) interface{} {
+	var best []int
+	var result interface{}
+	for _, route := range routes {
+		if rank := rankMatch(segments(route), segments(path)); rank != nil && greater(rank, best) {
+			best, result = rank, route
+		}
+	}
+	return result
+}
+
+// rankMatch returns the rank of each segment of the path matched by the route, or nil if it does not match
+func rankMatch(route []string, path []string) []int {
+	const (
+		prefix = iota + 1
+		wildcard
+		param
+		exact
+		end
+	)
+	rank := make([]int, 0, len(path)+1)
+	for i, part := range route {
+		if part == wildcardSegment && i == len(route)-1 {
+			// A trailing /* matches the remaining segments, including none
+			return append(rank, prefix)
+		}
+		if i == len(path) {
+			return nil
+		}
+		switch {
+		case part == path[i] && part != wildcardSegment && normalizeSegment(part) != paramSegment:
+			rank = append(rank, exact)
+		case len(path[i]) > 1 && normalizeSegment(part) == paramSegment:
+			rank = append(rank, param)
+		case len(path[i]) > 1 && part == wildcardSegment:
+			rank = append(rank, wildcard)
+		default:
+			return nil
+		}
+	}
+	if len(route) != len(path) {
+		return nil
+	}
+	return append(rank, end)
+}
+
+func greater(a []int, b []int) bool {
+	if b == nil {
+		return true
+	}
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] != b[i] {
+			return a[i] > b[i]
+		}
+	}
+	return len(a) > len(b)
+}
+
+func segments(key string) []string {
+	parts := make([]string, 0)
+	for part, i := PathSegmenter(key, 0); ; part, i = PathSegmenter(key, i) {
+		parts = append(parts, part)
+		if i == -1 {
+			return parts
+		}
+	}
+}
@@ -1,5 +1,12 @@
 package pathtrie
 
+const (
+	// wildcardSegment matches any number of segments at the end of a key, or a single segment elsewhere
+	wildcardSegment = "/*"
+	// paramSegment stores {name} path parameters, which match a single segment
+	paramSegment = "/{}"
+)
+
 type PathTrie struct {
 	segmenter StringSegmenter
 	value     interface{}
@@ -25,7 +32,7 @@ func NewPathTrie() *PathTrie {
 func (trie *PathTrie) Get(key string) interface{} {
 	node := trie
 	for part, i := trie.segmenter(key, 0); ; part, i = trie.segmenter(key, i) {
-		node = node.children[part]
+		node = node.children[normalizeSegment(part)]
 		if node == nil {
 			return nil
 		}
@@ -36,29 +43,50 @@ func (trie *PathTrie) Get(key string) interface{} {
 	return node.value
 }
 
-// Get returns the actions stored for the given endpoint.
+// GetActions returns the value of the most specific key matching the path.
+// Segments are compared from left to right, where an exact segment takes precedence
+// over a {param}, which takes precedence over a * matching a single segment, which
+// takes precedence over a trailing /* matching the rest of the path.
 func (trie *PathTrie) GetActions(key string) interface{} {
-	prefix := "/*"
-	node := trie
-	parent := trie.children[prefix]
-	for part, i := trie.segmenter(key, 0); ; part, i = trie.segmenter(key, i) {
-		node = node.children[part]
-		if node != nil && node.children[prefix] != nil {
-			parent = node.children[prefix]
+	return trie.match(key, 0)
+}
+
+// match returns the value of the most specific key matching the path from start
+func (trie *PathTrie) match(key string, start int) interface{} {
+	if start == -1 {
+		if trie.value != nil {
+			return trie.value
 		}
-		if node == nil || i == -1 {
-			break
+		// A trailing /* also matches the path it follows
+		if wildcard := trie.children[wildcardSegment]; wildcard != nil {
+			return wildcard.value
 		}
+		return nil
 	}
-
-	if node == nil || node.value == nil {
-		if parent == nil {
-			return nil
-		} else {
-			return parent.value
+	part, next := trie.segmenter(key, start)
+	// Segments of the path are not patterns, even when they look like one
+	if child := trie.children[part]; child != nil && part != wildcardSegment && part != paramSegment {
+		if value := child.match(key, next); value != nil {
+			return value
 		}
 	}
-	return node.value
+	// Wildcards do not match empty segments
+	if len(part) > 1 {
+		if child := trie.children[paramSegment]; child != nil {
+			if value := child.match(key, next); value != nil {
+				return value
+			}
+		}
+		if child := trie.children[wildcardSegment]; child != nil {
+			if value := child.match(key, next); value != nil {
+				return value
+			}
+		}
+	}
+	if wildcard := trie.children[wildcardSegment]; wildcard != nil {
+		return wildcard.value
+	}
+	return nil
 }
 
 // Put inserts the value into the trie at the given key, replacing any
@@ -69,6 +97,7 @@ func (trie *PathTrie) GetActions(key string) interface{} {
 func (trie *PathTrie) Put(key string, value interface{}) bool {
 	node := trie
 	for part, i := trie.segmenter(key, 0); ; part, i = trie.segmenter(key, i) {
+		part = normalizeSegment(part)
 		child, _ := node.children[part]
 		if child == nil {
 			child = PathTrieNode()
@@ -92,6 +121,7 @@ func (trie *PathTrie) Delete(key string) bool {
 	var path []nodeStr // record ancestors to check later
 	node := trie
 	for part, i := trie.segmenter(key, 0); ; part, i = trie.segmenter(key, i) {
+		part = normalizeSegment(part)
 		path = append(path, nodeStr{part: part, node: node})
 		node = node.children[part]
 		if node == nil {
@@ -120,6 +150,74 @@ func (trie *PathTrie) Delete(key string) bool {
 	return true // node (internal or not) existed and its value was nil'd
 }
 
+// With returns a copy of the trie with the value stored at the given key.
+// Only the nodes on the path to the key are copied, so the receiver is left
+// unchanged and can safely be read while the copy is built.
+func (trie *PathTrie) With(key string, value interface{}) Trie {
+	return trie.with(key, 0, value)
+}
+
+func (trie *PathTrie) with(key string, start int, value interface{}) *PathTrie {
+	node := trie.copyNode()
+	if start == -1 {
+		node.value = value
+		return node
+	}
+	part, next := trie.segmenter(key, start)
+	part = normalizeSegment(part)
+	child := trie.children[part]
+	if child == nil {
+		child = PathTrieNode()
+	}
+	node.children[part] = child.with(key, next, value)
+	return node
+}
+
+// Without returns a copy of the trie without the value stored at the given key,
+// removing the nodes left childless. The receiver is left unchanged.
+func (trie *PathTrie) Without(key string) Trie {
+	node, _ := trie.without(key, 0)
+	return node
+}
+
+func (trie *PathTrie) without(key string, start int) (*PathTrie, bool) {
+	if start == -1 {
+		node := trie.copyNode()
+		node.value = nil
+		return node, true
+	}
+	part, next := trie.segmenter(key, start)
+	part = normalizeSegment(part)
+	child := trie.children[part]
+	if child == nil {
+		return trie, false
+	}
+	child, found := child.without(key, next)
+	if !found {
+		return trie, false
+	}
+	node := trie.copyNode()
+	if child.value == nil && child.isLeaf() {
+		delete(node.children, part)
+	} else {
+		node.children[part] = child
+	}
+	return node, true
+}
+
+// copyNode returns a copy of the node sharing its children
+func (trie *PathTrie) copyNode() *PathTrie {
+	node := &PathTrie{
+		segmenter: trie.segmenter,
+		value:     trie.value,
+		children:  make(map[string]*PathTrie, len(trie.children)+1),
+	}
+	for part, child := range trie.children {
+		node.children[part] = child
+	}
+	return node
+}
+
 // PathTrie node and the part string key of the child the path descends into.
 type nodeStr struct {
 	node *PathTrie
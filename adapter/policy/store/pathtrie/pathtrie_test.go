@@ -80,6 +80,42 @@ func TestPathTrie(t *testing.T) {
 
 }
 
+func TestPathTrie_WithWithout(t *testing.T) {
+	var trie Trie = NewPathTrie()
+	versions := make([]Trie, 0)
+	for _, c := range getCases() {
+		versions = append(versions, trie)
+		trie = trie.With(c.key, c.value)
+	}
+	// Earlier versions are unchanged
+	for i, version := range versions {
+		for j, c := range getCases() {
+			if j < i {
+				assert.Equal(t, c.value, version.Get(c.key), c.key)
+			} else {
+				assert.Nil(t, version.Get(c.key), c.key)
+			}
+		}
+	}
+	assert.Equal(t, 3, trie.GetActions("/path/home"))
+
+	without := trie.Without("/path/*").Without("/missing")
+	assert.Equal(t, 3, trie.Get("/path/*"))
+	assert.Nil(t, without.Get("/path/*"))
+	assert.Equal(t, 2, without.GetActions("/path"))
+	assert.Nil(t, without.GetActions("/path/home"))
+
+	// Removing every value prunes the nodes left childless
+	for _, c := range getCases() {
+		without = without.Without(c.key)
+	}
+	children := make([]string, 0)
+	for part := range without.(*PathTrie).children {
+		children = append(children, part)
+	}
+	assert.Equal(t, []string{"/"}, children)
+}
+
 func TestPathTrie_GetActions(t *testing.T) {
 	trie := NewPathTrie()
 	initialValues := getCases()
@@ -99,4 +135,94 @@ func TestPathTrie_GetActions(t *testing.T) {
 			assert.Fail(t, fmt.Sprintf("expected key %s to have value %v, got %v", c.key, c.value, value))
 		}
 	}
-}
\ No newline at end of file
+}
+
+func TestPathTrie_GetActionsPatterns(t *testing.T) {
+	trie := NewPathTrie()
+	for _, c := range []Case{
+		{"/*", "root"},
+		{"/users/{id}", "user"},
+		{"/users/{userId}/orders", "orders"},
+		{"/users/me/orders", "my orders"},
+		{"/users/{id}/*", "user prefix"},
+		{"/api/*/admin", "admin"},
+		{"/api/v1/*", "v1"},
+		{"/api/*", "api"},
+	} {
+		trie.Put(c.key, c.value)
+	}
+
+	tests := []Case{
+		{"/users/1", "user"},
+		{"/users/1/orders", "orders"},
+		{"/users/me/orders", "my orders"},
+		{"/users/me/profile", "user prefix"},
+		{"/users/1/orders/2", "user prefix"},
+		{"/users", "root"},
+		{"/users/", "root"},
+		{"/api/v2/admin", "admin"},
+		{"/api/v1/admin", "v1"},
+		{"/api/v2/admin/users", "api"},
+		{"/api", "api"},
+		{"/other", "root"},
+	}
+	for _, c := range tests {
+		assert.Equal(t, c.value, trie.GetActions(c.key), c.key)
+	}
+
+	// Parameter names do not distinguish keys
+	assert.Equal(t, "orders", trie.Get("/users/{other}/orders"))
+	assert.True(t, trie.Delete("/users/{other}/orders"))
+	assert.Equal(t, "user prefix", trie.GetActions("/users/1/orders"))
+}
+
+// routes returns n distinct routes mixing exact segments, parameters, wildcards and prefixes
+func routes(n int) []string {
+	keys := make([]string, 0, n)
+	for i := 0; len(keys) < n; i++ {
+		switch i % 4 {
+		case 0:
+			keys = append(keys, fmt.Sprintf("/service%d/resource%d", i%50, i))
+		case 1:
+			keys = append(keys, fmt.Sprintf("/service%d/resource%d/{id}", i%50, i))
+		case 2:
+			keys = append(keys, fmt.Sprintf("/service%d/*/resource%d", i%50, i))
+		default:
+			keys = append(keys, fmt.Sprintf("/service%d/resource%d/*", i%50, i))
+		}
+	}
+	return keys
+}
+
+func benchmarkGetActions(b *testing.B, n int) {
+	trie := NewPathTrie()
+	for i, key := range routes(n) {
+		trie.Put(key, i)
+	}
+	paths := []string{
+		"/service7/resource7/1234",
+		"/service10/v1/resource10",
+		"/service3/resource3/a/b/c",
+		"/service1/missing/path",
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		trie.GetActions(paths[i%len(paths)])
+	}
+}
+
+func BenchmarkPathTrie_GetActions100(b *testing.B)   { benchmarkGetActions(b, 100) }
+func BenchmarkPathTrie_GetActions10000(b *testing.B) { benchmarkGetActions(b, 10000) }
+
+func BenchmarkPathTrie_Put(b *testing.B) {
+	keys := routes(10000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		trie := NewPathTrie()
+		for j, key := range keys {
+			trie.Put(key, j)
+		}
+	}
+}
@@ -6,4 +6,7 @@ type Trie interface {
 	GetActions(key string) interface{}
 	Put(key string, value interface{}) bool
 	Delete(key string) bool
+	// With and Without return modified copies, leaving the receiver unchanged
+	With(key string, value interface{}) Trie
+	Without(key string) Trie
 }
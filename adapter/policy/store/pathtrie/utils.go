@@ -24,3 +24,12 @@ func PathSegmenter(path string, start int) (segment string, next int) {
 	}
 	return path[start : start+end+1], start + end + 1
 }
+
+// normalizeSegment returns the segment a key segment is stored under.
+// Path parameters match any single segment, so their names are dropped.
+func normalizeSegment(segment string) string {
+	if len(segment) >= 3 && segment[1] == '{' && segment[len(segment)-1] == '}' {
+		return paramSegment
+	}
+	return segment
+}
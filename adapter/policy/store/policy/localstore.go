@@ -1,124 +1,835 @@
 package policy
 
 import (
+	"regexp"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+
+	"k8s.io/apimachinery/pkg/labels"
+
 	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/authserver/keyset"
 	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/client"
+	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/opa"
+	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/pkg/apis/policies/v1"
 	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/policy"
 	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/policy/store/pathtrie"
 )
 
-// LocalStore is responsible for storing and managing policy/client data
+// LocalStore is responsible for storing and managing policy/client data.
+// Reads use the published snapshot without locking, so they never observe a partial update.
+// Writers are serialized and publish a modified copy of the snapshot, sharing the data they did not change.
+// The zero value is an empty store ready to use.
 type LocalStore struct {
+	// mu serializes writers
+	mu sync.Mutex
+	// current holds the published *snapshot
+	current atomic.Value
+	// tx holds the unpublished draft of a store passed to Update or returned by Snapshot
+	tx *draft
+}
+
+// snapshot holds the data of a store. A published snapshot is never modified.
+type snapshot struct {
+	// version is incremented every time a snapshot is published
+	version uint64
 	// clients maps client_name -> client_config
 	clients map[string]client.Client // oidc config ClientName:Client
 	// policies maps endpoint -> list of actions
 	policies map[policy.Service]pathtrie.Trie
+	// regexPolicies maps service -> compiled regex endpoints and their actions, longest pattern first
+	regexPolicies map[policy.Service][]regexRoute
 	// policyMappings maps policy(namespace/name) -> list of created endpoints
 	policyMappings map[string][]policy.PolicyMapping
 	keysets        map[string]keyset.KeySet // jwt config ClientName:keyset
+	// tokenValidation maps jwt config ClientName -> token time validation settings
+	tokenValidation map[string]v1.TokenValidation
+	// regoPolicies maps configmap(namespace/name) -> compiled Rego modules
+	regoPolicies map[string]opa.Policy
+	// configs maps a config of a kind provided by a plugin -> its parsed form
+	configs map[policy.Dependency]interface{}
+	// dependents maps a referenced resource -> policies(namespace/name) referencing it
+	dependents map[policy.Dependency]map[string]struct{}
+	// secretReferences maps secret(namespace/name) -> oidc config ClientNames reading their client secret from it
+	secretReferences map[string]map[string]struct{}
+	// allowedNamespaces maps a jwt or oidc config -> namespaces allowed to reference it besides its own
+	allowedNamespaces map[policy.Dependency][]string
+	// clusterPolicies maps ClusterPolicy name -> selected namespaces and created endpoints
+	clusterPolicies map[string]clusterPolicy
+	// namespaceLabels maps namespace -> labels matched against ClusterPolicy selectors
+	namespaceLabels map[string]labels.Set
+	// conditionHeaders counts the policies whose conditions match each request header
+	conditionHeaders map[string]int
+	// conditionHeaderNames holds the sorted keys of conditionHeaders
+	conditionHeaderNames []string
+}
+
+// field identifies a map of a snapshot
+type field int
+
+const (
+	clientsField field = iota
+	policiesField
+	regexPoliciesField
+	policyMappingsField
+	keysetsField
+	tokenValidationField
+	regoPoliciesField
+	dependentsField
+	secretReferencesField
+	allowedNamespacesField
+	clusterPoliciesField
+	namespaceLabelsField
+	conditionHeadersField
+	configsField
+	fieldCount
+)
+
+// draft is a snapshot being modified by a writer. The maps it shares with
+// the snapshot it was copied from are copied before their first modification.
+type draft struct {
+	snapshot
+	// owned records the maps already copied by the draft
+	owned [fieldCount]bool
+}
+
+// own returns true the first time a map of the draft is modified, when it must be copied
+func (d *draft) own(f field) bool {
+	if d.owned[f] {
+		return false
+	}
+	d.owned[f] = true
+	return true
+}
+
+// regexRoute holds the actions of a regex endpoint and its pattern compiled to match whole paths
+type regexRoute struct {
+	pattern string
+	regexp  *regexp.Regexp
+	actions policy.Actions
 }
 
 // New creates a new local store
 func New() PolicyStore {
-	return &LocalStore{
-		clients:        make(map[string]client.Client),
-		policies:       make(map[policy.Service]pathtrie.Trie),
-		policyMappings: make(map[string][]policy.PolicyMapping),
-		keysets:        make(map[string]keyset.KeySet),
+	return &LocalStore{}
+}
+
+// Update applies every write of fn to a private copy of the store and publishes them at once,
+// so readers observe the store either before or after all of them. Reads within fn observe its writes.
+// The store passed to fn must not be used after fn returns.
+func (l *LocalStore) Update(fn func(store PolicyStore)) {
+	if l.tx != nil {
+		fn(l)
+		return
 	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	tx := &LocalStore{tx: &draft{snapshot: *l.load()}}
+	fn(tx)
+	tx.tx.version++
+	l.current.Store(&tx.tx.snapshot)
 }
 
-func (l *LocalStore) GetKeySet(clientName string) keyset.KeySet {
-	if l.keysets != nil {
-		return l.keysets[clientName]
+// Snapshot returns a store reading the data published when it is called.
+// Later updates are not observed, and writes to the snapshot are not published.
+func (l *LocalStore) Snapshot() PolicyStore {
+	return &LocalStore{tx: &draft{snapshot: *l.read()}}
+}
+
+// Version returns the number of updates published before the data read by the store
+func (l *LocalStore) Version() uint64 {
+	return l.read().version
+}
+
+// ConditionHeaders returns the sorted names of the request headers matched by the conditions of stored policies
+func (l *LocalStore) ConditionHeaders() []string {
+	return l.read().conditionHeaderNames
+}
+
+// read returns the snapshot read by the store
+func (l *LocalStore) read() *snapshot {
+	if l.tx != nil {
+		return &l.tx.snapshot
 	}
-	return nil
+	return l.load()
 }
 
-func (l *LocalStore) AddKeySet(clientName string, jwks keyset.KeySet) {
-	if l.keysets == nil {
-		l.keysets = make(map[string]keyset.KeySet)
+// load returns the published snapshot
+func (l *LocalStore) load() *snapshot {
+	if s, ok := l.current.Load().(*snapshot); ok {
+		return s
+	}
+	return &snapshot{}
+}
+
+// write applies fn to a draft of the store, publishing it unless the store is itself a draft
+func (l *LocalStore) write(fn func(d *draft)) {
+	if l.tx != nil {
+		fn(l.tx)
+		return
 	}
-	l.keysets[clientName] = jwks
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	d := &draft{snapshot: *l.load()}
+	fn(d)
+	d.version++
+	l.current.Store(&d.snapshot)
+}
+
+func (l *LocalStore) GetKeySet(clientName string) keyset.KeySet {
+	return l.read().keysets[clientName]
+}
+
+func (l *LocalStore) AddKeySet(clientName string, jwks keyset.KeySet) {
+	l.write(func(d *draft) {
+		d.ownKeysets()
+		d.keysets[clientName] = jwks
+	})
 }
 
 func (l *LocalStore) DeleteKeySet(clientName string) {
-	if l.keysets != nil {
-		delete(l.keysets, clientName)
+	if _, ok := l.read().keysets[clientName]; !ok {
+		return
 	}
+	l.write(func(d *draft) {
+		d.ownKeysets()
+		delete(d.keysets, clientName)
+	})
 }
 
+func (d *draft) ownKeysets() {
+	if d.own(keysetsField) {
+		keysets := make(map[string]keyset.KeySet, len(d.keysets)+1)
+		for k, v := range d.keysets {
+			keysets[k] = v
+		}
+		d.keysets = keysets
+	}
+}
 
-func (l *LocalStore) GetClient(clientName string) client.Client {
-	if l.clients != nil {
-		return l.clients[clientName]
+func (l *LocalStore) GetTokenValidation(clientName string) v1.TokenValidation {
+	return l.read().tokenValidation[clientName]
+}
+
+func (l *LocalStore) AddTokenValidation(clientName string, config v1.TokenValidation) {
+	l.write(func(d *draft) {
+		d.ownTokenValidation()
+		d.tokenValidation[clientName] = config
+	})
+}
+
+func (l *LocalStore) DeleteTokenValidation(clientName string) {
+	if _, ok := l.read().tokenValidation[clientName]; !ok {
+		return
 	}
-	return nil
+	l.write(func(d *draft) {
+		d.ownTokenValidation()
+		delete(d.tokenValidation, clientName)
+	})
 }
 
-func (l *LocalStore) AddClient(clientName string, clientObject client.Client) {
-	if l.clients == nil {
-		l.clients = make(map[string]client.Client)
+func (d *draft) ownTokenValidation() {
+	if d.own(tokenValidationField) {
+		tokenValidation := make(map[string]v1.TokenValidation, len(d.tokenValidation)+1)
+		for k, v := range d.tokenValidation {
+			tokenValidation[k] = v
+		}
+		d.tokenValidation = tokenValidation
 	}
-	l.clients[clientName] = clientObject
+}
+
+func (l *LocalStore) GetClient(clientName string) client.Client {
+	return l.read().clients[clientName]
+}
+
+func (l *LocalStore) AddClient(clientName string, clientObject client.Client) {
+	l.write(func(d *draft) {
+		d.ownClients()
+		d.clients[clientName] = clientObject
+	})
 }
 
 func (l *LocalStore) DeleteClient(clientName string) {
-	if l.clients != nil {
-		delete(l.clients, clientName)
+	l.write(func(d *draft) {
+		if _, ok := d.clients[clientName]; ok {
+			d.ownClients()
+			delete(d.clients, clientName)
+		}
+		d.setSecretReference(clientName, "")
+	})
+}
+
+func (d *draft) ownClients() {
+	if d.own(clientsField) {
+		clients := make(map[string]client.Client, len(d.clients)+1)
+		for k, v := range d.clients {
+			clients[k] = v
+		}
+		d.clients = clients
 	}
 }
 
-func (l *LocalStore) GetPolicies(endpoint policy.Endpoint) policy.RoutePolicy {
-	if l.policies != nil && l.policies[endpoint.Service] != nil {
-		actions, ok := (l.policies[endpoint.Service].GetActions(endpoint.Path)).(policy.Actions)
-		if ok {
-			result, present := actions[endpoint.Method]
-			if present { // found actions for method
-				return result
-			}
-			result, present = actions[policy.ALL]
-			if present { // check if actions are set for ALL
-				return result
+// SetSecretReference records the secret(namespace/name) an oidc config reads its client secret from,
+// replacing any previous reference. An empty secret removes the reference.
+func (l *LocalStore) SetSecretReference(clientName string, secret string) {
+	l.write(func(d *draft) {
+		d.setSecretReference(clientName, secret)
+	})
+}
+
+func (d *draft) setSecretReference(clientName string, secret string) {
+	if d.own(secretReferencesField) {
+		references := make(map[string]map[string]struct{}, len(d.secretReferences)+1)
+		for k, v := range d.secretReferences {
+			references[k] = v
+		}
+		d.secretReferences = references
+	}
+	for name, clients := range d.secretReferences {
+		if _, ok := clients[clientName]; ok {
+			if clients = withoutName(clients, clientName); len(clients) > 0 {
+				d.secretReferences[name] = clients
+			} else {
+				delete(d.secretReferences, name)
 			}
 		}
 	}
+	if secret == "" {
+		return
+	}
+	d.secretReferences[secret] = withName(d.secretReferences[secret], clientName)
+}
+
+// GetSecretReferences returns the oidc config ClientNames reading their client secret from the given secret
+func (l *LocalStore) GetSecretReferences(secret string) []string {
+	references := l.read().secretReferences[secret]
+	clients := make([]string, 0, len(references))
+	for name := range references {
+		clients = append(clients, name)
+	}
+	sort.Strings(clients)
+	return clients
+}
+
+func (l *LocalStore) GetRegoPolicy(name string) opa.Policy {
+	return l.read().regoPolicies[name]
+}
+
+func (l *LocalStore) AddRegoPolicy(name string, policy opa.Policy) {
+	l.write(func(d *draft) {
+		d.ownRegoPolicies()
+		d.regoPolicies[name] = policy
+	})
+}
+
+func (l *LocalStore) DeleteRegoPolicy(name string) {
+	if _, ok := l.read().regoPolicies[name]; !ok {
+		return
+	}
+	l.write(func(d *draft) {
+		d.ownRegoPolicies()
+		delete(d.regoPolicies, name)
+	})
+}
+
+func (d *draft) ownRegoPolicies() {
+	if d.own(regoPoliciesField) {
+		regoPolicies := make(map[string]opa.Policy, len(d.regoPolicies)+1)
+		for k, v := range d.regoPolicies {
+			regoPolicies[k] = v
+		}
+		d.regoPolicies = regoPolicies
+	}
+}
+
+func (l *LocalStore) GetConfig(config policy.Dependency) interface{} {
+	return l.read().configs[config]
+}
+
+func (l *LocalStore) AddConfig(config policy.Dependency, obj interface{}) {
+	l.write(func(d *draft) {
+		d.ownConfigs()
+		d.configs[config] = obj
+	})
+}
+
+func (l *LocalStore) DeleteConfig(config policy.Dependency) {
+	if _, ok := l.read().configs[config]; !ok {
+		return
+	}
+	l.write(func(d *draft) {
+		d.ownConfigs()
+		delete(d.configs, config)
+	})
+}
+
+func (d *draft) ownConfigs() {
+	if d.own(configsField) {
+		configs := make(map[policy.Dependency]interface{}, len(d.configs)+1)
+		for k, v := range d.configs {
+			configs[k] = v
+		}
+		d.configs = configs
+	}
+}
+
+// GetPolicies returns the policies with the highest precedence for the endpoint whose conditions match the request.
+// Policies registered for ALL methods are used if the method has none.
+// Regex endpoints are checked against the request path in a deterministic order.
+func (l *LocalStore) GetPolicies(endpoint policy.Endpoint, request policy.Request) policy.RoutePolicy {
+	for _, actions := range l.read().matchingActions(endpoint.Service, endpoint) {
+		if result, found := firstMatch(actions[endpoint.Method], request); found { // found actions for method
+			return result
+		}
+		if result, found := firstMatch(actions[policy.ALL], request); found { // check if actions are set for ALL
+			return result
+		}
+	}
 	return policy.NewRoutePolicy()
 }
 
-func (s *LocalStore) SetPolicies(endpoint policy.Endpoint, actions policy.RoutePolicy) {
-	if s.policies == nil {
-		s.policies = make(map[policy.Service]pathtrie.Trie)
+// firstMatch returns the first of the policies whose conditions match the request
+func firstMatch(policies []policy.RoutePolicy, request policy.Request) (policy.RoutePolicy, bool) {
+	for _, p := range policies {
+		if p.Conditions.Matches(request) {
+			return p, true
+		}
+	}
+	return policy.RoutePolicy{}, false
+}
+
+// ListPolicies returns every policy contributed to the endpoint, ordered by precedence
+func (l *LocalStore) ListPolicies(endpoint policy.Endpoint) []policy.RoutePolicy {
+	if actions, ok := l.read().storedActions(endpoint); ok {
+		return append([]policy.RoutePolicy(nil), actions[endpoint.Method]...)
+	}
+	return nil
+}
+
+// SetPolicies stores the policies contributed to the endpoint by actions.PolicyReference,
+// replacing any it previously contributed with the same conditions
+func (l *LocalStore) SetPolicies(endpoint policy.Endpoint, actions policy.RoutePolicy) {
+	l.write(func(d *draft) {
+		obj, _ := d.storedActions(endpoint)
+		contributed := make([]policy.RoutePolicy, 0, len(obj[endpoint.Method])+1)
+		for _, p := range obj[endpoint.Method] {
+			if p.PolicyReference != actions.PolicyReference || p.Conditions.String() != actions.Conditions.String() {
+				contributed = append(contributed, p)
+			}
+		}
+		contributed = append(contributed, actions)
+		sort.SliceStable(contributed, func(i, j int) bool {
+			return contributed[i].Precedes(contributed[j])
+		})
+		d.putActions(endpoint, withMethod(obj, endpoint.Method, contributed))
+	})
+}
+
+// DeletePolicies removes the policies contributed to the endpoint by the given policy reference
+func (l *LocalStore) DeletePolicies(endpoint policy.Endpoint, policyReference string) {
+	if obj, ok := l.read().storedActions(endpoint); !ok || !hasReference(obj[endpoint.Method], policyReference) {
+		return
+	}
+	l.write(func(d *draft) {
+		if obj, ok := d.storedActions(endpoint); ok {
+			d.putActions(endpoint, withMethod(obj, endpoint.Method, removeReference(obj[endpoint.Method], policyReference)))
+		}
+	})
+}
+
+// storedActions returns the actions stored for exactly the path or regex of the endpoint
+func (s *snapshot) storedActions(endpoint policy.Endpoint) (policy.Actions, bool) {
+	if endpoint.Regex {
+		for _, route := range s.regexPolicies[endpoint.Service] {
+			if route.pattern == endpoint.Path {
+				return route.actions, true
+			}
+		}
+		return nil, false
 	}
 	if s.policies[endpoint.Service] == nil {
-		s.policies[endpoint.Service] = pathtrie.NewPathTrie()
+		return nil, false
 	}
+	actions, ok := (s.policies[endpoint.Service].Get(endpoint.Path)).(policy.Actions)
+	return actions, ok
+}
 
-	if obj, ok := (s.policies[endpoint.Service].GetActions(endpoint.Path)).(policy.Actions); ok {
-		obj[endpoint.Method] = actions
+// putActions replaces the actions stored for the path or regex of the endpoint.
+// Regex patterns are compiled once when first stored and kept ordered from the longest pattern.
+// Endpoints left without actions are removed, so that they do not hide the broader patterns matching their path.
+func (d *draft) putActions(endpoint policy.Endpoint, obj policy.Actions) {
+	previous, stored := d.storedActions(endpoint)
+	if len(obj) == 0 && !stored {
+		return
+	}
+	if endpoint.Regex {
+		routes := make([]regexRoute, 0, len(d.regexPolicies[endpoint.Service])+1)
+		found := false
+		for _, route := range d.regexPolicies[endpoint.Service] {
+			if route.pattern == endpoint.Path {
+				found = true
+				if len(obj) == 0 {
+					continue
+				}
+				route.actions = obj
+			}
+			routes = append(routes, route)
+		}
+		if !found {
+			compiled, err := regexp.Compile("^(?:" + endpoint.Path + ")$")
+			if err != nil {
+				zap.L().Error("Could not compile path regex", zap.String("service", endpoint.Service.Name), zap.String("regex", endpoint.Path), zap.Error(err))
+				return
+			}
+			routes = append(routes, regexRoute{pattern: endpoint.Path, regexp: compiled, actions: obj})
+			sort.Slice(routes, func(i, j int) bool {
+				if len(routes[i].pattern) != len(routes[j].pattern) {
+					return len(routes[i].pattern) > len(routes[j].pattern)
+				}
+				return routes[i].pattern < routes[j].pattern
+			})
+		}
+		if d.own(regexPoliciesField) {
+			regexPolicies := make(map[policy.Service][]regexRoute, len(d.regexPolicies)+1)
+			for k, v := range d.regexPolicies {
+				regexPolicies[k] = v
+			}
+			d.regexPolicies = regexPolicies
+		}
+		if len(routes) > 0 {
+			d.regexPolicies[endpoint.Service] = routes
+		} else {
+			delete(d.regexPolicies, endpoint.Service)
+		}
+		d.countConditionHeaders(previous, obj)
+		return
+	}
+	trie := d.policies[endpoint.Service]
+	if trie == nil {
+		trie = pathtrie.NewPathTrie()
+	}
+	if d.own(policiesField) {
+		policies := make(map[policy.Service]pathtrie.Trie, len(d.policies)+1)
+		for k, v := range d.policies {
+			policies[k] = v
+		}
+		d.policies = policies
+	}
+	if len(obj) > 0 {
+		d.policies[endpoint.Service] = trie.With(endpoint.Path, obj)
 	} else {
-		obj := policy.NewActions()
-		obj[endpoint.Method] = actions
-		s.policies[endpoint.Service].Put(endpoint.Path, obj)
+		d.policies[endpoint.Service] = trie.Without(endpoint.Path)
 	}
+	d.countConditionHeaders(previous, obj)
 }
 
-func (s *LocalStore) GetPolicyMapping(policy string) []policy.PolicyMapping {
-	if s.policyMappings != nil {
-		return s.policyMappings[policy]
+// countConditionHeaders updates the request headers matched by policy conditions
+// after the actions of an endpoint are replaced
+func (d *draft) countConditionHeaders(previous policy.Actions, actions policy.Actions) {
+	delta := make(map[string]int)
+	for _, policies := range previous {
+		for _, p := range policies {
+			for _, header := range p.Conditions.Headers {
+				delta[header.Name]--
+			}
+		}
+	}
+	for _, policies := range actions {
+		for _, p := range policies {
+			for _, header := range p.Conditions.Headers {
+				delta[header.Name]++
+			}
+		}
+	}
+	changed := false
+	for _, n := range delta {
+		changed = changed || n != 0
+	}
+	if !changed {
+		return
+	}
+	if d.own(conditionHeadersField) {
+		conditionHeaders := make(map[string]int, len(d.conditionHeaders)+len(delta))
+		for k, v := range d.conditionHeaders {
+			conditionHeaders[k] = v
+		}
+		d.conditionHeaders = conditionHeaders
+	}
+	for name, n := range delta {
+		if d.conditionHeaders[name]+n > 0 {
+			d.conditionHeaders[name] += n
+		} else {
+			delete(d.conditionHeaders, name)
+		}
+	}
+	names := make([]string, 0, len(d.conditionHeaders))
+	for name := range d.conditionHeaders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	d.conditionHeaderNames = names
+}
+
+// withMethod returns a copy of the actions with the policies of the method replaced.
+// The method is removed if it has no policies.
+func withMethod(actions policy.Actions, method policy.Method, policies []policy.RoutePolicy) policy.Actions {
+	result := policy.NewActions()
+	for m, p := range actions {
+		result[m] = p
+	}
+	if len(policies) > 0 {
+		result[method] = policies
+	} else {
+		delete(result, method)
+	}
+	return result
+}
+
+// matchingActions returns the actions of the service matching the endpoint path.
+// Regex endpoints return the actions of every matching regex, in order.
+func (s *snapshot) matchingActions(service policy.Service, endpoint policy.Endpoint) []policy.Actions {
+	if endpoint.Regex {
+		var matches []policy.Actions
+		for _, route := range s.regexPolicies[service] {
+			if route.regexp.MatchString(endpoint.Path) {
+				matches = append(matches, route.actions)
+			}
+		}
+		return matches
+	}
+	if s.policies[service] == nil {
+		return nil
+	}
+	if actions, ok := (s.policies[service].GetActions(endpoint.Path)).(policy.Actions); ok {
+		return []policy.Actions{actions}
 	}
 	return nil
 }
 
-func (s *LocalStore) DeletePolicyMapping(policy string) {
-	if s.policyMappings != nil {
-		delete(s.policyMappings, policy)
+func (l *LocalStore) GetPolicyMapping(policy string) []policy.PolicyMapping {
+	return l.read().policyMappings[policy]
+}
+
+func (l *LocalStore) DeletePolicyMapping(policy string) {
+	if _, ok := l.read().policyMappings[policy]; !ok {
+		return
+	}
+	l.write(func(d *draft) {
+		d.removeDependents(policy, d.policyMappings[policy])
+		d.ownPolicyMappings()
+		delete(d.policyMappings, policy)
+	})
+}
+
+// AddPolicyMapping stores the endpoints created by a policy and tracks the resources it references
+func (l *LocalStore) AddPolicyMapping(name string, mapping []policy.PolicyMapping) {
+	l.write(func(d *draft) {
+		d.removeDependents(name, d.policyMappings[name])
+		d.ownPolicyMappings()
+		d.policyMappings[name] = mapping
+		d.ownDependents()
+		for _, dependency := range policy.Dependencies(mapping) {
+			d.dependents[dependency] = withName(d.dependents[dependency], name)
+		}
+	})
+}
+
+func (d *draft) ownPolicyMappings() {
+	if d.own(policyMappingsField) {
+		policyMappings := make(map[string][]policy.PolicyMapping, len(d.policyMappings)+1)
+		for k, v := range d.policyMappings {
+			policyMappings[k] = v
+		}
+		d.policyMappings = policyMappings
+	}
+}
+
+func (d *draft) ownDependents() {
+	if d.own(dependentsField) {
+		dependents := make(map[policy.Dependency]map[string]struct{}, len(d.dependents)+1)
+		for k, v := range d.dependents {
+			dependents[k] = v
+		}
+		d.dependents = dependents
+	}
+}
+
+// GetDependentPolicies returns the policies(namespace/name) referencing the given resource
+func (l *LocalStore) GetDependentPolicies(dependency policy.Dependency) []string {
+	dependents := l.read().dependents[dependency]
+	policies := make([]string, 0, len(dependents))
+	for name := range dependents {
+		policies = append(policies, name)
 	}
+	sort.Strings(policies)
+	return policies
+}
+
+// SetAllowedNamespaces records the namespaces allowed to reference a config. Nil removes the entry.
+func (l *LocalStore) SetAllowedNamespaces(config policy.Dependency, namespaces []string) {
+	if _, ok := l.read().allowedNamespaces[config]; !ok && namespaces == nil {
+		return
+	}
+	l.write(func(d *draft) {
+		if d.own(allowedNamespacesField) {
+			allowedNamespaces := make(map[policy.Dependency][]string, len(d.allowedNamespaces)+1)
+			for k, v := range d.allowedNamespaces {
+				allowedNamespaces[k] = v
+			}
+			d.allowedNamespaces = allowedNamespaces
+		}
+		if namespaces == nil {
+			delete(d.allowedNamespaces, config)
+			return
+		}
+		d.allowedNamespaces[config] = namespaces
+	})
+}
+
+// IsReferenceAllowed returns true if a Policy in the given namespace may reference the config
+func (l *LocalStore) IsReferenceAllowed(config policy.Dependency, namespace string) bool {
+	return policy.NamespaceAllowed(l.read().allowedNamespaces[config], config.Namespace(), namespace)
 }
 
-func (s *LocalStore) AddPolicyMapping(name string, mapping []policy.PolicyMapping) {
-	if s.policyMappings == nil {
-		s.policyMappings = make(map[string][]policy.PolicyMapping)
+// clusterPolicy holds the namespaces selected by a ClusterPolicy and the endpoints it created
+type clusterPolicy struct {
+	selector labels.Selector
+	mappings []policy.PolicyMapping
+}
+
+// SetClusterPolicy records the namespaces selected by a ClusterPolicy and the endpoints it created.
+// The policies of the endpoints are stored with SetPolicies under their ClusterService.
+func (l *LocalStore) SetClusterPolicy(name string, selector labels.Selector, mappings []policy.PolicyMapping) {
+	l.write(func(d *draft) {
+		d.ownClusterPolicies()
+		d.clusterPolicies[name] = clusterPolicy{selector: selector, mappings: mappings}
+	})
+}
+
+// GetClusterPolicyMapping returns the endpoints created by a ClusterPolicy
+func (l *LocalStore) GetClusterPolicyMapping(name string) []policy.PolicyMapping {
+	return l.read().clusterPolicies[name].mappings
+}
+
+// DeleteClusterPolicy removes a ClusterPolicy recorded with SetClusterPolicy
+func (l *LocalStore) DeleteClusterPolicy(name string) {
+	if _, ok := l.read().clusterPolicies[name]; !ok {
+		return
+	}
+	l.write(func(d *draft) {
+		d.ownClusterPolicies()
+		delete(d.clusterPolicies, name)
+	})
+}
+
+func (d *draft) ownClusterPolicies() {
+	if d.own(clusterPoliciesField) {
+		clusterPolicies := make(map[string]clusterPolicy, len(d.clusterPolicies)+1)
+		for k, v := range d.clusterPolicies {
+			clusterPolicies[k] = v
+		}
+		d.clusterPolicies = clusterPolicies
+	}
+}
+
+// SetNamespaceLabels records the labels of a namespace matched against ClusterPolicy selectors. Nil removes the entry.
+func (l *LocalStore) SetNamespaceLabels(namespace string, namespaceLabels map[string]string) {
+	if _, ok := l.read().namespaceLabels[namespace]; !ok && namespaceLabels == nil {
+		return
+	}
+	l.write(func(d *draft) {
+		if d.own(namespaceLabelsField) {
+			allLabels := make(map[string]labels.Set, len(d.namespaceLabels)+1)
+			for k, v := range d.namespaceLabels {
+				allLabels[k] = v
+			}
+			d.namespaceLabels = allLabels
+		}
+		if namespaceLabels == nil {
+			delete(d.namespaceLabels, namespace)
+			return
+		}
+		d.namespaceLabels[namespace] = labels.Set(namespaceLabels)
+	})
+}
+
+// GetClusterPolicies returns the ClusterPolicy with the highest precedence for the endpoint
+// among those selecting the namespace of its service and matching the request.
+// Policies targeting the service are used before those targeting every service,
+// and policies registered for ALL methods are used if the method has none.
+func (l *LocalStore) GetClusterPolicies(endpoint policy.Endpoint, request policy.Request) policy.RoutePolicy {
+	s := l.read()
+	namespaceLabels := s.namespaceLabels[endpoint.Service.Namespace]
+	for _, name := range []string{endpoint.Service.Name, policy.AllServices} {
+		for _, actions := range s.matchingActions(policy.ClusterService(name), endpoint) {
+			for _, method := range []policy.Method{endpoint.Method, policy.ALL} {
+				for _, p := range actions[method] {
+					if c, ok := s.clusterPolicies[p.PolicyReference]; ok && c.selector.Matches(namespaceLabels) && p.Conditions.Matches(request) {
+						return p
+					}
+				}
+			}
+		}
+	}
+	return policy.NewRoutePolicy()
+}
+
+// removeDependents stops tracking the resources referenced by the previous mapping of a policy
+func (d *draft) removeDependents(name string, mapping []policy.PolicyMapping) {
+	dependencies := policy.Dependencies(mapping)
+	if len(dependencies) == 0 {
+		return
+	}
+	d.ownDependents()
+	for _, dependency := range dependencies {
+		if dependents := withoutName(d.dependents[dependency], name); len(dependents) > 0 {
+			d.dependents[dependency] = dependents
+		} else {
+			delete(d.dependents, dependency)
+		}
+	}
+}
+
+// withName returns a copy of the set with the name added
+func withName(set map[string]struct{}, name string) map[string]struct{} {
+	result := make(map[string]struct{}, len(set)+1)
+	for k := range set {
+		result[k] = struct{}{}
+	}
+	result[name] = struct{}{}
+	return result
+}
+
+// withoutName returns a copy of the set without the name
+func withoutName(set map[string]struct{}, name string) map[string]struct{} {
+	result := make(map[string]struct{}, len(set))
+	for k := range set {
+		if k != name {
+			result[k] = struct{}{}
+		}
+	}
+	return result
+}
+
+// hasReference returns true if one of the policies was contributed by the given policy reference
+func hasReference(policies []policy.RoutePolicy, policyReference string) bool {
+	for _, p := range policies {
+		if p.PolicyReference == policyReference {
+			return true
+		}
+	}
+	return false
+}
+
+// removeReference returns a copy of policies without those contributed by the given policy reference
+func removeReference(policies []policy.RoutePolicy, policyReference string) []policy.RoutePolicy {
+	result := make([]policy.RoutePolicy, 0, len(policies)+1)
+	for _, p := range policies {
+		if p.PolicyReference != policyReference {
+			result = append(result, p)
+		}
 	}
-	s.policyMappings[name] = mapping
+	return result
 }
@@ -1,9 +1,13 @@
 package policy
 
 import (
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 
 	v1 "github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/pkg/apis/policies/v1"
 	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/policy"
@@ -16,6 +20,7 @@ const (
 	jwksurl      = "http://mockserver"
 	endpoint     = "service/path"
 	samplePolicy = "policy"
+	regoPolicy   = "ns/rego"
 )
 
 func getService() policy.Service {
@@ -27,16 +32,16 @@ func getService() policy.Service {
 
 func getActions() policy.Actions {
 	actions := policy.NewActions()
-	actions[policy.GET] = policy.RoutePolicy {
+	actions[policy.GET] = []policy.RoutePolicy{{
 		Actions: []v1.PathPolicy{
 			{PolicyType: "jwt", Config:"samplejwt"},
 		},
-	}
-	actions[policy.ALL] = policy.RoutePolicy {
+	}}
+	actions[policy.ALL] = []policy.RoutePolicy{{
 		Actions: []v1.PathPolicy{
 			{PolicyType:"oidc", Config:"sampleoidc", RedirectUri:"https://sampleapp.com"},
 		},
-	}
+	}}
 	return actions
 }
 
@@ -62,6 +67,19 @@ func TestLocalStore_KeySet(t *testing.T) {
 	keySetTest(t, New())
 }
 
+func tokenValidationTest(t *testing.T, store PolicyStore) {
+	config := v1.TokenValidation{Leeway: &metav1.Duration{Duration: time.Minute}}
+	assert.Equal(t, v1.TokenValidation{}, store.GetTokenValidation(clientname))
+	store.AddTokenValidation(clientname, config)
+	assert.Equal(t, config, store.GetTokenValidation(clientname))
+	store.DeleteTokenValidation(clientname)
+	assert.Equal(t, v1.TokenValidation{}, store.GetTokenValidation(clientname))
+}
+func TestLocalStore_TokenValidation(t *testing.T) {
+	tokenValidationTest(t, &LocalStore{})
+	tokenValidationTest(t, New())
+}
+
 func clientTest(t *testing.T, store PolicyStore) {
 	assert.Nil(t, store.GetClient(clientname))
 	store.AddClient(clientname, &fake.Client{})
@@ -72,14 +90,40 @@ func TestLocalStore_Client(t *testing.T) {
 	clientTest(t, New())
 }
 
+func regoPolicyTest(t *testing.T, store PolicyStore) {
+	assert.Nil(t, store.GetRegoPolicy(regoPolicy))
+	store.AddRegoPolicy(regoPolicy, &fake.RegoPolicy{})
+	assert.NotNil(t, store.GetRegoPolicy(regoPolicy))
+	store.DeleteRegoPolicy(regoPolicy)
+	assert.Nil(t, store.GetRegoPolicy(regoPolicy))
+}
+func TestLocalStore_RegoPolicy(t *testing.T) {
+	regoPolicyTest(t, &LocalStore{})
+	regoPolicyTest(t, New())
+}
+
+func configTest(t *testing.T, store PolicyStore) {
+	config := policy.Dependency{Kind: v1.NONE + 1, Name: "ns/apikeys"}
+	assert.Nil(t, store.GetConfig(config))
+	store.AddConfig(config, []string{"key"})
+	assert.Equal(t, []string{"key"}, store.GetConfig(config))
+	assert.Nil(t, store.GetConfig(policy.Dependency{Kind: v1.JWTCONFIG, Name: config.Name}))
+	store.DeleteConfig(config)
+	assert.Nil(t, store.GetConfig(config))
+}
+func TestLocalStore_Config(t *testing.T) {
+	configTest(t, &LocalStore{})
+	configTest(t, New())
+}
+
 func policiesTest(t *testing.T, store PolicyStore) {
-	assert.Equal(t, store.GetPolicies(getEndpoint(getService(), endpoint, policy.GET)), policy.NewRoutePolicy())
+	assert.Equal(t, store.GetPolicies(getEndpoint(getService(), endpoint, policy.GET), policy.Request{}), policy.NewRoutePolicy())
 	store.SetPolicies(getEndpoint(getService(), endpoint, policy.ALL),
 		policy.RoutePolicy{ Actions:[]v1.PathPolicy{ {PolicyType:"oidc", Config:"sampleoidc", RedirectUri:"https://sampleapp.com"}}})
 	store.SetPolicies(getEndpoint(getService(), endpoint, policy.GET),
 		policy.RoutePolicy{Actions: []v1.PathPolicy{{PolicyType: "jwt", Config:"samplejwt"}}})
-	assert.Equal(t, store.GetPolicies(getEndpoint(getService(), endpoint, policy.GET)), getActions()[policy.GET])
-	assert.Equal(t, store.GetPolicies(getEndpoint(getService(), endpoint, policy.PUT)), getActions()[policy.ALL])
+	assert.Equal(t, store.GetPolicies(getEndpoint(getService(), endpoint, policy.GET), policy.Request{}), getActions()[policy.GET][0])
+	assert.Equal(t, store.GetPolicies(getEndpoint(getService(), endpoint, policy.PUT), policy.Request{}), getActions()[policy.ALL][0])
 }
 
 func TestLocalStore_Policies(t *testing.T) {
@@ -87,6 +131,38 @@ func TestLocalStore_Policies(t *testing.T) {
 	policiesTest(t, New())
 }
 
+func policyPrecedenceTest(t *testing.T, store PolicyStore) {
+	ep := getEndpoint(getService(), endpoint, policy.GET)
+	low := policy.RoutePolicy{PolicyReference: "ns/low", Actions: []v1.PathPolicy{{PolicyType: "jwt", Config: "low"}}}
+	high := policy.RoutePolicy{PolicyReference: "ns/high", Priority: 10, Actions: []v1.PathPolicy{{PolicyType: "jwt", Config: "high"}}}
+	tie := policy.RoutePolicy{PolicyReference: "ns/a", Actions: []v1.PathPolicy{{PolicyType: "jwt", Config: "tie"}}}
+
+	store.SetPolicies(ep, low)
+	store.SetPolicies(ep, high)
+	store.SetPolicies(ep, tie)
+	assert.Equal(t, high, store.GetPolicies(ep, policy.Request{}))
+	assert.Equal(t, []policy.RoutePolicy{high, tie, low}, store.ListPolicies(ep))
+
+	// Updating a policy replaces its previous contribution
+	high.Priority = -1
+	store.SetPolicies(ep, high)
+	assert.Equal(t, []policy.RoutePolicy{tie, low, high}, store.ListPolicies(ep))
+
+	// Deleting a policy restores the next one
+	store.DeletePolicies(ep, tie.PolicyReference)
+	assert.Equal(t, low, store.GetPolicies(ep, policy.Request{}))
+	store.DeletePolicies(ep, low.PolicyReference)
+	store.DeletePolicies(ep, high.PolicyReference)
+	assert.Equal(t, policy.NewRoutePolicy(), store.GetPolicies(ep, policy.Request{}))
+	assert.Empty(t, store.ListPolicies(ep))
+	store.DeletePolicies(getEndpoint(policy.Service{Name: "missing"}, endpoint, policy.GET), low.PolicyReference)
+}
+
+func TestLocalStore_PolicyPrecedence(t *testing.T) {
+	policyPrecedenceTest(t, &LocalStore{})
+	policyPrecedenceTest(t, New())
+}
+
 func policyMappingTest(t *testing.T, store PolicyStore) {
 	assert.Nil(t, store.GetPolicyMapping(samplePolicy))
 	store.AddPolicyMapping(samplePolicy, []policy.PolicyMapping{})
@@ -98,3 +174,415 @@ func TestLocalStore_PolicyMapping(t *testing.T) {
 	policyMappingTest(t, &LocalStore{})
 	policyMappingTest(t, New())
 }
+
+func dependentPoliciesTest(t *testing.T, store PolicyStore) {
+	jwt := policy.Dependency{Kind: v1.JWTCONFIG, Name: "ns/samplejwt"}
+	oidc := policy.Dependency{Kind: v1.OIDCCONFIG, Name: "ns/sampleoidc"}
+	rego := policy.Dependency{Kind: v1.CONFIGMAP, Name: regoPolicy}
+	mapping := func(actions ...v1.PathPolicy) []policy.PolicyMapping {
+		return []policy.PolicyMapping{policy.NewPolicyMapping(getEndpoint(getService(), "/path", policy.GET), actions)}
+	}
+
+	assert.Empty(t, store.GetDependentPolicies(jwt))
+	store.AddPolicyMapping("ns/b", mapping(v1.PathPolicy{PolicyType: "jwt", Config: "samplejwt"}))
+	store.AddPolicyMapping("ns/a", mapping(v1.PathPolicy{PolicyType: "opa", Config: "samplejwt", RegoModule: "rego"}))
+	assert.Equal(t, []string{"ns/a", "ns/b"}, store.GetDependentPolicies(jwt))
+	assert.Equal(t, []string{"ns/a"}, store.GetDependentPolicies(rego))
+
+	// Updated mappings replace the previous dependencies
+	store.AddPolicyMapping("ns/a", mapping(v1.PathPolicy{PolicyType: "oidc", Config: "sampleoidc"}))
+	assert.Equal(t, []string{"ns/b"}, store.GetDependentPolicies(jwt))
+	assert.Empty(t, store.GetDependentPolicies(rego))
+	assert.Equal(t, []string{"ns/a"}, store.GetDependentPolicies(oidc))
+
+	store.DeletePolicyMapping("ns/a")
+	assert.Empty(t, store.GetDependentPolicies(oidc))
+}
+
+func TestLocalStore_DependentPolicies(t *testing.T) {
+	dependentPoliciesTest(t, &LocalStore{})
+	dependentPoliciesTest(t, New())
+}
+
+func secretReferencesTest(t *testing.T, store PolicyStore) {
+	assert.Empty(t, store.GetSecretReferences("ns/secret"))
+	store.SetSecretReference("ns/b", "ns/secret")
+	store.SetSecretReference("ns/a", "ns/secret")
+	assert.Equal(t, []string{"ns/a", "ns/b"}, store.GetSecretReferences("ns/secret"))
+
+	// A new reference replaces the previous one
+	store.SetSecretReference("ns/a", "ns/other")
+	assert.Equal(t, []string{"ns/b"}, store.GetSecretReferences("ns/secret"))
+	assert.Equal(t, []string{"ns/a"}, store.GetSecretReferences("ns/other"))
+
+	store.SetSecretReference("ns/b", "")
+	assert.Empty(t, store.GetSecretReferences("ns/secret"))
+	store.AddClient("ns/a", &fake.Client{})
+	store.DeleteClient("ns/a")
+	assert.Empty(t, store.GetSecretReferences("ns/other"))
+}
+
+func TestLocalStore_SecretReferences(t *testing.T) {
+	secretReferencesTest(t, &LocalStore{})
+	secretReferencesTest(t, New())
+}
+
+func allowedNamespacesTest(t *testing.T, store PolicyStore) {
+	config := policy.Dependency{Kind: v1.JWTCONFIG, Name: "shared/jwt"}
+	assert.True(t, store.IsReferenceAllowed(config, "shared"))
+	assert.False(t, store.IsReferenceAllowed(config, "ns"))
+
+	store.SetAllowedNamespaces(config, []string{"ns"})
+	assert.True(t, store.IsReferenceAllowed(config, "ns"))
+	assert.False(t, store.IsReferenceAllowed(config, "other"))
+	assert.False(t, store.IsReferenceAllowed(policy.Dependency{Kind: v1.OIDCCONFIG, Name: "shared/jwt"}, "ns"))
+
+	store.SetAllowedNamespaces(config, []string{"*"})
+	assert.True(t, store.IsReferenceAllowed(config, "other"))
+
+	store.SetAllowedNamespaces(config, nil)
+	assert.False(t, store.IsReferenceAllowed(config, "ns"))
+}
+
+func TestLocalStore_AllowedNamespaces(t *testing.T) {
+	allowedNamespacesTest(t, &LocalStore{})
+	allowedNamespacesTest(t, New())
+}
+
+func clusterPoliciesTest(t *testing.T, store PolicyStore) {
+	ep := getEndpoint(getService(), "/path", policy.GET)
+	assert.Equal(t, policy.NewRoutePolicy(), store.GetClusterPolicies(ep, policy.Request{}))
+
+	// A ClusterPolicy targeting every service in labeled namespaces
+	mapping := []policy.PolicyMapping{{Endpoint: getEndpoint(policy.ClusterService(policy.AllServices), "/path", policy.ALL)}}
+	store.SetPolicies(mapping[0].Endpoint, policy.RoutePolicy{PolicyReference: "all", Actions: []v1.PathPolicy{{PolicyType: "jwt", Config: "jwt"}}})
+	store.SetClusterPolicy("all", labels.SelectorFromSet(labels.Set{"env": "prod"}), mapping)
+	assert.Equal(t, mapping, store.GetClusterPolicyMapping("all"))
+	assert.Equal(t, policy.NewRoutePolicy(), store.GetClusterPolicies(ep, policy.Request{}))
+
+	store.SetNamespaceLabels("ns", map[string]string{"env": "prod"})
+	assert.Equal(t, "all", store.GetClusterPolicies(ep, policy.Request{}).PolicyReference)
+
+	// Policies targeting the service take precedence over those targeting every service
+	store.SetPolicies(getEndpoint(policy.ClusterService("sample"), "/path", policy.GET), policy.RoutePolicy{PolicyReference: "sample"})
+	store.SetClusterPolicy("sample", labels.Everything(), nil)
+	assert.Equal(t, "sample", store.GetClusterPolicies(ep, policy.Request{}).PolicyReference)
+	assert.Equal(t, "all", store.GetClusterPolicies(getEndpoint(getService(), "/path", policy.POST), policy.Request{}).PolicyReference)
+
+	// Namespace labels changing deselect the policy
+	store.DeleteClusterPolicy("sample")
+	store.SetNamespaceLabels("ns", map[string]string{})
+	assert.Equal(t, policy.NewRoutePolicy(), store.GetClusterPolicies(ep, policy.Request{}))
+	store.SetNamespaceLabels("ns", nil)
+	assert.Nil(t, store.GetClusterPolicyMapping("sample"))
+}
+
+func TestLocalStore_ClusterPolicies(t *testing.T) {
+	clusterPoliciesTest(t, &LocalStore{})
+	clusterPoliciesTest(t, New())
+}
+
+func conditionsTest(t *testing.T, store PolicyStore) {
+	ep := getEndpoint(getService(), "/path", policy.GET)
+	api := policy.RoutePolicy{PolicyReference: samplePolicy, Actions: []v1.PathPolicy{{PolicyType: "jwt", Config: "jwt"}}, Conditions: policy.NewConditions([]string{"api.example.com"}, nil, nil)}
+	web := policy.RoutePolicy{PolicyReference: samplePolicy, Actions: []v1.PathPolicy{{PolicyType: "oidc", Config: "oidc"}}}
+	store.SetPolicies(ep, web)
+	store.SetPolicies(ep, api)
+	assert.Equal(t, api, store.GetPolicies(ep, policy.Request{Host: "api.example.com"}))
+	assert.Equal(t, web, store.GetPolicies(ep, policy.Request{Host: "www.example.com"}))
+	assert.Equal(t, 2, len(store.ListPolicies(ep)))
+
+	// Policies with the same conditions are replaced
+	api.Actions = []v1.PathPolicy{{PolicyType: "opa", Config: "jwt"}}
+	store.SetPolicies(ep, api)
+	assert.Equal(t, api, store.GetPolicies(ep, policy.Request{Host: "api.example.com"}))
+	assert.Equal(t, 2, len(store.ListPolicies(ep)))
+
+	store.DeletePolicies(ep, samplePolicy)
+	assert.Equal(t, policy.NewRoutePolicy(), store.GetPolicies(ep, policy.Request{Host: "api.example.com"}))
+}
+
+func TestLocalStore_Conditions(t *testing.T) {
+	conditionsTest(t, &LocalStore{})
+	conditionsTest(t, New())
+}
+
+func regexTest(t *testing.T, store PolicyStore) {
+	jwt := policy.RoutePolicy{PolicyReference: samplePolicy, Actions: []v1.PathPolicy{{PolicyType: "jwt", Config: "jwt"}}}
+	oidc := policy.RoutePolicy{PolicyReference: samplePolicy, Actions: []v1.PathPolicy{{PolicyType: "oidc", Config: "oidc"}}}
+	users := policy.Endpoint{Service: getService(), Path: `/users/.*`, Method: policy.ALL, Regex: true}
+	orders := policy.Endpoint{Service: getService(), Path: `/users/[0-9]+/orders`, Method: policy.ALL, Regex: true}
+	store.SetPolicies(users, jwt)
+	store.SetPolicies(orders, oidc)
+	// Invalid patterns are not stored
+	store.SetPolicies(policy.Endpoint{Service: getService(), Path: `/(`, Method: policy.ALL, Regex: true}, jwt)
+
+	request := func(path string) policy.Endpoint {
+		return policy.Endpoint{Service: getService(), Path: path, Method: policy.GET, Regex: true}
+	}
+	// The longest matching pattern is used first
+	assert.Equal(t, oidc, store.GetPolicies(request("/users/1/orders"), policy.Request{}))
+	assert.Equal(t, jwt, store.GetPolicies(request("/users/a/orders"), policy.Request{}))
+	// Patterns match the whole path
+	assert.Equal(t, policy.NewRoutePolicy(), store.GetPolicies(request("/api/users/1"), policy.Request{}))
+	assert.Equal(t, policy.NewRoutePolicy(), store.GetPolicies(request("/("), policy.Request{}))
+	// Regex endpoints are separate from paths
+	assert.Equal(t, policy.NewRoutePolicy(), store.GetPolicies(getEndpoint(getService(), "/users/1", policy.GET), policy.Request{}))
+	assert.Equal(t, []policy.RoutePolicy{oidc}, store.ListPolicies(orders))
+
+	store.DeletePolicies(orders, samplePolicy)
+	assert.Equal(t, jwt, store.GetPolicies(request("/users/1/orders"), policy.Request{}))
+}
+
+func TestLocalStore_Regex(t *testing.T) {
+	regexTest(t, &LocalStore{})
+	regexTest(t, New())
+}
+
+func TestLocalStore_SetPoliciesExactPath(t *testing.T) {
+	store := New()
+	root := policy.RoutePolicy{PolicyReference: "root", Actions: []v1.PathPolicy{{PolicyType: "jwt", Config: "jwt"}}}
+	path := policy.RoutePolicy{PolicyReference: "path", Actions: []v1.PathPolicy{{PolicyType: "oidc", Config: "oidc"}}}
+	store.SetPolicies(getEndpoint(getService(), "/*", policy.ALL), root)
+	// Policies for a path are stored on the path rather than on a prefix matching it
+	store.SetPolicies(getEndpoint(getService(), "/path", policy.ALL), path)
+	assert.Equal(t, []policy.RoutePolicy{root}, store.ListPolicies(getEndpoint(getService(), "/*", policy.ALL)))
+	assert.Equal(t, path, store.GetPolicies(getEndpoint(getService(), "/path", policy.ALL), policy.Request{}))
+	assert.Equal(t, root, store.GetPolicies(getEndpoint(getService(), "/other", policy.ALL), policy.Request{}))
+}
+
+func deleteSpecificPolicyTest(t *testing.T, store PolicyStore) {
+	wildcard := policy.RoutePolicy{PolicyReference: "ns/a", Actions: []v1.PathPolicy{{PolicyType: "jwt", Config: "a"}}}
+	specific := policy.RoutePolicy{PolicyReference: "ns/b", Actions: []v1.PathPolicy{{PolicyType: "jwt", Config: "b"}}}
+	store.SetPolicies(getEndpoint(getService(), "/api/*", policy.ALL), wildcard)
+	store.SetPolicies(getEndpoint(getService(), "/api/x", policy.ALL), specific)
+	assert.Equal(t, specific, store.GetPolicies(getEndpoint(getService(), "/api/x", policy.GET), policy.Request{}))
+
+	// Deleting the only policy of a path falls back to the patterns matching it
+	store.DeletePolicies(getEndpoint(getService(), "/api/x", policy.ALL), specific.PolicyReference)
+	assert.Equal(t, wildcard, store.GetPolicies(getEndpoint(getService(), "/api/x", policy.GET), policy.Request{}))
+	assert.Equal(t, wildcard, store.GetPolicies(getEndpoint(getService(), "/api/y", policy.GET), policy.Request{}))
+	assert.Empty(t, store.ListPolicies(getEndpoint(getService(), "/api/x", policy.ALL)))
+}
+
+func TestLocalStore_DeleteSpecificPolicy(t *testing.T) {
+	deleteSpecificPolicyTest(t, &LocalStore{})
+	deleteSpecificPolicyTest(t, New())
+}
+
+func snapshotTest(t *testing.T, store PolicyStore) {
+	ep := getEndpoint(getService(), "/path", policy.ALL)
+	before := policy.RoutePolicy{PolicyReference: samplePolicy, Actions: []v1.PathPolicy{{PolicyType: "jwt", Config: "before"}}}
+	after := policy.RoutePolicy{PolicyReference: samplePolicy, Actions: []v1.PathPolicy{{PolicyType: "jwt", Config: "after"}}}
+	store.SetPolicies(ep, before)
+	store.AddKeySet(clientname, &fake.KeySet{})
+
+	snapshot := store.Snapshot()
+	store.SetPolicies(ep, after)
+	store.DeleteKeySet(clientname)
+	store.SetPolicies(getEndpoint(getService(), "/other", policy.ALL), after)
+
+	// The snapshot keeps reading the data published when it was taken
+	assert.Equal(t, before, snapshot.GetPolicies(ep, policy.Request{}))
+	assert.NotNil(t, snapshot.GetKeySet(clientname))
+	assert.Equal(t, policy.NewRoutePolicy(), snapshot.GetPolicies(getEndpoint(getService(), "/other", policy.ALL), policy.Request{}))
+	assert.Equal(t, after, store.GetPolicies(ep, policy.Request{}))
+	assert.Nil(t, store.GetKeySet(clientname))
+
+	// Writes to the snapshot are not published
+	snapshot.AddClient(clientname, &fake.Client{})
+	assert.NotNil(t, snapshot.GetClient(clientname))
+	assert.Nil(t, store.GetClient(clientname))
+}
+
+func TestLocalStore_Snapshot(t *testing.T) {
+	snapshotTest(t, &LocalStore{})
+	snapshotTest(t, New())
+}
+
+func updateTest(t *testing.T, store PolicyStore) {
+	ep := getEndpoint(getService(), "/path", policy.GET)
+	route := policy.RoutePolicy{PolicyReference: samplePolicy, Actions: []v1.PathPolicy{{PolicyType: "jwt", Config: "samplejwt"}}}
+	store.Update(func(tx PolicyStore) {
+		tx.SetPolicies(ep, route)
+		tx.AddPolicyMapping(samplePolicy, []policy.PolicyMapping{{Endpoint: ep}})
+		// Writes are observed within the update, but not published before it returns
+		assert.Equal(t, route, tx.GetPolicies(ep, policy.Request{}))
+		assert.Equal(t, policy.NewRoutePolicy(), store.GetPolicies(ep, policy.Request{}))
+		assert.Nil(t, store.GetPolicyMapping(samplePolicy))
+		// Nested updates are part of the enclosing one
+		tx.Update(func(nested PolicyStore) {
+			nested.AddKeySet(clientname, &fake.KeySet{})
+		})
+		assert.Nil(t, store.GetKeySet(clientname))
+	})
+	assert.Equal(t, route, store.GetPolicies(ep, policy.Request{}))
+	assert.Equal(t, []policy.PolicyMapping{{Endpoint: ep}}, store.GetPolicyMapping(samplePolicy))
+	assert.NotNil(t, store.GetKeySet(clientname))
+}
+
+func TestLocalStore_Update(t *testing.T) {
+	updateTest(t, &LocalStore{})
+	updateTest(t, New())
+}
+
+func versionTest(t *testing.T, store PolicyStore) {
+	ep := getEndpoint(getService(), "/path", policy.GET)
+	version := store.Version()
+	store.SetPolicies(ep, policy.RoutePolicy{PolicyReference: samplePolicy})
+	assert.Equal(t, version+1, store.Version())
+
+	// An update publishes a single version
+	snapshot := store.Snapshot()
+	store.Update(func(tx PolicyStore) {
+		tx.AddKeySet(clientname, &fake.KeySet{})
+		tx.DeletePolicies(ep, samplePolicy)
+	})
+	assert.Equal(t, version+2, store.Version())
+	assert.Equal(t, version+1, snapshot.Version())
+
+	// Deleting missing data is not an update
+	store.DeletePolicies(ep, samplePolicy)
+	store.DeleteClusterPolicy("missing")
+	assert.Equal(t, version+2, store.Version())
+}
+
+func TestLocalStore_Version(t *testing.T) {
+	versionTest(t, &LocalStore{})
+	versionTest(t, New())
+}
+
+func conditionHeadersTest(t *testing.T, store PolicyStore) {
+	exact := getEndpoint(getService(), "/path", policy.GET)
+	regex := policy.Endpoint{Service: getService(), Path: "/v[0-9]+", Method: policy.ALL, Regex: true}
+	tenant := policy.NewConditions(nil, nil, []v1.HeaderMatch{{Name: "X-Tenant", Value: "a"}})
+	both := policy.NewConditions(nil, nil, []v1.HeaderMatch{{Name: "x-tenant", Value: "b"}, {Name: "x-version"}})
+	assert.Empty(t, store.ConditionHeaders())
+
+	store.SetPolicies(exact, policy.RoutePolicy{PolicyReference: "ns/a", Conditions: tenant})
+	store.SetPolicies(regex, policy.RoutePolicy{PolicyReference: "ns/b", Conditions: both})
+	store.SetPolicies(exact, policy.RoutePolicy{PolicyReference: "ns/c"})
+	assert.Equal(t, []string{"x-tenant", "x-version"}, store.ConditionHeaders())
+
+	// Headers are tracked until no policy matches them
+	store.DeletePolicies(regex, "ns/b")
+	assert.Equal(t, []string{"x-tenant"}, store.ConditionHeaders())
+	store.SetPolicies(exact, policy.RoutePolicy{PolicyReference: "ns/a", Conditions: tenant})
+	assert.Equal(t, []string{"x-tenant"}, store.ConditionHeaders())
+	store.DeletePolicies(exact, "ns/a")
+	assert.Empty(t, store.ConditionHeaders())
+}
+
+func TestLocalStore_RemoveEmptyEndpoints(t *testing.T) {
+	store := &LocalStore{}
+	exact := getEndpoint(getService(), "/api/x", policy.ALL)
+	regex := policy.Endpoint{Service: getService(), Path: "/v[0-9]+", Method: policy.ALL, Regex: true}
+	route := policy.RoutePolicy{PolicyReference: samplePolicy, Actions: []v1.PathPolicy{{PolicyType: "jwt", Config: "jwt"}}}
+	store.SetPolicies(exact, route)
+	store.SetPolicies(regex, route)
+	snapshot := store.Snapshot()
+
+	store.DeletePolicies(exact, samplePolicy)
+	store.DeletePolicies(regex, samplePolicy)
+	assert.Nil(t, store.read().policies[getService()].Get("/api/x"))
+	assert.Empty(t, store.read().regexPolicies[getService()])
+	// Removed endpoints are not stored again when deleted
+	version := store.Version()
+	store.DeletePolicies(regex, samplePolicy)
+	assert.Equal(t, version, store.Version())
+
+	// Published snapshots keep the removed endpoints
+	assert.Equal(t, route, snapshot.GetPolicies(getEndpoint(getService(), "/api/x", policy.GET), policy.Request{}))
+	assert.Equal(t, route, snapshot.GetPolicies(policy.Endpoint{Service: getService(), Path: "/v1", Method: policy.GET, Regex: true}, policy.Request{}))
+}
+
+func TestLocalStore_ConditionHeaders(t *testing.T) {
+	conditionHeadersTest(t, &LocalStore{})
+	conditionHeadersTest(t, New())
+}
+
+func TestLocalStore_ConcurrentUpdates(t *testing.T) {
+	store := New()
+	exact := getEndpoint(getService(), "/api/items", policy.GET)
+	prefix := getEndpoint(getService(), "/api/*", policy.ALL)
+	regex := policy.Endpoint{Service: getService(), Path: "/api/[0-9]+", Method: policy.ALL, Regex: true}
+	endpoints := []policy.Endpoint{exact, prefix, regex}
+
+	// replace swaps every endpoint of the policy to the given config in a single update
+	replace := func(config string) {
+		store.Update(func(tx PolicyStore) {
+			for _, mapping := range tx.GetPolicyMapping(samplePolicy) {
+				tx.DeletePolicies(mapping.Endpoint, samplePolicy)
+			}
+			mappings := make([]policy.PolicyMapping, len(endpoints))
+			for i, ep := range endpoints {
+				tx.SetPolicies(ep, policy.RoutePolicy{PolicyReference: samplePolicy, Actions: []v1.PathPolicy{{PolicyType: "jwt", Config: config}}})
+				mappings[i] = policy.PolicyMapping{Endpoint: ep}
+			}
+			tx.AddPolicyMapping(samplePolicy, mappings)
+			tx.AddKeySet(clientname+"/"+config, &fake.KeySet{})
+			tx.DeleteKeySet(clientname + "/" + otherConfig(config))
+		})
+	}
+	replace("a")
+
+	done := make(chan struct{})
+	var readers sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				snapshot := store.Snapshot()
+				targets := []policy.Endpoint{
+					exact,
+					getEndpoint(getService(), "/api/other", policy.GET),
+					getEndpoint(getService(), "/api/42", policy.GET),
+				}
+				// Every endpoint of the snapshot is protected by the same version of the policy
+				config := ""
+				for _, target := range targets {
+					route := snapshot.GetPolicies(target, policy.Request{})
+					if !assert.Len(t, route.Actions, 1) {
+						return
+					}
+					if config == "" {
+						config = route.Actions[0].Config
+					}
+					assert.Equal(t, config, route.Actions[0].Config)
+				}
+				assert.NotNil(t, snapshot.GetKeySet(clientname+"/"+config))
+				assert.Nil(t, snapshot.GetKeySet(clientname+"/"+otherConfig(config)))
+				assert.Len(t, store.GetPolicyMapping(samplePolicy), len(endpoints))
+			}
+		}()
+	}
+
+	var writers sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		writers.Add(1)
+		go func() {
+			defer writers.Done()
+			for j := 0; j < 1000; j++ {
+				replace([]string{"a", "b"}[j%2])
+				store.SetNamespaceLabels("ns", map[string]string{"writer": "true"})
+			}
+		}()
+	}
+	writers.Wait()
+	close(done)
+	readers.Wait()
+}
+
+// otherConfig alternates between the configs used by TestLocalStore_ConcurrentUpdates
+func otherConfig(config string) string {
+	if config == "a" {
+		return "b"
+	}
+	return "a"
+}
@@ -1,23 +1,56 @@
 package policy
 
 import (
+	"k8s.io/apimachinery/pkg/labels"
+
 	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/authserver/keyset"
 	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/client"
+	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/opa"
+	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/pkg/apis/policies/v1"
 	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/policy"
 )
 
-// PolicyStore stores policy information
+// PolicyStore stores policy information. Implementations are safe for concurrent use.
 type PolicyStore interface {
+	// Update publishes the writes of fn at once
+	Update(fn func(store PolicyStore))
+	// Snapshot returns a consistent view of the store that is not affected by later updates
+	Snapshot() PolicyStore
+	// Version returns a number identifying the published data. It changes with every update.
+	Version() uint64
+	// ConditionHeaders returns the sorted names of the request headers matched by policy conditions
+	ConditionHeaders() []string
 	GetKeySet(clientName string) keyset.KeySet
 	AddKeySet(clientName string, jwks keyset.KeySet)
 	DeleteKeySet(clientName string)
+	GetTokenValidation(clientName string) v1.TokenValidation
+	AddTokenValidation(clientName string, config v1.TokenValidation)
+	DeleteTokenValidation(clientName string)
 	GetClient(clientName string) client.Client
 	AddClient(clientName string, client client.Client)
 	DeleteClient(clientName string)
-	GetPolicies(endpoint policy.Endpoint) policy.RoutePolicy
+	SetSecretReference(clientName string, secret string)
+	GetSecretReferences(secret string) []string
+	GetRegoPolicy(name string) opa.Policy
+	AddRegoPolicy(name string, policy opa.Policy)
+	DeleteRegoPolicy(name string)
+	// GetConfig returns a config of a kind provided by a plugin, stored under its kind and namespace/name key
+	GetConfig(config policy.Dependency) interface{}
+	AddConfig(config policy.Dependency, obj interface{})
+	DeleteConfig(config policy.Dependency)
+	GetPolicies(endpoint policy.Endpoint, request policy.Request) policy.RoutePolicy
+	ListPolicies(endpoint policy.Endpoint) []policy.RoutePolicy
 	SetPolicies(endpoint policy.Endpoint, actions policy.RoutePolicy)
-	// DeletePolicies(ep policy.Endpoint, obj interface{})
+	DeletePolicies(endpoint policy.Endpoint, policyReference string)
 	GetPolicyMapping(policy string) []policy.PolicyMapping
 	AddPolicyMapping(policy string, mapping []policy.PolicyMapping)
 	DeletePolicyMapping(policy string)
+	GetDependentPolicies(dependency policy.Dependency) []string
+	SetAllowedNamespaces(config policy.Dependency, namespaces []string)
+	IsReferenceAllowed(config policy.Dependency, namespace string) bool
+	SetClusterPolicy(name string, selector labels.Selector, mappings []policy.PolicyMapping)
+	GetClusterPolicyMapping(name string) []policy.PolicyMapping
+	DeleteClusterPolicy(name string)
+	GetClusterPolicies(endpoint policy.Endpoint, request policy.Request) policy.RoutePolicy
+	SetNamespaceLabels(namespace string, namespaceLabels map[string]string)
 }
@@ -12,7 +12,9 @@ import (
 	adapter "istio.io/api/mixer/adapter/model/v1beta1"
 	policy "istio.io/api/policy/v1beta1"
 	"istio.io/istio/mixer/pkg/status"
+	"k8s.io/client-go/kubernetes"
 
+	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/config"
 	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/errors"
 	adapterPolicy "github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/policy"
 	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/strategy"
@@ -28,14 +30,28 @@ const (
 // APIStrategy handles authorization requests
 type APIStrategy struct {
 	tokenUtil validator.TokenValidator
+	// options holds the global token time validation defaults
+	options validator.Options
+}
+
+func init() {
+	// jwt and opa policies share a strategy, and so its token cache
+	strategy.Register(strategy.Plugin{
+		Type:   adapterPolicy.TypeInfo{Name: "jwt"},
+		Shared: []adapterPolicy.TypeInfo{{Name: "opa"}},
+		New: func(cfg *config.Config, _ kubernetes.Interface) strategy.Strategy {
+			return New(cfg)
+		},
+	})
 }
 
 // //////////////// constructor //////////////////
 
 // New constructs a new APIStrategy used to handle API Requests
-func New() strategy.Strategy {
+func New(cfg *config.Config) strategy.Strategy {
 	return &APIStrategy{
-		tokenUtil: validator.NewTokenValidator(adapterPolicy.JWT),
+		tokenUtil: validator.NewCachedTokenValidator(validator.NewTokenValidator(adapterPolicy.JWT), cfg.TokenCacheSize),
+		options:   validator.Options{Leeway: cfg.TokenLeeway, MaxAge: cfg.TokenMaxAge},
 	}
 }
 
@@ -44,6 +60,12 @@ func New() strategy.Strategy {
 // HandleAuthorizationRequest parses and validates requests using the API Strategy
 func (s *APIStrategy) HandleAuthnZRequest(r *authnz.HandleAuthnZRequest, action *engine.Action) (*authnz.HandleAuthnZResponse, error) {
 
+	// Tokens cannot be validated without the referenced configuration
+	if action.Missing != nil {
+		zap.L().Info("Unauthorized: policy configuration is not available", zap.String("resource", action.Missing.String()))
+		return buildErrorResponse(errors.UnauthorizedHTTPException("token cannot be validated", nil)), nil
+	}
+
 	// Parse Authorization Header
 	tokens, err := getAuthTokensFromRequest(r)
 	if err != nil {
@@ -57,25 +79,63 @@ func (s *APIStrategy) HandleAuthnZRequest(r *authnz.HandleAuthnZRequest, action
 		return buildErrorResponse(err), nil
 	}
 
+	// Authorization failures are only reported once both tokens are authenticated
+	var scopeErr *errors.OAuthError
+	opts := s.options.Merge(action.TokenValidation)
+
 	// Validate Access Value
-	err = s.tokenUtil.Validate(tokens.Access, validator.Access, action.KeySet, action.Rules, "")
+	err = s.tokenUtil.Validate(tokens.Access, validator.Access, action.KeySet, action.Rules, "", opts)
 	if err != nil {
-		return validationError(err, "invalid access token")
+		if err.Code != errors.InsufficientScope {
+			return validationError(err, "invalid access token")
+		}
+		scopeErr = err
 	}
 
 	// Validate ID Value
 	if tokens.ID != "" {
-		err = s.tokenUtil.Validate(tokens.ID, validator.ID, action.KeySet, action.Rules, "")
+		err = s.tokenUtil.Validate(tokens.ID, validator.ID, action.KeySet, action.Rules, "", opts)
 		if err != nil {
-			return validationError(err, "invalid ID token")
+			if err.Code != errors.InsufficientScope {
+				return validationError(err, "invalid ID token")
+			}
+			if scopeErr == nil {
+				scopeErr = err
+			}
 		}
 	}
 
+	if scopeErr != nil {
+		return validationError(scopeErr, "insufficient scope")
+	}
+
+	// Evaluate policy expression
+	err = strategy.EvaluateExpression(action, tokens, r.Instance.Request, r.Instance.Target)
+	if err != nil {
+		return validationError(err, "access denied by policy expression")
+	}
+
+	// Evaluate Rego policy
+	err = strategy.EvaluateRego(action, tokens, r.Instance.Request, r.Instance.Target)
+	if err != nil {
+		return validationError(err, "access denied by rego policy")
+	}
+
 	zap.L().Info("Authorized: found valid authorization header")
 
-	return &authnz.HandleAuthnZResponse{
+	response := &authnz.HandleAuthnZResponse{
 		Result: &adapter.CheckResult{Status: status.OK},
-	}, nil
+	}
+
+	// Forward configured claims to the target service
+	if headers := strategy.ClaimHeaders(action, tokens); headers != nil {
+		response.Output = &authnz.OutputMsg{
+			Authorization: r.Instance.Request.Headers.Authorization,
+			Headers:       headers,
+		}
+	}
+
+	return response, nil
 }
 
 // //////////////// utilities //////////////////
@@ -120,17 +180,26 @@ func buildErrorResponse(err *errors.OAuthError) *authnz.HandleAuthnZResponse {
 		if scopes != "" {
 			header += ", scopes=\"" + scopes + "\""
 		}
-		if err.Code != "" {
+		// Only the errors defined by RFC 6750 are reported, so clients are not told to re-authenticate or
+		// request scopes when a policy denies the request
+		if err.BearerError() {
 			header += ", error=\"" + err.Code + "\""
 		}
 		if err.Msg != "" {
 			header += ", error_description=\"" + err.Msg + "\""
 		}
 	}
+	// Authenticated requests lacking the required scopes or denied by a policy are forbidden rather than unauthenticated
+	code := rpc.UNAUTHENTICATED
+	if err.Code == errors.InsufficientScope || err.Code == errors.AccessDenied {
+		code = rpc.PERMISSION_DENIED
+	} else if err.Code == errors.InternalServerError {
+		code = rpc.INTERNAL
+	}
 	return &authnz.HandleAuthnZResponse{
 		Result: &adapter.CheckResult{
 			Status: rpc.Status{
-				Code:    int32(rpc.UNAUTHENTICATED), // Response tells Mixer to reject request
+				Code:    int32(code), // Response tells Mixer to reject request
 				Message: err.Msg,
 				Details: []*types.Any{status.PackErrorDetail(&policy.DirectHttpResponse{
 					Code:    err.HTTPCode(), // Response Mixer remaps on request
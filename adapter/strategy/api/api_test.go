@@ -1,25 +1,46 @@
 package apistrategy
 
 import (
+	"crypto"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/expression"
 	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/pkg/apis/policies/v1"
+	adapterPolicy "github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/policy"
 	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/policy/engine"
+	adapterStrategy "github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/strategy"
 	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/validator"
 
 	"testing"
+	"time"
 
 	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/authserver/keyset"
+	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/config"
 	"github.com/ibm-cloud-security/app-identity-and-access-adapter/config/template"
 
+	"github.com/dgrijalva/jwt-go/v4"
 	"github.com/gogo/googleapis/google/rpc"
+	"github.com/gogo/protobuf/types"
 	"github.com/stretchr/testify/assert"
 	"istio.io/api/policy/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/errors"
+	"github.com/ibm-cloud-security/app-identity-and-access-adapter/tests/fake"
 )
 
 func TestNew(t *testing.T) {
-	strategy := New()
+	strategy := New(&config.Config{TokenLeeway: time.Minute})
 	assert.NotNil(t, strategy)
+	assert.Equal(t, validator.Options{Leeway: time.Minute}, strategy.(*APIStrategy).options)
+}
+
+func TestRegisteredStrategy(t *testing.T) {
+	strategies := adapterStrategy.NewStrategies(config.NewConfig(), nil)
+	assert.IsType(t, &APIStrategy{}, strategies[adapterPolicy.JWT])
+	// jwt and opa policies share the token cache of a single strategy
+	assert.True(t, strategies[adapterPolicy.JWT] == strategies[adapterPolicy.OPA])
 }
 
 func TestHandleAuthorizationRequest(t *testing.T) {
@@ -71,6 +92,78 @@ func TestHandleAuthorizationRequest(t *testing.T) {
 			"id",
 			errors.UnauthorizedHTTPException("invalid id token", nil),
 		},
+		{
+			generateAuthRequest("Bearer access id"),
+			&engine.Action{},
+			"insufficient scope",
+			int32(7),
+			"access",
+			errors.ForbiddenHTTPException("insufficient scope", []string{"read"}),
+		},
+		{
+			generateAuthRequest("Bearer access id"),
+			&engine.Action{},
+			"insufficient scope",
+			int32(7),
+			"id",
+			errors.ForbiddenHTTPException("insufficient scope", []string{"read"}),
+		},
+		{
+			generateAuthRequest("Bearer access id"),
+			&engine.Action{Expression: generateExpression(`request.path == ""`)},
+			"",
+			int32(0),
+			"",
+			nil,
+		},
+		{
+			generateAuthRequest("Bearer access id"),
+			&engine.Action{Expression: generateExpression(`request.path == "/admin"`)},
+			"access denied by policy expression",
+			int32(7),
+			"",
+			nil,
+		},
+		{
+			generateAuthRequest("Bearer access id"),
+			&engine.Action{Expression: generateExpression(`claims.missing == "value"`)},
+			"access denied by policy expression",
+			int32(13),
+			"",
+			nil,
+		},
+		{
+			generateAuthRequest("Bearer access id"),
+			&engine.Action{Rego: &fake.RegoPolicy{Allowed: true}},
+			"",
+			int32(0),
+			"",
+			nil,
+		},
+		{
+			generateAuthRequest("Bearer access id"),
+			&engine.Action{Rego: &fake.RegoPolicy{Allowed: false}},
+			"access denied by rego policy",
+			int32(7),
+			"",
+			nil,
+		},
+		{
+			generateAuthRequest("Bearer access id"),
+			&engine.Action{Rego: &fake.RegoPolicy{Err: fmt.Errorf("eval failed")}},
+			"access denied by rego policy",
+			int32(13),
+			"",
+			nil,
+		},
+		{
+			generateAuthRequest("Bearer access id"),
+			&engine.Action{Missing: &adapterPolicy.Dependency{Kind: v1.JWTCONFIG, Name: "ns/missing"}},
+			"token cannot be validated",
+			int32(16),
+			"",
+			nil,
+		},
 	}
 
 	for _, t_ := range tests {
@@ -91,6 +184,40 @@ func TestHandleAuthorizationRequest(t *testing.T) {
 	}
 }
 
+func TestValidationOptions(t *testing.T) {
+	var opts validator.Options
+	api := APIStrategy{
+		tokenUtil: MockValidator{opts: &opts},
+		options:   validator.Options{Leeway: time.Second, MaxAge: time.Hour},
+	}
+	action := &engine.Action{
+		TokenValidation: v1.TokenValidation{Leeway: &metav1.Duration{Duration: time.Minute}},
+	}
+	checkresult, err := api.HandleAuthnZRequest(generateAuthRequest("Bearer access"), action)
+	assert.Nil(t, err)
+	assert.Equal(t, int32(rpc.OK), checkresult.Result.Status.Code)
+	assert.Equal(t, validator.Options{Leeway: time.Minute, MaxAge: time.Hour}, opts)
+}
+
+func TestClaimHeaders(t *testing.T) {
+	access, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"sub": "user"}).SignedString([]byte("secret"))
+	api := APIStrategy{tokenUtil: MockValidator{}}
+
+	// Output is only set when headers are configured
+	checkresult, err := api.HandleAuthnZRequest(generateAuthRequest("Bearer "+access), &engine.Action{})
+	assert.Nil(t, err)
+	assert.Nil(t, checkresult.Output)
+
+	action := &engine.Action{
+		PathPolicy: v1.PathPolicy{Headers: []v1.ClaimHeader{{Claim: "sub", Header: "x-user-id"}}},
+	}
+	checkresult, err = api.HandleAuthnZRequest(generateAuthRequest("Bearer "+access), action)
+	assert.Nil(t, err)
+	assert.Equal(t, int32(rpc.OK), checkresult.Result.Status.Code)
+	assert.Equal(t, "Bearer "+access, checkresult.Output.Authorization)
+	assert.Equal(t, map[string]string{"x-user-id": "user"}, checkresult.Output.Headers)
+}
+
 func TestParseRequest(t *testing.T) {
 	var tests = []struct {
 		r           *authnz.HandleAuthnZRequest
@@ -154,9 +281,10 @@ func TestErrorResponse(t *testing.T) {
 	var tests = []struct {
 		err          *errors.OAuthError
 		Message      string
+		Code         rpc.Code
 		ResponseCode v1beta1.HttpStatusCode
 		Body         string
-		HeadersMap   map[string]string
+		Header       string
 	}{
 		{
 			err: &errors.OAuthError{
@@ -165,17 +293,47 @@ func TestErrorResponse(t *testing.T) {
 				Scopes: []string{"scope"},
 			},
 			Message:      "missing header",
+			Code:         rpc.UNAUTHENTICATED,
 			ResponseCode: v1beta1.Unauthorized,
-			Body:         errors.InvalidToken,
-			HeadersMap:   make(map[string]string),
+			Body:         errors.Unauthorized,
+			Header:       "Bearer realm=\"token\", scopes=\"scope\", error=\"invalid_token\", error_description=\"missing header\"",
+		},
+		{
+			err:          errors.ForbiddenHTTPException("insufficient scope", []string{"read", "write"}),
+			Message:      "insufficient scope",
+			Code:         rpc.PERMISSION_DENIED,
+			ResponseCode: v1beta1.Forbidden,
+			Body:         errors.Forbidden,
+			Header:       "Bearer realm=\"token\", scopes=\"read write\", error=\"insufficient_scope\", error_description=\"insufficient scope\"",
+		},
+		{
+			err:          errors.AccessDeniedHTTPException("access denied by policy expression"),
+			Message:      "access denied by policy expression",
+			Code:         rpc.PERMISSION_DENIED,
+			ResponseCode: v1beta1.Forbidden,
+			Body:         errors.Forbidden,
+			Header:       "Bearer realm=\"token\", error_description=\"access denied by policy expression\"",
+		},
+		{
+			err:          errors.InternalServerErrorHTTPException("could not evaluate policy expression"),
+			Message:      "could not evaluate policy expression",
+			Code:         rpc.INTERNAL,
+			ResponseCode: v1beta1.InternalServerError,
+			Body:         errors.InternalServerError,
+			Header:       "Bearer realm=\"token\", error_description=\"could not evaluate policy expression\"",
 		},
 	}
 	for _, test := range tests {
 		t.Run("Error Parse", func(st *testing.T) {
 			checkresult := buildErrorResponse(test.err)
-			assert.Equal(st, checkresult.Result.Status.Code, int32(rpc.UNAUTHENTICATED))
+			assert.Equal(st, checkresult.Result.Status.Code, int32(test.Code))
 			assert.Equal(st, checkresult.Result.Status.Message, test.Message)
-			assert.NotNil(st, checkresult.Result.Status.Details)
+			assert.Equal(st, 1, len(checkresult.Result.Status.Details))
+			response := &v1beta1.DirectHttpResponse{}
+			assert.Nil(st, types.UnmarshalAny(checkresult.Result.Status.Details[0], response))
+			assert.Equal(st, test.ResponseCode, response.Code)
+			assert.Equal(st, test.Body, response.Body)
+			assert.Equal(st, test.Header, response.Headers[wwwAuthenticate])
 		})
 	}
 }
@@ -192,14 +350,80 @@ func generateAuthRequest(header string) *authnz.HandleAuthnZRequest {
 	}
 }
 
+func generateExpression(source string) expression.Program {
+	program, _ := expression.Compile(source)
+	return program
+}
+
 type MockValidator struct {
 	invalidToken string
 	err          *errors.OAuthError
+	opts         *validator.Options
 }
 
-func (v MockValidator) Validate(tkn string, tokenType validator.Token, ks keyset.KeySet, rules []v1.Rule, userInfoEndpoint string) *errors.OAuthError {
+func (v MockValidator) Validate(tkn string, tokenType validator.Token, ks keyset.KeySet, rules []v1.Rule, userInfoEndpoint string, opts validator.Options) *errors.OAuthError {
+	if v.opts != nil {
+		*v.opts = opts
+	}
 	if tkn == v.invalidToken {
 		return v.err
 	}
 	return nil
 }
+
+// rsaKeySet serves the public key of the test signing key
+type rsaKeySet struct{ key crypto.PublicKey }
+
+func (k *rsaKeySet) PublicKeyURL() string                  { return "https://keys.com/publickeys" }
+func (k *rsaKeySet) PublicKey(kid string) crypto.PublicKey { return k.key }
+func (k *rsaKeySet) SyncError() error                      { return nil }
+func (k *rsaKeySet) Generation() uint64                    { return 1 }
+func (k *rsaKeySet) OnSync(handler func(err error))        {}
+
+// benchmarkHandleAuthnZRequest validates requests signed with the test key, spread over the given number of tokens
+func benchmarkHandleAuthnZRequest(b *testing.B, cacheSize int, tokens int) {
+	privateData, _ := ioutil.ReadFile("../../../tests/keys/key.private")
+	privateKey, err := jwt.ParseRSAPrivateKeyFromPEM(privateData)
+	if err != nil {
+		b.Fatal(err)
+	}
+	publicData, _ := ioutil.ReadFile("../../../tests/keys/key.pub")
+	publicKey, err := jwt.ParseRSAPublicKeyFromPEM(publicData)
+	if err != nil {
+		b.Fatal(err)
+	}
+	requests := make([]*authnz.HandleAuthnZRequest, tokens)
+	for i := range requests {
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+			"sub":   fmt.Sprintf("user%d", i),
+			"aud":   "client",
+			"scope": "read write",
+			"exp":   time.Now().Add(time.Hour).Unix(),
+		})
+		token.Header["kid"] = "kid"
+		signed, err := token.SignedString(privateKey)
+		if err != nil {
+			b.Fatal(err)
+		}
+		requests[i] = generateAuthRequest("Bearer " + signed)
+	}
+	api := New(&config.Config{TokenCacheSize: cacheSize})
+	action := &engine.Action{
+		PathPolicy: v1.PathPolicy{
+			Rules: []v1.Rule{{Claim: "aud", Match: "ANY", Values: []string{"client"}}, {Claim: "scope", Values: []string{"read"}}},
+		},
+		KeySet: &rsaKeySet{key: publicKey},
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		response, err := api.HandleAuthnZRequest(requests[i%tokens], action)
+		if err != nil || response.Result.Status.Code != int32(rpc.OK) {
+			b.Fatal(err, response.Result.Status)
+		}
+	}
+}
+
+func BenchmarkHandleAuthnZRequest_Uncached(b *testing.B) { benchmarkHandleAuthnZRequest(b, 0, 100) }
+func BenchmarkHandleAuthnZRequest_Cached(b *testing.B)   { benchmarkHandleAuthnZRequest(b, 1024, 100) }
@@ -0,0 +1,96 @@
+package strategy
+
+import (
+	"fmt"
+	"sync"
+
+	"go.uber.org/zap"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/config"
+	v1 "github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/pkg/apis/policies/v1"
+	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/policy"
+	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/policy/engine"
+)
+
+// Factory creates a strategy from the adapter configuration
+type Factory func(cfg *config.Config, kubeClient kubernetes.Interface) Strategy
+
+// Plugin describes a strategy and the policy type it enforces
+type Plugin struct {
+	// Type describes the policy type. Only the name is used for the built-in types.
+	// Types without a config must set the ConfigKind to v1.NONE.
+	Type policy.TypeInfo
+	// Shared describes further policy types enforced by the same strategy.
+	// A single strategy is created for all the types of a plugin, so they share its state, such as its token cache.
+	Shared []policy.TypeInfo
+	// Config resolves the configs referenced by policies of the types from the policy store.
+	// It is required for new types referencing a config.
+	Config engine.ConfigAccessor
+	// New creates the strategy
+	New Factory
+}
+
+// plugin is a registered strategy and the types it enforces
+type plugin struct {
+	types []policy.Type
+	new   Factory
+}
+
+var (
+	pluginsMu sync.RWMutex
+	// plugins holds the registered strategies
+	plugins []plugin
+	// registered holds the types with a registered strategy
+	registered = make(map[policy.Type]struct{})
+)
+
+// Register adds a strategy for the policy types named by the plugin, and returns the type of Plugin.Type.
+// New policy types are registered along with their config accessor, so that Policies may use them
+// without changes to the adapter, engine or handlers. Plugins usually register from an init function.
+// It panics if a strategy is already registered for one of the types.
+func Register(p Plugin) policy.Type {
+	if p.New == nil {
+		panic(fmt.Sprintf("strategy: plugin %q does not create a strategy", p.Type.Name))
+	}
+	pluginsMu.Lock()
+	defer pluginsMu.Unlock()
+	infos := append([]policy.TypeInfo{p.Type}, p.Shared...)
+	for _, info := range infos {
+		if _, ok := registered[policy.NewType(info.Name)]; ok {
+			panic(fmt.Sprintf("strategy: a strategy is already registered for type %q", info.Name))
+		}
+		if policy.NewType(info.Name) == policy.NONE && info.ConfigKind != v1.NONE && p.Config == nil {
+			panic(fmt.Sprintf("strategy: plugin %q does not resolve its config", info.Name))
+		}
+	}
+	types := make([]policy.Type, len(infos))
+	for i, info := range infos {
+		t := policy.NewType(info.Name)
+		if t == policy.NONE {
+			t = policy.RegisterType(info)
+		}
+		if p.Config != nil {
+			engine.RegisterConfigAccessor(t, p.Config)
+		}
+		registered[t] = struct{}{}
+		types[i] = t
+	}
+	plugins = append(plugins, plugin{types: types, new: p.New})
+	return types[0]
+}
+
+// NewStrategies creates the strategies of every registered policy type
+func NewStrategies(cfg *config.Config, kubeClient kubernetes.Interface) map[policy.Type]Strategy {
+	pluginsMu.RLock()
+	defer pluginsMu.RUnlock()
+	strategies := make(map[policy.Type]Strategy, len(registered))
+	for _, p := range plugins {
+		zap.L().Debug("Creating strategy", zap.String("type", p.types[0].String()))
+		s := p.new(cfg, kubeClient)
+		for _, t := range p.types {
+			strategies[t] = s
+		}
+	}
+	return strategies
+}
@@ -0,0 +1,70 @@
+package strategy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/config"
+	v1 "github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/pkg/apis/policies/v1"
+	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/policy"
+	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/policy/engine"
+	"github.com/ibm-cloud-security/app-identity-and-access-adapter/config/template"
+)
+
+// apiKeyStrategy accepts requests with an API key
+type apiKeyStrategy struct {
+	cfg *config.Config
+}
+
+func (s *apiKeyStrategy) HandleAuthnZRequest(*authnz.HandleAuthnZRequest, *engine.Action) (*authnz.HandleAuthnZResponse, error) {
+	return nil, nil
+}
+
+func newAPIKeyStrategy(cfg *config.Config, _ kubernetes.Interface) Strategy {
+	return &apiKeyStrategy{cfg: cfg}
+}
+
+var (
+	apiKeyType = Register(Plugin{
+		Type: policy.TypeInfo{Name: "apikey", ConfigKind: v1.NONE, Authorization: true},
+		New:  newAPIKeyStrategy,
+	})
+	// headerKeyType shares its strategy with queryKeyType
+	headerKeyType = Register(Plugin{
+		Type:   policy.TypeInfo{Name: "headerkey", ConfigKind: v1.NONE},
+		Shared: []policy.TypeInfo{{Name: "querykey", ConfigKind: v1.NONE}},
+		New:    newAPIKeyStrategy,
+	})
+)
+
+func TestRegister(t *testing.T) {
+	assert.Equal(t, apiKeyType, policy.NewType("apikey"))
+	assert.True(t, apiKeyType.Info().Authorization)
+
+	// Each type has a single strategy
+	assert.Panics(t, func() { Register(Plugin{Type: policy.TypeInfo{Name: "apikey"}, New: newAPIKeyStrategy}) })
+	// Plugins create a strategy and resolve the configs they reference
+	assert.Panics(t, func() { Register(Plugin{Type: policy.TypeInfo{Name: "nostrategy", ConfigKind: v1.NONE}}) })
+	assert.Panics(t, func() {
+		Register(Plugin{Type: policy.TypeInfo{Name: "noconfig", ConfigKind: v1.JWTCONFIG}, New: newAPIKeyStrategy})
+	})
+	assert.Equal(t, policy.NONE, policy.NewType("noconfig"))
+	// Shared types are checked before any type is registered
+	assert.Panics(t, func() {
+		Register(Plugin{Type: policy.TypeInfo{Name: "otherkey", ConfigKind: v1.NONE}, Shared: []policy.TypeInfo{{Name: "querykey"}}, New: newAPIKeyStrategy})
+	})
+	assert.Equal(t, policy.NONE, policy.NewType("otherkey"))
+}
+
+func TestNewStrategies(t *testing.T) {
+	cfg := config.NewConfig()
+	strategies := NewStrategies(cfg, nil)
+	assert.Equal(t, &apiKeyStrategy{cfg: cfg}, strategies[apiKeyType])
+	// Types registered by a plugin share a single strategy
+	assert.True(t, strategies[headerKeyType] == strategies[policy.NewType("querykey")])
+	assert.False(t, strategies[headerKeyType] == strategies[apiKeyType])
+	// The built-in strategies register from their own packages
+	assert.Nil(t, strategies[policy.JWT])
+}
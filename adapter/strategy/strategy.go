@@ -1,14 +1,28 @@
 package strategy
 
 import (
+	"encoding/json"
 	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
 
+	"github.com/dgrijalva/jwt-go/v4"
+	"go.uber.org/zap"
 	policy "istio.io/api/policy/v1beta1"
 
+	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/errors"
+	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/expression"
+	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/opa"
 	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/policy/engine"
+	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/validator"
 	"github.com/ibm-cloud-security/app-identity-and-access-adapter/config/template"
 )
 
+// maxHeaderLength bounds the length of a forwarded claim header value
+const maxHeaderLength = 4096
+
 // Strategy defines the entry point to an authentication handler
 type Strategy interface {
 	HandleAuthnZRequest(*authnz.HandleAuthnZRequest, *engine.Action) (*authnz.HandleAuthnZResponse, error)
@@ -38,3 +52,177 @@ func decodeValue(in interface{}) interface{} {
 		return fmt.Sprintf("%v", in)
 	}
 }
+
+// EvaluateExpression evaluates the action expression against the claims of previously validated tokens.
+// Access token claims are used unless the access token is opaque, in which case the ID token claims are used.
+// Returns nil if the action does not configure an expression or the expression evaluates to true,
+// an Access Denied error if it evaluates to false and an Internal Server Error if it cannot be evaluated.
+func EvaluateExpression(action *engine.Action, tokens *validator.RawTokens, request *authnz.RequestMsg, target *authnz.TargetMsg) *errors.OAuthError {
+	if action == nil || action.Expression == nil {
+		return nil
+	}
+
+	input := expression.Input{
+		Claims: tokenClaims(tokens),
+		Request: expression.Request{
+			Path:    request.Path,
+			Headers: make(map[string]string),
+		},
+	}
+	if target != nil {
+		input.Request.Method = target.Method
+		input.Target = expression.Target{Namespace: target.Namespace, Service: target.Service}
+	}
+	if request.Headers != nil {
+		input.Request.Headers = decodeStringMap(request.Headers.Properties)
+	}
+
+	ok, err := action.Expression.Eval(input)
+	if err != nil {
+		zap.L().Warn("Could not evaluate policy expression", zap.Error(err))
+		return errors.InternalServerErrorHTTPException("could not evaluate policy expression")
+	}
+	if !ok {
+		return errors.AccessDeniedHTTPException("policy expression evaluated to false")
+	}
+	return nil
+}
+
+// EvaluateRego evaluates the action Rego query against the claims of previously validated tokens
+// and the incoming request. Returns nil if the action does not configure Rego modules or the query
+// evaluates to true, an Access Denied error if it evaluates to false and an Internal Server Error if
+// it cannot be evaluated.
+func EvaluateRego(action *engine.Action, tokens *validator.RawTokens, request *authnz.RequestMsg, target *authnz.TargetMsg) *errors.OAuthError {
+	if action == nil || action.Rego == nil {
+		return nil
+	}
+
+	input := opa.Input{
+		Claims: tokenClaims(tokens),
+		Request: opa.Request{
+			Scheme:  request.Scheme,
+			Host:    request.Host,
+			Path:    request.Path,
+			Headers: make(map[string]string),
+			Params:  make(map[string]string),
+		},
+	}
+	if target != nil {
+		input.Request.Method = target.Method
+		input.Target = opa.Target{Namespace: target.Namespace, Service: target.Service}
+	}
+	if request.Headers != nil {
+		input.Request.Headers = decodeStringMap(request.Headers.Properties)
+	}
+	if request.Params != nil {
+		input.Request.Params = decodeStringMap(request.Params.Properties)
+	}
+
+	allowed, err := action.Rego.Eval(action.RegoQuery, input)
+	if err != nil {
+		zap.L().Warn("Could not evaluate Rego policy", zap.Error(err))
+		return errors.InternalServerErrorHTTPException("could not evaluate rego policy")
+	}
+	if !allowed {
+		return errors.AccessDeniedHTTPException("rego policy denied request")
+	}
+	return nil
+}
+
+// ClaimHeaders maps the claims of previously validated tokens onto the headers configured by the action.
+// Header names are lower cased and values are sanitized. Headers whose claim is missing are set to an
+// empty value so that they replace any value sent by the client. Returns nil if no headers are configured.
+func ClaimHeaders(action *engine.Action, tokens *validator.RawTokens) map[string]string {
+	if action == nil || len(action.Headers) == 0 {
+		return nil
+	}
+
+	claims := map[string]map[string]interface{}{
+		"":                        tokenClaims(tokens),
+		validator.Access.String(): parseClaims(tokens.Access),
+		validator.ID.String():     parseClaims(tokens.ID),
+	}
+
+	headers := make(map[string]string, len(action.Headers))
+	for _, header := range action.Headers {
+		if err := validator.ValidateHeader(header); err != nil {
+			zap.L().Debug("Skipping invalid claim header", zap.String("header", header.Header), zap.Error(err))
+			continue
+		}
+		headers[strings.ToLower(header.Header)] = sanitizeHeaderValue(headerValue(claims[header.Source][header.Claim]))
+	}
+	return headers
+}
+
+// headerValue formats a claim as a header value. Arrays are joined using commas.
+func headerValue(claim interface{}) string {
+	switch v := claim.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case json.Number:
+		return v.String()
+	case []interface{}:
+		values := make([]string, len(v))
+		for i, value := range v {
+			values[i] = headerValue(value)
+		}
+		return strings.Join(values, ",")
+	default:
+		out, err := json.Marshal(v)
+		if err != nil {
+			return ""
+		}
+		return string(out)
+	}
+}
+
+// sanitizeHeaderValue strips control characters and invalid UTF-8 to prevent header injection
+// and truncates the value to maxHeaderLength bytes
+func sanitizeHeaderValue(value string) string {
+	var b strings.Builder
+	for _, r := range value {
+		if r == utf8.RuneError || unicode.IsControl(r) {
+			continue
+		}
+		if b.Len()+utf8.RuneLen(r) > maxHeaderLength {
+			break
+		}
+		b.WriteRune(r)
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// tokenClaims returns the claims of the access token, or of the ID token if the access token is opaque.
+// Token signatures have already been verified by the token validator.
+func tokenClaims(tokens *validator.RawTokens) map[string]interface{} {
+	for _, token := range []string{tokens.Access, tokens.ID} {
+		if claims := parseClaims(token); claims != nil {
+			return claims
+		}
+	}
+	return make(map[string]interface{})
+}
+
+// parseClaims returns the claims of the given token, or nil if the token is not a JWT
+func parseClaims(token string) map[string]interface{} {
+	claims := jwt.MapClaims{}
+	if _, _, err := new(jwt.Parser).ParseUnverified(token, claims); err != nil {
+		return nil
+	}
+	return claims
+}
+
+// decodeStringMap decodes gRPC values into a string map
+func decodeStringMap(in map[string]*policy.Value) map[string]string {
+	out := make(map[string]string, len(in))
+	for k, v := range DecodeValueMap(in) {
+		out[k] = fmt.Sprintf("%v", v)
+	}
+	return out
+}
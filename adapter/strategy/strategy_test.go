@@ -1,10 +1,21 @@
 package strategy
 
 import (
+	"fmt"
+	"strings"
 	"testing"
 
+	"github.com/dgrijalva/jwt-go/v4"
 	"github.com/stretchr/testify/assert"
 	"istio.io/api/policy/v1beta1"
+
+	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/errors"
+	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/opa"
+	v1 "github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/pkg/apis/policies/v1"
+	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/policy/engine"
+	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/validator"
+	"github.com/ibm-cloud-security/app-identity-and-access-adapter/config/template"
+	"github.com/ibm-cloud-security/app-identity-and-access-adapter/tests/fake"
 )
 
 func TestDecodeValueMap(t *testing.T) {
@@ -18,6 +29,95 @@ func TestDecodeValueMap(t *testing.T) {
 
 }
 
+func TestEvaluateRego(t *testing.T) {
+	token, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"sub": "user"}).SignedString([]byte("secret"))
+	tokens := &validator.RawTokens{Access: token}
+	request := &authnz.RequestMsg{
+		Scheme:  "https",
+		Host:    "example.com",
+		Path:    "/admin",
+		Headers: &authnz.HeadersMsg{Properties: generateMap()},
+		Params:  &authnz.QueryParamsMsg{Properties: generateMap()},
+	}
+	target := &authnz.TargetMsg{Namespace: "ns", Service: "svc", Method: "GET"}
+
+	// No rego policy
+	assert.Nil(t, EvaluateRego(&engine.Action{}, tokens, request, target))
+
+	// Allowed
+	rego := &fake.RegoPolicy{Allowed: true}
+	assert.Nil(t, EvaluateRego(&engine.Action{PathPolicy: v1.PathPolicy{RegoQuery: "data.custom.allow"}, Rego: rego}, tokens, request, target))
+	assert.Equal(t, "data.custom.allow", rego.Query)
+	assert.Equal(t, "user", rego.Input.Claims["sub"])
+	assert.Equal(t, opa.Target{Namespace: "ns", Service: "svc"}, rego.Input.Target)
+	assert.Equal(t, "GET", rego.Input.Request.Method)
+	assert.Equal(t, "https", rego.Input.Request.Scheme)
+	assert.Equal(t, "example.com", rego.Input.Request.Host)
+	assert.Equal(t, "/admin", rego.Input.Request.Path)
+	assert.Equal(t, "Value_StringValue", rego.Input.Request.Headers["Value_StringValue"])
+	assert.Equal(t, "1", rego.Input.Request.Params["Value_Int64Value"])
+
+	// Denied
+	err := EvaluateRego(&engine.Action{Rego: &fake.RegoPolicy{}}, tokens, request, target)
+	assert.Equal(t, "rego policy denied request", err.Msg)
+	assert.Equal(t, errors.AccessDenied, err.Code)
+
+	// Evaluation failure
+	err = EvaluateRego(&engine.Action{Rego: &fake.RegoPolicy{Err: fmt.Errorf("eval failed")}}, tokens, request, target)
+	assert.Equal(t, "could not evaluate rego policy", err.Msg)
+	assert.Equal(t, errors.InternalServerError, err.Code)
+}
+
+func TestClaimHeaders(t *testing.T) {
+	access, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub":    "user",
+		"groups": []string{"admin", "dev"},
+		"admin":  true,
+		"level":  3,
+		"name":   " Jane\r\nX-Injected: true ",
+		"tenant": map[string]string{"id": "t1"},
+		"long":   strings.Repeat("a", maxHeaderLength+10),
+	}).SignedString([]byte("secret"))
+	id, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"sub": "id-user", "email": "user@example.com"}).SignedString([]byte("secret"))
+
+	// No headers configured
+	assert.Nil(t, ClaimHeaders(&engine.Action{}, &validator.RawTokens{Access: access}))
+
+	action := &engine.Action{PathPolicy: v1.PathPolicy{Headers: []v1.ClaimHeader{
+		{Claim: "sub", Header: "X-User-Id"},
+		{Claim: "groups", Header: "x-user-groups"},
+		{Claim: "admin", Header: "x-admin"},
+		{Claim: "level", Header: "x-level"},
+		{Claim: "name", Header: "x-name"},
+		{Claim: "tenant", Header: "x-tenant"},
+		{Claim: "long", Header: "x-long"},
+		{Claim: "missing", Header: "x-missing"},
+		{Claim: "sub", Header: "x-id-sub", Source: validator.ID.String()},
+		{Claim: "email", Header: "x-email"},
+		{Claim: "sub", Header: "authorization"},
+		{Claim: "sub", Header: "x bad"},
+	}}}
+
+	headers := ClaimHeaders(action, &validator.RawTokens{Access: access, ID: id})
+	assert.Equal(t, map[string]string{
+		"x-user-id":     "user",
+		"x-user-groups": "admin,dev",
+		"x-admin":       "true",
+		"x-level":       "3",
+		"x-name":        "JaneX-Injected: true",
+		"x-tenant":      `{"id":"t1"}`,
+		"x-long":        strings.Repeat("a", maxHeaderLength),
+		"x-missing":     "",
+		"x-id-sub":      "id-user",
+		"x-email":       "",
+	}, headers)
+
+	// Opaque access tokens fall back to the ID token claims
+	headers = ClaimHeaders(action, &validator.RawTokens{Access: "opaque", ID: id})
+	assert.Equal(t, "id-user", headers["x-user-id"])
+	assert.Equal(t, "user@example.com", headers["x-email"])
+}
+
 func generateMap() map[string]*v1beta1.Value {
 	return map[string]*v1beta1.Value{
 		"Value_BoolValue": {
@@ -10,7 +10,9 @@ import (
 
 	"go.uber.org/zap"
 
+	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/authserver"
 	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/client"
+	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/validator"
 	authnz "github.com/ibm-cloud-security/app-identity-and-access-adapter/config/template"
 )
 
@@ -96,3 +98,11 @@ func generateSessionIDCookie(c client.Client, value *string) *http.Cookie {
 		Expires:  time.Now().Add(time.Hour * time.Duration(2160)), // 90 days
 	}
 }
+
+// rawTokens converts a token response into the raw access and id tokens
+func rawTokens(tokens *authserver.TokenResponse) *validator.RawTokens {
+	return &validator.RawTokens{
+		Access: tokens.AccessToken,
+		ID:     tokens.IdentityToken,
+	}
+}
@@ -25,7 +25,6 @@ import (
 	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/config"
 	oAuthError "github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/errors"
 	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/networking"
-	policiesV1 "github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/pkg/apis/policies/v1"
 	adapterPolicy "github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/policy"
 	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/policy/engine"
 	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/strategy"
@@ -56,6 +55,8 @@ type WebStrategy struct {
 	ctx        *config.Config
 	tokenUtil  validator.TokenValidator
 	kubeClient kubernetes.Interface
+	// options holds the global token time validation defaults
+	options validator.Options
 
 	// mutex protects all fields below
 	mutex      *sync.Mutex
@@ -75,12 +76,17 @@ type OidcCookie struct {
 	Expiration time.Time
 }
 
+func init() {
+	strategy.Register(strategy.Plugin{Type: adapterPolicy.TypeInfo{Name: "oidc"}, New: New})
+}
+
 // New creates an instance of an OIDC protection agent.
 func New(ctx *config.Config, kubeClient kubernetes.Interface) strategy.Strategy {
 	w := &WebStrategy{
 		ctx:        ctx,
-		tokenUtil:  validator.NewTokenValidator(adapterPolicy.OIDC),
+		tokenUtil:  validator.NewCachedTokenValidator(validator.NewTokenValidator(adapterPolicy.OIDC), ctx.TokenCacheSize),
 		kubeClient: kubeClient,
+		options:    validator.Options{Leeway: ctx.TokenLeeway, MaxAge: ctx.TokenMaxAge},
 		mutex:      &sync.Mutex{},
 		tokenCache: new(sync.Map),
 	}
@@ -97,6 +103,11 @@ func New(ctx *config.Config, kubeClient kubernetes.Interface) strategy.Strategy
 
 // HandleAuthnZRequest acts as the entry point to an OAuth 2.0 / OIDC flow. It processes OAuth 2.0 / OIDC requests.
 func (w *WebStrategy) HandleAuthnZRequest(r *authnz.HandleAuthnZRequest, action *engine.Action) (*authnz.HandleAuthnZResponse, error) {
+	if action.Missing != nil {
+		zap.L().Info("Service unavailable: policy configuration is not available", zap.String("resource", action.Missing.String()))
+		return buildUnavailableResponse(), nil
+	}
+
 	if strings.HasSuffix(r.Instance.Request.Path, logoutEndpoint) {
 		zap.L().Debug("Received logout request.", zap.String("client_name", action.Client.Name()))
 		return w.handleLogout(r, action)
@@ -108,7 +119,7 @@ func (w *WebStrategy) HandleAuthnZRequest(r *authnz.HandleAuthnZRequest, action
 			return w.handleErrorCallback(errors.New(r.Instance.Request.Params.Error))
 		} else if r.Instance.Request.Params.Code != "" {
 			zap.L().Debug("Received authorization code")
-			return w.handleAuthorizationCodeCallback(r.Instance.Request.Params.Code, r.Instance.Request, action)
+			return w.handleAuthorizationCodeCallback(r.Instance.Request.Params.Code, r.Instance.Request, r.Instance.Target, action)
 		} else {
 			zap.L().Debug("Unexpected response on callback endpoint /oidc/callback. Triggering re-authentication.")
 			return w.handleAuthorizationCodeFlow(r.Instance.Request, action)
@@ -116,7 +127,7 @@ func (w *WebStrategy) HandleAuthnZRequest(r *authnz.HandleAuthnZRequest, action
 	}
 
 	// Not in an OAuth 2.0 / OIDC flow, check for current authn/z session
-	res, err := w.isAuthorized(r.Instance.Request.Headers.Cookies, action)
+	res, err := w.isAuthorized(r, action)
 	if res != nil || err != nil {
 		return res, err
 	}
@@ -126,7 +137,7 @@ func (w *WebStrategy) HandleAuthnZRequest(r *authnz.HandleAuthnZRequest, action
 
 // isAuthorized checks for the existence of valid cookies
 // returns an error in the event of an Internal Server Error
-func (w *WebStrategy) isAuthorized(cookies string, action *engine.Action) (*authnz.HandleAuthnZResponse, error) {
+func (w *WebStrategy) isAuthorized(r *authnz.HandleAuthnZRequest, action *engine.Action) (*authnz.HandleAuthnZResponse, error) {
 	if action.Client == nil {
 		zap.L().Warn("Internal server error: OIDC client not provided")
 		return nil, errors.New("invalid OIDC configuration")
@@ -134,7 +145,7 @@ func (w *WebStrategy) isAuthorized(cookies string, action *engine.Action) (*auth
 
 	// Parse Cookies
 	header := http.Header{}
-	header.Add("Cookie", cookies)
+	header.Add("Cookie", r.Instance.Request.Headers.Cookies)
 	request := http.Request{Header: header}
 
 	sessionCookie, err := request.Cookie(buildTokenCookieName(sessionCookie, action.Client))
@@ -158,11 +169,12 @@ func (w *WebStrategy) isAuthorized(cookies string, action *engine.Action) (*auth
 	// Validate session
 	userInfoEndpoint := action.Client.AuthorizationServer().UserInfoEndpoint()
 	keySet := action.Client.AuthorizationServer().KeySet()
+	opts := w.options.Merge(action.TokenValidation)
 
 	handleTokenValidationError := func(validationErr *oAuthError.OAuthError) (*authnz.HandleAuthnZResponse, error) {
 		if validationErr.Msg == oAuthError.ExpiredTokenError().Msg {
 			zap.L().Debug("Tokens have expired", zap.String("client_name", action.Client.Name()))
-			return w.handleRefreshTokens(sessionCookie.Value, session, action.Client, action.Rules)
+			return w.handleRefreshTokens(r, sessionCookie.Value, session, action)
 		}
 
 		zap.L().Debug("Tokens are invalid - starting a new session", zap.String("client_name", action.Client.Name()), zap.String("session_id", sessionCookie.Value), zap.Error(validationErr))
@@ -170,14 +182,20 @@ func (w *WebStrategy) isAuthorized(cookies string, action *engine.Action) (*auth
 		return nil, nil
 	}
 
-	if validationErr := w.tokenUtil.Validate(session.AccessToken, validator.Access, keySet, action.Rules, userInfoEndpoint); validationErr != nil {
+	if validationErr := w.tokenUtil.Validate(session.AccessToken, validator.Access, keySet, action.Rules, userInfoEndpoint, opts); validationErr != nil {
 		return handleTokenValidationError(validationErr)
 	}
 
-	if validationErr := w.tokenUtil.Validate(session.IdentityToken, validator.ID, keySet, action.Rules, userInfoEndpoint); validationErr != nil {
+	if validationErr := w.tokenUtil.Validate(session.IdentityToken, validator.ID, keySet, action.Rules, userInfoEndpoint, opts); validationErr != nil {
 		return handleTokenValidationError(validationErr)
 	}
 
+	// The session stays valid when the expression denies the request, since a new login would be denied as well
+	if expressionErr := strategy.EvaluateExpression(action, rawTokens(session), r.Instance.Request, r.Instance.Target); expressionErr != nil {
+		zap.L().Debug("Session denied by policy expression", zap.String("client_name", action.Client.Name()), zap.String("session_id", sessionCookie.Value), zap.Error(expressionErr))
+		return buildExpressionErrorResponse(expressionErr), nil
+	}
+
 	zap.L().Debug("User is currently authenticated")
 
 	// Pass request through to service
@@ -185,28 +203,35 @@ func (w *WebStrategy) isAuthorized(cookies string, action *engine.Action) (*auth
 		Result: &v1beta1.CheckResult{Status: status.WithMessage(rpc.OK, "User is authenticated")},
 		Output: &authnz.OutputMsg{
 			Authorization: strings.Join([]string{bearer, session.AccessToken, session.IdentityToken}, " "),
+			Headers:       strategy.ClaimHeaders(action, rawTokens(session)),
 		},
 	}, nil
 }
 
 // handleRefreshTokens attempts to update an expired session using the refresh token flow
-func (w *WebStrategy) handleRefreshTokens(sessionID string, session *authserver.TokenResponse, c client.Client, rules []policiesV1.Rule) (*authnz.HandleAuthnZResponse, error) {
+func (w *WebStrategy) handleRefreshTokens(r *authnz.HandleAuthnZRequest, sessionID string, session *authserver.TokenResponse, action *engine.Action) (*authnz.HandleAuthnZResponse, error) {
+	c := action.Client
+	rules := action.Rules
 	if session.RefreshToken == "" {
 		zap.L().Debug("Refresh token not provided", zap.String("client_name", c.Name()))
 		return nil, nil
 	}
 	userInfoEndpoint := c.AuthorizationServer().UserInfoEndpoint()
 	keySet := c.AuthorizationServer().KeySet()
+	opts := w.options.Merge(action.TokenValidation)
 
 	if tokens, err := c.RefreshToken(session.RefreshToken); err != nil {
 		zap.L().Info("Could not retrieve tokens using the refresh token", zap.String("client_name", c.Name()), zap.Error(err))
 		return nil, nil
-	} else if validationErr := w.tokenUtil.Validate(tokens.AccessToken, validator.Access, keySet, rules, userInfoEndpoint); validationErr != nil {
+	} else if validationErr := w.tokenUtil.Validate(tokens.AccessToken, validator.Access, keySet, rules, userInfoEndpoint, opts); validationErr != nil {
 		zap.L().Debug("Could not validate Access tokens. Beginning a new session.", zap.String("client_name", c.Name()), zap.String("session_id", sessionID), zap.Error(validationErr))
 		return nil, nil
-	} else if validationErr := w.tokenUtil.Validate(tokens.IdentityToken, validator.ID, keySet, rules, userInfoEndpoint); validationErr != nil {
+	} else if validationErr := w.tokenUtil.Validate(tokens.IdentityToken, validator.ID, keySet, rules, userInfoEndpoint, opts); validationErr != nil {
 		zap.L().Debug("Could not validate Id tokens. Beginning a new session.", zap.String("client_name", c.Name()), zap.String("session_id", sessionID), zap.Error(validationErr))
 		return nil, nil
+	} else if expressionErr := strategy.EvaluateExpression(action, rawTokens(tokens), r.Instance.Request, r.Instance.Target); expressionErr != nil {
+		zap.L().Debug("Refreshed tokens denied by policy expression", zap.String("client_name", c.Name()), zap.String("session_id", sessionID), zap.Error(expressionErr))
+		return buildExpressionErrorResponse(expressionErr), nil
 	} else {
 		zap.L().Debug("Updated tokens using refresh token", zap.String("client_name", c.Name()), zap.String("session_id", sessionID))
 		cookie := generateSessionIDCookie(c, &sessionID)
@@ -216,6 +241,7 @@ func (w *WebStrategy) handleRefreshTokens(sessionID string, session *authserver.
 			Output: &authnz.OutputMsg{
 				Authorization: strings.Join([]string{bearer, tokens.AccessToken, tokens.IdentityToken}, " "),
 				SessionCookie: cookie.String(),
+				Headers:       strategy.ClaimHeaders(action, rawTokens(tokens)),
 			},
 		}, nil
 	}
@@ -235,6 +261,40 @@ func (w *WebStrategy) handleErrorCallback(err error) (*authnz.HandleAuthnZRespon
 	}, nil
 }
 
+// buildExpressionErrorResponse returns a PermissionDenied CheckResult, responding with 403 Forbidden when the policy
+// expression denied an authenticated user, or an Internal CheckResult, responding with 500 Internal Server Error
+// when the expression could not be evaluated
+func buildExpressionErrorResponse(err *oAuthError.OAuthError) *authnz.HandleAuthnZResponse {
+	code := rpc.PERMISSION_DENIED
+	if err.Code == oAuthError.InternalServerError {
+		code = rpc.INTERNAL
+	}
+	return &authnz.HandleAuthnZResponse{
+		Result: &v1beta1.CheckResult{Status: rpc.Status{
+			Code:    int32(code),
+			Message: err.Error(),
+			Details: []*types.Any{status.PackErrorDetail(&policy.DirectHttpResponse{
+				Code: err.HTTPCode(),
+				Body: err.ShortDescription(),
+			})},
+		}},
+	}
+}
+
+// buildUnavailableResponse returns an Unavailable CheckResult, responding with 503 Service Unavailable
+func buildUnavailableResponse() *authnz.HandleAuthnZResponse {
+	return &authnz.HandleAuthnZResponse{
+		Result: &v1beta1.CheckResult{Status: rpc.Status{
+			Code:    int32(rpc.UNAVAILABLE),
+			Message: "authentication is not available",
+			Details: []*types.Any{status.PackErrorDetail(&policy.DirectHttpResponse{
+				Code: policy.ServiceUnavailable,
+				Body: "Service Unavailable",
+			})},
+		}},
+	}
+}
+
 // handleLogout processes logout requests by deleting session cookies and returning to the base path
 func (w *WebStrategy) handleLogout(r *authnz.HandleAuthnZRequest, action *engine.Action) (*authnz.HandleAuthnZResponse, error) {
 	header := http.Header{}
@@ -278,7 +338,7 @@ func (w *WebStrategy) handleLogout(path string, action *engine.Action) (*authnz.
 */
 
 // handleAuthorizationCodeCallback processes a successful OAuth 2.0 callback containing a authorization code
-func (w *WebStrategy) handleAuthorizationCodeCallback(code interface{}, request *authnz.RequestMsg, action *engine.Action) (*authnz.HandleAuthnZResponse, error) {
+func (w *WebStrategy) handleAuthorizationCodeCallback(code interface{}, request *authnz.RequestMsg, target *authnz.TargetMsg, action *engine.Action) (*authnz.HandleAuthnZResponse, error) {
 
 	err := w.validateState(request, action.Client)
 	if err != nil {
@@ -297,19 +357,26 @@ func (w *WebStrategy) handleAuthorizationCodeCallback(code interface{}, request
 
 	userInfoEndpoint := action.Client.AuthorizationServer().UserInfoEndpoint()
 	keySet := action.Client.AuthorizationServer().KeySet()
+	opts := w.options.Merge(action.TokenValidation)
 
-	validationErr := w.tokenUtil.Validate(response.AccessToken, validator.Access, keySet, action.Rules, userInfoEndpoint)
+	validationErr := w.tokenUtil.Validate(response.AccessToken, validator.Access, keySet, action.Rules, userInfoEndpoint, opts)
 	if validationErr != nil {
 		zap.L().Info("OIDC callback: Access token failed validation", zap.Error(validationErr), zap.String("client_name", action.Client.Name()))
 		return w.handleErrorCallback(validationErr)
 	}
 
-	validationErr = w.tokenUtil.Validate(response.IdentityToken, validator.ID, keySet, action.Rules, userInfoEndpoint)
+	validationErr = w.tokenUtil.Validate(response.IdentityToken, validator.ID, keySet, action.Rules, userInfoEndpoint, opts)
 	if validationErr != nil {
 		zap.L().Info("OIDC callback: ID token failed validation", zap.Error(validationErr), zap.String("client_name", action.Client.Name()))
 		return w.handleErrorCallback(validationErr)
 	}
 
+	validationErr = strategy.EvaluateExpression(action, rawTokens(response), request, target)
+	if validationErr != nil {
+		zap.L().Info("OIDC callback: tokens denied by policy expression", zap.Error(validationErr), zap.String("client_name", action.Client.Name()))
+		return buildExpressionErrorResponse(validationErr), nil
+	}
+
 	cookie := generateSessionIDCookie(action.Client, nil)
 	w.tokenCache.Store(cookie.Value, response)
 
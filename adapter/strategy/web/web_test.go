@@ -8,9 +8,12 @@ import (
 	"testing"
 	"time"
 
+	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/expression"
 	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/pkg/apis/policies/v1"
 	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/validator"
 
+	"github.com/dgrijalva/jwt-go/v4"
+	"github.com/gogo/googleapis/google/rpc"
 	"github.com/gogo/protobuf/types"
 	"github.com/gorilla/securecookie"
 	"github.com/stretchr/testify/assert"
@@ -21,6 +24,7 @@ import (
 	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/authserver/keyset"
 	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/config"
 	err "github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/errors"
+	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/policy"
 	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/policy/engine"
 	"github.com/ibm-cloud-security/app-identity-and-access-adapter/config/template"
 	"github.com/ibm-cloud-security/app-identity-and-access-adapter/tests/fake"
@@ -127,6 +131,31 @@ func TestRefreshTokenFlow(t *testing.T) {
 			int32(0),
 			nil,
 		},
+		{ // Refreshed tokens are denied by policy expression
+			generateAuthnzRequest(createCookie().String(), "", "", "", ""),
+			&engine.Action{
+				Expression: compileExpression(`request.method == "POST"`),
+				Client: fake.NewClient(&fake.TokenResponse{
+					Res: &authserver.TokenResponse{
+						AccessToken:   "access",
+						IdentityToken: "identity",
+						RefreshToken:  "refresh",
+						ExpiresIn:     10,
+					},
+				}),
+			},
+			defaultState,
+			&authserver.TokenResponse{
+				AccessToken:   "access",
+				IdentityToken: "Token is expired",
+				RefreshToken:  "refresh",
+				ExpiresIn:     10,
+			},
+			&v1beta1.DirectHttpResponse{Code: 403, Body: "Forbidden"},
+			"access_denied: policy expression evaluated to false",
+			int32(7),
+			nil,
+		},
 		{ // Refresh token request fails
 			generateAuthnzRequest(createCookie().String(), "", "", "", ""),
 			&engine.Action{
@@ -217,6 +246,25 @@ func TestRefreshTokenFlow(t *testing.T) {
 	}
 }
 
+func TestSessionClaimHeaders(t *testing.T) {
+	access, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"sub": "user", "groups": []string{"a", "b"}}).SignedString([]byte("secret"))
+	api := WebStrategy{
+		tokenCache: new(sync.Map),
+		encrpytor:  defaultSecureCookie,
+		tokenUtil:  MockValidator{},
+	}
+	api.tokenCache.Store(defaultState, &authserver.TokenResponse{AccessToken: access, IdentityToken: "identity"})
+	action := &engine.Action{
+		PathPolicy: v1.PathPolicy{Headers: []v1.ClaimHeader{{Claim: "sub", Header: "X-User-Id"}, {Claim: "groups", Header: "x-user-groups"}}},
+		Client:     fake.NewClient(nil),
+	}
+
+	r, errs := api.HandleAuthnZRequest(generateAuthnzRequest(createCookie().String(), "", "", "", ""), action)
+	assert.Nil(t, errs)
+	assert.Equal(t, "User is authenticated", r.Result.Status.Message)
+	assert.Equal(t, map[string]string{"x-user-id": "user", "x-user-groups": "a,b"}, r.Output.Headers)
+}
+
 func TestErrCallback(t *testing.T) {
 	var tests = []struct {
 		req            *authnz.HandleAuthnZRequest
@@ -266,6 +314,21 @@ func TestErrCallback(t *testing.T) {
 	}
 }
 
+func TestMissingConfiguration(t *testing.T) {
+	api := WebStrategy{
+		tokenUtil: MockValidator{},
+	}
+	action := &engine.Action{Missing: &policy.Dependency{Kind: v1.OIDCCONFIG, Name: "ns/missing"}}
+	for _, path := range []string{"", callbackEndpoint, logoutEndpoint} {
+		r, e := api.HandleAuthnZRequest(generateAuthnzRequest("", "", "", path, ""), action)
+		assert.Nil(t, e)
+		assert.Equal(t, int32(rpc.UNAVAILABLE), r.Result.Status.Code)
+		response := &v1beta1.DirectHttpResponse{}
+		assert.Nil(t, types.UnmarshalAny(r.Result.Status.Details[0], response))
+		assert.Equal(t, v1beta1.ServiceUnavailable, response.Code)
+	}
+}
+
 func TestCodeCallback(t *testing.T) {
 	// Test strategy
 	api := New(config.NewConfig(), k8sfake.NewSimpleClientset()).(*WebStrategy)
@@ -418,6 +481,71 @@ func TestCodeCallback(t *testing.T) {
 	}
 }
 
+func TestCodeCallbackExpression(t *testing.T) {
+	api := New(config.NewConfig(), k8sfake.NewSimpleClientset()).(*WebStrategy)
+	api.encrpytor = defaultSecureCookie
+	api.tokenUtil = MockValidator{func(s string) *err.OAuthError { return nil }}
+	cookie, _ := api.generateEncryptedCookie("oidc-cookie-id", defaultSessionOidcCookie())
+
+	var tests = []struct {
+		expression string
+		status     int32
+		code       v1beta1.HttpStatusCode
+		body       string
+		message    string
+	}{
+		{`request.method == "GET" && target.service == "service"`, 16, 302, "", "Successfully authenticated : redirecting to original URL"},
+		{`request.method == "POST"`, 7, 403, "Forbidden", "access_denied: policy expression evaluated to false"},
+		{`claims.missing == "value"`, 13, 500, "Internal Server Error", "Internal Server Error: could not evaluate policy expression"},
+	}
+
+	for _, ts := range tests {
+		test := ts
+		t.Run("callback expression", func(t *testing.T) {
+			t.Parallel()
+			action := &engine.Action{Expression: compileExpression(test.expression)}
+			action.Client = fake.NewClient(defaultSuccessTokenResponse())
+
+			req := generateAuthnzRequest(cookie.String(), "code", "", callbackEndpoint, defaultState)
+			r, errs := api.HandleAuthnZRequest(req, action)
+			assert.Nil(t, errs)
+			assert.Equal(t, test.status, r.Result.Status.Code)
+			assert.Equal(t, test.message, r.Result.Status.Message)
+			compareDirectHttpResponses(t, r, &v1beta1.DirectHttpResponse{Code: test.code, Body: test.body})
+		})
+	}
+}
+
+func TestSessionExpression(t *testing.T) {
+	var tests = []struct {
+		expression string
+		status     int32
+		message    string
+	}{
+		{`request.method == "GET"`, 0, "User is authenticated"},
+		{`request.method == "POST"`, 7, "access_denied: policy expression evaluated to false"},
+		{`claims.missing == "value"`, 13, "Internal Server Error: could not evaluate policy expression"},
+	}
+
+	for _, test := range tests {
+		api := WebStrategy{
+			tokenCache: new(sync.Map),
+			encrpytor:  defaultSecureCookie,
+			tokenUtil:  MockValidator{},
+		}
+		api.tokenCache.Store(defaultState, &authserver.TokenResponse{AccessToken: "access", IdentityToken: "identity"})
+		action := &engine.Action{Expression: compileExpression(test.expression), Client: fake.NewClient(nil)}
+
+		// Denied sessions are kept rather than starting a new login, which would be denied as well
+		r, errs := api.HandleAuthnZRequest(generateAuthnzRequest(createCookie().String(), "", "", "", ""), action)
+		assert.Nil(t, errs, test.expression)
+		assert.Equal(t, test.status, r.Result.Status.Code, test.expression)
+		assert.Equal(t, test.message, r.Result.Status.Message, test.expression)
+		_, ok := api.tokenCache.Load(defaultState)
+		assert.True(t, ok, test.expression)
+	}
+}
+
 func TestLogout(t *testing.T) {
 	var tests = []struct {
 		req            *authnz.HandleAuthnZRequest
@@ -456,6 +584,11 @@ func TestLogout(t *testing.T) {
 	}
 }
 
+func compileExpression(source string) expression.Program {
+	program, _ := expression.Compile(source)
+	return program
+}
+
 func compareDirectHttpResponses(t *testing.T, r *authnz.HandleAuthnZResponse, expected *v1beta1.DirectHttpResponse) {
 	if expected == nil {
 		return
@@ -531,7 +664,7 @@ type MockValidator struct {
 	validate func(string) *err.OAuthError
 }
 
-func (v MockValidator) Validate(tkn string, tokenType validator.Token, ks keyset.KeySet, rules []v1.Rule, userInfoEndpoint string) *err.OAuthError {
+func (v MockValidator) Validate(tkn string, tokenType validator.Token, ks keyset.KeySet, rules []v1.Rule, userInfoEndpoint string, opts validator.Options) *err.OAuthError {
 	if v.validate == nil {
 		return nil
 	}
@@ -0,0 +1,108 @@
+package validator
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/dgrijalva/jwt-go/v4"
+	lru "github.com/hashicorp/golang-lru"
+	"go.uber.org/zap"
+
+	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/authserver/keyset"
+	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/errors"
+	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/pkg/apis/policies/v1"
+)
+
+// CachedTokenValidator remembers the tokens accepted by a TokenValidator, so that repeated
+// requests with the same token and rules skip parsing and signature verification.
+// Only successful validations are cached. Entries expire no later than the exp claim of the token,
+// and tokens without an exp claim are not cached.
+// Entries are bound to the generation of the key set, so they stop matching once keys are added, replaced
+// or removed. A token signed by a revoked key is still accepted until the key set is synced again,
+// as the key set is only refreshed when its config is updated or a token with an unknown kid is seen.
+type CachedTokenValidator struct {
+	validator TokenValidator
+	results   *lru.Cache
+}
+
+// validationKey identifies a validation by a hash of the token and a fingerprint of the other inputs
+type validationKey struct {
+	token       [sha256.Size]byte
+	fingerprint [sha256.Size]byte
+}
+
+// NewCachedTokenValidator wraps the validator with a cache of up to size accepted tokens.
+// Sizes below one disable the cache and return the validator unchanged.
+func NewCachedTokenValidator(validator TokenValidator, size int) TokenValidator {
+	if validator == nil || size < 1 {
+		return validator
+	}
+	results, err := lru.New(size)
+	if err != nil {
+		zap.L().Warn("Could not create the token validation cache", zap.Int("size", size), zap.Error(err))
+		return validator
+	}
+	return &CachedTokenValidator{validator: validator, results: results}
+}
+
+// Validate returns nil if the token was recently accepted with the same inputs, and validates it otherwise
+func (c *CachedTokenValidator) Validate(tokenStr string, tokenType Token, jwks keyset.KeySet, rules []v1.Rule, userInfoEndpoint string, opts Options) *errors.OAuthError {
+	if tokenStr == "" || jwks == nil {
+		return c.validator.Validate(tokenStr, tokenType, jwks, rules, userInfoEndpoint, opts)
+	}
+
+	key := validationKey{
+		token:       sha256.Sum256([]byte(tokenStr)),
+		fingerprint: fingerprint(tokenType, jwks, rules, userInfoEndpoint, opts),
+	}
+	now := jwt.Now().Time
+	if value, ok := c.results.Get(key); ok {
+		if now.Before(value.(time.Time)) {
+			zap.L().Debug("Token has been validated previously")
+			return nil
+		}
+		c.results.Remove(key)
+	}
+
+	if err := c.validator.Validate(tokenStr, tokenType, jwks, rules, userInfoEndpoint, opts); err != nil {
+		return err
+	}
+	if expiresAt := cacheExpiry(tokenStr, opts); expiresAt != nil && now.Before(*expiresAt) {
+		c.results.Add(key, *expiresAt)
+	}
+	return nil
+}
+
+// fingerprint returns a hash of the validation inputs other than the token, including the generation of the keys
+func fingerprint(tokenType Token, jwks keyset.KeySet, rules []v1.Rule, userInfoEndpoint string, opts Options) [sha256.Size]byte {
+	encodedRules, _ := json.Marshal(rules)
+	hash := sha256.New()
+	_, _ = fmt.Fprintf(hash, "%d\x00%s\x00%d\x00%s\x00%d\x00%d\x00", tokenType, jwks.PublicKeyURL(), jwks.Generation(), userInfoEndpoint, opts.Leeway, opts.MaxAge)
+	_, _ = hash.Write(encodedRules)
+	var sum [sha256.Size]byte
+	copy(sum[:], hash.Sum(nil))
+	return sum
+}
+
+// cacheExpiry returns the time an accepted token stops being cached: its expiration,
+// or the time it exceeds the maximum age if that is sooner. Tokens without an exp claim return nil.
+func cacheExpiry(tokenStr string, opts Options) *time.Time {
+	claims := jwt.MapClaims{}
+	if _, _, err := new(jwt.Parser).ParseUnverified(tokenStr, claims); err != nil {
+		return nil
+	}
+	expiresAt := getTimeClaim(claims, exp)
+	if expiresAt == nil || opts.MaxAge <= 0 {
+		return expiresAt
+	}
+	for _, name := range []string{iat, authTime} {
+		if t := getTimeClaim(claims, name); t != nil {
+			if maxAge := t.Add(opts.MaxAge + opts.Leeway); maxAge.Before(*expiresAt) {
+				expiresAt = &maxAge
+			}
+		}
+	}
+	return expiresAt
+}
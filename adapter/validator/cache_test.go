@@ -0,0 +1,148 @@
+package validator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go/v4"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/authserver/keyset"
+	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/errors"
+	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/pkg/apis/policies/v1"
+	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/policy"
+)
+
+// countingValidator counts the validations reaching the wrapped validator
+type countingValidator struct {
+	validator TokenValidator
+	calls     int
+}
+
+func (c *countingValidator) Validate(tokenStr string, tokenType Token, jwks keyset.KeySet, rules []v1.Rule, userInfoEndpoint string, opts Options) *errors.OAuthError {
+	c.calls++
+	if c.validator == nil {
+		return nil
+	}
+	return c.validator.Validate(tokenStr, tokenType, jwks, rules, userInfoEndpoint, opts)
+}
+
+func signedToken(claims jwt.MapClaims) string {
+	token, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte("secret"))
+	return token
+}
+
+func TestNewCachedTokenValidator(t *testing.T) {
+	jwtValidator := NewTokenValidator(policy.JWT)
+	assert.Equal(t, jwtValidator, NewCachedTokenValidator(jwtValidator, 0))
+	assert.Nil(t, NewCachedTokenValidator(nil, 10))
+	assert.IsType(t, &CachedTokenValidator{}, NewCachedTokenValidator(jwtValidator, 10))
+}
+
+func TestCachedTokenValidator(t *testing.T) {
+	counter := &countingValidator{validator: &JwtTokenValidator{}}
+	cached := NewCachedTokenValidator(counter, 10)
+	rules := []v1.Rule{{Claim: "aud", Match: ALL, Values: []string{testAud}}}
+
+	// Accepted tokens are validated once
+	assert.Nil(t, cached.Validate(validAudStrToken, Access, testKeySet, rules, "", Options{}))
+	assert.Nil(t, cached.Validate(validAudStrToken, Access, testKeySet, rules, "", Options{}))
+	assert.Equal(t, 1, counter.calls)
+
+	// Different rules, token types, key sets or options are validated again
+	assert.NotNil(t, cached.Validate(validAudStrToken, Access, testKeySet, []v1.Rule{{Claim: "aud", Match: ALL, Values: []string{"other"}}}, "", Options{}))
+	assert.Nil(t, cached.Validate(validAudStrToken, ID, testKeySet, rules, "", Options{}))
+	assert.NotNil(t, cached.Validate(validAudStrToken, Access, &localKeySet{url: "ignore"}, rules, "", Options{}))
+	assert.Nil(t, cached.Validate(validAudStrToken, Access, testKeySet, rules, "", Options{Leeway: time.Minute}))
+	assert.Equal(t, 5, counter.calls)
+
+	// Rejected tokens are not cached
+	assert.NotNil(t, cached.Validate(expiredToken, Access, testKeySet, emptyRule, "", Options{}))
+	assert.NotNil(t, cached.Validate(expiredToken, Access, testKeySet, emptyRule, "", Options{}))
+	assert.Equal(t, 7, counter.calls)
+
+	// Missing tokens and key sets are not cached
+	assert.NotNil(t, cached.Validate("", Access, testKeySet, emptyRule, "", Options{}))
+	assert.NotNil(t, cached.Validate(validAudStrToken, Access, nil, emptyRule, "", Options{}))
+	assert.Equal(t, 9, counter.calls)
+}
+
+func TestCachedTokenValidatorKeyRotation(t *testing.T) {
+	counter := &countingValidator{validator: &JwtTokenValidator{}}
+	cached := NewCachedTokenValidator(counter, 10)
+	keys := &localKeySet{url: "https://keys.com/publickeys", generation: 1}
+
+	assert.Nil(t, cached.Validate(validAudStrToken, Access, keys, emptyRule, "", Options{}))
+	assert.Nil(t, cached.Validate(validAudStrToken, Access, keys, emptyRule, "", Options{}))
+	assert.Equal(t, 1, counter.calls)
+
+	// Tokens signed by a revoked key are rejected once the keys are synced
+	keys.generation, keys.revoked = 2, true
+	assert.NotNil(t, cached.Validate(validAudStrToken, Access, keys, emptyRule, "", Options{}))
+	assert.Equal(t, 2, counter.calls)
+}
+
+func TestCachedTokenValidatorExpiry(t *testing.T) {
+	now := time.Unix(1500000000, 0)
+	jwt.TimeFunc = func() time.Time { return now }
+	defer func() { jwt.TimeFunc = time.Now }()
+
+	tests := []struct {
+		name    string
+		claims  jwt.MapClaims
+		opts    Options
+		expires time.Duration
+	}{
+		{
+			name:    "expiration",
+			claims:  jwt.MapClaims{exp: now.Add(time.Hour).Unix()},
+			expires: time.Hour,
+		},
+		{
+			name:    "maximum age",
+			claims:  jwt.MapClaims{exp: now.Add(time.Hour).Unix(), iat: now.Add(-time.Minute).Unix()},
+			opts:    Options{MaxAge: 10 * time.Minute, Leeway: time.Minute},
+			expires: 10 * time.Minute,
+		},
+		{
+			name:    "maximum age from auth_time",
+			claims:  jwt.MapClaims{exp: now.Add(time.Hour).Unix(), iat: now.Unix(), authTime: now.Add(-5 * time.Minute).Unix()},
+			opts:    Options{MaxAge: 10 * time.Minute},
+			expires: 5 * time.Minute,
+		},
+		{
+			name:   "no expiration",
+			claims: jwt.MapClaims{iat: now.Unix()},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(st *testing.T) {
+			counter := &countingValidator{}
+			cached := NewCachedTokenValidator(counter, 10)
+			token := signedToken(test.claims)
+			now = time.Unix(1500000000, 0)
+			start := now
+			assert.Nil(st, cached.Validate(token, Access, testKeySet, emptyRule, "", test.opts))
+			if test.expires == 0 {
+				assert.Nil(st, cached.Validate(token, Access, testKeySet, emptyRule, "", test.opts))
+				assert.Equal(st, 2, counter.calls)
+				return
+			}
+			now = start.Add(test.expires - time.Second)
+			assert.Nil(st, cached.Validate(token, Access, testKeySet, emptyRule, "", test.opts))
+			assert.Equal(st, 1, counter.calls)
+			now = start.Add(test.expires)
+			assert.Nil(st, cached.Validate(token, Access, testKeySet, emptyRule, "", test.opts))
+			assert.Equal(st, 2, counter.calls)
+		})
+	}
+}
+
+func TestCachedTokenValidatorOpaqueTokens(t *testing.T) {
+	counter := &countingValidator{}
+	cached := NewCachedTokenValidator(counter, 10)
+	assert.Nil(t, cached.Validate("opaque", Access, testKeySet, emptyRule, "https://userinfo", Options{}))
+	assert.Nil(t, cached.Validate("opaque", Access, testKeySet, emptyRule, "https://userinfo", Options{}))
+	assert.Equal(t, 2, counter.calls)
+}
@@ -2,10 +2,12 @@
 package validator
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/dgrijalva/jwt-go/v4"
 	"go.uber.org/zap"
@@ -21,11 +23,39 @@ const (
 	scope = "scope"
 	NOT   = "NOT"
 	ANY   = "ANY"
+	ALL   = "ALL"
+	allOf = "allOf"
+	anyOf = "anyOf"
+	not   = "not"
+
+	exp      = "exp"
+	nbf      = "nbf"
+	iat      = "iat"
+	authTime = "auth_time"
 )
 
 // TokenValidator parses and validates JWT tokens according to policies
 type TokenValidator interface {
-	Validate(token string, tokenType Token, jwks keyset.KeySet, rules []v1.Rule, userInfoEndpoint string) *errors.OAuthError
+	Validate(token string, tokenType Token, jwks keyset.KeySet, rules []v1.Rule, userInfoEndpoint string, opts Options) *errors.OAuthError
+}
+
+// Options configures the validation of time based claims
+type Options struct {
+	// Leeway is the clock skew tolerated when validating exp, nbf, iat and auth_time
+	Leeway time.Duration
+	// MaxAge is the maximum age of a token based on its iat and auth_time claims. Zero disables the check.
+	MaxAge time.Duration
+}
+
+// Merge returns the options overridden by the values set in the given configuration
+func (o Options) Merge(config v1.TokenValidation) Options {
+	if config.Leeway != nil {
+		o.Leeway = config.Leeway.Duration
+	}
+	if config.MaxAge != nil {
+		o.MaxAge = config.MaxAge.Duration
+	}
+	return o
 }
 
 // Validator implements the TokenValidator
@@ -62,7 +92,7 @@ func NewTokenValidator(policyType policy.Type) TokenValidator {
 
 // Validate validates tokens according to the specified policies.
 // If any policy fails, the entire request should be rejected
-func (o *OidcTokenValidator) Validate(tokenStr string, tokenType Token, jwks keyset.KeySet, rules []v1.Rule, userInfoEndpoint string) *errors.OAuthError {
+func (o *OidcTokenValidator) Validate(tokenStr string, tokenType Token, jwks keyset.KeySet, rules []v1.Rule, userInfoEndpoint string, opts Options) *errors.OAuthError {
 
 	if tokenStr == "" {
 		zap.L().Debug("Unauthorized - Token does not exist")
@@ -88,7 +118,7 @@ func (o *OidcTokenValidator) Validate(tokenStr string, tokenType Token, jwks key
 	}
 
 	// Parse the token - validate expiration and signature
-	token, err := validateSignature(tokenStr, jwks)
+	token, err := validateSignature(tokenStr, jwks, opts)
 	if err != nil {
 		if tokenType != Access {
 			zap.L().Debug("Unauthorized - invalid token", zap.String("token", tokenStr), zap.Error(err))
@@ -101,6 +131,10 @@ func (o *OidcTokenValidator) Validate(tokenStr string, tokenType Token, jwks key
 	// Validate token
 	claimErr := validateClaims(token, tokenType, rules)
 	if claimErr != nil {
+		if claimErr.Code == errors.InsufficientScope {
+			zap.L().Debug("Forbidden - insufficient scope", zap.String("token", tokenStr), zap.Error(claimErr))
+			return claimErr
+		}
 		zap.L().Debug("Unauthorized - invalid token", zap.String("token", tokenStr), zap.Error(err))
 		return errors.UnauthorizedHTTPException(claimErr.Msg, findRequiredScopes(rules))
 	}
@@ -111,7 +145,7 @@ func (o *OidcTokenValidator) Validate(tokenStr string, tokenType Token, jwks key
 
 // Validate validates tokens according to the specified policies.
 // If any policy fails, the entire request should be rejected
-func (*JwtTokenValidator) Validate(tokenStr string, tokenType Token, jwks keyset.KeySet, rules []v1.Rule, userInfoEndpoint string) *errors.OAuthError {
+func (*JwtTokenValidator) Validate(tokenStr string, tokenType Token, jwks keyset.KeySet, rules []v1.Rule, userInfoEndpoint string, opts Options) *errors.OAuthError {
 
 	if tokenStr == "" {
 		zap.L().Debug("Unauthorized - Token does not exist")
@@ -126,7 +160,7 @@ func (*JwtTokenValidator) Validate(tokenStr string, tokenType Token, jwks keyset
 	}
 
 	// Parse the token - validate expiration and signature
-	token, err := validateSignature(tokenStr, jwks)
+	token, err := validateSignature(tokenStr, jwks, opts)
 	if err != nil {
 		zap.L().Debug("Unauthorized - invalid token", zap.String("token", tokenStr), zap.Error(err))
 		return errors.UnauthorizedHTTPException(err.Error(), findRequiredScopes(rules))
@@ -135,6 +169,10 @@ func (*JwtTokenValidator) Validate(tokenStr string, tokenType Token, jwks keyset
 	// Validate token
 	claimErr := validateClaims(token, tokenType, rules)
 	if claimErr != nil {
+		if claimErr.Code == errors.InsufficientScope {
+			zap.L().Debug("Forbidden - insufficient scope", zap.String("token", tokenStr), zap.Error(claimErr))
+			return claimErr
+		}
 		zap.L().Debug("Unauthorized - invalid token", zap.String("token", tokenStr), zap.Error(err))
 		return errors.UnauthorizedHTTPException(claimErr.Msg, findRequiredScopes(rules))
 	}
@@ -167,8 +205,9 @@ func validateAccessTokenString(userInfoEndpoint string, tokenStr string, rules [
 		return errors.UnauthorizedHTTPException("Unauthorized - invalid token", findRequiredScopes(rules))
 	}
 
+	// Claims of opaque tokens cannot be checked, including those required within rule groups
 	for _, rule := range rules {
-		if rule.Source == Access.String() {
+		if hasSource(rule, Access) {
 			zap.L().Warn("Unauthorized - rules configured for opaque access token")
 			return errors.BadRequestHTTPException("Unauthorized - rules configured for opaque access token")
 		}
@@ -177,18 +216,51 @@ func validateAccessTokenString(userInfoEndpoint string, tokenStr string, rules [
 	return nil
 }
 
-// Find scopes returns the required scope rules to return in www-authenticate header
+// appliesTo checks if a top level rule is evaluated against tokens of the given type.
+// Rules without a source apply to access tokens.
+func appliesTo(rule v1.Rule, tokenType Token) bool {
+	return rule.Source == tokenType.String() || (rule.Source == "" && tokenType == Access)
+}
+
+// hasSource checks if the rule or any rule nested within it is set to the given source
+func hasSource(rule v1.Rule, tokenType Token) bool {
+	if rule.Source == tokenType.String() {
+		return true
+	}
+	for _, r := range append(append([]v1.Rule{}, rule.AllOf...), rule.AnyOf...) {
+		if hasSource(r, tokenType) {
+			return true
+		}
+	}
+	return rule.Not != nil && hasSource(*rule.Not, tokenType)
+}
+
+// Find scopes returns the required scope rules to return in www-authenticate header,
+// including those within rule groups. Scopes within not groups are not required.
 func findRequiredScopes(rules []v1.Rule) []string {
-	for _, r := range rules {
-		if r.Claim == scope {
-			return r.Values
+	var scopes []string
+	seen := make(map[string]bool)
+	var find func(rules []v1.Rule)
+	find = func(rules []v1.Rule) {
+		for _, r := range rules {
+			if r.Claim == scope {
+				for _, v := range r.Values {
+					if !seen[v] {
+						seen[v] = true
+						scopes = append(scopes, v)
+					}
+				}
+			}
+			find(r.AllOf)
+			find(r.AnyOf)
 		}
 	}
-	return nil
+	find(rules)
+	return scopes
 }
 
-// validateSignature parses the given token and verifies the ExpiresAt, NotBefore, and signature
-func validateSignature(token string, jwks keyset.KeySet) (*jwt.Token, error) {
+// validateSignature parses the given token and verifies the signature and time based claims
+func validateSignature(token string, jwks keyset.KeySet, opts Options) (*jwt.Token, error) {
 
 	// Method used by token library to get public key for signature validation
 	getKey := func(token *jwt.Token) (interface{}, error) {
@@ -210,7 +282,67 @@ func validateSignature(token string, jwks keyset.KeySet) (*jwt.Token, error) {
 		return key, nil
 	}
 
-	return jwt.Parse(token, getKey)
+	// Time based claims are validated separately to account for clock skew
+	parser := &jwt.Parser{SkipClaimsValidation: true}
+	parsed, err := parser.Parse(token, getKey)
+	if err != nil {
+		return parsed, err
+	}
+
+	if claims, ok := parsed.Claims.(jwt.MapClaims); ok {
+		if err := validateTimeClaims(claims, opts, jwt.Now().Time); err != nil {
+			parsed.Valid = false
+			return parsed, err
+		}
+	}
+
+	return parsed, nil
+}
+
+// validateTimeClaims verifies the exp, nbf, iat and auth_time claims allowing for the configured leeway.
+// Claims that are not set, or are not numeric, are not validated.
+func validateTimeClaims(claims jwt.MapClaims, opts Options, now time.Time) error {
+	if expiresAt := getTimeClaim(claims, exp); expiresAt != nil && now.After(expiresAt.Add(opts.Leeway)) {
+		return &jwt.ExpiredError{Now: now.Unix(), ExpiredBy: now.Sub(*expiresAt), Claims: claims}
+	}
+
+	if notBefore := getTimeClaim(claims, nbf); notBefore != nil && now.Add(opts.Leeway).Before(*notBefore) {
+		return fmt.Errorf("token is not valid yet")
+	}
+
+	if opts.MaxAge <= 0 {
+		return nil
+	}
+
+	if getTimeClaim(claims, iat) == nil {
+		return fmt.Errorf("token validation error - `%s` is required to validate the token age", iat)
+	}
+	for _, name := range []string{iat, authTime} {
+		if t := getTimeClaim(claims, name); t != nil && now.Sub(*t) > opts.MaxAge+opts.Leeway {
+			return fmt.Errorf("token validation error - `%s` exceeds the maximum age of %s", name, opts.MaxAge)
+		}
+	}
+
+	return nil
+}
+
+// getTimeClaim returns the NumericDate claim with the given name
+func getTimeClaim(claims jwt.MapClaims, name string) *time.Time {
+	var seconds float64
+	switch v := claims[name].(type) {
+	case float64:
+		seconds = v
+	case json.Number:
+		f, err := v.Float64()
+		if err != nil {
+			return nil
+		}
+		seconds = f
+	default:
+		return nil
+	}
+	t := jwt.NewTime(seconds).Time
+	return &t
 }
 
 // validateClaims validates claims based on policies
@@ -227,17 +359,110 @@ func validateClaims(token *jwt.Token, tokenType Token, rules []v1.Rule) *errors.
 		return err
 	}
 
+	// Authentication failures take precedence over insufficient scope
+	var scopeErr *scopeError
 	for _, rule := range rules {
-		if (rule.Source == tokenType.String()) || (rule.Source == "" && Access.String() == tokenType.String()) {
-			if err := checkAccessPolicy(rule, claims); err != nil {
+		if appliesTo(rule, tokenType) {
+			if err := checkRule(rule, claims, ""); err != nil {
+				if e, ok := err.(*scopeError); ok {
+					if scopeErr == nil {
+						scopeErr = e
+					}
+					continue
+				}
 				return errors.UnauthorizedHTTPException(err.Error(), nil)
 			}
 		}
 	}
 
+	if scopeErr != nil {
+		return errors.ForbiddenHTTPException(scopeErr.msg, scopeErr.scopes)
+	}
+
 	return nil
 }
 
+// scopeError reports a failed scope rule. Scope failures of a validly signed token
+// are authorization failures rather than authentication failures.
+type scopeError struct {
+	msg    string
+	scopes []string
+}
+
+func (e *scopeError) Error() string {
+	return e.msg
+}
+
+// checkRule evaluates a rule tree against the claims map. The branch describes
+// the position of the rule within its tree and is reported on failure.
+func checkRule(rule v1.Rule, claims jwt.MapClaims, branch string) error {
+	switch {
+	case len(rule.AllOf) > 0:
+		for i, r := range rule.AllOf {
+			if err := checkRule(r, claims, branchName(branch, allOf, i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	case len(rule.AnyOf) > 0:
+		failures := make([]string, 0, len(rule.AnyOf))
+		// The group is a scope failure only if every branch failed on scope
+		scopes := make([]string, 0)
+		onlyScopes := true
+		for i, r := range rule.AnyOf {
+			err := checkRule(r, claims, branchName(branch, anyOf, i))
+			if err == nil {
+				return nil
+			}
+			if e, ok := err.(*scopeError); ok {
+				scopes = append(scopes, e.scopes...)
+			} else {
+				onlyScopes = false
+			}
+			failures = append(failures, err.Error())
+		}
+		msg := fmt.Sprintf("token validation error - expected one of `%s` to match: %s", branchName(branch, anyOf, -1), strings.Join(failures, "; "))
+		if onlyScopes {
+			return &scopeError{msg: msg, scopes: scopes}
+		}
+		return fmt.Errorf("%s", msg)
+	case rule.Not != nil:
+		if err := checkRule(*rule.Not, claims, branchName(branch, not, -1)); err == nil {
+			return fmt.Errorf("token validation error - expected `%s` to not match", branchName(branch, not, -1))
+		}
+		return nil
+	default:
+		if err := checkAccessPolicy(rule, claims); err != nil {
+			msg := err.Error()
+			if branch != "" {
+				msg = fmt.Sprintf("%s (%s)", msg, branch)
+			}
+			if isScopeRule(rule) {
+				return &scopeError{msg: msg, scopes: rule.Values}
+			}
+			return fmt.Errorf("%s", msg)
+		}
+		return nil
+	}
+}
+
+// isScopeRule checks if the rule requires scopes. Rules excluding scopes are not treated as scope requirements.
+func isScopeRule(rule v1.Rule) bool {
+	return rule.Claim == scope && rule.Match != NOT
+}
+
+// branchName appends a rule group to the branch path, e.g. anyOf[1].allOf[0]
+func branchName(branch string, group string, index int) string {
+	name := group
+	if index >= 0 {
+		name = fmt.Sprintf("%s[%d]", group, index)
+	}
+	if branch == "" {
+		return name
+	}
+	return branch + "." + name
+}
+
 // checkAccessPolicy is used to validate a specific claim with the claims map
 func checkAccessPolicy(rule v1.Rule, claims jwt.MapClaims) error {
 	m, err := convertClaimType(getNestedClaim(rule.Claim, claims))
@@ -255,6 +480,140 @@ func checkAccessPolicy(rule v1.Rule, claims jwt.MapClaims) error {
 	}
 }
 
+// ValidateRules checks that the given rules form a well defined rule tree.
+// It is used to report misconfigured rules when policies are parsed.
+func ValidateRules(rules []v1.Rule) error {
+	for i, rule := range rules {
+		if err := validateRule(rule, "", fmt.Sprintf("rules[%d]", i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ValidateHeaders checks that the given claim to header mappings can be safely forwarded.
+// It is used to report misconfigured headers when policies are parsed.
+func ValidateHeaders(headers []v1.ClaimHeader) error {
+	for i, header := range headers {
+		if err := ValidateHeader(header); err != nil {
+			return fmt.Errorf("invalid header headers[%d]: %s", i, err)
+		}
+	}
+	return nil
+}
+
+// ValidateHeader checks that a claim may be forwarded using the given header
+func ValidateHeader(header v1.ClaimHeader) error {
+	if header.Claim == "" {
+		return fmt.Errorf("claim is required")
+	}
+	if !isHeaderName(header.Header) {
+		return fmt.Errorf("`%s` is not a valid header name", header.Header)
+	}
+	if reservedHeaders[strings.ToLower(header.Header)] {
+		return fmt.Errorf("`%s` is a reserved header", header.Header)
+	}
+	switch header.Source {
+	case "", Access.String(), ID.String():
+		return nil
+	default:
+		return fmt.Errorf("unknown source `%s`", header.Source)
+	}
+}
+
+// ValidateHeaderMatches checks the header names matched by a Policy target
+func ValidateHeaderMatches(headers []v1.HeaderMatch) error {
+	for i, header := range headers {
+		if !isHeaderName(header.Name) {
+			return fmt.Errorf("invalid header match headers[%d]: `%s` is not a valid header name", i, header.Name)
+		}
+	}
+	return nil
+}
+
+// reservedHeaders may not be overwritten with claim values
+var reservedHeaders = map[string]bool{
+	"authorization":       true,
+	"connection":          true,
+	"content-length":      true,
+	"cookie":              true,
+	"host":                true,
+	"proxy-authorization": true,
+	"set-cookie":          true,
+	"te":                  true,
+	"transfer-encoding":   true,
+	"upgrade":             true,
+}
+
+// isHeaderName reports whether the name is a valid RFC 7230 header field name
+func isHeaderName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, c := range name {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case strings.ContainsRune("!#$%&'*+-.^_`|~", c):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// validateRule checks a single node of a rule tree along with its children
+func validateRule(rule v1.Rule, parentSource string, branch string) error {
+	if parentSource != "" && rule.Source != "" && rule.Source != parentSource {
+		return fmt.Errorf("invalid rule %s: source `%s` does not match its group source `%s`", branch, rule.Source, parentSource)
+	}
+	switch rule.Source {
+	case "", Access.String(), ID.String():
+	default:
+		return fmt.Errorf("invalid rule %s: unknown source `%s`", branch, rule.Source)
+	}
+	source := rule.Source
+	if source == "" {
+		source = parentSource
+	}
+
+	groups := 0
+	for _, set := range []bool{rule.Claim != "", len(rule.AllOf) > 0, len(rule.AnyOf) > 0, rule.Not != nil} {
+		if set {
+			groups++
+		}
+	}
+	if groups != 1 {
+		return fmt.Errorf("invalid rule %s: expected exactly one of claim, %s, %s or %s", branch, allOf, anyOf, not)
+	}
+
+	if rule.Claim != "" {
+		switch rule.Match {
+		case "", ALL, ANY, NOT:
+			return nil
+		default:
+			return fmt.Errorf("invalid rule %s: unknown match `%s`", branch, rule.Match)
+		}
+	}
+
+	if rule.Match != "" || len(rule.Values) > 0 {
+		return fmt.Errorf("invalid rule %s: match and values are not supported on rule groups", branch)
+	}
+	for i, r := range rule.AllOf {
+		if err := validateRule(r, source, branchName(branch, allOf, i)); err != nil {
+			return err
+		}
+	}
+	for i, r := range rule.AnyOf {
+		if err := validateRule(r, source, branchName(branch, anyOf, i)); err != nil {
+			return err
+		}
+	}
+	if rule.Not != nil {
+		return validateRule(*rule.Not, source, branchName(branch, not, -1))
+	}
+	return nil
+}
+
 func getNestedClaim(claim string, claims jwt.MapClaims) interface{} {
 	tiers := strings.Split(claim, ".")
 	for i, tier := range tiers {
@@ -3,6 +3,7 @@ package validator
 import (
 	"context"
 	"crypto"
+	"encoding/json"
 	e "errors"
 	"io/ioutil"
 
@@ -10,6 +11,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/networking"
 	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/pkg/apis/policies/v1"
@@ -17,6 +19,7 @@ import (
 
 	"github.com/dgrijalva/jwt-go/v4"
 	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/authserver/keyset"
 	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/errors"
@@ -40,11 +43,15 @@ const (
 
 // //// Mocks /////
 
-type localKeySet struct{ url string }
+type localKeySet struct {
+	url        string
+	generation uint64
+	revoked    bool
+}
 
 func (k *localKeySet) PublicKeyURL() string { return k.url }
 func (k *localKeySet) PublicKey(kid string) crypto.PublicKey {
-	if kid == testKid && k.url != "ignore" {
+	if kid == testKid && k.url != "ignore" && !k.revoked {
 		keyData, _ := ioutil.ReadFile(pathToPublicKey)
 		key, _ := jwt.ParseRSAPublicKeyFromPEM(keyData)
 		return key
@@ -52,6 +59,12 @@ func (k *localKeySet) PublicKey(kid string) crypto.PublicKey {
 	return nil
 }
 
+func (k *localKeySet) SyncError() error { return nil }
+
+func (k *localKeySet) Generation() uint64 { return k.generation }
+
+func (k *localKeySet) OnSync(handler func(err error)) {}
+
 var testKeySet = &localKeySet{url: "https://keys.com/publickeys"}
 
 var emptyRule = []v1.Rule{}
@@ -233,6 +246,51 @@ func TestTokenValidation(t *testing.T) {
 				Source: ID.String(),
 			},
 		}},
+		// Rule groups
+		{validAudStrToken, ID, nil, testKeySet, []v1.Rule{
+			{
+				Source: ID.String(),
+				AnyOf: []v1.Rule{
+					{Claim: "scope", Values: []string{"admin"}},
+					{AllOf: []v1.Rule{
+						{Claim: "scope", Values: []string{"appid_default"}},
+						{Claim: "obj.str", Values: []string{"hello"}},
+					}},
+				},
+			},
+		}},
+		{validAudStrToken, ID, &errors.OAuthError{Code: errors.InvalidToken, Msg: "token validation error - expected `not` to not match"}, testKeySet, []v1.Rule{
+			{
+				Source: ID.String(),
+				Not:    &v1.Rule{Claim: "tenant", Match: "ANY", Values: []string{"71b34890-a94f-4ef2-a4b6-ce094aa68092"}},
+			},
+		}},
+		// Scope failures
+		{validAudStrToken, ID, &errors.OAuthError{Code: errors.InsufficientScope, Msg: "token validation error - expected claim `scope` to match all of: [admin]", Scopes: []string{"admin"}}, testKeySet, []v1.Rule{
+			{Claim: "scope", Values: []string{"admin"}, Source: ID.String()},
+		}},
+		{validAudStrToken, ID, &errors.OAuthError{Code: errors.InvalidToken, Msg: "token validation error - expected claim `tenant` to match all of: [other]"}, testKeySet, []v1.Rule{
+			{Claim: "scope", Values: []string{"admin"}, Source: ID.String()},
+			{Claim: "tenant", Values: []string{"other"}, Source: ID.String()},
+		}},
+		{validAudStrToken, ID, &errors.OAuthError{Code: errors.InsufficientScope, Scopes: []string{"admin", "write"}}, testKeySet, []v1.Rule{
+			{
+				Source: ID.String(),
+				AnyOf: []v1.Rule{
+					{Claim: "scope", Values: []string{"admin"}},
+					{Claim: "scope", Values: []string{"write"}},
+				},
+			},
+		}},
+		{validAudStrToken, ID, &errors.OAuthError{Code: errors.InvalidToken}, testKeySet, []v1.Rule{
+			{
+				Source: ID.String(),
+				AnyOf: []v1.Rule{
+					{Claim: "scope", Values: []string{"admin"}},
+					{Claim: "tenant", Values: []string{"other"}},
+				},
+			},
+		}},
 		// Empty claim
 		{validAudStrToken, ID, &errors.OAuthError{Code: errors.InvalidToken, Msg: "token validation error - expected claim `` does not exist - rule requires: []"}, testKeySet, []v1.Rule{
 			{
@@ -245,7 +303,7 @@ func TestTokenValidation(t *testing.T) {
 	for _, e := range tests {
 		t.Run("Validate", func(st *testing.T) {
 			runTest := func(v TokenValidator) {
-				oaErr := v.Validate(e.token, e.tokenType, e.jwks, e.rules, userinfo)
+				oaErr := v.Validate(e.token, e.tokenType, e.jwks, e.rules, userinfo, Options{})
 				if e.err != nil {
 					if e.err.Code != "" {
 						assert.Equal(st, e.err.Code, oaErr.Code)
@@ -266,6 +324,54 @@ func TestTokenValidation(t *testing.T) {
 	}
 }
 
+func TestValidateTimeClaims(t *testing.T) {
+	now := time.Unix(1000000, 0)
+	at := func(offset time.Duration) float64 {
+		return float64(now.Add(offset).Unix())
+	}
+	var tests = []struct {
+		name   string
+		claims jwt.MapClaims
+		opts   Options
+		err    string
+	}{
+		{"no claims", jwt.MapClaims{}, Options{}, ""},
+		{"valid", jwt.MapClaims{exp: at(time.Minute), nbf: at(-time.Minute), iat: at(-time.Minute)}, Options{}, ""},
+		{"expired", jwt.MapClaims{exp: at(-time.Second)}, Options{}, errors.ExpiredToken},
+		{"expired within leeway", jwt.MapClaims{exp: at(-time.Second)}, Options{Leeway: time.Minute}, ""},
+		{"expired beyond leeway", jwt.MapClaims{exp: at(-2 * time.Minute)}, Options{Leeway: time.Minute}, errors.ExpiredToken},
+		{"json number exp", jwt.MapClaims{exp: json.Number("999999")}, Options{}, errors.ExpiredToken},
+		{"non numeric exp", jwt.MapClaims{exp: "999999"}, Options{}, ""},
+		{"not valid yet", jwt.MapClaims{nbf: at(time.Second)}, Options{}, "token is not valid yet"},
+		{"not valid yet within leeway", jwt.MapClaims{nbf: at(time.Second)}, Options{Leeway: time.Minute}, ""},
+		{"max age", jwt.MapClaims{iat: at(-time.Minute)}, Options{MaxAge: time.Hour}, ""},
+		{"max age exceeded", jwt.MapClaims{iat: at(-2 * time.Hour)}, Options{MaxAge: time.Hour}, "token validation error - `iat` exceeds the maximum age of 1h0m0s"},
+		{"max age within leeway", jwt.MapClaims{iat: at(-time.Hour - time.Second)}, Options{MaxAge: time.Hour, Leeway: time.Minute}, ""},
+		{"max age auth_time exceeded", jwt.MapClaims{iat: at(-time.Minute), authTime: at(-2 * time.Hour)}, Options{MaxAge: time.Hour}, "token validation error - `auth_time` exceeds the maximum age of 1h0m0s"},
+		{"max age missing iat", jwt.MapClaims{}, Options{MaxAge: time.Hour}, "token validation error - `iat` is required to validate the token age"},
+	}
+	for _, e := range tests {
+		test := e
+		t.Run(test.name, func(st *testing.T) {
+			err := validateTimeClaims(test.claims, test.opts, now)
+			if test.err != "" {
+				assert.EqualError(st, err, test.err)
+			} else {
+				assert.Nil(st, err)
+			}
+		})
+	}
+}
+
+func TestOptionsMerge(t *testing.T) {
+	defaults := Options{Leeway: time.Second, MaxAge: time.Hour}
+	assert.Equal(t, defaults, defaults.Merge(v1.TokenValidation{}))
+	assert.Equal(t, Options{Leeway: time.Minute, MaxAge: 0}, defaults.Merge(v1.TokenValidation{
+		Leeway: &metav1.Duration{Duration: time.Minute},
+		MaxAge: &metav1.Duration{Duration: 0},
+	}))
+}
+
 // ///// Claim Validation //////
 
 func TestClaimValidation(t *testing.T) {
@@ -401,6 +507,156 @@ func TestClaimValidation(t *testing.T) {
 	}
 }
 
+func TestRuleTreeValidation(t *testing.T) {
+	var tests = []struct {
+		name      string
+		rule      v1.Rule
+		expectErr error
+	}{
+		{
+			"allOf matches",
+			v1.Rule{AllOf: []v1.Rule{
+				{Claim: "string", Values: []string{"1"}},
+				{Claim: "arr_string", Match: "ANY", Values: []string{"5"}},
+			}},
+			nil,
+		},
+		{
+			"allOf reports failing branch",
+			v1.Rule{AllOf: []v1.Rule{
+				{Claim: "string", Values: []string{"1"}},
+				{Claim: "arr_string", Values: []string{"6"}},
+			}},
+			e.New("token validation error - expected claim `arr_string` to match all of: [6] (allOf[1])"),
+		},
+		{
+			"anyOf matches second branch",
+			v1.Rule{AnyOf: []v1.Rule{
+				{Claim: "string", Values: []string{"admin"}},
+				{AllOf: []v1.Rule{
+					{Claim: "string_arr", Values: []string{"2"}},
+					{Claim: "bool", Values: []string{"true"}},
+				}},
+			}},
+			nil,
+		},
+		{
+			"anyOf reports all branches",
+			v1.Rule{AnyOf: []v1.Rule{
+				{Claim: "string", Values: []string{"admin"}},
+				{AllOf: []v1.Rule{
+					{Claim: "string_arr", Values: []string{"2"}},
+					{Claim: "bool", Values: []string{"false"}},
+				}},
+			}},
+			e.New("token validation error - expected one of `anyOf` to match: " +
+				"token validation error - expected claim `string` to match all of: [admin] (anyOf[0]); " +
+				"token validation error - expected claim `bool` to match all of: [false] (anyOf[1].allOf[1])"),
+		},
+		{
+			"not inverts match",
+			v1.Rule{Not: &v1.Rule{Claim: "string", Values: []string{"6"}}},
+			nil,
+		},
+		{
+			"not reports matching rule",
+			v1.Rule{AllOf: []v1.Rule{
+				{Not: &v1.Rule{Claim: "string", Values: []string{"1"}}},
+			}},
+			e.New("token validation error - expected `allOf[0].not` to not match"),
+		},
+	}
+
+	var claimMap jwt.MapClaims = make(map[string]interface{})
+	claimMap["string"] = "1"
+	claimMap["arr_string"] = []interface{}{"1", "2", "3", "4", "5"}
+	claimMap["string_arr"] = "1 2 3 4 5"
+	claimMap["bool"] = true
+
+	for _, e := range tests {
+		test := e
+		t.Run(test.name, func(st *testing.T) {
+			err := checkRule(test.rule, claimMap, "")
+			if test.expectErr != nil {
+				assert.EqualError(st, err, test.expectErr.Error())
+			} else {
+				assert.Nil(st, err)
+			}
+		})
+	}
+}
+
+func TestValidateRules(t *testing.T) {
+	var tests = []struct {
+		name      string
+		rules     []v1.Rule
+		expectErr string
+	}{
+		{"empty", []v1.Rule{}, ""},
+		{"leaf", []v1.Rule{{Claim: "scope", Match: "ANY", Values: []string{"read"}}}, ""},
+		{"nested", []v1.Rule{{Source: ID.String(), AnyOf: []v1.Rule{
+			{Claim: "groups", Values: []string{"admin"}},
+			{AllOf: []v1.Rule{
+				{Claim: "scope", Values: []string{"read"}, Source: ID.String()},
+				{Not: &v1.Rule{Claim: "tenant", Values: []string{"blocked"}}},
+			}},
+		}}}, ""},
+		{"missing claim", []v1.Rule{{Values: []string{"read"}}}, "invalid rule rules[0]: expected exactly one of claim, allOf, anyOf or not"},
+		{"claim and group", []v1.Rule{{Claim: "scope", AnyOf: []v1.Rule{{Claim: "aud"}}}}, "invalid rule rules[0]: expected exactly one of claim, allOf, anyOf or not"},
+		{"unknown match", []v1.Rule{{AllOf: []v1.Rule{{Claim: "scope", Match: "SOME"}}}}, "invalid rule rules[0].allOf[0]: unknown match `SOME`"},
+		{"unknown source", []v1.Rule{{Claim: "scope", Source: "refresh_token"}}, "invalid rule rules[0]: unknown source `refresh_token`"},
+		{"group values", []v1.Rule{{Match: "ANY", AnyOf: []v1.Rule{{Claim: "aud"}}}}, "invalid rule rules[0]: match and values are not supported on rule groups"},
+		{"mixed sources", []v1.Rule{{Source: ID.String(), Not: &v1.Rule{Claim: "aud", Source: Access.String()}}}, "invalid rule rules[0].not: source `access_token` does not match its group source `id_token`"},
+	}
+
+	for _, e := range tests {
+		test := e
+		t.Run(test.name, func(st *testing.T) {
+			err := ValidateRules(test.rules)
+			if test.expectErr != "" {
+				assert.EqualError(st, err, test.expectErr)
+			} else {
+				assert.Nil(st, err)
+			}
+		})
+	}
+}
+
+func TestValidateHeaders(t *testing.T) {
+	var tests = []struct {
+		name      string
+		headers   []v1.ClaimHeader
+		expectErr string
+	}{
+		{"empty", []v1.ClaimHeader{}, ""},
+		{"valid", []v1.ClaimHeader{{Claim: "sub", Header: "x-user-id"}, {Claim: "groups", Header: "X-User-Groups", Source: ID.String()}}, ""},
+		{"missing claim", []v1.ClaimHeader{{Header: "x-user-id"}}, "invalid header headers[0]: claim is required"},
+		{"missing header", []v1.ClaimHeader{{Claim: "sub"}}, "invalid header headers[0]: `` is not a valid header name"},
+		{"invalid header", []v1.ClaimHeader{{Claim: "sub", Header: "x-user-id"}, {Claim: "sub", Header: "x-user\r\n"}}, "invalid header headers[1]: `x-user\r\n` is not a valid header name"},
+		{"pseudo header", []v1.ClaimHeader{{Claim: "sub", Header: ":path"}}, "invalid header headers[0]: `:path` is not a valid header name"},
+		{"reserved header", []v1.ClaimHeader{{Claim: "sub", Header: "Authorization"}}, "invalid header headers[0]: `Authorization` is a reserved header"},
+		{"unknown source", []v1.ClaimHeader{{Claim: "sub", Header: "x-user-id", Source: "refresh_token"}}, "invalid header headers[0]: unknown source `refresh_token`"},
+	}
+
+	for _, e := range tests {
+		test := e
+		t.Run(test.name, func(st *testing.T) {
+			err := ValidateHeaders(test.headers)
+			if test.expectErr != "" {
+				assert.EqualError(st, err, test.expectErr)
+			} else {
+				assert.Nil(st, err)
+			}
+		})
+	}
+}
+
+func TestValidateHeaderMatches(t *testing.T) {
+	assert.Nil(t, ValidateHeaderMatches(nil))
+	assert.Nil(t, ValidateHeaderMatches([]v1.HeaderMatch{{Name: "x-tenant"}, {Name: "X-Version", Value: "2"}}))
+	assert.EqualError(t, ValidateHeaderMatches([]v1.HeaderMatch{{Name: "x-tenant"}, {Value: "2"}}), "invalid header match headers[1]: `` is not a valid header name")
+}
+
 func TestValidateClaims(t *testing.T) {
 	err := validateClaims(nil, Access, nil)
 	assert.Equal(t, "Internal Server Error", err.Error())
@@ -447,6 +703,51 @@ func TestValidateAccessTokenString(t *testing.T) {
 			},
 			err: errors.BadRequestHTTPException("Unauthorized - rules configured for opaque access token"),
 		},
+		{
+			tokenUrl: userinfo,
+			tokenStr: validToken,
+			rules: []v1.Rule{
+				{
+					AnyOf: []v1.Rule{
+						{Claim: "int", Values: []string{"100"}},
+						{Claim: "aud", Values: []string{"client"}, Source: Access.String()},
+					},
+				},
+			},
+			err: errors.BadRequestHTTPException("Unauthorized - rules configured for opaque access token"),
+		},
+		{
+			tokenUrl: userinfo,
+			tokenStr: validToken,
+			rules: []v1.Rule{
+				{
+					Source: ID.String(),
+					AllOf: []v1.Rule{
+						{Claim: "int", Values: []string{"100"}},
+					},
+				},
+				{
+					AllOf: []v1.Rule{
+						{Not: &v1.Rule{Claim: "int", Values: []string{"100"}, Source: Access.String()}},
+					},
+					Source: ID.String(),
+				},
+			},
+			err: errors.BadRequestHTTPException("Unauthorized - rules configured for opaque access token"),
+		},
+		{
+			tokenUrl: userinfo,
+			tokenStr: validToken,
+			rules: []v1.Rule{
+				{
+					Source: ID.String(),
+					AllOf: []v1.Rule{
+						{Claim: "int", Values: []string{"100"}},
+					},
+				},
+			},
+			err: nil,
+		},
 	}
 
 	for _, test := range tests {
@@ -488,6 +789,45 @@ func TestValidateAccessTokenString(t *testing.T) {
 	}
 }
 
+func TestFindRequiredScopes(t *testing.T) {
+	tests := []struct {
+		name     string
+		rules    []v1.Rule
+		expected []string
+	}{
+		{"no rules", emptyRule, nil},
+		{
+			"top level",
+			[]v1.Rule{{Claim: scope, Values: []string{"read"}}},
+			[]string{"read"},
+		},
+		{
+			"nested groups",
+			[]v1.Rule{
+				{Claim: "aud", Values: []string{"client"}},
+				{AnyOf: []v1.Rule{
+					{Claim: scope, Values: []string{"read"}},
+					{AllOf: []v1.Rule{{Claim: scope, Values: []string{"admin", "read"}}}},
+				}},
+			},
+			[]string{"read", "admin"},
+		},
+		{
+			"not groups",
+			[]v1.Rule{
+				{Not: &v1.Rule{Claim: scope, Values: []string{"banned"}}},
+			},
+			nil,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(st *testing.T) {
+			assert.Equal(st, test.expected, findRequiredScopes(test.rules))
+		})
+	}
+}
+
 func TestNewTokenValidator(t *testing.T) {
 	tests := []struct {
 		tokenType policy.Type
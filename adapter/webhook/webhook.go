@@ -0,0 +1,146 @@
+// Package webhook implements a Kubernetes validating admission webhook for the policy resources
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"go.uber.org/zap"
+	admission "k8s.io/api/admission/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	v1 "github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/pkg/apis/policies/v1"
+	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/pkg/client/clientset/versioned"
+	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/policy/handler/crdeventhandler"
+)
+
+const (
+	// ValidatePath is the path the API server sends admission reviews to
+	ValidatePath = "/validate"
+	// maxBodySize limits the size of an admission review
+	maxBodySize = 3 * 1024 * 1024
+)
+
+// Webhook validates Policy, ClusterPolicy, JwtConfig and OidcConfig resources before they are stored
+type Webhook struct {
+	kubeClient     kubernetes.Interface
+	policiesClient versioned.Interface
+}
+
+// New creates a new Webhook. The clients are used to check the resources referenced by Policies.
+func New(kubeClient kubernetes.Interface, policiesClient versioned.Interface) *Webhook {
+	return &Webhook{
+		kubeClient:     kubeClient,
+		policiesClient: policiesClient,
+	}
+}
+
+// NewServer returns an HTTPS server serving the webhook on the given address
+func NewServer(addr string, webhook *Webhook) *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle(ValidatePath, webhook)
+	return &http.Server{Addr: addr, Handler: mux}
+}
+
+// ServeHTTP answers an admission review
+func (w *Webhook) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(rw, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if contentType := req.Header.Get("Content-Type"); !strings.HasPrefix(contentType, "application/json") {
+		http.Error(rw, fmt.Sprintf("unsupported content type %q", contentType), http.StatusUnsupportedMediaType)
+		return
+	}
+	body, err := ioutil.ReadAll(http.MaxBytesReader(rw, req.Body, maxBodySize))
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	review := admission.AdmissionReview{}
+	if err := json.Unmarshal(body, &review); err != nil || review.Request == nil {
+		zap.L().Warn("Could not decode admission review", zap.Error(err))
+		http.Error(rw, "invalid admission review", http.StatusBadRequest)
+		return
+	}
+
+	review.Response = w.Review(review.Request)
+	review.Response.UID = review.Request.UID
+	review.Request = nil
+
+	rw.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(rw).Encode(&review); err != nil {
+		zap.L().Warn("Could not write admission review", zap.Error(err))
+	}
+}
+
+// Review validates the object of an admission request
+func (w *Webhook) Review(req *admission.AdmissionRequest) *admission.AdmissionResponse {
+	if req.Operation == admission.Delete {
+		return allow()
+	}
+
+	var problems []string
+	var err error
+	switch req.Kind.Kind {
+	case v1.POLICY.String():
+		obj := &v1.Policy{}
+		if err = json.Unmarshal(req.Object.Raw, obj); err == nil {
+			obj.Namespace = req.Namespace
+			problems = crdeventhandler.ValidatePolicy(obj)
+			problems = append(problems, crdeventhandler.ValidatePolicyReferences(obj, w.kubeClient, w.policiesClient)...)
+		}
+	case v1.CLUSTERPOLICY.String():
+		obj := &v1.ClusterPolicy{}
+		if err = json.Unmarshal(req.Object.Raw, obj); err == nil {
+			problems = crdeventhandler.ValidateClusterPolicy(obj)
+		}
+	case v1.JWTCONFIG.String():
+		obj := &v1.JwtConfig{}
+		if err = json.Unmarshal(req.Object.Raw, obj); err == nil {
+			problems = crdeventhandler.ValidateJwtConfig(obj)
+		}
+	case v1.OIDCCONFIG.String():
+		obj := &v1.OidcConfig{}
+		if err = json.Unmarshal(req.Object.Raw, obj); err == nil {
+			problems = crdeventhandler.ValidateOidcConfig(obj)
+		}
+	default:
+		zap.L().Debug("Ignoring admission review of unsupported kind", zap.String("kind", req.Kind.Kind))
+		return allow()
+	}
+
+	// Cluster scoped resources have no namespace
+	key := req.Name
+	if req.Namespace != "" {
+		key = req.Namespace + "/" + req.Name
+	}
+	if err != nil {
+		return deny(fmt.Sprintf("could not decode %s %s: %v", req.Kind.Kind, key, err))
+	}
+	if len(problems) > 0 {
+		zap.L().Info("Rejected invalid resource", zap.String("kind", req.Kind.Kind), zap.String("name", req.Name), zap.String("namespace", req.Namespace), zap.Strings("problems", problems))
+		return deny(fmt.Sprintf("%s %s is invalid: %s", req.Kind.Kind, key, strings.Join(problems, "; ")))
+	}
+	return allow()
+}
+
+func allow() *admission.AdmissionResponse {
+	return &admission.AdmissionResponse{Allowed: true}
+}
+
+func deny(message string) *admission.AdmissionResponse {
+	return &admission.AdmissionResponse{
+		Allowed: false,
+		Result: &metav1.Status{
+			Status:  metav1.StatusFailure,
+			Message: message,
+			Reason:  metav1.StatusReasonInvalid,
+			Code:    http.StatusUnprocessableEntity,
+		},
+	}
+}
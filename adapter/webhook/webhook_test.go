@@ -0,0 +1,191 @@
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	admission "k8s.io/api/admission/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+
+	v1 "github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/pkg/apis/policies/v1"
+	policiesFake "github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/pkg/client/clientset/versioned/fake"
+)
+
+const ns = "ns"
+
+func jwtConfig(name string, jwksURL string) *v1.JwtConfig {
+	return &v1.JwtConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: ns},
+		Spec:       v1.JwtConfigSpec{JwksURL: jwksURL},
+	}
+}
+
+func oidcConfig(name string, discoveryURL string) *v1.OidcConfig {
+	return &v1.OidcConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: ns},
+		Spec:       v1.OidcConfigSpec{ClientID: "id", ClientSecret: "secret", DiscoveryURL: discoveryURL},
+	}
+}
+
+func policy(name string, method string, policyType string, config string) *v1.Policy {
+	return &v1.Policy{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: v1.PolicySpec{
+			Target: []v1.TargetElement{
+				{
+					ServiceName: "service",
+					Paths: []v1.PathConfig{
+						{Exact: "/path", Method: method, Policies: []v1.PathPolicy{{PolicyType: policyType, Config: config}}},
+					},
+				},
+			},
+		},
+	}
+}
+
+func request(kind v1.CrdType, operation admission.Operation, obj interface{}) *admission.AdmissionRequest {
+	raw, _ := json.Marshal(obj)
+	return &admission.AdmissionRequest{
+		UID:       types.UID("uid"),
+		Kind:      metav1.GroupVersionKind{Group: v1.SchemeGroupVersion.Group, Version: v1.SchemeGroupVersion.Version, Kind: kind.String()},
+		Namespace: ns,
+		Name:      "sample",
+		Operation: operation,
+		Object:    runtime.RawExtension{Raw: raw},
+	}
+}
+
+func clusterRequest(kind v1.CrdType, operation admission.Operation, obj interface{}) *admission.AdmissionRequest {
+	req := request(kind, operation, obj)
+	req.Namespace = ""
+	return req
+}
+
+func TestReview(t *testing.T) {
+	webhook := New(fake.NewSimpleClientset(), policiesFake.NewSimpleClientset(jwtConfig("jwt", "https://localhost/keys")))
+
+	tests := []struct {
+		name    string
+		req     *admission.AdmissionRequest
+		allowed bool
+		message string
+	}{
+		{
+			name:    "valid policy",
+			req:     request(v1.POLICY, admission.Create, policy("sample", "GET", "jwt", "jwt")),
+			allowed: true,
+		},
+		{
+			name:    "unknown method",
+			req:     request(v1.POLICY, admission.Update, policy("sample", "FETCH", "jwt", "jwt")),
+			message: `Policy ns/sample is invalid: service: unknown method "FETCH"`,
+		},
+		{
+			name:    "missing config",
+			req:     request(v1.POLICY, admission.Update, policy("sample", "HEAD", "oidc", "oidc")),
+			message: `Policy ns/sample is invalid: OidcConfig ns/oidc does not exist`,
+		},
+		{
+			name: "invalid cluster policy",
+			req: clusterRequest(v1.CLUSTERPOLICY, admission.Create, &v1.ClusterPolicy{
+				ObjectMeta: metav1.ObjectMeta{Name: "sample"},
+				Spec: v1.ClusterPolicySpec{
+					NamespaceSelector: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "env", Operator: "Among"}}},
+					PolicySpec:        policy("sample", "GET", "jwt", "jwt").Spec,
+				},
+			}),
+			message: `ClusterPolicy sample is invalid: namespaceSelector: "Among" is not a valid pod selector operator`,
+		},
+		{
+			name:    "invalid jwt config",
+			req:     request(v1.JWTCONFIG, admission.Create, jwtConfig("sample", "localhost/keys")),
+			message: `JwtConfig ns/sample is invalid: jwksUrl: "localhost/keys" is not an absolute http or https URL`,
+		},
+		{
+			name:    "valid oidc config",
+			req:     request(v1.OIDCCONFIG, admission.Create, oidcConfig("sample", "https://localhost")),
+			allowed: true,
+		},
+		{
+			name:    "invalid oidc config",
+			req:     request(v1.OIDCCONFIG, admission.Create, oidcConfig("sample", "ftp://localhost")),
+			message: `OidcConfig ns/sample is invalid: discoveryUrl: "ftp://localhost" is not an absolute http or https URL`,
+		},
+		{
+			name:    "delete",
+			req:     request(v1.POLICY, admission.Delete, policy("sample", "FETCH", "oidc", "oidc")),
+			allowed: true,
+		},
+		{
+			name:    "unsupported kind",
+			req:     request(v1.CONFIGMAP, admission.Create, struct{}{}),
+			allowed: true,
+		},
+		{
+			name:    "undecodable object",
+			req:     request(v1.POLICY, admission.Create, "policy"),
+			message: "could not decode Policy ns/sample: json: cannot unmarshal string into Go value of type v1.Policy",
+		},
+	}
+
+	for _, test := range tests {
+		response := webhook.Review(test.req)
+		assert.Equal(t, test.allowed, response.Allowed, test.name)
+		if test.allowed {
+			assert.Nil(t, response.Result, test.name)
+		} else {
+			assert.Equal(t, test.message, response.Result.Message, test.name)
+			assert.Equal(t, metav1.StatusReasonInvalid, response.Result.Reason, test.name)
+		}
+	}
+}
+
+func TestServeHTTP(t *testing.T) {
+	server := httptest.NewTLSServer(NewServer("", New(fake.NewSimpleClientset(), policiesFake.NewSimpleClientset())).Handler)
+	defer server.Close()
+	client := server.Client()
+
+	// Valid review
+	review := admission.AdmissionReview{
+		TypeMeta: metav1.TypeMeta{APIVersion: "admission.k8s.io/v1beta1", Kind: "AdmissionReview"},
+		Request:  request(v1.JWTCONFIG, admission.Create, jwtConfig("sample", "https://localhost/keys")),
+	}
+	body, _ := json.Marshal(review)
+	res, err := client.Post(server.URL+ValidatePath, "application/json", bytes.NewReader(body))
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	result := admission.AdmissionReview{}
+	assert.Nil(t, json.NewDecoder(res.Body).Decode(&result))
+	_ = res.Body.Close()
+	assert.Equal(t, review.TypeMeta, result.TypeMeta)
+	assert.Nil(t, result.Request)
+	assert.Equal(t, types.UID("uid"), result.Response.UID)
+	assert.True(t, result.Response.Allowed)
+
+	// Malformed review
+	res, err = client.Post(server.URL+ValidatePath, "application/json", bytes.NewReader([]byte("{")))
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+
+	// Unsupported content type
+	res, err = client.Post(server.URL+ValidatePath, "text/plain", bytes.NewReader(body))
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusUnsupportedMediaType, res.StatusCode)
+
+	// Unsupported method
+	res, err = client.Get(server.URL + ValidatePath)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusMethodNotAllowed, res.StatusCode)
+
+	// Unknown path
+	res, err = client.Post(server.URL+"/mutate", "application/json", bytes.NewReader(body))
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusNotFound, res.StatusCode)
+}
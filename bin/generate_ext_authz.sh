@@ -0,0 +1,49 @@
+#!/usr/bin/env bash
+#
+# Copyright 2019 APP ID Authors. All Rights Reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#    http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+#
+# Generates config/envoy/service/auth/v3/external_auth.pb.go from external_auth.proto
+# with protoc and the protoc-gen-gogo plugin of the gogo/protobuf version in go.mod.
+# Requires protoc 3 on the PATH. Pass --check to fail if the generated file is out of date.
+
+set -e
+
+sourceDir="$(cd "$(dirname "${BASH_SOURCE[0]}")/.." && pwd)"
+protoDir="${sourceDir}/config/envoy/service/auth/v3"
+workDir="$(mktemp -d)"
+trap 'rm -rf "${workDir}"' EXIT
+
+cd "${sourceDir}"
+
+# Build the plugin and resolve google/rpc/status.proto at the versions pinned in go.mod
+go build -o "${workDir}/protoc-gen-gogo" github.com/gogo/protobuf/protoc-gen-gogo
+googleapis="$(go list -m -f '{{.Dir}}' github.com/gogo/googleapis)"
+
+cd "${protoDir}"
+protoc -I . -I "${googleapis}" \
+    --plugin=protoc-gen-gogo="${workDir}/protoc-gen-gogo" \
+    --gogo_out=plugins=grpc,Mgoogle/rpc/status.proto=github.com/gogo/googleapis/google/rpc:"${workDir}" \
+    external_auth.proto
+
+if [ "$1" == "--check" ]; then
+    if ! diff -u external_auth.pb.go "${workDir}/external_auth.pb.go"; then
+        echo "external_auth.pb.go is out of date, run bin/generate_ext_authz.sh"
+        exit 1
+    fi
+    exit 0
+fi
+
+cp "${workDir}/external_auth.pb.go" external_auth.pb.go
+echo "Generated ${protoDir}/external_auth.pb.go"
@@ -35,7 +35,14 @@ function installKubectl() {
     sudo mv ./kubectl /usr/local/bin/kubectl
 }
 
+function installProtoc() {
+    wget https://github.com/protocolbuffers/protobuf/releases/download/v3.6.1/protoc-3.6.1-linux-x86_64.zip
+    unzip protoc-3.6.1-linux-x86_64.zip -d protoc3
+    sudo mv protoc3/bin/protoc /usr/local/bin/protoc
+}
+
 installHelm
 installKubectl
+installProtoc
 installIBMCloudCLI
 installIBMCloudPlugins
@@ -35,6 +35,16 @@ func getCmd() *cobra.Command {
 	f.Int8VarP(&sa.Level, "level", "l", sa.Level, "Set output log level. Range [-1, 7].")
 	f.VarP(&sa.HashKeySize, "hash-key", "", "The size of the HMAC signature key. It is recommended to use a key with 32 or 64 bytes.")
 	f.VarP(&sa.BlockKeySize, "block-key", "", "The size of the AES blockKey size used to encrypt the cookie value. Valid lengths are 16, 24, or 32.")
+	f.DurationVarP(&sa.TokenLeeway, "token-leeway", "", sa.TokenLeeway, "The default clock skew tolerated when validating token exp, nbf, iat and auth_time claims.")
+	f.DurationVarP(&sa.TokenMaxAge, "token-max-age", "", sa.TokenMaxAge, "The default maximum age of a token based on its iat and auth_time claims. Zero disables the check.")
+	f.IntVarP(&sa.TokenCacheSize, "token-cache-size", "", sa.TokenCacheSize, "Maximum number of accepted tokens cached to skip repeated validations. Zero disables the cache.")
+	f.Uint16VarP(&sa.WebhookPort, "webhook-port", "", sa.WebhookPort, "TCP port to serve the validating admission webhook on. Zero disables the webhook.")
+	f.StringVarP(&sa.WebhookCertFile, "webhook-cert", "", sa.WebhookCertFile, "TLS certificate file of the validating admission webhook.")
+	f.StringVarP(&sa.WebhookKeyFile, "webhook-key", "", sa.WebhookKeyFile, "TLS private key file of the validating admission webhook.")
+	f.BoolVarP(&sa.CORSPreflightBypass, "cors-preflight-bypass", "", sa.CORSPreflightBypass, "Allow CORS preflight OPTIONS requests without authentication.")
+	f.IntVarP(&sa.PolicyCacheSize, "policy-cache-size", "", sa.PolicyCacheSize, "Maximum number of policy decisions cached by the policy engine. Zero disables the cache.")
+	f.Uint16VarP(&sa.MetricsPort, "metrics-port", "", sa.MetricsPort, "TCP port to serve Prometheus metrics on. Zero disables the metrics endpoint.")
+	f.Uint16VarP(&sa.ForwardAuthPort, "forward-auth-port", "", sa.ForwardAuthPort, "TCP port to serve the HTTP forward authentication endpoint for NGINX and Traefik on. Zero disables the endpoint.")
 
 	return cmd
 }
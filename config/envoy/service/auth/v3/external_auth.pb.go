@@ -0,0 +1,1067 @@
+// Code generated by protoc-gen-gogo. DO NOT EDIT.
+// source: external_auth.proto
+
+// Package envoy.service.auth.v3 is the subset of the Envoy external authorization API
+// used by the adapter. Field numbers and names match
+// envoy/service/auth/v3/{external_auth,attribute_context}.proto, envoy/config/core/v3/base.proto
+// and envoy/type/v3/http_status.proto of the Envoy v1.32 API, so the messages are wire compatible with Envoy.
+// Fields the adapter does not read or write are omitted and skipped when decoding.
+//
+// The Envoy protos are not compiled directly, as their validation and annotation imports
+// and the go-control-plane packages require newer protobuf and gRPC libraries than Istio.
+//
+// Regenerate external_auth.pb.go with bin/generate_ext_authz.sh.
+
+package authv3
+
+import (
+	context "context"
+	fmt "fmt"
+	rpc "github.com/gogo/googleapis/google/rpc"
+	proto "github.com/gogo/protobuf/proto"
+	grpc "google.golang.org/grpc"
+	math "math"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.GoGoProtoPackageIsVersion2 // please upgrade the proto package
+
+// StatusCode is an HTTP response status code
+type StatusCode int32
+
+const (
+	// Empty is not a valid status code
+	StatusCode_Empty               StatusCode = 0
+	StatusCode_OK                  StatusCode = 200
+	StatusCode_Found               StatusCode = 302
+	StatusCode_BadRequest          StatusCode = 400
+	StatusCode_Unauthorized        StatusCode = 401
+	StatusCode_Forbidden           StatusCode = 403
+	StatusCode_NotFound            StatusCode = 404
+	StatusCode_InternalServerError StatusCode = 500
+	StatusCode_ServiceUnavailable  StatusCode = 503
+)
+
+var StatusCode_name = map[int32]string{
+	0:   "Empty",
+	200: "OK",
+	302: "Found",
+	400: "BadRequest",
+	401: "Unauthorized",
+	403: "Forbidden",
+	404: "NotFound",
+	500: "InternalServerError",
+	503: "ServiceUnavailable",
+}
+
+var StatusCode_value = map[string]int32{
+	"Empty":               0,
+	"OK":                  200,
+	"Found":               302,
+	"BadRequest":          400,
+	"Unauthorized":        401,
+	"Forbidden":           403,
+	"NotFound":            404,
+	"InternalServerError": 500,
+	"ServiceUnavailable":  503,
+}
+
+func (x StatusCode) String() string {
+	return proto.EnumName(StatusCode_name, int32(x))
+}
+
+func (StatusCode) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_911791676da0a922, []int{0}
+}
+
+// HeaderAppendAction describes how a header is added
+type HeaderValueOption_HeaderAppendAction int32
+
+const (
+	// Append the value to any existing header
+	HeaderValueOption_APPEND_IF_EXISTS_OR_ADD HeaderValueOption_HeaderAppendAction = 0
+	// Add the header only when it is not already present
+	HeaderValueOption_ADD_IF_ABSENT HeaderValueOption_HeaderAppendAction = 1
+	// Replace any existing header
+	HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD HeaderValueOption_HeaderAppendAction = 2
+	// Replace the header only when it is already present
+	HeaderValueOption_OVERWRITE_IF_EXISTS HeaderValueOption_HeaderAppendAction = 3
+)
+
+var HeaderValueOption_HeaderAppendAction_name = map[int32]string{
+	0: "APPEND_IF_EXISTS_OR_ADD",
+	1: "ADD_IF_ABSENT",
+	2: "OVERWRITE_IF_EXISTS_OR_ADD",
+	3: "OVERWRITE_IF_EXISTS",
+}
+
+var HeaderValueOption_HeaderAppendAction_value = map[string]int32{
+	"APPEND_IF_EXISTS_OR_ADD":    0,
+	"ADD_IF_ABSENT":              1,
+	"OVERWRITE_IF_EXISTS_OR_ADD": 2,
+	"OVERWRITE_IF_EXISTS":        3,
+}
+
+func (x HeaderValueOption_HeaderAppendAction) String() string {
+	return proto.EnumName(HeaderValueOption_HeaderAppendAction_name, int32(x))
+}
+
+func (HeaderValueOption_HeaderAppendAction) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_911791676da0a922, []int{3, 0}
+}
+
+// CheckRequest is sent by Envoy for each request to authorize
+type CheckRequest struct {
+	// The request attributes
+	Attributes           *AttributeContext `protobuf:"bytes,1,opt,name=attributes,proto3" json:"attributes,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
+	XXX_unrecognized     []byte            `json:"-"`
+	XXX_sizecache        int32             `json:"-"`
+}
+
+func (m *CheckRequest) Reset()         { *m = CheckRequest{} }
+func (m *CheckRequest) String() string { return proto.CompactTextString(m) }
+func (*CheckRequest) ProtoMessage()    {}
+func (*CheckRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_911791676da0a922, []int{0}
+}
+func (m *CheckRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CheckRequest.Unmarshal(m, b)
+}
+func (m *CheckRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CheckRequest.Marshal(b, m, deterministic)
+}
+func (m *CheckRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CheckRequest.Merge(m, src)
+}
+func (m *CheckRequest) XXX_Size() int {
+	return xxx_messageInfo_CheckRequest.Size(m)
+}
+func (m *CheckRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_CheckRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CheckRequest proto.InternalMessageInfo
+
+func (m *CheckRequest) GetAttributes() *AttributeContext {
+	if m != nil {
+		return m.Attributes
+	}
+	return nil
+}
+
+// AttributeContext describes the request being authorized
+type AttributeContext struct {
+	// The source of the request
+	Source *AttributeContext_Peer `protobuf:"bytes,1,opt,name=source,proto3" json:"source,omitempty"`
+	// The destination of the request
+	Destination *AttributeContext_Peer `protobuf:"bytes,2,opt,name=destination,proto3" json:"destination,omitempty"`
+	// The request
+	Request *AttributeContext_Request `protobuf:"bytes,4,opt,name=request,proto3" json:"request,omitempty"`
+	// The context extensions configured on the Envoy route or virtual host
+	ContextExtensions    map[string]string `protobuf:"bytes,10,rep,name=context_extensions,json=contextExtensions,proto3" json:"context_extensions,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
+	XXX_unrecognized     []byte            `json:"-"`
+	XXX_sizecache        int32             `json:"-"`
+}
+
+func (m *AttributeContext) Reset()         { *m = AttributeContext{} }
+func (m *AttributeContext) String() string { return proto.CompactTextString(m) }
+func (*AttributeContext) ProtoMessage()    {}
+func (*AttributeContext) Descriptor() ([]byte, []int) {
+	return fileDescriptor_911791676da0a922, []int{1}
+}
+func (m *AttributeContext) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_AttributeContext.Unmarshal(m, b)
+}
+func (m *AttributeContext) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_AttributeContext.Marshal(b, m, deterministic)
+}
+func (m *AttributeContext) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_AttributeContext.Merge(m, src)
+}
+func (m *AttributeContext) XXX_Size() int {
+	return xxx_messageInfo_AttributeContext.Size(m)
+}
+func (m *AttributeContext) XXX_DiscardUnknown() {
+	xxx_messageInfo_AttributeContext.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_AttributeContext proto.InternalMessageInfo
+
+func (m *AttributeContext) GetSource() *AttributeContext_Peer {
+	if m != nil {
+		return m.Source
+	}
+	return nil
+}
+
+func (m *AttributeContext) GetDestination() *AttributeContext_Peer {
+	if m != nil {
+		return m.Destination
+	}
+	return nil
+}
+
+func (m *AttributeContext) GetRequest() *AttributeContext_Request {
+	if m != nil {
+		return m.Request
+	}
+	return nil
+}
+
+func (m *AttributeContext) GetContextExtensions() map[string]string {
+	if m != nil {
+		return m.ContextExtensions
+	}
+	return nil
+}
+
+// Peer describes a service taking part in the request
+type AttributeContext_Peer struct {
+	// The canonical service name of the peer
+	Service string `protobuf:"bytes,2,opt,name=service,proto3" json:"service,omitempty"`
+	// The labels of the peer
+	Labels map[string]string `protobuf:"bytes,3,rep,name=labels,proto3" json:"labels,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	// The authenticated identity of the peer
+	Principal string `protobuf:"bytes,4,opt,name=principal,proto3" json:"principal,omitempty"`
+	// The PEM encoded certificate of the peer
+	Certificate          string   `protobuf:"bytes,5,opt,name=certificate,proto3" json:"certificate,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *AttributeContext_Peer) Reset()         { *m = AttributeContext_Peer{} }
+func (m *AttributeContext_Peer) String() string { return proto.CompactTextString(m) }
+func (*AttributeContext_Peer) ProtoMessage()    {}
+func (*AttributeContext_Peer) Descriptor() ([]byte, []int) {
+	return fileDescriptor_911791676da0a922, []int{1, 0}
+}
+func (m *AttributeContext_Peer) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_AttributeContext_Peer.Unmarshal(m, b)
+}
+func (m *AttributeContext_Peer) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_AttributeContext_Peer.Marshal(b, m, deterministic)
+}
+func (m *AttributeContext_Peer) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_AttributeContext_Peer.Merge(m, src)
+}
+func (m *AttributeContext_Peer) XXX_Size() int {
+	return xxx_messageInfo_AttributeContext_Peer.Size(m)
+}
+func (m *AttributeContext_Peer) XXX_DiscardUnknown() {
+	xxx_messageInfo_AttributeContext_Peer.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_AttributeContext_Peer proto.InternalMessageInfo
+
+func (m *AttributeContext_Peer) GetService() string {
+	if m != nil {
+		return m.Service
+	}
+	return ""
+}
+
+func (m *AttributeContext_Peer) GetLabels() map[string]string {
+	if m != nil {
+		return m.Labels
+	}
+	return nil
+}
+
+func (m *AttributeContext_Peer) GetPrincipal() string {
+	if m != nil {
+		return m.Principal
+	}
+	return ""
+}
+
+func (m *AttributeContext_Peer) GetCertificate() string {
+	if m != nil {
+		return m.Certificate
+	}
+	return ""
+}
+
+// Request describes the request being authorized
+type AttributeContext_Request struct {
+	// The HTTP request
+	Http                 *AttributeContext_HttpRequest `protobuf:"bytes,2,opt,name=http,proto3" json:"http,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}                      `json:"-"`
+	XXX_unrecognized     []byte                        `json:"-"`
+	XXX_sizecache        int32                         `json:"-"`
+}
+
+func (m *AttributeContext_Request) Reset()         { *m = AttributeContext_Request{} }
+func (m *AttributeContext_Request) String() string { return proto.CompactTextString(m) }
+func (*AttributeContext_Request) ProtoMessage()    {}
+func (*AttributeContext_Request) Descriptor() ([]byte, []int) {
+	return fileDescriptor_911791676da0a922, []int{1, 1}
+}
+func (m *AttributeContext_Request) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_AttributeContext_Request.Unmarshal(m, b)
+}
+func (m *AttributeContext_Request) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_AttributeContext_Request.Marshal(b, m, deterministic)
+}
+func (m *AttributeContext_Request) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_AttributeContext_Request.Merge(m, src)
+}
+func (m *AttributeContext_Request) XXX_Size() int {
+	return xxx_messageInfo_AttributeContext_Request.Size(m)
+}
+func (m *AttributeContext_Request) XXX_DiscardUnknown() {
+	xxx_messageInfo_AttributeContext_Request.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_AttributeContext_Request proto.InternalMessageInfo
+
+func (m *AttributeContext_Request) GetHttp() *AttributeContext_HttpRequest {
+	if m != nil {
+		return m.Http
+	}
+	return nil
+}
+
+// HttpRequest describes an HTTP request
+type AttributeContext_HttpRequest struct {
+	// The request id
+	Id string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	// The HTTP method
+	Method string `protobuf:"bytes,2,opt,name=method,proto3" json:"method,omitempty"`
+	// The request headers, keyed by lower case name
+	Headers map[string]string `protobuf:"bytes,3,rep,name=headers,proto3" json:"headers,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	// The request path, including the query and fragment
+	Path string `protobuf:"bytes,4,opt,name=path,proto3" json:"path,omitempty"`
+	// The host of the request
+	Host string `protobuf:"bytes,5,opt,name=host,proto3" json:"host,omitempty"`
+	// The scheme of the request
+	Scheme string `protobuf:"bytes,6,opt,name=scheme,proto3" json:"scheme,omitempty"`
+	// Always empty; the query is part of the path
+	Query string `protobuf:"bytes,7,opt,name=query,proto3" json:"query,omitempty"`
+	// Always empty; the fragment is part of the path
+	Fragment string `protobuf:"bytes,8,opt,name=fragment,proto3" json:"fragment,omitempty"`
+	// The size of the request body
+	Size_ int64 `protobuf:"varint,9,opt,name=size,proto3" json:"size,omitempty"`
+	// The protocol of the request
+	Protocol string `protobuf:"bytes,10,opt,name=protocol,proto3" json:"protocol,omitempty"`
+	// The request body, when buffering is enabled
+	Body string `protobuf:"bytes,11,opt,name=body,proto3" json:"body,omitempty"`
+	// The raw request body, when packing as bytes is enabled
+	RawBody              []byte   `protobuf:"bytes,12,opt,name=raw_body,json=rawBody,proto3" json:"raw_body,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *AttributeContext_HttpRequest) Reset()         { *m = AttributeContext_HttpRequest{} }
+func (m *AttributeContext_HttpRequest) String() string { return proto.CompactTextString(m) }
+func (*AttributeContext_HttpRequest) ProtoMessage()    {}
+func (*AttributeContext_HttpRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_911791676da0a922, []int{1, 2}
+}
+func (m *AttributeContext_HttpRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_AttributeContext_HttpRequest.Unmarshal(m, b)
+}
+func (m *AttributeContext_HttpRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_AttributeContext_HttpRequest.Marshal(b, m, deterministic)
+}
+func (m *AttributeContext_HttpRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_AttributeContext_HttpRequest.Merge(m, src)
+}
+func (m *AttributeContext_HttpRequest) XXX_Size() int {
+	return xxx_messageInfo_AttributeContext_HttpRequest.Size(m)
+}
+func (m *AttributeContext_HttpRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_AttributeContext_HttpRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_AttributeContext_HttpRequest proto.InternalMessageInfo
+
+func (m *AttributeContext_HttpRequest) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *AttributeContext_HttpRequest) GetMethod() string {
+	if m != nil {
+		return m.Method
+	}
+	return ""
+}
+
+func (m *AttributeContext_HttpRequest) GetHeaders() map[string]string {
+	if m != nil {
+		return m.Headers
+	}
+	return nil
+}
+
+func (m *AttributeContext_HttpRequest) GetPath() string {
+	if m != nil {
+		return m.Path
+	}
+	return ""
+}
+
+func (m *AttributeContext_HttpRequest) GetHost() string {
+	if m != nil {
+		return m.Host
+	}
+	return ""
+}
+
+func (m *AttributeContext_HttpRequest) GetScheme() string {
+	if m != nil {
+		return m.Scheme
+	}
+	return ""
+}
+
+func (m *AttributeContext_HttpRequest) GetQuery() string {
+	if m != nil {
+		return m.Query
+	}
+	return ""
+}
+
+func (m *AttributeContext_HttpRequest) GetFragment() string {
+	if m != nil {
+		return m.Fragment
+	}
+	return ""
+}
+
+func (m *AttributeContext_HttpRequest) GetSize_() int64 {
+	if m != nil {
+		return m.Size_
+	}
+	return 0
+}
+
+func (m *AttributeContext_HttpRequest) GetProtocol() string {
+	if m != nil {
+		return m.Protocol
+	}
+	return ""
+}
+
+func (m *AttributeContext_HttpRequest) GetBody() string {
+	if m != nil {
+		return m.Body
+	}
+	return ""
+}
+
+func (m *AttributeContext_HttpRequest) GetRawBody() []byte {
+	if m != nil {
+		return m.RawBody
+	}
+	return nil
+}
+
+// HeaderValue is a header name and value
+type HeaderValue struct {
+	// The header name
+	Key string `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	// The header value
+	Value string `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+	// The header value as bytes
+	RawValue             []byte   `protobuf:"bytes,3,opt,name=raw_value,json=rawValue,proto3" json:"raw_value,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *HeaderValue) Reset()         { *m = HeaderValue{} }
+func (m *HeaderValue) String() string { return proto.CompactTextString(m) }
+func (*HeaderValue) ProtoMessage()    {}
+func (*HeaderValue) Descriptor() ([]byte, []int) {
+	return fileDescriptor_911791676da0a922, []int{2}
+}
+func (m *HeaderValue) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_HeaderValue.Unmarshal(m, b)
+}
+func (m *HeaderValue) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_HeaderValue.Marshal(b, m, deterministic)
+}
+func (m *HeaderValue) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_HeaderValue.Merge(m, src)
+}
+func (m *HeaderValue) XXX_Size() int {
+	return xxx_messageInfo_HeaderValue.Size(m)
+}
+func (m *HeaderValue) XXX_DiscardUnknown() {
+	xxx_messageInfo_HeaderValue.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_HeaderValue proto.InternalMessageInfo
+
+func (m *HeaderValue) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
+func (m *HeaderValue) GetValue() string {
+	if m != nil {
+		return m.Value
+	}
+	return ""
+}
+
+func (m *HeaderValue) GetRawValue() []byte {
+	if m != nil {
+		return m.RawValue
+	}
+	return nil
+}
+
+// HeaderValueOption is a header to add to a request or response
+type HeaderValueOption struct {
+	// The header
+	Header *HeaderValue `protobuf:"bytes,1,opt,name=header,proto3" json:"header,omitempty"`
+	// How the header is added
+	AppendAction HeaderValueOption_HeaderAppendAction `protobuf:"varint,3,opt,name=append_action,json=appendAction,proto3,enum=envoy.service.auth.v3.HeaderValueOption_HeaderAppendAction" json:"append_action,omitempty"`
+	// Whether a header with an empty value is kept
+	KeepEmptyValue       bool     `protobuf:"varint,4,opt,name=keep_empty_value,json=keepEmptyValue,proto3" json:"keep_empty_value,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *HeaderValueOption) Reset()         { *m = HeaderValueOption{} }
+func (m *HeaderValueOption) String() string { return proto.CompactTextString(m) }
+func (*HeaderValueOption) ProtoMessage()    {}
+func (*HeaderValueOption) Descriptor() ([]byte, []int) {
+	return fileDescriptor_911791676da0a922, []int{3}
+}
+func (m *HeaderValueOption) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_HeaderValueOption.Unmarshal(m, b)
+}
+func (m *HeaderValueOption) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_HeaderValueOption.Marshal(b, m, deterministic)
+}
+func (m *HeaderValueOption) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_HeaderValueOption.Merge(m, src)
+}
+func (m *HeaderValueOption) XXX_Size() int {
+	return xxx_messageInfo_HeaderValueOption.Size(m)
+}
+func (m *HeaderValueOption) XXX_DiscardUnknown() {
+	xxx_messageInfo_HeaderValueOption.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_HeaderValueOption proto.InternalMessageInfo
+
+func (m *HeaderValueOption) GetHeader() *HeaderValue {
+	if m != nil {
+		return m.Header
+	}
+	return nil
+}
+
+func (m *HeaderValueOption) GetAppendAction() HeaderValueOption_HeaderAppendAction {
+	if m != nil {
+		return m.AppendAction
+	}
+	return HeaderValueOption_APPEND_IF_EXISTS_OR_ADD
+}
+
+func (m *HeaderValueOption) GetKeepEmptyValue() bool {
+	if m != nil {
+		return m.KeepEmptyValue
+	}
+	return false
+}
+
+// HttpStatus is an HTTP response status
+type HttpStatus struct {
+	// The status code
+	Code                 StatusCode `protobuf:"varint,1,opt,name=code,proto3,enum=envoy.service.auth.v3.StatusCode" json:"code,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}   `json:"-"`
+	XXX_unrecognized     []byte     `json:"-"`
+	XXX_sizecache        int32      `json:"-"`
+}
+
+func (m *HttpStatus) Reset()         { *m = HttpStatus{} }
+func (m *HttpStatus) String() string { return proto.CompactTextString(m) }
+func (*HttpStatus) ProtoMessage()    {}
+func (*HttpStatus) Descriptor() ([]byte, []int) {
+	return fileDescriptor_911791676da0a922, []int{4}
+}
+func (m *HttpStatus) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_HttpStatus.Unmarshal(m, b)
+}
+func (m *HttpStatus) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_HttpStatus.Marshal(b, m, deterministic)
+}
+func (m *HttpStatus) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_HttpStatus.Merge(m, src)
+}
+func (m *HttpStatus) XXX_Size() int {
+	return xxx_messageInfo_HttpStatus.Size(m)
+}
+func (m *HttpStatus) XXX_DiscardUnknown() {
+	xxx_messageInfo_HttpStatus.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_HttpStatus proto.InternalMessageInfo
+
+func (m *HttpStatus) GetCode() StatusCode {
+	if m != nil {
+		return m.Code
+	}
+	return StatusCode_Empty
+}
+
+// DeniedHttpResponse is returned to the client when a request is denied
+type DeniedHttpResponse struct {
+	// The response status
+	Status *HttpStatus `protobuf:"bytes,1,opt,name=status,proto3" json:"status,omitempty"`
+	// The response headers
+	Headers []*HeaderValueOption `protobuf:"bytes,2,rep,name=headers,proto3" json:"headers,omitempty"`
+	// The response body
+	Body                 string   `protobuf:"bytes,3,opt,name=body,proto3" json:"body,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DeniedHttpResponse) Reset()         { *m = DeniedHttpResponse{} }
+func (m *DeniedHttpResponse) String() string { return proto.CompactTextString(m) }
+func (*DeniedHttpResponse) ProtoMessage()    {}
+func (*DeniedHttpResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_911791676da0a922, []int{5}
+}
+func (m *DeniedHttpResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DeniedHttpResponse.Unmarshal(m, b)
+}
+func (m *DeniedHttpResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DeniedHttpResponse.Marshal(b, m, deterministic)
+}
+func (m *DeniedHttpResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DeniedHttpResponse.Merge(m, src)
+}
+func (m *DeniedHttpResponse) XXX_Size() int {
+	return xxx_messageInfo_DeniedHttpResponse.Size(m)
+}
+func (m *DeniedHttpResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_DeniedHttpResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DeniedHttpResponse proto.InternalMessageInfo
+
+func (m *DeniedHttpResponse) GetStatus() *HttpStatus {
+	if m != nil {
+		return m.Status
+	}
+	return nil
+}
+
+func (m *DeniedHttpResponse) GetHeaders() []*HeaderValueOption {
+	if m != nil {
+		return m.Headers
+	}
+	return nil
+}
+
+func (m *DeniedHttpResponse) GetBody() string {
+	if m != nil {
+		return m.Body
+	}
+	return ""
+}
+
+// OkHttpResponse modifies a request allowed through to the upstream
+type OkHttpResponse struct {
+	// Headers added to the upstream request
+	Headers []*HeaderValueOption `protobuf:"bytes,2,rep,name=headers,proto3" json:"headers,omitempty"`
+	// Headers removed from the upstream request
+	HeadersToRemove []string `protobuf:"bytes,5,rep,name=headers_to_remove,json=headersToRemove,proto3" json:"headers_to_remove,omitempty"`
+	// Headers added to the response sent to the client
+	ResponseHeadersToAdd []*HeaderValueOption `protobuf:"bytes,6,rep,name=response_headers_to_add,json=responseHeadersToAdd,proto3" json:"response_headers_to_add,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}             `json:"-"`
+	XXX_unrecognized     []byte               `json:"-"`
+	XXX_sizecache        int32                `json:"-"`
+}
+
+func (m *OkHttpResponse) Reset()         { *m = OkHttpResponse{} }
+func (m *OkHttpResponse) String() string { return proto.CompactTextString(m) }
+func (*OkHttpResponse) ProtoMessage()    {}
+func (*OkHttpResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_911791676da0a922, []int{6}
+}
+func (m *OkHttpResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_OkHttpResponse.Unmarshal(m, b)
+}
+func (m *OkHttpResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_OkHttpResponse.Marshal(b, m, deterministic)
+}
+func (m *OkHttpResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_OkHttpResponse.Merge(m, src)
+}
+func (m *OkHttpResponse) XXX_Size() int {
+	return xxx_messageInfo_OkHttpResponse.Size(m)
+}
+func (m *OkHttpResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_OkHttpResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_OkHttpResponse proto.InternalMessageInfo
+
+func (m *OkHttpResponse) GetHeaders() []*HeaderValueOption {
+	if m != nil {
+		return m.Headers
+	}
+	return nil
+}
+
+func (m *OkHttpResponse) GetHeadersToRemove() []string {
+	if m != nil {
+		return m.HeadersToRemove
+	}
+	return nil
+}
+
+func (m *OkHttpResponse) GetResponseHeadersToAdd() []*HeaderValueOption {
+	if m != nil {
+		return m.ResponseHeadersToAdd
+	}
+	return nil
+}
+
+// CheckResponse is the result of an authorization check
+type CheckResponse struct {
+	// The status of the check. OK allows the request, any other code denies it.
+	Status *rpc.Status `protobuf:"bytes,1,opt,name=status,proto3" json:"status,omitempty"`
+	// The HTTP response
+	//
+	// Types that are valid to be assigned to HttpResponse:
+	//	*CheckResponse_DeniedResponse
+	//	*CheckResponse_OkResponse
+	HttpResponse         isCheckResponse_HttpResponse `protobuf_oneof:"http_response"`
+	XXX_NoUnkeyedLiteral struct{}                     `json:"-"`
+	XXX_unrecognized     []byte                       `json:"-"`
+	XXX_sizecache        int32                        `json:"-"`
+}
+
+func (m *CheckResponse) Reset()         { *m = CheckResponse{} }
+func (m *CheckResponse) String() string { return proto.CompactTextString(m) }
+func (*CheckResponse) ProtoMessage()    {}
+func (*CheckResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_911791676da0a922, []int{7}
+}
+func (m *CheckResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CheckResponse.Unmarshal(m, b)
+}
+func (m *CheckResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CheckResponse.Marshal(b, m, deterministic)
+}
+func (m *CheckResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CheckResponse.Merge(m, src)
+}
+func (m *CheckResponse) XXX_Size() int {
+	return xxx_messageInfo_CheckResponse.Size(m)
+}
+func (m *CheckResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_CheckResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CheckResponse proto.InternalMessageInfo
+
+type isCheckResponse_HttpResponse interface {
+	isCheckResponse_HttpResponse()
+}
+
+type CheckResponse_DeniedResponse struct {
+	DeniedResponse *DeniedHttpResponse `protobuf:"bytes,2,opt,name=denied_response,json=deniedResponse,proto3,oneof"`
+}
+type CheckResponse_OkResponse struct {
+	OkResponse *OkHttpResponse `protobuf:"bytes,3,opt,name=ok_response,json=okResponse,proto3,oneof"`
+}
+
+func (*CheckResponse_DeniedResponse) isCheckResponse_HttpResponse() {}
+func (*CheckResponse_OkResponse) isCheckResponse_HttpResponse()     {}
+
+func (m *CheckResponse) GetHttpResponse() isCheckResponse_HttpResponse {
+	if m != nil {
+		return m.HttpResponse
+	}
+	return nil
+}
+
+func (m *CheckResponse) GetStatus() *rpc.Status {
+	if m != nil {
+		return m.Status
+	}
+	return nil
+}
+
+func (m *CheckResponse) GetDeniedResponse() *DeniedHttpResponse {
+	if x, ok := m.GetHttpResponse().(*CheckResponse_DeniedResponse); ok {
+		return x.DeniedResponse
+	}
+	return nil
+}
+
+func (m *CheckResponse) GetOkResponse() *OkHttpResponse {
+	if x, ok := m.GetHttpResponse().(*CheckResponse_OkResponse); ok {
+		return x.OkResponse
+	}
+	return nil
+}
+
+// XXX_OneofFuncs is for the internal use of the proto package.
+func (*CheckResponse) XXX_OneofFuncs() (func(msg proto.Message, b *proto.Buffer) error, func(msg proto.Message, tag, wire int, b *proto.Buffer) (bool, error), func(msg proto.Message) (n int), []interface{}) {
+	return _CheckResponse_OneofMarshaler, _CheckResponse_OneofUnmarshaler, _CheckResponse_OneofSizer, []interface{}{
+		(*CheckResponse_DeniedResponse)(nil),
+		(*CheckResponse_OkResponse)(nil),
+	}
+}
+
+func _CheckResponse_OneofMarshaler(msg proto.Message, b *proto.Buffer) error {
+	m := msg.(*CheckResponse)
+	// http_response
+	switch x := m.HttpResponse.(type) {
+	case *CheckResponse_DeniedResponse:
+		_ = b.EncodeVarint(2<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.DeniedResponse); err != nil {
+			return err
+		}
+	case *CheckResponse_OkResponse:
+		_ = b.EncodeVarint(3<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.OkResponse); err != nil {
+			return err
+		}
+	case nil:
+	default:
+		return fmt.Errorf("CheckResponse.HttpResponse has unexpected type %T", x)
+	}
+	return nil
+}
+
+func _CheckResponse_OneofUnmarshaler(msg proto.Message, tag, wire int, b *proto.Buffer) (bool, error) {
+	m := msg.(*CheckResponse)
+	switch tag {
+	case 2: // http_response.denied_response
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(DeniedHttpResponse)
+		err := b.DecodeMessage(msg)
+		m.HttpResponse = &CheckResponse_DeniedResponse{msg}
+		return true, err
+	case 3: // http_response.ok_response
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(OkHttpResponse)
+		err := b.DecodeMessage(msg)
+		m.HttpResponse = &CheckResponse_OkResponse{msg}
+		return true, err
+	default:
+		return false, nil
+	}
+}
+
+func _CheckResponse_OneofSizer(msg proto.Message) (n int) {
+	m := msg.(*CheckResponse)
+	// http_response
+	switch x := m.HttpResponse.(type) {
+	case *CheckResponse_DeniedResponse:
+		s := proto.Size(x.DeniedResponse)
+		n += 1 // tag and wire
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case *CheckResponse_OkResponse:
+		s := proto.Size(x.OkResponse)
+		n += 1 // tag and wire
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case nil:
+	default:
+		panic(fmt.Sprintf("proto: unexpected type %T in oneof", x))
+	}
+	return n
+}
+
+func init() {
+	proto.RegisterEnum("envoy.service.auth.v3.StatusCode", StatusCode_name, StatusCode_value)
+	proto.RegisterEnum("envoy.service.auth.v3.HeaderValueOption_HeaderAppendAction", HeaderValueOption_HeaderAppendAction_name, HeaderValueOption_HeaderAppendAction_value)
+	proto.RegisterType((*CheckRequest)(nil), "envoy.service.auth.v3.CheckRequest")
+	proto.RegisterType((*AttributeContext)(nil), "envoy.service.auth.v3.AttributeContext")
+	proto.RegisterMapType((map[string]string)(nil), "envoy.service.auth.v3.AttributeContext.ContextExtensionsEntry")
+	proto.RegisterType((*AttributeContext_Peer)(nil), "envoy.service.auth.v3.AttributeContext.Peer")
+	proto.RegisterMapType((map[string]string)(nil), "envoy.service.auth.v3.AttributeContext.Peer.LabelsEntry")
+	proto.RegisterType((*AttributeContext_Request)(nil), "envoy.service.auth.v3.AttributeContext.Request")
+	proto.RegisterType((*AttributeContext_HttpRequest)(nil), "envoy.service.auth.v3.AttributeContext.HttpRequest")
+	proto.RegisterMapType((map[string]string)(nil), "envoy.service.auth.v3.AttributeContext.HttpRequest.HeadersEntry")
+	proto.RegisterType((*HeaderValue)(nil), "envoy.service.auth.v3.HeaderValue")
+	proto.RegisterType((*HeaderValueOption)(nil), "envoy.service.auth.v3.HeaderValueOption")
+	proto.RegisterType((*HttpStatus)(nil), "envoy.service.auth.v3.HttpStatus")
+	proto.RegisterType((*DeniedHttpResponse)(nil), "envoy.service.auth.v3.DeniedHttpResponse")
+	proto.RegisterType((*OkHttpResponse)(nil), "envoy.service.auth.v3.OkHttpResponse")
+	proto.RegisterType((*CheckResponse)(nil), "envoy.service.auth.v3.CheckResponse")
+}
+
+func init() { proto.RegisterFile("external_auth.proto", fileDescriptor_911791676da0a922) }
+
+var fileDescriptor_911791676da0a922 = []byte{
+	// 1103 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xac, 0x96, 0x4d, 0x6f, 0x1b, 0xc5,
+	0x1b, 0xc0, 0xb3, 0xde, 0xc4, 0x2f, 0x8f, 0x5f, 0xb2, 0x79, 0xda, 0x7f, 0xb3, 0x7f, 0x17, 0xa1,
+	0x60, 0x40, 0x98, 0x08, 0x39, 0x52, 0x22, 0xa4, 0x36, 0x48, 0x08, 0x27, 0x76, 0x70, 0x04, 0x4a,
+	0xa2, 0x71, 0xda, 0xa2, 0x5e, 0x96, 0xf5, 0xee, 0x34, 0x5e, 0xc5, 0xde, 0xd9, 0xce, 0x8e, 0xdd,
+	0xba, 0x12, 0x1f, 0x01, 0x09, 0x04, 0x67, 0x0e, 0x5c, 0xb8, 0xf1, 0x39, 0xf8, 0x0c, 0xdc, 0xb8,
+	0xc3, 0x0d, 0x89, 0x2b, 0xda, 0x99, 0x71, 0xbc, 0x79, 0x31, 0x38, 0x15, 0xa7, 0x9d, 0x79, 0x66,
+	0x9e, 0xdf, 0xf3, 0xb6, 0xcf, 0xcc, 0xc0, 0x1d, 0xfa, 0x52, 0x50, 0x1e, 0xba, 0x03, 0xc7, 0x1d,
+	0x89, 0x7e, 0x23, 0xe2, 0x4c, 0x30, 0xfc, 0x1f, 0x0d, 0xc7, 0x6c, 0xd2, 0x88, 0x29, 0x1f, 0x07,
+	0x1e, 0x6d, 0xc8, 0x95, 0xf1, 0x4e, 0x75, 0xfd, 0x8c, 0xb1, 0xb3, 0x01, 0xdd, 0xe2, 0x91, 0xb7,
+	0x15, 0x0b, 0x57, 0x8c, 0x62, 0xb5, 0xbf, 0xf6, 0x04, 0x4a, 0xfb, 0x7d, 0xea, 0x9d, 0x13, 0xfa,
+	0x7c, 0x44, 0x63, 0x81, 0x9f, 0x02, 0xb8, 0x42, 0xf0, 0xa0, 0x37, 0x12, 0x34, 0xb6, 0x8d, 0x0d,
+	0xa3, 0x5e, 0xdc, 0x7e, 0xaf, 0x71, 0x23, 0xb4, 0xd1, 0x9c, 0x6e, 0xdc, 0x67, 0xa1, 0xa0, 0x2f,
+	0x05, 0x49, 0xa9, 0xd6, 0xbe, 0x2e, 0x80, 0x75, 0x75, 0x03, 0xb6, 0x20, 0x1b, 0xb3, 0x11, 0xf7,
+	0xa8, 0x26, 0x7f, 0xb0, 0x20, 0xb9, 0x71, 0x42, 0x29, 0x27, 0x5a, 0x17, 0x8f, 0xa0, 0xe8, 0xd3,
+	0x58, 0x04, 0xa1, 0x2b, 0x02, 0x16, 0xda, 0x99, 0xd7, 0x40, 0xa5, 0x01, 0x78, 0x08, 0x39, 0xae,
+	0xc2, 0xb7, 0x97, 0x25, 0x6b, 0x6b, 0x51, 0x96, 0xce, 0x1a, 0x99, 0xea, 0xe3, 0x10, 0xd0, 0x53,
+	0x6b, 0x4e, 0x52, 0x9d, 0x30, 0x0e, 0x58, 0x18, 0xdb, 0xb0, 0x61, 0xd6, 0x8b, 0xdb, 0x1f, 0x2f,
+	0x4a, 0xd5, 0xdf, 0xf6, 0x05, 0xa0, 0x1d, 0x0a, 0x3e, 0x21, 0x6b, 0xde, 0x55, 0x79, 0xf5, 0x0f,
+	0x03, 0x96, 0x93, 0x78, 0xd0, 0x86, 0x9c, 0xc6, 0xca, 0x74, 0x14, 0xc8, 0x74, 0x8a, 0x27, 0x90,
+	0x1d, 0xb8, 0x3d, 0x3a, 0x88, 0x6d, 0x53, 0x7a, 0xf1, 0xe0, 0x36, 0x79, 0x6a, 0x7c, 0x2e, 0x55,
+	0x95, 0x7d, 0xcd, 0xc1, 0x37, 0xa0, 0x10, 0xf1, 0x20, 0xf4, 0x82, 0xc8, 0x1d, 0xc8, 0x84, 0x15,
+	0xc8, 0x4c, 0x80, 0x1b, 0x50, 0xf4, 0x28, 0x17, 0xc1, 0xb3, 0xc0, 0x73, 0x05, 0xb5, 0x57, 0xe4,
+	0x7a, 0x5a, 0x54, 0x7d, 0x08, 0xc5, 0x14, 0x16, 0x2d, 0x30, 0xcf, 0xe9, 0x44, 0xfe, 0x10, 0x05,
+	0x92, 0x0c, 0xf1, 0x2e, 0xac, 0x8c, 0xdd, 0xc1, 0x68, 0x1a, 0x8a, 0x9a, 0xec, 0x66, 0x1e, 0x18,
+	0x55, 0x02, 0xb9, 0xd9, 0x8f, 0xba, 0xdc, 0x17, 0x22, 0xd2, 0xd5, 0xdf, 0x59, 0x34, 0xaa, 0x8e,
+	0x10, 0xd1, 0xb4, 0x6a, 0x12, 0x50, 0xfd, 0xc1, 0x84, 0x62, 0x4a, 0x8a, 0x15, 0xc8, 0x04, 0xbe,
+	0x76, 0x27, 0x13, 0xf8, 0x78, 0x0f, 0xb2, 0x43, 0x2a, 0xfa, 0xcc, 0xd7, 0xee, 0xe8, 0x19, 0x3e,
+	0x85, 0x5c, 0x9f, 0xba, 0x3e, 0xe5, 0xd3, 0xcc, 0x7e, 0xf2, 0x1a, 0x3e, 0x34, 0x3a, 0x0a, 0xa1,
+	0x32, 0x3c, 0x05, 0x22, 0xc2, 0x72, 0xe4, 0x8a, 0xbe, 0xce, 0xae, 0x1c, 0x27, 0xb2, 0x3e, 0x8b,
+	0x85, 0xce, 0xa8, 0x1c, 0x27, 0xbe, 0xc5, 0x5e, 0x9f, 0x0e, 0xa9, 0x9d, 0x55, 0xbe, 0xa9, 0x59,
+	0x92, 0xc1, 0xe7, 0x23, 0xca, 0x27, 0x76, 0x4e, 0x65, 0x50, 0x4e, 0xb0, 0x0a, 0xf9, 0x67, 0xdc,
+	0x3d, 0x1b, 0xd2, 0x50, 0xd8, 0x79, 0xb9, 0x70, 0x31, 0x4f, 0xe8, 0x71, 0xf0, 0x8a, 0xda, 0x85,
+	0x0d, 0xa3, 0x6e, 0x12, 0x39, 0x4e, 0xf6, 0xcb, 0x43, 0xc2, 0x63, 0x03, 0x1b, 0xd4, 0xfe, 0xe9,
+	0x3c, 0xd9, 0xdf, 0x63, 0xfe, 0xc4, 0x2e, 0x2a, 0x6f, 0x92, 0x31, 0xfe, 0x1f, 0xf2, 0xdc, 0x7d,
+	0xe1, 0x48, 0x79, 0x69, 0xc3, 0xa8, 0x97, 0x48, 0x8e, 0xbb, 0x2f, 0xf6, 0x98, 0x3f, 0xa9, 0xee,
+	0x42, 0x29, 0x1d, 0xe9, 0xad, 0x8a, 0xde, 0x82, 0x7b, 0x37, 0x77, 0xc4, 0x6d, 0x28, 0x35, 0x02,
+	0x45, 0xe5, 0xc1, 0xe3, 0x44, 0xb4, 0xa8, 0x2a, 0xde, 0x87, 0x42, 0x12, 0x93, 0x5a, 0x31, 0x65,
+	0x50, 0x49, 0x90, 0x12, 0x52, 0xfb, 0x35, 0x03, 0x6b, 0x29, 0xe8, 0x71, 0x24, 0x8f, 0x93, 0x5d,
+	0xc8, 0xaa, 0x3a, 0xea, 0x43, 0xae, 0x36, 0xe7, 0xbf, 0x48, 0x69, 0x12, 0xad, 0x81, 0x5f, 0x42,
+	0xd9, 0x8d, 0x22, 0x1a, 0xfa, 0x8e, 0xeb, 0xc9, 0xc3, 0x2d, 0x31, 0x59, 0xd9, 0xfe, 0xe8, 0xdf,
+	0x11, 0xca, 0xb8, 0x96, 0x34, 0x25, 0xa3, 0x29, 0x11, 0xa4, 0xe4, 0xa6, 0x66, 0x58, 0x07, 0xeb,
+	0x9c, 0xd2, 0xc8, 0xa1, 0xc3, 0x48, 0x4c, 0x74, 0x5c, 0xc9, 0x6f, 0x96, 0x27, 0x95, 0x44, 0xde,
+	0x4e, 0xc4, 0x2a, 0xba, 0xaf, 0x00, 0xaf, 0xd3, 0xf0, 0x3e, 0xac, 0x37, 0x4f, 0x4e, 0xda, 0x47,
+	0x2d, 0xe7, 0xf0, 0xc0, 0x69, 0x7f, 0x71, 0xd8, 0x3d, 0xed, 0x3a, 0xc7, 0xc4, 0x69, 0xb6, 0x5a,
+	0xd6, 0x12, 0xae, 0x41, 0xb9, 0xd9, 0x92, 0x2b, 0xcd, 0xbd, 0x6e, 0xfb, 0xe8, 0xd4, 0x32, 0xf0,
+	0x4d, 0xa8, 0x1e, 0x3f, 0x6e, 0x93, 0x27, 0xe4, 0xf0, 0xb4, 0x7d, 0x5d, 0x25, 0x83, 0xeb, 0x70,
+	0xe7, 0x86, 0x75, 0xcb, 0xac, 0xed, 0x03, 0x24, 0x8d, 0xd2, 0x95, 0xb7, 0x15, 0x7e, 0x08, 0xcb,
+	0x1e, 0xf3, 0xd5, 0xbd, 0x51, 0xd9, 0x7e, 0x6b, 0x4e, 0x3e, 0xd4, 0xe6, 0x7d, 0xe6, 0x53, 0x22,
+	0xb7, 0xd7, 0x7e, 0x32, 0x00, 0x5b, 0x34, 0x0c, 0xa8, 0xaf, 0x9a, 0x2e, 0x8e, 0x58, 0x18, 0x53,
+	0x7c, 0x08, 0x59, 0x75, 0x0b, 0xea, 0x12, 0xcd, 0xe3, 0xcd, 0x1c, 0x20, 0x5a, 0x01, 0xf7, 0x66,
+	0x6d, 0x9f, 0x91, 0x6d, 0x5f, 0x5f, 0xb4, 0x36, 0x97, 0xda, 0x5b, 0x36, 0x89, 0x39, 0x6b, 0x9e,
+	0xda, 0x6f, 0x06, 0x54, 0x8e, 0xcf, 0x2f, 0x79, 0xf9, 0x5f, 0x98, 0xda, 0x84, 0x35, 0x3d, 0x74,
+	0x04, 0x73, 0x38, 0x1d, 0xb2, 0x71, 0x72, 0x28, 0x9b, 0xf5, 0x02, 0x59, 0xd5, 0x0b, 0xa7, 0x8c,
+	0x48, 0x31, 0x3a, 0xb0, 0xce, 0xb5, 0x6d, 0x27, 0xa5, 0xe4, 0xfa, 0xbe, 0x9d, 0xbd, 0xa5, 0xfd,
+	0xbb, 0x53, 0x50, 0x67, 0x6a, 0xa3, 0xe9, 0xfb, 0xb5, 0xdf, 0x0d, 0x28, 0xeb, 0xd7, 0x86, 0x0e,
+	0x71, 0xf3, 0x4a, 0x21, 0xb0, 0xa1, 0x1e, 0x2a, 0x0d, 0x1e, 0x79, 0x8d, 0x2b, 0x99, 0x3f, 0x85,
+	0x55, 0x5f, 0x96, 0xd2, 0x99, 0xc2, 0xf5, 0xe1, 0xff, 0xfe, 0x1c, 0xb7, 0xae, 0x17, 0xbe, 0xb3,
+	0x44, 0x2a, 0x8a, 0x71, 0xe1, 0x41, 0x07, 0x8a, 0xec, 0x7c, 0x46, 0x34, 0x25, 0xf1, 0xdd, 0x39,
+	0xc4, 0xcb, 0x05, 0xea, 0x2c, 0x11, 0x60, 0x17, 0xb1, 0xec, 0xad, 0x42, 0x39, 0xb9, 0x50, 0x2e,
+	0x58, 0x9b, 0x3f, 0x1a, 0x00, 0xb3, 0x3f, 0x12, 0x0b, 0xb0, 0x22, 0xbb, 0xcb, 0x5a, 0xc2, 0x1c,
+	0x64, 0x8e, 0x3f, 0xb3, 0x7e, 0x31, 0x10, 0x60, 0xe5, 0x80, 0x8d, 0x42, 0xdf, 0xfa, 0x39, 0x83,
+	0xab, 0x00, 0x7b, 0xae, 0xaf, 0x2f, 0x06, 0xeb, 0x1b, 0x13, 0xd7, 0xa0, 0xf4, 0x28, 0x4c, 0x4c,
+	0x33, 0x1e, 0xbc, 0xa2, 0xbe, 0xf5, 0xad, 0x89, 0x15, 0x28, 0x1c, 0x30, 0xde, 0x0b, 0x7c, 0x9f,
+	0x86, 0xd6, 0x77, 0x26, 0x96, 0x21, 0x7f, 0xc4, 0x84, 0x42, 0x7c, 0x6f, 0xa2, 0x0d, 0x77, 0x0e,
+	0x43, 0xf5, 0x28, 0xec, 0x52, 0x3e, 0xa6, 0xbc, 0xcd, 0x39, 0xe3, 0xd6, 0x9f, 0x26, 0xae, 0x03,
+	0x76, 0x55, 0x30, 0x8f, 0x42, 0x77, 0xec, 0x06, 0x03, 0xb7, 0x37, 0xa0, 0xd6, 0x5f, 0xe6, 0xb6,
+	0x07, 0xe5, 0xa6, 0x36, 0xa1, 0x5e, 0x43, 0x04, 0x56, 0x64, 0x8d, 0xf0, 0xed, 0x39, 0x49, 0x48,
+	0xbf, 0x17, 0xab, 0xef, 0xfc, 0xf3, 0x26, 0x9d, 0x9a, 0xfc, 0xd3, 0x6c, 0xb2, 0x30, 0xde, 0xe9,
+	0x65, 0xe5, 0x0d, 0xb2, 0xf3, 0xf7, 0x00, 0x05, 0xaa, 0x59, 0x75, 0xbd, 0x0a, 0x00, 0x00,
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConn
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion4
+
+// AuthorizationClient is the client API for Authorization service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type AuthorizationClient interface {
+	// Check performs an authorization check based on the attributes of the request
+	Check(ctx context.Context, in *CheckRequest, opts ...grpc.CallOption) (*CheckResponse, error)
+}
+
+type authorizationClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewAuthorizationClient(cc *grpc.ClientConn) AuthorizationClient {
+	return &authorizationClient{cc}
+}
+
+func (c *authorizationClient) Check(ctx context.Context, in *CheckRequest, opts ...grpc.CallOption) (*CheckResponse, error) {
+	out := new(CheckResponse)
+	err := c.cc.Invoke(ctx, "/envoy.service.auth.v3.Authorization/Check", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// AuthorizationServer is the server API for Authorization service.
+type AuthorizationServer interface {
+	// Check performs an authorization check based on the attributes of the request
+	Check(context.Context, *CheckRequest) (*CheckResponse, error)
+}
+
+func RegisterAuthorizationServer(s *grpc.Server, srv AuthorizationServer) {
+	s.RegisterService(&_Authorization_serviceDesc, srv)
+}
+
+func _Authorization_Check_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CheckRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthorizationServer).Check(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/envoy.service.auth.v3.Authorization/Check",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthorizationServer).Check(ctx, req.(*CheckRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _Authorization_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "envoy.service.auth.v3.Authorization",
+	HandlerType: (*AuthorizationServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Check",
+			Handler:    _Authorization_Check_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "external_auth.proto",
+}
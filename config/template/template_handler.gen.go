@@ -57,6 +57,9 @@ type Output struct {
 
 	// The session-cookie to append to the response
 	SessionCookie string
+
+	// Headers to set on the request forwarded to the target service
+	Headers map[string]string
 }
 
 func NewOutput() *Output {
@@ -73,6 +76,11 @@ func (o *Output) SetSessionCookie(val string) {
 	o.SessionCookie = val
 }
 
+func (o *Output) SetHeaders(val map[string]string) {
+	o.fieldsSet["headers"] = true
+	o.Headers = val
+}
+
 func (o *Output) WasSet(field string) bool {
 	_, found := o.fieldsSet[field]
 	return found
@@ -127,6 +127,8 @@ type OutputMsg struct {
 	Authorization string `protobuf:"bytes,1,opt,name=authorization,proto3" json:"authorization,omitempty"`
 	// The session-cookie to append to the response
 	SessionCookie string `protobuf:"bytes,2,opt,name=sessionCookie,proto3" json:"sessionCookie,omitempty"`
+	// Headers to set on the request forwarded to the target service
+	Headers map[string]string `protobuf:"bytes,3,rep,name=headers,proto3" json:"headers,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
 }
 
 func (m *OutputMsg) Reset()      { *m = OutputMsg{} }
@@ -809,6 +811,7 @@ func init() {
 	proto.RegisterType((*HandleAuthnZRequest)(nil), "authnz.HandleAuthnZRequest")
 	proto.RegisterType((*HandleAuthnZResponse)(nil), "authnz.HandleAuthnZResponse")
 	proto.RegisterType((*OutputMsg)(nil), "authnz.OutputMsg")
+	proto.RegisterMapType((map[string]string)(nil), "authnz.OutputMsg.HeadersEntry")
 	proto.RegisterType((*InstanceMsg)(nil), "authnz.InstanceMsg")
 	proto.RegisterType((*TargetMsg)(nil), "authnz.TargetMsg")
 	proto.RegisterMapType((map[string]*v1beta11.Value)(nil), "authnz.TargetMsg.PropertiesEntry")
@@ -843,79 +846,80 @@ func init() {
 }
 
 var fileDescriptor_89cbb87e127b4851 = []byte{
-	// 1138 bytes of a gzipped FileDescriptorProto
+	// 1163 bytes of a gzipped FileDescriptorProto
 	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xc4, 0x58, 0xcf, 0x6f, 0x1b, 0x45,
-	0x14, 0xf6, 0x6e, 0x1c, 0xa7, 0x7e, 0x26, 0x29, 0x8c, 0x4d, 0xd9, 0xa6, 0xd1, 0x36, 0xb8, 0x88,
-	0x06, 0x70, 0x77, 0x49, 0x2a, 0xa2, 0x0a, 0xc4, 0x21, 0xb5, 0x28, 0x0d, 0x14, 0x11, 0x96, 0x0a,
-	0xa4, 0x1e, 0x30, 0x1b, 0x7b, 0x6a, 0xaf, 0x62, 0xef, 0x2c, 0x3b, 0xe3, 0xa8, 0xae, 0x40, 0x42,
-	0x48, 0x1c, 0xb8, 0x20, 0x44, 0xef, 0x9c, 0xb9, 0x81, 0x38, 0x22, 0xfe, 0x80, 0xaa, 0xa7, 0x08,
-	0x09, 0xa9, 0x17, 0x24, 0xe2, 0xf4, 0x80, 0x04, 0x87, 0x1e, 0x38, 0x70, 0x44, 0x33, 0x3b, 0xfb,
-	0x33, 0x8b, 0xa9, 0x14, 0x11, 0xdf, 0x66, 0xde, 0x7c, 0xbb, 0xfb, 0xbe, 0xef, 0xbd, 0xef, 0x69,
-	0x6c, 0x58, 0xb7, 0x3b, 0xb6, 0xc7, 0xb0, 0x7f, 0xa1, 0x4d, 0xdc, 0x9b, 0x4e, 0xd7, 0x64, 0x78,
-	0xe0, 0xf5, 0x6d, 0x86, 0xa3, 0x45, 0xab, 0x67, 0xbb, 0x9d, 0x3e, 0xf6, 0x5b, 0x14, 0xfb, 0xbb,
-	0x4e, 0x1b, 0x1b, 0x9e, 0x4f, 0x18, 0x41, 0x25, 0x7b, 0xc8, 0x7a, 0xee, 0xed, 0xc5, 0x5a, 0x97,
-	0x74, 0x89, 0x08, 0x99, 0x7c, 0x15, 0x9c, 0x2e, 0x36, 0x06, 0xce, 0x2d, 0xec, 0x9b, 0xf2, 0xdd,
-	0xe6, 0x80, 0x74, 0x70, 0xdf, 0xdc, 0x5d, 0xdd, 0xc6, 0xcc, 0x5e, 0x35, 0xf1, 0x2d, 0x86, 0x5d,
-	0xea, 0x10, 0x97, 0x4a, 0xf4, 0xe9, 0x2e, 0x21, 0xdd, 0x3e, 0x36, 0xc5, 0x6e, 0x7b, 0x78, 0xd3,
-	0xb4, 0xdd, 0x91, 0x3c, 0x3a, 0x3f, 0xe9, 0x45, 0xed, 0x1e, 0x6e, 0xef, 0x48, 0xe0, 0x59, 0x8f,
-	0xf4, 0x9d, 0xf6, 0x28, 0x3a, 0xdb, 0xb5, 0xfb, 0x43, 0xdc, 0x62, 0x23, 0x0f, 0x87, 0x1f, 0xc9,
-	0x00, 0xe2, 0xa3, 0xfa, 0x37, 0x0a, 0x54, 0xaf, 0x0a, 0x96, 0x1b, 0x9c, 0xd4, 0x0d, 0x0b, 0x7f,
-	0x34, 0xc4, 0x94, 0x21, 0x13, 0x4e, 0x38, 0x2e, 0x65, 0xb6, 0xdb, 0xc6, 0x9a, 0xb2, 0xac, 0xac,
-	0x54, 0xd6, 0xaa, 0x46, 0x40, 0xdb, 0xd8, 0x94, 0xf1, 0xb7, 0x68, 0xd7, 0x8a, 0x40, 0xe8, 0x15,
-	0x58, 0x90, 0x99, 0xb6, 0x02, 0x39, 0x35, 0x55, 0x3c, 0x56, 0x33, 0x02, 0x86, 0x46, 0xc8, 0xd0,
-	0xd8, 0x70, 0x47, 0xd6, 0xbc, 0xc4, 0x36, 0x05, 0x14, 0x9d, 0x86, 0x13, 0x1d, 0xdc, 0x19, 0x7a,
-	0x2d, 0xa7, 0xa3, 0xcd, 0x2c, 0x2b, 0x2b, 0x65, 0x6b, 0x4e, 0xec, 0x37, 0x3b, 0xf5, 0x2f, 0x14,
-	0xa8, 0xa5, 0x13, 0xa4, 0x1e, 0x71, 0x29, 0x46, 0x57, 0xa0, 0xe4, 0x63, 0x3a, 0xec, 0x33, 0x99,
-	0x9f, 0x61, 0x38, 0x94, 0x39, 0xc4, 0x10, 0xaa, 0x19, 0xf2, 0xfd, 0x86, 0x50, 0xcd, 0x90, 0xc4,
-	0x8d, 0x26, 0x57, 0xcd, 0x12, 0x4f, 0x59, 0xf2, 0x69, 0xf4, 0x1c, 0x94, 0xc8, 0x90, 0x79, 0x43,
-	0x26, 0x13, 0x7e, 0x22, 0xe4, 0xf9, 0xb6, 0x88, 0x72, 0x96, 0x12, 0x50, 0x7f, 0x1f, 0xca, 0x51,
-	0x10, 0x3d, 0x03, 0xf3, 0x1c, 0x48, 0x7c, 0xe7, 0xb6, 0xcd, 0x1c, 0xe2, 0x8a, 0x34, 0xca, 0x56,
-	0x3a, 0xc8, 0x51, 0x14, 0x53, 0x5e, 0xf1, 0x26, 0x21, 0x3b, 0x0e, 0x16, 0x1f, 0x29, 0x5b, 0xe9,
-	0x60, 0xfd, 0x13, 0xa8, 0x24, 0x54, 0x45, 0x4f, 0x42, 0xd1, 0xb5, 0x07, 0x58, 0xfb, 0xee, 0xde,
-	0x4f, 0x75, 0x01, 0x17, 0x5b, 0xd4, 0x80, 0x39, 0x3f, 0x28, 0x8f, 0xa4, 0x8c, 0xc2, 0x54, 0x65,
-	0xd5, 0x78, 0xae, 0x21, 0x84, 0xf3, 0x62, 0xb6, 0xdf, 0xc5, 0x87, 0x78, 0x5d, 0x17, 0x51, 0xc1,
-	0x2b, 0x00, 0xd4, 0xbf, 0x54, 0xa1, 0x1c, 0x45, 0xd1, 0x12, 0x94, 0xf9, 0xe7, 0xa8, 0x67, 0xcb,
-	0xda, 0x97, 0xad, 0x38, 0x80, 0x34, 0x98, 0x93, 0x6e, 0x90, 0x54, 0xc2, 0x2d, 0x3a, 0x05, 0xa5,
-	0x01, 0x66, 0x3d, 0x12, 0x96, 0x50, 0xee, 0x10, 0x82, 0xa2, 0x67, 0xb3, 0x9e, 0x56, 0x0c, 0xa8,
-	0xf0, 0x35, 0xda, 0x00, 0xf0, 0x7c, 0xe2, 0x61, 0x9f, 0x39, 0x98, 0x6a, 0xb3, 0xcb, 0x33, 0x2b,
-	0x95, 0xb5, 0xa7, 0x0f, 0x25, 0x68, 0x6c, 0x45, 0x98, 0xd7, 0x5c, 0xe6, 0x8f, 0xac, 0xc4, 0x43,
-	0x8b, 0x37, 0xe0, 0x64, 0xe6, 0x18, 0x3d, 0x0e, 0x33, 0x3b, 0x78, 0x24, 0x73, 0xe6, 0x4b, 0xb4,
-	0x0a, 0xb3, 0xc2, 0x0d, 0x52, 0x83, 0x33, 0xb2, 0x47, 0x02, 0x3f, 0x44, 0x6d, 0xf1, 0x1e, 0x87,
-	0x58, 0x01, 0xf2, 0x65, 0xf5, 0x92, 0x52, 0xff, 0x53, 0x01, 0xb8, 0x8a, 0xed, 0x0e, 0xf6, 0x29,
-	0x57, 0x44, 0x83, 0xb9, 0xb6, 0x28, 0x14, 0x95, 0xef, 0x0e, 0xb7, 0x87, 0x9b, 0x40, 0xcd, 0x6b,
-	0x82, 0xcb, 0x29, 0xb6, 0x33, 0x82, 0x6d, 0x3d, 0x64, 0x1b, 0x7f, 0x67, 0x6a, 0x74, 0xff, 0x52,
-	0x60, 0xe1, 0x9d, 0x21, 0xf6, 0x47, 0x5b, 0xb6, 0x6f, 0x0f, 0x04, 0xe5, 0x1a, 0xcc, 0x62, 0xdf,
-	0x27, 0xbe, 0x7c, 0x7b, 0xb0, 0xe1, 0xa5, 0x6c, 0x93, 0x4e, 0x58, 0x79, 0xb1, 0xe6, 0x48, 0xca,
-	0x6c, 0x86, 0x65, 0xd5, 0x83, 0x0d, 0xba, 0x92, 0xa2, 0x5c, 0x14, 0x94, 0x9f, 0x0d, 0x29, 0xa7,
-	0xbf, 0x35, 0x35, 0xda, 0xf7, 0x54, 0x80, 0xd8, 0x39, 0xbc, 0x7f, 0x69, 0xbb, 0x87, 0x07, 0x61,
-	0xd3, 0xcb, 0x1d, 0x27, 0xdd, 0x23, 0x94, 0x85, 0xa4, 0xf9, 0x3a, 0xea, 0xe9, 0x99, 0x44, 0x4f,
-	0x37, 0x60, 0xae, 0x17, 0xd4, 0x52, 0xb4, 0x7a, 0xc2, 0x9e, 0x71, 0x89, 0xad, 0x10, 0x82, 0x0c,
-	0x28, 0x79, 0x42, 0x01, 0x6d, 0x56, 0x80, 0x4f, 0xe5, 0x8b, 0x63, 0x49, 0x54, 0xa6, 0x87, 0x4a,
-	0xe9, 0x1e, 0x8a, 0x59, 0x4c, 0x4d, 0x4c, 0x1b, 0x8a, 0xd7, 0x47, 0x1e, 0x46, 0x17, 0xb2, 0x43,
-	0xaa, 0x9a, 0x49, 0x92, 0xa3, 0xe2, 0x29, 0xf5, 0x7c, 0x66, 0x4a, 0xa1, 0xf4, 0x10, 0x10, 0xe0,
-	0x70, 0x4c, 0x7d, 0xaf, 0x00, 0xc4, 0xe1, 0x8c, 0x22, 0xb3, 0x69, 0x45, 0x62, 0xdc, 0x44, 0x45,
-	0x3e, 0x78, 0x14, 0x45, 0x5e, 0x4a, 0x2a, 0xb2, 0xb0, 0x76, 0x76, 0x82, 0x22, 0x22, 0xdf, 0x84,
-	0x2a, 0x3f, 0x28, 0x50, 0x91, 0xd5, 0x17, 0x39, 0x37, 0x73, 0x26, 0xc1, 0xb9, 0x4c, 0x9b, 0x4c,
-	0x3d, 0xe9, 0x1f, 0x15, 0x38, 0x99, 0xe8, 0x42, 0x91, 0xf8, 0xeb, 0x39, 0x7e, 0x3e, 0x9f, 0xd3,
-	0xb2, 0x53, 0x4f, 0xfe, 0x6b, 0x15, 0x2a, 0x89, 0x4e, 0xe3, 0xfd, 0x98, 0x76, 0x65, 0x35, 0x47,
-	0xee, 0xd8, 0x96, 0x66, 0xc6, 0x96, 0x4f, 0xfd, 0x0b, 0xc7, 0xc8, 0x97, 0xcd, 0x1c, 0x5f, 0x9e,
-	0xcb, 0x69, 0xf9, 0xa9, 0x8a, 0xf2, 0x31, 0xcc, 0x87, 0xf7, 0x0b, 0x41, 0x01, 0xad, 0x67, 0x5d,
-	0xba, 0x94, 0x49, 0x39, 0x05, 0x8f, 0xed, 0x7a, 0x31, 0x63, 0xd7, 0x33, 0x69, 0xbf, 0xa5, 0x9f,
-	0x0a, 0x7d, 0xfb, 0xb9, 0x0a, 0xd5, 0x9c, 0xf3, 0x63, 0xb9, 0x68, 0xbc, 0x99, 0x33, 0x24, 0x5e,
-	0x98, 0x90, 0xf4, 0xc4, 0x32, 0xbd, 0xfa, 0x28, 0x65, 0xaa, 0x25, 0xcb, 0x54, 0x4e, 0x56, 0xe1,
-	0x80, 0x5f, 0x65, 0x83, 0x3e, 0x4b, 0x0b, 0x71, 0xd4, 0xfb, 0xc5, 0xb5, 0x9c, 0xa9, 0xd2, 0xc8,
-	0xb4, 0xf9, 0xb1, 0xb1, 0xfc, 0x43, 0x01, 0x2d, 0x61, 0x96, 0x34, 0xd3, 0xa3, 0x5e, 0x2b, 0xb6,
-	0x72, 0xc6, 0xd0, 0x8b, 0x39, 0x16, 0x3d, 0x36, 0xb6, 0xbf, 0xa8, 0x50, 0xcb, 0xb3, 0xcc, 0x91,
-	0x6f, 0x13, 0xeb, 0xd9, 0xb9, 0xb5, 0x34, 0xa9, 0xa0, 0xf1, 0x00, 0xbb, 0x94, 0x19, 0x60, 0xcb,
-	0xff, 0xa5, 0x4e, 0x34, 0xc9, 0xae, 0xe5, 0x4c, 0xb2, 0xc6, 0xa4, 0xb1, 0xf0, 0x3f, 0xea, 0xba,
-	0xf6, 0x61, 0xfa, 0x67, 0xe9, 0xbb, 0xd2, 0xfa, 0x9b, 0xf0, 0x58, 0x32, 0x8c, 0xa2, 0xf9, 0x93,
-	0xf3, 0x1b, 0x76, 0x71, 0x29, 0xff, 0x30, 0xf8, 0xfd, 0x78, 0xf9, 0x8d, 0xbb, 0xfb, 0x7a, 0x61,
-	0x6f, 0x5f, 0x2f, 0xdc, 0xdf, 0xd7, 0x0b, 0x0f, 0xf7, 0xf5, 0xc2, 0xa7, 0x63, 0x5d, 0xf9, 0x76,
-	0xac, 0x17, 0xee, 0x8e, 0x75, 0x65, 0x6f, 0xac, 0x2b, 0xbf, 0x8d, 0x75, 0xe5, 0xf7, 0xb1, 0x5e,
-	0x78, 0x38, 0xd6, 0x95, 0xaf, 0x0e, 0xf4, 0xc2, 0xde, 0x81, 0x5e, 0xb8, 0x7f, 0xa0, 0x17, 0xfe,
-	0xfe, 0xf9, 0xc1, 0x1d, 0xb5, 0xf8, 0xd9, 0xaf, 0x0f, 0xee, 0xa8, 0xf2, 0x9f, 0x80, 0xed, 0x92,
-	0xf8, 0x71, 0x7b, 0xf1, 0x9f, 0x00, 0x00, 0x00, 0xff, 0xff, 0xf6, 0x9e, 0xae, 0xef, 0x52, 0x10,
-	0x00, 0x00,
+	0x14, 0xf6, 0x6e, 0x1c, 0xa7, 0x7e, 0x6e, 0x52, 0x98, 0x98, 0xb2, 0x75, 0xa3, 0x6d, 0x70, 0x11,
+	0x0d, 0xe0, 0xee, 0x12, 0x57, 0x44, 0x51, 0x11, 0x87, 0xc4, 0xa2, 0x34, 0x50, 0x44, 0x58, 0x2a,
+	0x0e, 0x3d, 0x60, 0x36, 0xf6, 0xd4, 0x5e, 0xc5, 0xde, 0x59, 0x76, 0xc7, 0x51, 0x5d, 0x81, 0x84,
+	0x90, 0x38, 0x70, 0x41, 0x88, 0xde, 0x39, 0x73, 0x03, 0x71, 0x44, 0xfc, 0x01, 0x55, 0x25, 0xa4,
+	0x08, 0x09, 0xa9, 0x17, 0x24, 0xe2, 0xf4, 0x80, 0x04, 0x87, 0x1e, 0x38, 0x70, 0x44, 0x33, 0x3b,
+	0xfb, 0xd3, 0x5b, 0x53, 0x29, 0x6a, 0x7c, 0x9b, 0x1f, 0xdf, 0xec, 0xbc, 0xef, 0x7b, 0xdf, 0x7b,
+	0x1a, 0x1b, 0xd6, 0xcc, 0xb6, 0xe9, 0x50, 0xec, 0x5e, 0x6c, 0x11, 0xfb, 0xa6, 0xd5, 0xd1, 0x29,
+	0xee, 0x3b, 0x3d, 0x93, 0xe2, 0x70, 0xd0, 0xec, 0x9a, 0x76, 0xbb, 0x87, 0xdd, 0xa6, 0x87, 0xdd,
+	0x3d, 0xab, 0x85, 0x35, 0xc7, 0x25, 0x94, 0xa0, 0x82, 0x39, 0xa0, 0x5d, 0xfb, 0x76, 0xa5, 0xdc,
+	0x21, 0x1d, 0xc2, 0x97, 0x74, 0x36, 0xf2, 0x77, 0x2b, 0xb5, 0xbe, 0x75, 0x0b, 0xbb, 0xba, 0xf8,
+	0xb6, 0xde, 0x27, 0x6d, 0xdc, 0xd3, 0xf7, 0x56, 0x77, 0x30, 0x35, 0x57, 0x75, 0x7c, 0x8b, 0x62,
+	0xdb, 0xb3, 0x88, 0xed, 0x09, 0xf4, 0x99, 0x0e, 0x21, 0x9d, 0x1e, 0xd6, 0xf9, 0x6c, 0x67, 0x70,
+	0x53, 0x37, 0xed, 0xa1, 0xd8, 0xba, 0x30, 0xe9, 0x43, 0xad, 0x2e, 0x6e, 0xed, 0x0a, 0xe0, 0x39,
+	0x87, 0xf4, 0xac, 0xd6, 0x30, 0xdc, 0xdb, 0x33, 0x7b, 0x03, 0xdc, 0xa4, 0x43, 0x07, 0x07, 0x97,
+	0xa4, 0x00, 0xd1, 0x56, 0xf5, 0x5b, 0x09, 0x16, 0xaf, 0x72, 0x96, 0x1b, 0x8c, 0xd4, 0x0d, 0x03,
+	0x7f, 0x3c, 0xc0, 0x1e, 0x45, 0x3a, 0x9c, 0xb0, 0x6c, 0x8f, 0x9a, 0x76, 0x0b, 0x2b, 0xd2, 0xb2,
+	0xb4, 0x52, 0xaa, 0x2f, 0x6a, 0x3e, 0x6d, 0x6d, 0x4b, 0xac, 0xbf, 0xe3, 0x75, 0x8c, 0x10, 0x84,
+	0x5e, 0x83, 0x05, 0x11, 0x69, 0xd3, 0x97, 0x53, 0x91, 0xf9, 0xb1, 0xb2, 0xe6, 0x33, 0xd4, 0x02,
+	0x86, 0xda, 0x86, 0x3d, 0x34, 0xe6, 0x05, 0xb6, 0xc1, 0xa1, 0xe8, 0x0c, 0x9c, 0x68, 0xe3, 0xf6,
+	0xc0, 0x69, 0x5a, 0x6d, 0x65, 0x66, 0x59, 0x5a, 0x29, 0x1a, 0x73, 0x7c, 0xbe, 0xd5, 0xae, 0x7e,
+	0x29, 0x41, 0x39, 0x19, 0xa0, 0xe7, 0x10, 0xdb, 0xc3, 0xe8, 0x0a, 0x14, 0x5c, 0xec, 0x0d, 0x7a,
+	0x54, 0xc4, 0xa7, 0x69, 0x96, 0x47, 0x2d, 0xa2, 0x71, 0xd5, 0x34, 0xf1, 0x7d, 0x8d, 0xab, 0xa6,
+	0x09, 0xe2, 0x5a, 0x83, 0xa9, 0x66, 0xf0, 0x53, 0x86, 0x38, 0x8d, 0x5e, 0x84, 0x02, 0x19, 0x50,
+	0x67, 0x40, 0x45, 0xc0, 0x4f, 0x07, 0x3c, 0xdf, 0xe5, 0xab, 0x8c, 0xa5, 0x00, 0x54, 0x7f, 0x91,
+	0xa0, 0x18, 0xae, 0xa2, 0xe7, 0x61, 0x9e, 0x21, 0x89, 0x6b, 0xdd, 0x36, 0xa9, 0x45, 0x6c, 0x1e,
+	0x47, 0xd1, 0x48, 0x2e, 0x32, 0x94, 0x87, 0x3d, 0x96, 0xf2, 0x06, 0x21, 0xbb, 0x16, 0xe6, 0xb7,
+	0x14, 0x8d, 0xe4, 0x22, 0x5a, 0x87, 0xb9, 0x2e, 0x36, 0xdb, 0xd8, 0xf5, 0x94, 0x99, 0xe5, 0x99,
+	0x95, 0x52, 0x5d, 0x1d, 0x8b, 0x42, 0xbb, 0xea, 0x03, 0xde, 0xb0, 0xa9, 0x3b, 0x34, 0x02, 0x78,
+	0xe5, 0x32, 0x9c, 0x8c, 0x6f, 0xa0, 0xa7, 0x60, 0x66, 0x17, 0x0f, 0x45, 0x2c, 0x6c, 0x88, 0xca,
+	0x30, 0xcb, 0x1d, 0x21, 0x6e, 0xf6, 0x27, 0x97, 0xe5, 0x75, 0xa9, 0xfa, 0x29, 0x94, 0x62, 0xc9,
+	0x44, 0xcf, 0x40, 0xde, 0x36, 0xfb, 0x58, 0xf9, 0xfe, 0xde, 0xcf, 0x55, 0x0e, 0xe5, 0x53, 0x54,
+	0x83, 0x39, 0xd7, 0x77, 0x85, 0x50, 0x1a, 0x05, 0xb1, 0x09, 0xb3, 0x30, 0x89, 0x02, 0x08, 0x93,
+	0x93, 0x9a, 0x6e, 0x07, 0x8f, 0xc9, 0x79, 0x9d, 0xaf, 0x72, 0x39, 0x7d, 0x40, 0xf5, 0x2b, 0x19,
+	0x8a, 0xe1, 0x2a, 0x5a, 0x82, 0x22, 0xbb, 0xce, 0x73, 0x4c, 0x61, 0xb9, 0xa2, 0x11, 0x2d, 0x20,
+	0x05, 0xe6, 0x44, 0x11, 0x0a, 0x1a, 0xc1, 0x14, 0x9d, 0x86, 0x42, 0x1f, 0xd3, 0x2e, 0x09, 0x9c,
+	0x23, 0x66, 0x08, 0x41, 0xde, 0x31, 0x69, 0x57, 0xc9, 0xfb, 0x54, 0xd8, 0x18, 0x6d, 0x00, 0x38,
+	0x2e, 0x71, 0xb0, 0x4b, 0x2d, 0xec, 0x29, 0xb3, 0x5c, 0xe9, 0xe7, 0xc6, 0x02, 0xd4, 0xb6, 0x43,
+	0x8c, 0x2f, 0x76, 0xec, 0x50, 0xe5, 0x06, 0x9c, 0x4a, 0x6d, 0x67, 0x48, 0xbe, 0x1a, 0x97, 0xbc,
+	0x54, 0x3f, 0x2b, 0xac, 0xe9, 0x97, 0x61, 0xe8, 0xc6, 0x0f, 0x18, 0x24, 0x9e, 0x8f, 0xbf, 0x25,
+	0x00, 0x91, 0x4c, 0xa6, 0x88, 0x02, 0x73, 0x2d, 0x6e, 0x0f, 0x4f, 0x7c, 0x3b, 0x98, 0x8e, 0x5b,
+	0x4f, 0xce, 0xb2, 0xde, 0x66, 0x82, 0xad, 0xef, 0xab, 0x6a, 0xc0, 0x36, 0xba, 0x67, 0x6a, 0x74,
+	0xff, 0x91, 0x60, 0xe1, 0xbd, 0x01, 0x76, 0x87, 0xdb, 0xa6, 0x6b, 0xf6, 0x39, 0xe5, 0x32, 0xcc,
+	0x62, 0xd7, 0x25, 0xae, 0xf8, 0xba, 0x3f, 0x61, 0xa9, 0x6c, 0x91, 0x76, 0x90, 0x79, 0x3e, 0x66,
+	0x48, 0x8f, 0x9a, 0x14, 0x8b, 0xac, 0xfb, 0x13, 0x74, 0x25, 0x41, 0x39, 0xcf, 0x29, 0xbf, 0x10,
+	0x50, 0x4e, 0xde, 0x35, 0x35, 0xda, 0xf7, 0x64, 0x80, 0xa8, 0x72, 0x98, 0x7f, 0xbd, 0x56, 0x17,
+	0xf7, 0x03, 0xd3, 0x8b, 0x19, 0x23, 0xdd, 0x25, 0x1e, 0x0d, 0x48, 0xb3, 0x71, 0xe8, 0xe9, 0x99,
+	0x98, 0xa7, 0x6b, 0x51, 0xeb, 0xc8, 0x27, 0xcb, 0x33, 0x4a, 0x71, 0xd8, 0x2e, 0x90, 0x06, 0x05,
+	0x87, 0x2b, 0xa0, 0xcc, 0x72, 0xf0, 0xe9, 0x6c, 0x71, 0x0c, 0x81, 0x4a, 0x79, 0xa8, 0x90, 0xf4,
+	0x50, 0xc4, 0x62, 0x6a, 0x62, 0x9a, 0x90, 0xbf, 0x3e, 0x74, 0x30, 0xba, 0x98, 0x6e, 0x52, 0x8b,
+	0xa9, 0x20, 0x19, 0x2a, 0xea, 0x52, 0x2f, 0xa5, 0xba, 0x14, 0x4a, 0x36, 0x01, 0x0e, 0x0e, 0xda,
+	0xd4, 0x0f, 0x12, 0x40, 0xb4, 0x8c, 0x36, 0x33, 0x7a, 0x48, 0x75, 0xfc, 0xf8, 0x44, 0x45, 0x3e,
+	0x7c, 0x1c, 0x45, 0x5e, 0x8d, 0x2b, 0xb2, 0x50, 0x3f, 0x37, 0x41, 0x11, 0x1e, 0x6f, 0x4c, 0x95,
+	0x1f, 0x25, 0x28, 0x89, 0xec, 0xf3, 0x98, 0x1b, 0x19, 0x9d, 0xe0, 0x7c, 0xca, 0x26, 0x53, 0x0f,
+	0xfa, 0x27, 0x09, 0x4e, 0xc5, 0x5c, 0xc8, 0x03, 0x7f, 0x33, 0xa3, 0x9e, 0x2f, 0x64, 0x58, 0x76,
+	0xea, 0xc1, 0x7f, 0x23, 0x43, 0x29, 0xe6, 0x34, 0xe6, 0xc7, 0x64, 0x55, 0x2e, 0x66, 0xc8, 0x1d,
+	0x95, 0xa5, 0x9e, 0x2a, 0xcb, 0x67, 0x1f, 0xc1, 0x31, 0xac, 0xcb, 0x46, 0x46, 0x5d, 0x9e, 0xcf,
+	0xb0, 0xfc, 0x54, 0x45, 0xf9, 0x04, 0xe6, 0x83, 0xf7, 0x05, 0xa7, 0x80, 0xd6, 0xd2, 0x55, 0xba,
+	0x94, 0x0a, 0x39, 0x01, 0x8f, 0xca, 0xf5, 0x52, 0xaa, 0x5c, 0xcf, 0x26, 0xeb, 0x2d, 0x79, 0x2a,
+	0xa8, 0xdb, 0x2f, 0x64, 0x58, 0xcc, 0xd8, 0x3f, 0x96, 0x87, 0xc6, 0xdb, 0x19, 0x4d, 0xe2, 0xe5,
+	0x09, 0x41, 0x4f, 0x4c, 0xd3, 0xeb, 0x8f, 0x93, 0xa6, 0x47, 0xbf, 0xf2, 0x0e, 0xd9, 0x0b, 0xda,
+	0xf7, 0x59, 0x52, 0x88, 0xa3, 0xbe, 0x2f, 0xae, 0x65, 0x74, 0x95, 0x5a, 0xca, 0xe6, 0xc7, 0xc6,
+	0xf2, 0x2f, 0x09, 0x94, 0x58, 0xb1, 0x24, 0x99, 0x1e, 0xf5, 0x59, 0xb1, 0x9d, 0xd1, 0x86, 0x5e,
+	0xc9, 0x28, 0xd1, 0x63, 0x63, 0xfb, 0x9b, 0x0c, 0xe5, 0xac, 0x92, 0x39, 0xf2, 0x6b, 0x62, 0x2d,
+	0xdd, 0xb7, 0x96, 0x26, 0x25, 0x34, 0x6a, 0x60, 0xeb, 0xa9, 0x06, 0xb6, 0xfc, 0x7f, 0xea, 0x84,
+	0x9d, 0xec, 0x5a, 0x46, 0x27, 0xab, 0x4d, 0x6a, 0x0b, 0x4f, 0x50, 0xd7, 0xfa, 0x47, 0xc9, 0x5f,
+	0xc3, 0xef, 0x8b, 0xd2, 0xdf, 0x82, 0x93, 0xf1, 0x65, 0x14, 0xf6, 0x9f, 0x8c, 0x9f, 0xce, 0x95,
+	0xa5, 0xec, 0x4d, 0xff, 0x67, 0xeb, 0xe6, 0x5b, 0x77, 0x0f, 0xd4, 0xdc, 0xfe, 0x81, 0x9a, 0xbb,
+	0x7f, 0xa0, 0xe6, 0x1e, 0x1e, 0xa8, 0xb9, 0xcf, 0x46, 0xaa, 0xf4, 0xdd, 0x48, 0xcd, 0xdd, 0x1d,
+	0xa9, 0xd2, 0xfe, 0x48, 0x95, 0xfe, 0x18, 0xa9, 0xd2, 0x9f, 0x23, 0x35, 0xf7, 0x70, 0xa4, 0x4a,
+	0x5f, 0x1f, 0xaa, 0xb9, 0xfd, 0x43, 0x35, 0x77, 0xff, 0x50, 0xcd, 0xfd, 0xfb, 0xeb, 0x83, 0x3b,
+	0x72, 0xfe, 0xf3, 0xdf, 0x1f, 0xdc, 0x91, 0xc5, 0x1f, 0x10, 0x3b, 0x05, 0xfe, 0x9b, 0xfa, 0xd2,
+	0x7f, 0x03, 0x00, 0x6a, 0x18, 0x0a, 0x1f, 0xc9, 0x10, 0x00, 0x00,
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -1101,6 +1105,23 @@ func (m *OutputMsg) MarshalTo(dAtA []byte) (int, error) {
 		i = encodeVarintTemplateHandlerService(dAtA, i, uint64(len(m.SessionCookie)))
 		i += copy(dAtA[i:], m.SessionCookie)
 	}
+	if len(m.Headers) > 0 {
+		for k, _ := range m.Headers {
+			dAtA[i] = 0x1a
+			i++
+			v := m.Headers[k]
+			mapSize := 1 + len(k) + sovTemplateHandlerService(uint64(len(k))) + 1 + len(v) + sovTemplateHandlerService(uint64(len(v)))
+			i = encodeVarintTemplateHandlerService(dAtA, i, uint64(mapSize))
+			dAtA[i] = 0xa
+			i++
+			i = encodeVarintTemplateHandlerService(dAtA, i, uint64(len(k)))
+			i += copy(dAtA[i:], k)
+			dAtA[i] = 0x12
+			i++
+			i = encodeVarintTemplateHandlerService(dAtA, i, uint64(len(v)))
+			i += copy(dAtA[i:], v)
+		}
+	}
 	return i, nil
 }
 
@@ -1957,6 +1978,14 @@ func (m *OutputMsg) Size() (n int) {
 	if l > 0 {
 		n += 1 + l + sovTemplateHandlerService(uint64(l))
 	}
+	if len(m.Headers) > 0 {
+		for k, v := range m.Headers {
+			_ = k
+			_ = v
+			mapEntrySize := 1 + len(k) + sovTemplateHandlerService(uint64(len(k))) + 1 + len(v) + sovTemplateHandlerService(uint64(len(v)))
+			n += mapEntrySize + 1 + sovTemplateHandlerService(uint64(mapEntrySize))
+		}
+	}
 	return n
 }
 
@@ -2399,9 +2428,20 @@ func (this *OutputMsg) String() string {
 	if this == nil {
 		return "nil"
 	}
+	keysForHeaders := make([]string, 0, len(this.Headers))
+	for k, _ := range this.Headers {
+		keysForHeaders = append(keysForHeaders, k)
+	}
+	github_com_gogo_protobuf_sortkeys.Strings(keysForHeaders)
+	mapStringForHeaders := "map[string]string{"
+	for _, k := range keysForHeaders {
+		mapStringForHeaders += fmt.Sprintf("%v: %v,", k, this.Headers[k])
+	}
+	mapStringForHeaders += "}"
 	s := strings.Join([]string{`&OutputMsg{`,
 		`Authorization:` + fmt.Sprintf("%v", this.Authorization) + `,`,
 		`SessionCookie:` + fmt.Sprintf("%v", this.SessionCookie) + `,`,
+		`Headers:` + mapStringForHeaders + `,`,
 		`}`,
 	}, "")
 	return s
@@ -3093,6 +3133,133 @@ func (m *OutputMsg) Unmarshal(dAtA []byte) error {
 			}
 			m.SessionCookie = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Headers", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTemplateHandlerService
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthTemplateHandlerService
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthTemplateHandlerService
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.Headers == nil {
+				m.Headers = make(map[string]string)
+			}
+			var mapkey string
+			var mapvalue string
+			for iNdEx < postIndex {
+				entryPreIndex := iNdEx
+				var wire uint64
+				for shift := uint(0); ; shift += 7 {
+					if shift >= 64 {
+						return ErrIntOverflowTemplateHandlerService
+					}
+					if iNdEx >= l {
+						return io.ErrUnexpectedEOF
+					}
+					b := dAtA[iNdEx]
+					iNdEx++
+					wire |= uint64(b&0x7F) << shift
+					if b < 0x80 {
+						break
+					}
+				}
+				fieldNum := int32(wire >> 3)
+				if fieldNum == 1 {
+					var stringLenmapkey uint64
+					for shift := uint(0); ; shift += 7 {
+						if shift >= 64 {
+							return ErrIntOverflowTemplateHandlerService
+						}
+						if iNdEx >= l {
+							return io.ErrUnexpectedEOF
+						}
+						b := dAtA[iNdEx]
+						iNdEx++
+						stringLenmapkey |= uint64(b&0x7F) << shift
+						if b < 0x80 {
+							break
+						}
+					}
+					intStringLenmapkey := int(stringLenmapkey)
+					if intStringLenmapkey < 0 {
+						return ErrInvalidLengthTemplateHandlerService
+					}
+					postStringIndexmapkey := iNdEx + intStringLenmapkey
+					if postStringIndexmapkey < 0 {
+						return ErrInvalidLengthTemplateHandlerService
+					}
+					if postStringIndexmapkey > l {
+						return io.ErrUnexpectedEOF
+					}
+					mapkey = string(dAtA[iNdEx:postStringIndexmapkey])
+					iNdEx = postStringIndexmapkey
+				} else if fieldNum == 2 {
+					var stringLenmapvalue uint64
+					for shift := uint(0); ; shift += 7 {
+						if shift >= 64 {
+							return ErrIntOverflowTemplateHandlerService
+						}
+						if iNdEx >= l {
+							return io.ErrUnexpectedEOF
+						}
+						b := dAtA[iNdEx]
+						iNdEx++
+						stringLenmapvalue |= uint64(b&0x7F) << shift
+						if b < 0x80 {
+							break
+						}
+					}
+					intStringLenmapvalue := int(stringLenmapvalue)
+					if intStringLenmapvalue < 0 {
+						return ErrInvalidLengthTemplateHandlerService
+					}
+					postStringIndexmapvalue := iNdEx + intStringLenmapvalue
+					if postStringIndexmapvalue < 0 {
+						return ErrInvalidLengthTemplateHandlerService
+					}
+					if postStringIndexmapvalue > l {
+						return io.ErrUnexpectedEOF
+					}
+					mapvalue = string(dAtA[iNdEx:postStringIndexmapvalue])
+					iNdEx = postStringIndexmapvalue
+				} else {
+					iNdEx = entryPreIndex
+					skippy, err := skipTemplateHandlerService(dAtA[iNdEx:])
+					if err != nil {
+						return err
+					}
+					if skippy < 0 {
+						return ErrInvalidLengthTemplateHandlerService
+					}
+					if (iNdEx + skippy) > postIndex {
+						return io.ErrUnexpectedEOF
+					}
+					iNdEx += skippy
+				}
+			}
+			m.Headers[mapkey] = mapvalue
+			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipTemplateHandlerService(dAtA[iNdEx:])
@@ -12,6 +12,7 @@ type AuthServer struct {
 	TknEndpoint  string
 	AuthEndpoint string
 	UserInfoURL  string
+	Err          error
 }
 
 func NewAuthServer() *AuthServer {
@@ -47,6 +48,12 @@ func (m *AuthServer) SetKeySet(k keyset.KeySet) {
 	m.Keys = k
 }
 
+func (m *AuthServer) SyncError() error {
+	return m.Err
+}
+
+func (m *AuthServer) OnSync(handler func(err error)) {}
+
 func (m *AuthServer) GetTokens(authnMethod string, clientID string, clientSecret string, authorizationCode string, redirectURI string, refreshToken string) (*authserver.TokenResponse, error) {
 	return nil, nil
 }
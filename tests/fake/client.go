@@ -2,6 +2,7 @@ package fake
 
 import (
 	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/authserver"
+	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/pkg/apis/policies/v1"
 )
 
 type TokenResponse struct {
@@ -16,6 +17,7 @@ type Client struct {
 	ClientID      string
 	ClientSecret  string
 	Scopes        []string
+	Validation    v1.TokenValidation
 }
 
 func NewClient(tokenResponse *TokenResponse) *Client {
@@ -44,6 +46,10 @@ func (m *Client) Scope() string {
 	return "openid profile email"
 }
 
+func (m *Client) TokenValidation() v1.TokenValidation {
+	return m.Validation
+}
+
 func (m *Client) AuthorizationServer() authserver.AuthorizationServerService {
 	return m.Server
 }
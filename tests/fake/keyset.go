@@ -4,7 +4,11 @@ import "crypto"
 
 type KeySet struct {
 	url string
+	Err error
 }
 
 func (k *KeySet) PublicKeyURL() string                  { return k.url }
 func (k *KeySet) PublicKey(kid string) crypto.PublicKey { return nil }
+func (k *KeySet) SyncError() error                      { return k.Err }
+func (k *KeySet) Generation() uint64                    { return 1 }
+func (k *KeySet) OnSync(handler func(err error))        {}
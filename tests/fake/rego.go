@@ -0,0 +1,18 @@
+package fake
+
+import (
+	"github.com/ibm-cloud-security/app-identity-and-access-adapter/adapter/opa"
+)
+
+type RegoPolicy struct {
+	Allowed bool
+	Err     error
+	Input   *opa.Input
+	Query   string
+}
+
+func (p *RegoPolicy) Eval(query string, input opa.Input) (bool, error) {
+	p.Query = query
+	p.Input = &input
+	return p.Allowed, p.Err
+}